@@ -0,0 +1,259 @@
+package iataplaces
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Airline is a carrier from the OpenFlights airlines dataset.
+type Airline struct {
+	IATA     string `json:"iata,omitempty"`
+	ICAO     string `json:"icao,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Callsign string `json:"callsign,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Active   bool   `json:"active"`
+}
+
+// Code returns the IATA code, or the ICAO code for airlines without one.
+func (al Airline) Code() string {
+	if al.IATA != "" {
+		return al.IATA
+	}
+	return al.ICAO
+}
+
+// routeIndex links airlines and airports; built once by LoadRoutes and
+// never modified afterwards, so stores may share it.
+type routeIndex struct {
+	airports map[string][]string // airline code -> IATA codes, sorted
+	airlines map[string][]string // IATA code -> airline codes, sorted
+}
+
+// airlineIndex holds LoadAirlines data keyed by both codes; like
+// routeIndex it is replaced, never modified.
+type airlineIndex map[string]*Airline
+
+// LoadRoutesFromFile reads routes from a file; see LoadRoutes.
+func (s *Store) LoadRoutesFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open routes: %w", err)
+	}
+	defer f.Close()
+	return s.LoadRoutes(f)
+}
+
+// LoadRoutes reads the OpenFlights routes.dat format (airline, airline ID,
+// source airport, source ID, destination airport, destination ID,
+// codeshare, stops, equipment; no header) and records which airlines serve
+// which airports. Routes to airports not in the store are ignored. It
+// replaces any routes loaded before.
+func (s *Store) LoadRoutes(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	type pair struct{ airline, airport string }
+	var pairs []pair
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read route: %w", err)
+		}
+		if len(rec) < 5 {
+			continue
+		}
+		airline := openFlightsValue(rec[0])
+		if airline == "" {
+			continue
+		}
+		for _, code := range []string{rec[2], rec[4]} {
+			if code = openFlightsValue(code); len(code) == 3 {
+				pairs = append(pairs, pair{airline, code})
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	airports := map[string]map[string]bool{}
+	airlines := map[string]map[string]bool{}
+	for _, p := range pairs {
+		if _, ok := s.byIATA[p.airport]; !ok {
+			continue
+		}
+		if airports[p.airline] == nil {
+			airports[p.airline] = map[string]bool{}
+		}
+		airports[p.airline][p.airport] = true
+		if airlines[p.airport] == nil {
+			airlines[p.airport] = map[string]bool{}
+		}
+		airlines[p.airport][p.airline] = true
+	}
+	s.routes = &routeIndex{airports: sortedSets(airports), airlines: sortedSets(airlines)}
+	return nil
+}
+
+// LoadAirlinesFromFile reads airlines from a file; see LoadAirlines.
+func (s *Store) LoadAirlinesFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open airlines: %w", err)
+	}
+	defer f.Close()
+	return s.LoadAirlines(f)
+}
+
+// LoadAirlines reads the OpenFlights airlines.dat format (ID, name, alias,
+// IATA, ICAO, callsign, country, active; no header), so AirlinesAt can
+// name carriers and AirportsServedBy accepts either of an airline's codes.
+func (s *Store) LoadAirlines(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	idx := airlineIndex{}
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read airline: %w", err)
+		}
+		if len(rec) < 8 {
+			continue
+		}
+		al := &Airline{
+			Name:     openFlightsValue(rec[1]),
+			IATA:     strings.ToUpper(openFlightsValue(rec[3])),
+			ICAO:     strings.ToUpper(openFlightsValue(rec[4])),
+			Callsign: openFlightsValue(rec[5]),
+			Country:  openFlightsValue(rec[6]),
+			Active:   strings.EqualFold(openFlightsValue(rec[7]), "Y"),
+		}
+		// Codes are reused by defunct carriers; an active one wins.
+		for _, code := range []string{al.IATA, al.ICAO} {
+			if code == "" {
+				continue
+			}
+			if prev, ok := idx[code]; !ok || !prev.Active && al.Active {
+				idx[code] = al
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	s.airlines = idx
+	return nil
+}
+
+// AirportsServedBy returns the airports an airline flies to or from
+// according to the loaded routes, ordered by IATA code. airline is an IATA
+// or ICAO airline code.
+func (s *Store) AirportsServedBy(airline string) []*Airport {
+	if s == nil {
+		return nil
+	}
+	airline = normalizeCode(airline)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.routes == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var out []*Airport
+	for _, code := range s.airlineCodes(airline) {
+		for _, ap := range s.routes.airports[code] {
+			if a, ok := s.byIATA[ap]; ok && !seen[ap] {
+				seen[ap] = true
+				out = append(out, s.out(a))
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IATACode < out[j].IATACode })
+	return out
+}
+
+// AirlinesAt returns the airlines with routes at an airport, ordered by
+// code. Names and other details are filled in when LoadAirlines was
+// called.
+func (s *Store) AirlinesAt(code string) []Airline {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.routes == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var out []Airline
+	for _, c := range s.routes.airlines[normalizeCode(code)] {
+		al := Airline{IATA: c}
+		if len(c) == 3 {
+			al = Airline{ICAO: c}
+		}
+		if known, ok := s.airlines[c]; ok {
+			al = *known
+		}
+		if key := al.IATA + "/" + al.ICAO; !seen[key] {
+			seen[key] = true
+			out = append(out, al)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code() < out[j].Code() })
+	return out
+}
+
+// airlineCodes returns code plus the airline's other code when known.
+// s.mu must be held.
+func (s *Store) airlineCodes(code string) []string {
+	codes := []string{code}
+	if al, ok := s.airlines[code]; ok {
+		for _, c := range []string{al.IATA, al.ICAO} {
+			if c != "" && c != code {
+				codes = append(codes, c)
+			}
+		}
+	}
+	return codes
+}
+
+// openFlightsValue trims a field and maps the \N null marker to "".
+func openFlightsValue(v string) string {
+	v = strings.TrimSpace(v)
+	if v == `\N` || v == "-" {
+		return ""
+	}
+	return v
+}
+
+func sortedSets(m map[string]map[string]bool) map[string][]string {
+	out := make(map[string][]string, len(m))
+	for k, set := range m {
+		list := make([]string, 0, len(set))
+		for v := range set {
+			list = append(list, v)
+		}
+		sort.Strings(list)
+		out[k] = list
+	}
+	return out
+}
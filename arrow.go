@@ -0,0 +1,477 @@
+package iataplaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WriteArrow writes airports as an Apache Arrow IPC file (also known as
+// Feather v2) holding one record batch with the CSVColumns as typed
+// columns, for Arrow-based tools such as DuckDB, Polars or pyarrow.
+func WriteArrow(w io.Writer, airports []*Airport) error {
+	aw := &arrowWriter{w: w}
+	aw.write([]byte(arrowMagic + "\x00\x00"))
+	aw.message(arrowSchema(), nil)
+	batch, body := arrowRecordBatch(airports)
+	batchBlock := aw.message(batch, body)
+	aw.write(arrowEOS)
+
+	footer := arrowFooter([]arrowBlock{batchBlock})
+	aw.write(footer)
+	aw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	aw.write([]byte(arrowMagic))
+	return aw.err
+}
+
+// WriteArrowStream writes airports in the Arrow IPC streaming format, for
+// readers that consume Arrow over a pipe or socket.
+func WriteArrowStream(w io.Writer, airports []*Airport) error {
+	aw := &arrowWriter{w: w}
+	aw.message(arrowSchema(), nil)
+	batch, body := arrowRecordBatch(airports)
+	aw.message(batch, body)
+	aw.write(arrowEOS)
+	return aw.err
+}
+
+const arrowMagic = "ARROW1"
+
+// arrowEOS marks the end of an IPC stream: a continuation marker followed
+// by a zero metadata length.
+var arrowEOS = []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+
+// Flatbuffer enum and union values from the Arrow format's Schema.fbs and
+// Message.fbs.
+const (
+	arrowMetadataV5 = 4
+
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt           = 2
+	arrowTypeFloatingPoint = 3
+	arrowTypeUtf8          = 5
+	arrowTypeBool          = 6
+	arrowTypeTimestamp     = 10
+
+	arrowPrecisionDouble = 2
+	arrowUnitSecond      = 0
+)
+
+type arrowKind int
+
+const (
+	arrowUtf8 arrowKind = iota
+	arrowInt64
+	arrowFloat64
+	arrowBool
+	arrowTimestamp // seconds since the epoch, UTC
+)
+
+// arrowColumn describes one column and how to read it from an airport.
+// num serves both integer and timestamp columns; ok=false is a null.
+type arrowColumn struct {
+	name     string
+	kind     arrowKind
+	nullable bool
+	str      func(*Airport) string
+	num      func(*Airport) (v int64, ok bool)
+	flt      func(*Airport) float64
+	flag     func(*Airport) bool
+}
+
+func optionalInt(v *int64) (int64, bool) {
+	if v == nil {
+		return 0, false
+	}
+	return *v, true
+}
+
+// arrowColumns mirrors CSVColumns with native types.
+var arrowColumns = []arrowColumn{
+	{name: "id", kind: arrowInt64, num: func(a *Airport) (int64, bool) { return a.ID, true }},
+	{name: "ident", str: func(a *Airport) string { return a.Ident }},
+	{name: "type", str: func(a *Airport) string { return a.Type }},
+	{name: "name", str: func(a *Airport) string { return a.Name }},
+	{name: "latitude_deg", kind: arrowFloat64, flt: func(a *Airport) float64 { return a.LatitudeDeg }},
+	{name: "longitude_deg", kind: arrowFloat64, flt: func(a *Airport) float64 { return a.LongitudeDeg }},
+	{name: "elevation_ft", kind: arrowInt64, nullable: true, num: func(a *Airport) (int64, bool) { return optionalInt(a.ElevationFt) }},
+	{name: "continent", str: func(a *Airport) string { return a.Continent }},
+	{name: "country_name", str: func(a *Airport) string { return a.CountryName }},
+	{name: "iso_country", str: func(a *Airport) string { return a.IsoCountry }},
+	{name: "region_name", str: func(a *Airport) string { return a.RegionName }},
+	{name: "iso_region", str: func(a *Airport) string { return a.IsoRegion }},
+	{name: "local_region", str: func(a *Airport) string { return a.LocalRegion }},
+	{name: "municipality", str: func(a *Airport) string { return a.Municipality }},
+	{name: "scheduled_service", kind: arrowBool, flag: func(a *Airport) bool { return a.Scheduled }},
+	{name: "gps_code", str: func(a *Airport) string { return a.GPSCode }},
+	{name: "icao_code", str: func(a *Airport) string { return a.ICAOCode }},
+	{name: "iata_code", str: func(a *Airport) string { return a.IATACode }},
+	{name: "local_code", str: func(a *Airport) string { return a.LocalCode }},
+	{name: "home_link", str: func(a *Airport) string { return a.HomeLink }},
+	{name: "wikipedia_link", str: func(a *Airport) string { return a.WikipediaLink }},
+	{name: "keywords", str: func(a *Airport) string { return a.Keywords }},
+	{name: "score", kind: arrowInt64, nullable: true, num: func(a *Airport) (int64, bool) { return optionalInt(a.Score) }},
+	{name: "last_updated", kind: arrowTimestamp, nullable: true, num: func(a *Airport) (int64, bool) {
+		if a.LastUpdateTime == nil {
+			return 0, false
+		}
+		return a.LastUpdateTime.Unix(), true
+	}},
+}
+
+// arrowBlock locates a message in an IPC file, for the footer.
+type arrowBlock struct {
+	offset     int64
+	metaLength int32 // prefix, flatbuffer and padding
+	bodyLength int64
+}
+
+// arrowWriter writes encapsulated IPC messages, remembering the first
+// error and the number of bytes written.
+type arrowWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (aw *arrowWriter) write(p []byte) {
+	if aw.err != nil {
+		return
+	}
+	n, err := aw.w.Write(p)
+	aw.n += int64(n)
+	aw.err = err
+}
+
+// message writes a continuation marker, the metadata length, the
+// flatbuffer metadata padded to 8 bytes and the body.
+func (aw *arrowWriter) message(meta, body []byte) arrowBlock {
+	padded := align8(len(meta))
+	block := arrowBlock{offset: aw.n, metaLength: int32(8 + padded), bodyLength: int64(len(body))}
+	prefix := []byte{0xff, 0xff, 0xff, 0xff}
+	prefix = binary.LittleEndian.AppendUint32(prefix, uint32(padded))
+	aw.write(prefix)
+	aw.write(meta)
+	aw.write(make([]byte, padded-len(meta)))
+	aw.write(body)
+	return block
+}
+
+func align8(n int) int { return (n + 7) &^ 7 }
+
+// buildSchema adds the Schema table to b and returns its offset.
+func buildSchema(b *fbBuilder) uint32 {
+	fields := make([]uint32, len(arrowColumns))
+	for i, col := range arrowColumns {
+		name := b.createString(col.name)
+		var typeType byte
+		var typ uint32
+		switch col.kind {
+		case arrowUtf8:
+			typeType = arrowTypeUtf8
+			b.startTable(0)
+			typ = b.endTable()
+		case arrowInt64:
+			typeType = arrowTypeInt
+			b.startTable(2)
+			b.addInt32(0, 64)  // bitWidth
+			b.addBool(1, true) // is_signed
+			typ = b.endTable()
+		case arrowFloat64:
+			typeType = arrowTypeFloatingPoint
+			b.startTable(1)
+			b.addInt16(0, arrowPrecisionDouble)
+			typ = b.endTable()
+		case arrowBool:
+			typeType = arrowTypeBool
+			b.startTable(0)
+			typ = b.endTable()
+		case arrowTimestamp:
+			typeType = arrowTypeTimestamp
+			tz := b.createString("UTC")
+			b.startTable(2)
+			b.addOffset(1, tz)
+			b.addInt16(0, arrowUnitSecond)
+			typ = b.endTable()
+		}
+		children := b.createOffsetVector(nil)
+
+		b.startTable(7)
+		b.addOffset(0, name)
+		b.addOffset(3, typ)
+		b.addOffset(5, children)
+		b.addBool(1, col.nullable)
+		b.addByte(2, typeType)
+		fields[i] = b.endTable()
+	}
+	fieldVec := b.createOffsetVector(fields)
+
+	b.startTable(4)
+	b.addOffset(1, fieldVec)
+	b.addBool(0, false) // little-endian
+	return b.endTable()
+}
+
+// arrowSchema returns the metadata of the Schema message.
+func arrowSchema() []byte {
+	b := &fbBuilder{}
+	schema := buildSchema(b)
+	return b.finish(arrowMessage(b, arrowHeaderSchema, schema, 0))
+}
+
+func arrowMessage(b *fbBuilder, headerType byte, header uint32, bodyLength int64) uint32 {
+	b.startTable(5)
+	b.addInt64(3, bodyLength)
+	b.addOffset(2, header)
+	b.addInt16(0, arrowMetadataV5)
+	b.addByte(1, headerType)
+	return b.endTable()
+}
+
+// arrowRecordBatch encodes airports as one record batch, returning the
+// message metadata and body.
+func arrowRecordBatch(airports []*Airport) (meta, body []byte) {
+	n := len(airports)
+	type fieldNode struct{ length, nulls int64 }
+	type buffer struct{ offset, length int64 }
+	var nodes []fieldNode
+	var buffers []buffer
+	var bodyBuf bytes.Buffer
+
+	addBuffer := func(p []byte) {
+		buffers = append(buffers, buffer{int64(bodyBuf.Len()), int64(len(p))})
+		bodyBuf.Write(p)
+		bodyBuf.Write(make([]byte, align8(len(p))-len(p)))
+	}
+
+	for _, col := range arrowColumns {
+		switch col.kind {
+		case arrowUtf8:
+			offsets := make([]byte, 0, 4*(n+1))
+			var data []byte
+			offsets = binary.LittleEndian.AppendUint32(offsets, 0)
+			for _, a := range airports {
+				data = append(data, col.str(a)...)
+				offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(data)))
+			}
+			nodes = append(nodes, fieldNode{int64(n), 0})
+			addBuffer(nil)
+			addBuffer(offsets)
+			addBuffer(data)
+		case arrowInt64, arrowTimestamp:
+			values := make([]byte, 0, 8*n)
+			validity := make([]byte, (n+7)/8)
+			nulls := 0
+			for i, a := range airports {
+				v, ok := col.num(a)
+				if ok {
+					validity[i/8] |= 1 << (i % 8)
+				} else {
+					nulls++
+				}
+				values = binary.LittleEndian.AppendUint64(values, uint64(v))
+			}
+			if nulls == 0 {
+				validity = nil
+			}
+			nodes = append(nodes, fieldNode{int64(n), int64(nulls)})
+			addBuffer(validity)
+			addBuffer(values)
+		case arrowFloat64:
+			values := make([]byte, 0, 8*n)
+			for _, a := range airports {
+				values = binary.LittleEndian.AppendUint64(values, math.Float64bits(col.flt(a)))
+			}
+			nodes = append(nodes, fieldNode{int64(n), 0})
+			addBuffer(nil)
+			addBuffer(values)
+		case arrowBool:
+			bits := make([]byte, (n+7)/8)
+			for i, a := range airports {
+				if col.flag(a) {
+					bits[i/8] |= 1 << (i % 8)
+				}
+			}
+			nodes = append(nodes, fieldNode{int64(n), 0})
+			addBuffer(nil)
+			addBuffer(bits)
+		}
+	}
+
+	b := &fbBuilder{}
+	nodeVec := b.createStructVector(16, len(nodes), func(i int) {
+		b.prependInt64(nodes[i].nulls)
+		b.prependInt64(nodes[i].length)
+	})
+	bufferVec := b.createStructVector(16, len(buffers), func(i int) {
+		b.prependInt64(buffers[i].length)
+		b.prependInt64(buffers[i].offset)
+	})
+	b.startTable(5)
+	b.addInt64(0, int64(n))
+	b.addOffset(1, nodeVec)
+	b.addOffset(2, bufferVec)
+	batch := b.endTable()
+
+	body = bodyBuf.Bytes()
+	return b.finish(arrowMessage(b, arrowHeaderRecordBatch, batch, int64(len(body)))), body
+}
+
+// arrowFooter returns the footer flatbuffer of an IPC file.
+func arrowFooter(batches []arrowBlock) []byte {
+	b := &fbBuilder{}
+	schema := buildSchema(b)
+	blocks := func(list []arrowBlock) uint32 {
+		return b.createStructVector(24, len(list), func(i int) {
+			b.prependInt64(list[i].bodyLength)
+			b.prependInt32(0) // padding
+			b.prependInt32(list[i].metaLength)
+			b.prependInt64(list[i].offset)
+		})
+	}
+	dictionaries := blocks(nil)
+	recordBatches := blocks(batches)
+
+	b.startTable(5)
+	b.addOffset(1, schema)
+	b.addOffset(2, dictionaries)
+	b.addOffset(3, recordBatches)
+	b.addInt16(0, arrowMetadataV5)
+	return b.finish(b.endTable())
+}
+
+// fbBuilder is a minimal FlatBuffers builder, enough for the Arrow
+// metadata. Like the reference implementation it builds back to front:
+// children are written before the tables that refer to them, and offsets
+// are measured from the end of the buffer.
+type fbBuilder struct {
+	buf      []byte // buf[0] is the lowest address built so far
+	minAlign int
+
+	fields     []uint32 // offsets of the current table's fields; 0 if absent
+	tableStart uint32
+}
+
+func (b *fbBuilder) offset() uint32 { return uint32(len(b.buf)) }
+
+func (b *fbBuilder) prepend(p []byte) {
+	b.buf = append(append(make([]byte, 0, len(p)+len(b.buf)), p...), b.buf...)
+}
+
+// prep pads so that a value of size bytes written after additional more
+// bytes ends up aligned.
+func (b *fbBuilder) prep(size, additional int) {
+	if size > b.minAlign {
+		b.minAlign = size
+	}
+	padding := -(len(b.buf) + additional) & (size - 1)
+	b.prepend(make([]byte, padding))
+}
+
+func (b *fbBuilder) prependByte(v byte) { b.prep(1, 0); b.prepend([]byte{v}) }
+
+func (b *fbBuilder) prependInt16(v int16) {
+	b.prep(2, 0)
+	b.prepend(binary.LittleEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (b *fbBuilder) prependInt32(v int32) {
+	b.prep(4, 0)
+	b.prepend(binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (b *fbBuilder) prependInt64(v int64) {
+	b.prep(8, 0)
+	b.prepend(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+}
+
+// prependOffset writes a reference to an object built earlier.
+func (b *fbBuilder) prependOffset(off uint32) {
+	b.prep(4, 0)
+	b.prepend(binary.LittleEndian.AppendUint32(nil, b.offset()+4-off))
+}
+
+func (b *fbBuilder) createString(s string) uint32 {
+	b.prep(4, len(s)+1)
+	b.prepend(append([]byte(s), 0))
+	b.prependInt32(int32(len(s)))
+	return b.offset()
+}
+
+func (b *fbBuilder) createOffsetVector(offs []uint32) uint32 {
+	b.prep(4, 4*len(offs))
+	for i := len(offs) - 1; i >= 0; i-- {
+		b.prependOffset(offs[i])
+	}
+	b.prependInt32(int32(len(offs)))
+	return b.offset()
+}
+
+// createStructVector writes n 8-byte aligned structs of size bytes each;
+// write(i) prepends the fields of struct i, last field first.
+func (b *fbBuilder) createStructVector(size, n int, write func(i int)) uint32 {
+	b.prep(4, size*n)
+	b.prep(8, size*n)
+	for i := n - 1; i >= 0; i-- {
+		write(i)
+	}
+	b.prependInt32(int32(n))
+	return b.offset()
+}
+
+func (b *fbBuilder) startTable(numFields int) {
+	b.fields = make([]uint32, numFields)
+	b.tableStart = b.offset()
+}
+
+func (b *fbBuilder) addByte(slot int, v byte)   { b.prependByte(v); b.fields[slot] = b.offset() }
+func (b *fbBuilder) addInt16(slot int, v int16) { b.prependInt16(v); b.fields[slot] = b.offset() }
+func (b *fbBuilder) addInt32(slot int, v int32) { b.prependInt32(v); b.fields[slot] = b.offset() }
+func (b *fbBuilder) addInt64(slot int, v int64) { b.prependInt64(v); b.fields[slot] = b.offset() }
+func (b *fbBuilder) addOffset(slot int, off uint32) {
+	b.prependOffset(off)
+	b.fields[slot] = b.offset()
+}
+
+func (b *fbBuilder) addBool(slot int, v bool) {
+	var x byte
+	if v {
+		x = 1
+	}
+	b.addByte(slot, x)
+}
+
+// endTable writes the table's vtable and returns the table's offset.
+func (b *fbBuilder) endTable() uint32 {
+	b.prependInt32(0) // vtable offset, patched below
+	table := b.offset()
+
+	n := len(b.fields)
+	for n > 0 && b.fields[n-1] == 0 {
+		n--
+	}
+	vtable := make([]uint16, 2+n)
+	vtable[0] = uint16(2 * len(vtable))
+	vtable[1] = uint16(table - b.tableStart)
+	for i, f := range b.fields[:n] {
+		if f != 0 {
+			vtable[2+i] = uint16(table - f)
+		}
+	}
+	for i := len(vtable) - 1; i >= 0; i-- {
+		b.prependInt16(int16(vtable[i]))
+	}
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-int(table):], b.offset()-table)
+	b.fields = nil
+	return table
+}
+
+// finish writes the root offset and returns the finished buffer.
+func (b *fbBuilder) finish(root uint32) []byte {
+	b.prep(max(b.minAlign, 4), 4)
+	b.prependOffset(root)
+	return b.buf
+}
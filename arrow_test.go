@@ -0,0 +1,383 @@
+package iataplaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fbTable reads a FlatBuffers table, independently of fbBuilder.
+type fbTable struct {
+	buf []byte
+	pos int
+}
+
+func fbRoot(buf []byte) fbTable {
+	return fbTable{buf, int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of a field, or 0 when it is absent.
+func (t fbTable) field(slot int) int {
+	vt := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*slot >= int(binary.LittleEndian.Uint16(t.buf[vt:])) {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vt+4+2*slot:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTable) uint8(slot int) uint8 {
+	if p := t.field(slot); p != 0 {
+		return t.buf[p]
+	}
+	return 0
+}
+
+func (t fbTable) int16(slot int) int16 {
+	if p := t.field(slot); p != 0 {
+		return int16(binary.LittleEndian.Uint16(t.buf[p:]))
+	}
+	return 0
+}
+
+func (t fbTable) int32(slot int) int32 {
+	if p := t.field(slot); p != 0 {
+		return int32(binary.LittleEndian.Uint32(t.buf[p:]))
+	}
+	return 0
+}
+
+func (t fbTable) int64(slot int) int64 {
+	if p := t.field(slot); p != 0 {
+		return int64(binary.LittleEndian.Uint64(t.buf[p:]))
+	}
+	return 0
+}
+
+// deref follows the offset stored in a field.
+func (t fbTable) deref(slot int) int {
+	p := t.field(slot)
+	if p == 0 {
+		return 0
+	}
+	return p + int(binary.LittleEndian.Uint32(t.buf[p:]))
+}
+
+func (t fbTable) table(slot int) fbTable { return fbTable{t.buf, t.deref(slot)} }
+
+func (t fbTable) string(slot int) string {
+	p := t.deref(slot)
+	if p == 0 {
+		return ""
+	}
+	n := int(binary.LittleEndian.Uint32(t.buf[p:]))
+	return string(t.buf[p+4 : p+4+n])
+}
+
+// vector returns the length of a vector field and the position of its
+// first element.
+func (t fbTable) vector(slot int) (n, start int) {
+	p := t.deref(slot)
+	if p == 0 {
+		return 0, 0
+	}
+	return int(binary.LittleEndian.Uint32(t.buf[p:])), p + 4
+}
+
+func (t fbTable) tableAt(vecPos int) fbTable {
+	return fbTable{t.buf, vecPos + int(binary.LittleEndian.Uint32(t.buf[vecPos:]))}
+}
+
+// arrowField is a decoded Schema field.
+type arrowField struct {
+	name     string
+	nullable bool
+	typeType uint8
+	typ      fbTable
+}
+
+func readArrowSchema(schema fbTable) []arrowField {
+	n, start := schema.vector(1)
+	fields := make([]arrowField, n)
+	for i := range fields {
+		f := schema.tableAt(start + 4*i)
+		fields[i] = arrowField{name: f.string(0), nullable: f.uint8(1) == 1, typeType: f.uint8(2), typ: f.table(3)}
+	}
+	return fields
+}
+
+// readArrowMessage reads the encapsulated message at data[pos:] and
+// returns its Message table, its body and the position after it.
+func readArrowMessage(t *testing.T, data []byte, pos int) (fbTable, []byte, int) {
+	t.Helper()
+	if got := binary.LittleEndian.Uint32(data[pos:]); got != 0xffffffff {
+		t.Fatalf("no continuation marker at %d: %#x", pos, got)
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[pos+4:]))
+	if metaLen%8 != 0 {
+		t.Errorf("metadata length %d isn't a multiple of 8", metaLen)
+	}
+	meta := data[pos+8 : pos+8+metaLen]
+	msg := fbRoot(meta)
+	if v := msg.int16(0); v != arrowMetadataV5 {
+		t.Errorf("metadata version %d, want V5", v)
+	}
+	bodyStart := pos + 8 + metaLen
+	bodyLen := int(msg.int64(3))
+	return msg, data[bodyStart : bodyStart+bodyLen], bodyStart + bodyLen
+}
+
+// readArrowBatch decodes a record batch into one slice of values per
+// column; nulls are nil.
+func readArrowBatch(t *testing.T, fields []arrowField, batch fbTable, body []byte) [][]any {
+	t.Helper()
+	rows := int(batch.int64(0))
+	nNodes, nodes := batch.vector(1)
+	nBufs, bufs := batch.vector(2)
+	if nNodes != len(fields) {
+		t.Fatalf("%d field nodes for %d fields", nNodes, len(fields))
+	}
+	buffer := func(i int) []byte {
+		off := binary.LittleEndian.Uint64(batch.buf[bufs+16*i:])
+		n := binary.LittleEndian.Uint64(batch.buf[bufs+16*i+8:])
+		if off%8 != 0 {
+			t.Errorf("buffer %d at unaligned offset %d", i, off)
+		}
+		return body[off : off+n]
+	}
+	bit := func(bits []byte, i int) bool { return bits[i/8]&(1<<(i%8)) != 0 }
+
+	cols := make([][]any, len(fields))
+	b := 0
+	for c, f := range fields {
+		length := int(binary.LittleEndian.Uint64(batch.buf[nodes+16*c:]))
+		nulls := int(binary.LittleEndian.Uint64(batch.buf[nodes+16*c+8:]))
+		if length != rows {
+			t.Errorf("%s: length %d, want %d", f.name, length, rows)
+		}
+		validity := buffer(b)
+		if nulls > 0 && len(validity) == 0 {
+			t.Errorf("%s: %d nulls without a validity bitmap", f.name, nulls)
+		}
+		if nulls > 0 && !f.nullable {
+			t.Errorf("%s: nulls in a non-nullable field", f.name)
+		}
+		valid := func(i int) bool { return len(validity) == 0 || bit(validity, i) }
+		values := buffer(b + 1)
+		col := make([]any, rows)
+		counted := 0
+		switch f.typeType {
+		case arrowTypeUtf8:
+			data := buffer(b + 2)
+			for i := range col {
+				lo := binary.LittleEndian.Uint32(values[4*i:])
+				hi := binary.LittleEndian.Uint32(values[4*i+4:])
+				col[i] = string(data[lo:hi])
+			}
+			b += 3
+		case arrowTypeInt, arrowTypeTimestamp:
+			for i := range col {
+				if valid(i) {
+					col[i] = int64(binary.LittleEndian.Uint64(values[8*i:]))
+				} else {
+					counted++
+				}
+			}
+			b += 2
+		case arrowTypeFloatingPoint:
+			for i := range col {
+				col[i] = math.Float64frombits(binary.LittleEndian.Uint64(values[8*i:]))
+			}
+			b += 2
+		case arrowTypeBool:
+			for i := range col {
+				col[i] = bit(values, i)
+			}
+			b += 2
+		default:
+			t.Fatalf("%s: unexpected type %d", f.name, f.typeType)
+		}
+		if counted != nulls {
+			t.Errorf("%s: null count %d, validity bitmap has %d", f.name, nulls, counted)
+		}
+		cols[c] = col
+	}
+	if b != nBufs {
+		t.Errorf("read %d buffers of %d", b, nBufs)
+	}
+	return cols
+}
+
+// checkArrowData checks the schema and the batch decoded from testCSV.
+func checkArrowData(t *testing.T, fields []arrowField, cols [][]any) {
+	t.Helper()
+	var names []string
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	if !slices.Equal(names, CSVColumns) {
+		t.Fatalf("fields = %v, want %v", names, CSVColumns)
+	}
+	for _, f := range fields {
+		wantNullable := f.name == "elevation_ft" || f.name == "score" || f.name == "last_updated"
+		if f.nullable != wantNullable {
+			t.Errorf("%s: nullable = %v", f.name, f.nullable)
+		}
+		switch f.name {
+		case "id", "elevation_ft", "score":
+			if f.typeType != arrowTypeInt || f.typ.int32(0) != 64 || f.typ.uint8(1) != 1 {
+				t.Errorf("%s: not a signed 64-bit int", f.name)
+			}
+		case "latitude_deg", "longitude_deg":
+			if f.typeType != arrowTypeFloatingPoint || f.typ.int16(0) != arrowPrecisionDouble {
+				t.Errorf("%s: not a double", f.name)
+			}
+		case "scheduled_service":
+			if f.typeType != arrowTypeBool {
+				t.Errorf("%s: type %d, want bool", f.name, f.typeType)
+			}
+		case "last_updated":
+			if f.typeType != arrowTypeTimestamp || f.typ.int16(0) != arrowUnitSecond || f.typ.string(1) != "UTC" {
+				t.Errorf("%s: not a UTC timestamp in seconds", f.name)
+			}
+		default:
+			if f.typeType != arrowTypeUtf8 {
+				t.Errorf("%s: type %d, want utf8", f.name, f.typeType)
+			}
+		}
+	}
+
+	row := func(code string) map[string]any {
+		col := slices.Index(names, "iata_code")
+		for i, v := range cols[col] {
+			if v == code {
+				m := make(map[string]any)
+				for c, name := range names {
+					m[name] = cols[c][i]
+				}
+				return m
+			}
+		}
+		t.Fatalf("no row for %s", code)
+		return nil
+	}
+	lhr := row("LHR")
+	want := map[string]any{
+		"id":                int64(2434),
+		"ident":             "EGLL",
+		"name":              "London Heathrow Airport",
+		"latitude_deg":      51.4706,
+		"longitude_deg":     -0.461941,
+		"elevation_ft":      int64(83),
+		"scheduled_service": true,
+		"keywords":          "LON, Londres",
+		"local_code":        "",
+		"score":             int64(1251675),
+		"last_updated":      time.Date(2022, 10, 18, 18, 48, 50, 0, time.UTC).Unix(),
+	}
+	for name, v := range want {
+		if lhr[name] != v {
+			t.Errorf("LHR %s = %#v, want %#v", name, lhr[name], v)
+		}
+	}
+	zzv := row("ZZV")
+	for _, name := range []string{"elevation_ft", "score", "last_updated"} {
+		if zzv[name] != nil {
+			t.Errorf("ZZV %s = %#v, want null", name, zzv[name])
+		}
+	}
+	if zzv["scheduled_service"] != false {
+		t.Errorf("ZZV scheduled_service = %v", zzv["scheduled_service"])
+	}
+}
+
+func TestWriteArrow(t *testing.T) {
+	airports := loadTestStore(t).All()
+	var buf bytes.Buffer
+	if err := WriteArrow(&buf, airports); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("ARROW1\x00\x00")) || !bytes.HasSuffix(data, []byte("ARROW1")) {
+		t.Fatal("missing ARROW1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-10:]))
+	footer := fbRoot(data[len(data)-10-footerLen : len(data)-10])
+	if v := footer.int16(0); v != arrowMetadataV5 {
+		t.Errorf("footer version %d, want V5", v)
+	}
+	fields := readArrowSchema(footer.table(1))
+
+	// The schema message comes first, then the batch the footer points at.
+	msg, _, _ := readArrowMessage(t, data, 8)
+	if msg.uint8(1) != arrowHeaderSchema {
+		t.Fatalf("first message has header type %d, want schema", msg.uint8(1))
+	}
+	if got := readArrowSchema(msg.table(2)); len(got) != len(fields) {
+		t.Errorf("schema message has %d fields, footer %d", len(got), len(fields))
+	}
+
+	n, blocks := footer.vector(3)
+	if n != 1 {
+		t.Fatalf("%d record batches, want 1", n)
+	}
+	offset := int(binary.LittleEndian.Uint64(footer.buf[blocks:]))
+	metaLen := int(binary.LittleEndian.Uint32(footer.buf[blocks+8:]))
+	bodyLen := int(binary.LittleEndian.Uint64(footer.buf[blocks+16:]))
+	msg, body, _ := readArrowMessage(t, data, offset)
+	if msg.uint8(1) != arrowHeaderRecordBatch {
+		t.Fatalf("block points at header type %d, want a record batch", msg.uint8(1))
+	}
+	if 8+int(binary.LittleEndian.Uint32(data[offset+4:])) != metaLen || len(body) != bodyLen {
+		t.Errorf("block lengths %d/%d don't match the message", metaLen, bodyLen)
+	}
+	batch := msg.table(2)
+	if rows := batch.int64(0); rows != int64(len(airports)) {
+		t.Errorf("batch has %d rows, want %d", rows, len(airports))
+	}
+	checkArrowData(t, fields, readArrowBatch(t, fields, batch, body))
+}
+
+func TestWriteArrowStream(t *testing.T) {
+	airports := loadTestStore(t).All()
+	var buf bytes.Buffer
+	if err := WriteArrowStream(&buf, airports); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	msg, _, pos := readArrowMessage(t, data, 0)
+	if msg.uint8(1) != arrowHeaderSchema {
+		t.Fatalf("first message has header type %d, want schema", msg.uint8(1))
+	}
+	fields := readArrowSchema(msg.table(2))
+	msg, body, pos := readArrowMessage(t, data, pos)
+	if msg.uint8(1) != arrowHeaderRecordBatch {
+		t.Fatalf("second message has header type %d, want a record batch", msg.uint8(1))
+	}
+	checkArrowData(t, fields, readArrowBatch(t, fields, msg.table(2), body))
+	if !bytes.Equal(data[pos:], arrowEOS) {
+		t.Errorf("stream ends with % x, want the end-of-stream marker", data[pos:])
+	}
+}
+
+func TestWriteArrowEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArrowStream(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	msg, _, pos := readArrowMessage(t, buf.Bytes(), 0)
+	fields := readArrowSchema(msg.table(2))
+	msg, body, _ := readArrowMessage(t, buf.Bytes(), pos)
+	cols := readArrowBatch(t, fields, msg.table(2), body)
+	for c, col := range cols {
+		if len(col) != 0 {
+			t.Errorf("%s has %d values", fields[c].name, len(col))
+		}
+	}
+}
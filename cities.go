@@ -0,0 +1,103 @@
+package iataplaces
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownCity is returned (wrapped) when no row of the dataset names a
+// city.
+var ErrUnknownCity = errors.New("unknown city")
+
+// cityIndex locates municipalities by the mean position of every dataset
+// row naming them, including rows without an IATA code, so towns served
+// only by a heliport or airstrip still have a position.
+type cityIndex map[cityKey]cityPoint
+
+type cityKey struct {
+	country string // ISO code, upper case
+	city    string // lower case
+}
+
+type cityPoint struct {
+	latSum, lonSum float64
+	n              int
+}
+
+func (ci cityIndex) add(country, municipality string, lat, lon float64) {
+	country = toUpperASCII(country)
+	for _, name := range cityNames(municipality) {
+		k := cityKey{country, name}
+		p := ci[k]
+		p.latSum += lat
+		p.lonSum += lon
+		p.n++
+		ci[k] = p
+	}
+}
+
+// cityNames returns the lookup names for a municipality field: the whole
+// value and, for values like "Luton, Bedfordshire" or "Paris (Orly)", the
+// part before the first comma or parenthesis.
+func cityNames(municipality string) []string {
+	full := strings.ToLower(strings.TrimSpace(municipality))
+	if full == "" {
+		return nil
+	}
+	if i := strings.IndexAny(full, ",("); i > 0 {
+		if short := strings.TrimSpace(full[:i]); short != "" {
+			return []string{full, short}
+		}
+	}
+	return []string{full}
+}
+
+// CityLocation returns the position of a city named in the dataset's
+// municipality column, averaged over all rows naming it. country is an
+// ISO code; when empty, the city must be unambiguous.
+func (s *Store) CityLocation(city, country string) (lat, lon float64, err error) {
+	name := strings.ToLower(strings.TrimSpace(city))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if country != "" {
+		p, ok := s.cities[cityKey{toUpperASCII(country), name}]
+		if !ok {
+			return 0, 0, fmt.Errorf("iataplaces: %w %q in %s", ErrUnknownCity, city, toUpperASCII(country))
+		}
+		return p.latSum / float64(p.n), p.lonSum / float64(p.n), nil
+	}
+
+	var found []cityKey
+	for k := range s.cities {
+		if k.city == name {
+			found = append(found, k)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return 0, 0, fmt.Errorf("iataplaces: %w %q", ErrUnknownCity, city)
+	case 1:
+		p := s.cities[found[0]]
+		return p.latSum / float64(p.n), p.lonSum / float64(p.n), nil
+	}
+	countries := make([]string, len(found))
+	for i, k := range found {
+		countries[i] = k.country
+	}
+	sort.Strings(countries)
+	return 0, 0, fmt.Errorf("iataplaces: city %q is in several countries (%s); pass one", city, strings.Join(countries, ", "))
+}
+
+// AirportsNearCity returns up to n airports closest to a city, nearest
+// first, locating the city from the dataset itself; see CityLocation. It
+// covers towns without an airport of their own and takes the same options
+// as Nearest.
+func (s *Store) AirportsNearCity(city, country string, n int, opts ...NearestOption) ([]NearbyAirport, error) {
+	lat, lon, err := s.CityLocation(city, country)
+	if err != nil {
+		return nil, err
+	}
+	return s.Nearest(lat, lon, n, opts...), nil
+}
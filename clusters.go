@@ -0,0 +1,108 @@
+package iataplaces
+
+import (
+	"math"
+	"sort"
+)
+
+// BBox is a longitude/latitude rectangle. MinLon > MaxLon describes a box
+// crossing the antimeridian.
+type BBox struct {
+	MinLon float64 `json:"min_lon"`
+	MinLat float64 `json:"min_lat"`
+	MaxLon float64 `json:"max_lon"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+// Contains reports whether the point lies in the box, edges included.
+func (b BBox) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+func (b *BBox) extend(lat, lon float64) {
+	b.MinLat, b.MaxLat = math.Min(b.MinLat, lat), math.Max(b.MaxLat, lat)
+	b.MinLon, b.MaxLon = math.Min(b.MinLon, lon), math.Max(b.MaxLon, lon)
+}
+
+// Cluster is a group of nearby airports drawn as one map marker.
+type Cluster struct {
+	Lat    float64  `json:"lat"` // mean position of the members
+	Lon    float64  `json:"lon"`
+	Count  int      `json:"count"`
+	Bounds BBox     `json:"bounds"`  // zoom to this to split the cluster
+	Top    *Airport `json:"airport"` // the largest member, for labels; the only one when Count is 1
+}
+
+// clusterCellPx is the side of a clustering cell in 256-pixel tile pixels,
+// roughly the spacing at which markers stop overlapping.
+const clusterCellPx = 64
+
+// Clusters groups the airports inside bbox for a web map at the given
+// zoom level (0-22, as in slippy map tiles). Airports falling into the same
+// 64-pixel cell of the Web Mercator grid form one cluster, so marker counts
+// stay bounded at low zoom and every airport stands alone once zoomed in.
+// Clusters are ordered by size, largest first.
+func (s *Store) Clusters(bbox BBox, zoom int) []Cluster {
+	zoom = min(max(zoom, 0), 22)
+	cells := float64(int64(256/clusterCellPx) << zoom)
+
+	type cellKey struct{ x, y int64 }
+	groups := map[cellKey]*Cluster{}
+	var order []cellKey
+	for _, a := range s.Filter(func(a *Airport) bool { return bbox.Contains(a.LatitudeDeg, a.LongitudeDeg) }) {
+		k := cellKey{
+			x: int64(mercatorX(a.LongitudeDeg) * cells),
+			y: int64(mercatorY(a.LatitudeDeg) * cells),
+		}
+		c := groups[k]
+		if c == nil {
+			c = &Cluster{
+				Bounds: BBox{MinLon: a.LongitudeDeg, MinLat: a.LatitudeDeg, MaxLon: a.LongitudeDeg, MaxLat: a.LatitudeDeg},
+				Top:    a,
+			}
+			groups[k] = c
+			order = append(order, k)
+		}
+		c.Count++
+		c.Lat += a.LatitudeDeg
+		c.Lon += a.LongitudeDeg
+		c.Bounds.extend(a.LatitudeDeg, a.LongitudeDeg)
+		if preferAirport(a, c.Top) {
+			c.Top = a
+		}
+	}
+
+	out := make([]Cluster, len(order))
+	for i, k := range order {
+		c := groups[k]
+		c.Lat /= float64(c.Count)
+		c.Lon /= float64(c.Count)
+		out[i] = *c
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+// maxMercatorLat is where Web Mercator maps are cut off.
+const maxMercatorLat = 85.05112878
+
+// mercatorX maps a longitude to [0, 1) across the Web Mercator world.
+func mercatorX(lon float64) float64 {
+	x := (lon + 180) / 360
+	return math.Min(math.Max(x, 0), math.Nextafter(1, 0))
+}
+
+// mercatorY maps a latitude to [0, 1) from the top of the Web Mercator
+// world, clamping the poles.
+func mercatorY(lat float64) float64 {
+	lat = math.Min(math.Max(lat, -maxMercatorLat), maxMercatorLat)
+	sin := math.Sin(radians(lat))
+	y := 0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)
+	return math.Min(math.Max(y, 0), math.Nextafter(1, 0))
+}
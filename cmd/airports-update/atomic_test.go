@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := writeFile(t, "manifest.json", "old")
+	if err := writeFileAtomic(path, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "new" {
+		t.Errorf("file holds %q", b)
+	}
+	if names := dirNames(t, filepath.Dir(path)); len(names) != 1 {
+		t.Errorf("directory holds %v", names)
+	}
+	if err := writeFileAtomic(filepath.Join(path, "x"), []byte("new")); err == nil {
+		t.Error("write under a file succeeded")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "airports-latest.csv")
+	touch(t, dir, "airports-latest.csv")
+	src := writeFile(t, "airports.csv.tmp", testAirports)
+	if err := publish(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != testAirports {
+		t.Errorf("published file holds %q", b)
+	}
+	if fileExists(src) {
+		t.Error("source still exists")
+	}
+	// A failed publish leaves the destination alone.
+	if err := publish(src, dst); err == nil {
+		t.Error("publishing a missing file succeeded")
+	}
+	if b, _ := os.ReadFile(dst); string(b) != testAirports {
+		t.Errorf("failed publish changed the destination to %q", b)
+	}
+
+	if err := copyFile(dst, filepath.Join(dir, "copy.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "copy.csv")); string(b) != testAirports {
+		t.Errorf("copy holds %q", b)
+	}
+}
+
+func TestRunLeavesNoTemporaryFiles(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.binary = true
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range dirNames(t, u.outDir) {
+		if strings.HasSuffix(name, ".tmp") {
+			t.Errorf("%s left behind", name)
+		}
+	}
+}
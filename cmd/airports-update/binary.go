@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// binaryLatestName is the pre-parsed airports store written by -binary.
+const binaryLatestName = "airports-latest.bin"
+
+// writeBinary parses the airports CSV at csvPath and writes the resulting
+// store to binPath in the gob format read by iataplaces.LoadFromGob, so
+// services can skip CSV parsing at startup. The file is replaced by rename,
+// never written in place.
+func writeBinary(csvPath, binPath string) error {
+	r, err := openCSV(csvPath)
+	if err != nil {
+		return err
+	}
+	store, err := iataplaces.LoadFromReader(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", csvPath, err)
+	}
+
+	tmp := binPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := iataplaces.WriteGob(f, store.All()); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return publish(tmp, binPath)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func TestWriteBinary(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.binary = true
+	u.compress, u.compressExt = "gzip", ".gz" // the binary is parsed from the compressed snapshot
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	binPath := filepath.Join(u.outDir, binaryLatestName)
+	if err := verifyChecksum(binPath); err != nil {
+		t.Error(err)
+	}
+	f, err := os.Open(binPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	store, err := iataplaces.LoadFromGob(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	for _, a := range store.All() {
+		codes = append(codes, a.IATACode)
+	}
+	slices.Sort(codes)
+	if !slices.Equal(codes, []string{"JFK", "LGW", "LHR"}) {
+		t.Errorf("binary store has %v", codes)
+	}
+	if fileExists(binPath + ".tmp") {
+		t.Error("temporary file left behind")
+	}
+
+	dir := t.TempDir()
+	if err := writeBinary(filepath.Join(dir, "missing.csv"), filepath.Join(dir, "x.bin")); err == nil {
+		t.Error("writeBinary of a missing CSV succeeded")
+	}
+	if fileExists(filepath.Join(dir, "x.bin")) {
+		t.Error("failed writeBinary left a file")
+	}
+}
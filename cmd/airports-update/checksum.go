@@ -0,0 +1,55 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sha256File returns the hex SHA-256 of a file's contents.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeChecksum writes path.sha256 in sha256sum's format, so it can also
+// be checked with "sha256sum -c".
+func writeChecksum(path, sum string) error {
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return writeFileAtomic(path+".sha256", []byte(line))
+}
+
+// verifyChecksum checks path against the digest in path.sha256.
+func verifyChecksum(path string) error {
+	b, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return fmt.Errorf("read checksum: %w", err)
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return fmt.Errorf("%s.sha256 is empty", path)
+	}
+	want := strings.ToLower(fields[0])
+
+	got, err := sha256File(path)
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", path, err)
+	}
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", path, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	path := writeFile(t, "airports.csv", testAirports)
+	sum, err := sha256File(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte(testAirports))
+	if sum != hex.EncodeToString(want[:]) {
+		t.Errorf("sha256File = %s", sum)
+	}
+	if err := writeChecksum(path, sum); err != nil {
+		t.Fatal(err)
+	}
+	// The sha256sum format: digest, two spaces, file name.
+	b, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != sum+"  airports.csv\n" {
+		t.Errorf(".sha256 file = %q", b)
+	}
+	if err := verifyChecksum(path); err != nil {
+		t.Errorf("verifyChecksum = %v", err)
+	}
+
+	// An upper-case digest, as some tools write, still matches.
+	if err := os.WriteFile(path+".sha256", []byte(strings.ToUpper(sum)+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksum(path); err != nil {
+		t.Errorf("verifyChecksum with upper-case digest = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(testAirports+"4,XXXX,closed,Gone,0,0,ZZ,\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksum(path); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("verifyChecksum of a modified file = %v", err)
+	}
+	if err := os.WriteFile(path+".sha256", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChecksum(path); err == nil {
+		t.Error("verifyChecksum with an empty .sha256 succeeded")
+	}
+	if err := verifyChecksum(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("verifyChecksum without a .sha256 succeeded")
+	}
+}
+
+func TestRunWritesChecksums(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(u.results) != 1 || u.results[0].Snapshot == "" {
+		t.Fatalf("results %+v", u.results)
+	}
+	for _, name := range []string{u.results[0].Snapshot, "airports-latest.csv"} {
+		if err := verifyChecksum(filepath.Join(u.outDir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
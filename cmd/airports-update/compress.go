@@ -0,0 +1,101 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressExt returns the file extension for a -compress method.
+func compressExt(method string) (string, error) {
+	switch method {
+	case "", "none":
+		return "", nil
+	case "gzip":
+		return ".gz", nil
+	case "zstd":
+		return ".zst", nil
+	}
+	return "", fmt.Errorf("unknown -compress %q (want gzip or zstd)", method)
+}
+
+// compressFile writes src to dst compressed by method, replacing dst
+// atomically.
+func compressFile(src, dst, method string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	var zw io.WriteCloser
+	switch method {
+	case "gzip":
+		zw, err = gzip.NewWriterLevel(out, gzip.BestCompression)
+	case "zstd":
+		zw, err = zstd.NewWriter(out, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	default:
+		err = fmt.Errorf("unknown compression %q", method)
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return publish(tmp, dst)
+}
+
+// openCSV opens a CSV file, decompressing it if its name ends in .gz or
+// .zst.
+func openCSV(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, func() error { zr.Close(); return f.Close() }}, nil
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, func() error { zr.Close(); return f.Close() }}, nil
+	}
+	return f, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressExt(t *testing.T) {
+	for method, want := range map[string]string{"": "", "none": "", "gzip": ".gz", "zstd": ".zst"} {
+		if got, err := compressExt(method); err != nil || got != want {
+			t.Errorf("compressExt(%q) = %q, %v; want %q", method, got, err, want)
+		}
+	}
+	if _, err := compressExt("xz"); err == nil {
+		t.Error("compressExt(xz) succeeded")
+	}
+}
+
+func TestCompressFile(t *testing.T) {
+	src := writeFile(t, "airports.csv", testAirports)
+	for method, ext := range map[string]string{"gzip": ".gz", "zstd": ".zst"} {
+		dst := filepath.Join(t.TempDir(), "airports.csv"+ext)
+		if err := compressFile(src, dst, method); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if fileExists(dst + ".tmp") {
+			t.Errorf("%s: temporary file left behind", method)
+		}
+		raw, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(string(raw), "id,") {
+			t.Errorf("%s: file isn't compressed", method)
+		}
+		if got := readAll(t, dst); got != testAirports {
+			t.Errorf("%s: round trip gave %q", method, got)
+		}
+	}
+	if err := compressFile(src, filepath.Join(t.TempDir(), "x"), "bzip2"); err == nil {
+		t.Error("unknown method accepted")
+	}
+}
+
+// readAll reads a file through openCSV.
+func readAll(t *testing.T, path string) string {
+	t.Helper()
+	r, err := openCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRunCompressed(t *testing.T) {
+	for _, compressLatest := range []bool{false, true} {
+		m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+		u := newTestUpdater(t, m)
+		u.compress, u.compressExt, u.compressLatest = "zstd", ".zst", compressLatest
+		if err := u.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		snapshot := u.results[0].Snapshot
+		if !strings.HasSuffix(snapshot, ".csv.zst") {
+			t.Errorf("snapshot %s isn't compressed", snapshot)
+		}
+		if got := readAll(t, filepath.Join(u.outDir, snapshot)); got != testAirports {
+			t.Errorf("snapshot holds %q", got)
+		}
+		latest := "airports-latest.csv"
+		if compressLatest {
+			latest += ".zst"
+		}
+		if got := readAll(t, filepath.Join(u.outDir, latest)); got != testAirports {
+			t.Errorf("compressLatest %v: %s holds %q", compressLatest, latest, got)
+		}
+		if err := verifyChecksum(filepath.Join(u.outDir, latest)); err != nil {
+			t.Error(err)
+		}
+
+		// The next run diffs against the compressed latest file.
+		m.set("/airports.csv", testAirports+"4,EGSS,large_airport,London Stansted Airport,51.88,0.23,GB,STN\n")
+		if err := u.run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if u.diff == nil || u.diff.Added != 1 {
+			t.Errorf("compressLatest %v: diff %+v", compressLatest, u.diff)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile sets flags from a YAML file whose keys are flag names
+// (snake_case is accepted too). ${VAR} references are expanded first.
+// Flags given on the command line win over the file; repeatable flags such
+// as url and header take a list.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	var settings map[string]any
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(raw))), &settings); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := strings.ReplaceAll(key, "_", "-")
+		f := fs.Lookup(name)
+		if f == nil || name == "config" {
+			return fmt.Errorf("config %s: unknown setting %q", path, key)
+		}
+		if explicit[name] {
+			continue
+		}
+		values, err := settingValues(settings[key])
+		if err != nil {
+			return fmt.Errorf("config %s: %s: %w", path, key, err)
+		}
+		if _, repeatable := f.Value.(*stringList); len(values) != 1 && !repeatable {
+			return fmt.Errorf("config %s: %s takes a single value", path, key)
+		}
+		for _, v := range values {
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("config %s: %s: %w", path, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// settingValues renders a YAML scalar, or a list of them, as flag values.
+func settingValues(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return []string{""}, nil
+	case []any:
+		out := make([]string, len(v))
+		for i, item := range v {
+			if _, nested := item.([]any); nested {
+				return nil, fmt.Errorf("nested lists are not supported")
+			}
+			if _, nested := item.(map[string]any); nested {
+				return nil, fmt.Errorf("maps are not supported")
+			}
+			out[i] = fmt.Sprint(item)
+		}
+		return out, nil
+	case map[string]any:
+		return nil, fmt.Errorf("maps are not supported")
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
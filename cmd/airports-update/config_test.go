@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFlags mirrors a few of main's flags.
+func testFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("airports-update", flag.ContinueOnError)
+	fs.String("config", "", "")
+	fs.String("out", "data", "")
+	fs.Int("keep", 0, "")
+	fs.Duration("interval", 24*time.Hour, "")
+	fs.Bool("dry-run", false, "")
+	fs.Var(&stringList{}, "url", "")
+	return fs
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	t.Setenv("MIRROR_HOST", "mirror.example")
+	path := writeFile(t, "config.yaml", `
+out: /var/lib/airports
+keep: 14
+interval: 6h
+dry_run: true
+url:
+  - https://ourairports.com/airports.csv
+  - https://${MIRROR_HOST}/airports.csv
+`)
+	fs := testFlags()
+	if err := fs.Parse([]string{"-keep", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(fs, path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"out":      "/var/lib/airports",
+		"interval": "6h0m0s",
+		"dry-run":  "true",
+		"keep":     "3", // the command line wins
+		"url":      "https://ourairports.com/airports.csv,https://mirror.example/airports.csv",
+	}
+	for name, v := range want {
+		if got := fs.Lookup(name).Value.String(); got != v {
+			t.Errorf("%s = %q, want %q", name, got, v)
+		}
+	}
+}
+
+func TestApplyConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name, yaml, wantErr string
+	}{
+		{"unknown setting", "retention: 3\n", `unknown setting "retention"`},
+		{"config itself", "config: other.yaml\n", `unknown setting "config"`},
+		{"list for a single value", "out: [a, b]\n", "takes a single value"},
+		{"map", "out: {dir: a}\n", "maps are not supported"},
+		{"nested list", "url: [[a]]\n", "nested lists"},
+		{"bad value", "keep: lots\n", "keep"},
+		{"not YAML", "out: [\n", "parse config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := testFlags()
+			err := applyConfigFile(fs, writeFile(t, "config.yaml", tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("applyConfigFile = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	fs := testFlags()
+	if err := applyConfigFile(fs, "missing.yaml"); err == nil {
+		t.Error("missing config file read")
+	}
+}
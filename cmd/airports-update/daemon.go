@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// schedule returns the time of the next run after t.
+type schedule func(t time.Time) time.Time
+
+// parseSchedule builds a schedule from a standard five-field cron
+// expression, or from a fixed interval when expr is empty.
+func parseSchedule(expr string, interval time.Duration) (schedule, error) {
+	if expr != "" {
+		sched, err := cron.ParseStandard(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -schedule %q: %w", expr, err)
+		}
+		return sched.Next, nil
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("-interval must be positive")
+	}
+	return func(t time.Time) time.Time { return t.Add(interval) }, nil
+}
+
+// daemon runs an update straight away and then on every tick of next,
+// delayed by up to jitter so a fleet of sidecars doesn't hit upstream at
+// the same moment. It returns when ctx is cancelled; an update in progress
+// is abandoned, leaving any partial download to be resumed next time.
+func (u *updater) daemon(ctx context.Context, next schedule, jitter time.Duration) {
+	for {
+		switch err := u.run(ctx); {
+		case ctx.Err() != nil:
+			slog.Info("shutting down")
+			return
+		case errors.Is(err, errNotModified):
+			slog.Info("nothing to do")
+		case err != nil:
+			slog.Error("update failed", "err", err)
+		}
+
+		at := next(time.Now())
+		if jitter > 0 {
+			at = at.Add(rand.N(jitter))
+		}
+		slog.Info("next update scheduled", "at", at.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			slog.Info("shutting down")
+			return
+		case <-time.After(time.Until(at)):
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	from := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr     string
+		interval time.Duration
+		want     time.Time
+	}{
+		{"", 6 * time.Hour, from.Add(6 * time.Hour)},
+		{"0 3 * * *", 0, time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Hour, time.Date(2024, 5, 1, 10, 45, 0, 0, time.UTC)},
+		{"@weekly", 0, time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		next, err := parseSchedule(tt.expr, tt.interval)
+		if err != nil {
+			t.Errorf("parseSchedule(%q, %v): %v", tt.expr, tt.interval, err)
+			continue
+		}
+		if got := next(from); !got.Equal(tt.want) {
+			t.Errorf("parseSchedule(%q, %v) next = %v, want %v", tt.expr, tt.interval, got, tt.want)
+		}
+	}
+	for _, bad := range []struct {
+		expr     string
+		interval time.Duration
+	}{{"0 3 * *", 0}, {"", 0}, {"", -time.Hour}} {
+		if _, err := parseSchedule(bad.expr, bad.interval); err == nil {
+			t.Errorf("parseSchedule(%q, %v) succeeded", bad.expr, bad.interval)
+		}
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u.daemon(ctx, func(t time.Time) time.Time { return t.Add(10 * time.Millisecond) }, time.Millisecond)
+	}()
+
+	// The first run downloads; later ones find nothing new and carry on.
+	deadline := time.After(10 * time.Second)
+	for {
+		m.mu.Lock()
+		n := len(m.requests)
+		m.mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("only %d requests in 10s", n)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon didn't stop when cancelled")
+	}
+	if got := m.lastRequest(t, "/airports.csv").Header.Get("If-None-Match"); got == "" {
+		t.Error("later runs weren't conditional")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// defaultOpenFlightsURL is the directory the OpenFlights .dat files are
+// fetched from.
+const defaultOpenFlightsURL = "https://raw.githubusercontent.com/jpatokal/openflights/master/data/"
+
+// dataset is one of the OurAirports CSV files, or one of the OpenFlights
+// .dat files.
+type dataset struct {
+	name string // as given to -datasets
+	file string // local base name, without extension
+
+	// OpenFlights files are headerless CSV with a fixed number of fields,
+	// published under their own name in a separate directory.
+	remote string // upstream file name; "" for OurAirports, which uses file.csv
+	fields int    // fields per record; 0 for files with a header row
+}
+
+// knownDatasets lists the files OurAirports publishes side by side, then
+// the OpenFlights files.
+var knownDatasets = []dataset{
+	{name: "airports", file: "airports"},
+	{name: "runways", file: "runways"},
+	{name: "countries", file: "countries"},
+	{name: "regions", file: "regions"},
+	{name: "navaids", file: "navaids"},
+	{name: "frequencies", file: "airport-frequencies"},
+	{name: "openflights-airports", file: "openflights-airports", remote: "airports.dat", fields: 14},
+	{name: "openflights-airlines", file: "openflights-airlines", remote: "airlines.dat", fields: 8},
+	{name: "openflights-routes", file: "openflights-routes", remote: "routes.dat", fields: 9},
+}
+
+// openFlights reports whether ds comes from OpenFlights rather than
+// OurAirports.
+func (ds dataset) openFlights() bool {
+	return ds.remote != ""
+}
+
+// ext is the extension of ds's snapshot and latest files, before any
+// compression suffix.
+func (ds dataset) ext() string {
+	if ds.openFlights() {
+		return ".dat"
+	}
+	return ".csv"
+}
+
+// parseDatasets resolves a comma-separated -datasets value.
+func parseDatasets(list string) ([]dataset, error) {
+	var out []dataset
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		found := false
+		for _, ds := range knownDatasets {
+			if ds.name == name {
+				out = append(out, ds)
+				found = true
+				break
+			}
+		}
+		if !found {
+			var names []string
+			for _, ds := range knownDatasets {
+				names = append(names, ds.name)
+			}
+			return nil, fmt.Errorf("unknown dataset %q (want %s)", name, strings.Join(names, ", "))
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no datasets given")
+	}
+	return out, nil
+}
+
+// datasetURL returns where to fetch ds from a source: the airports URL
+// itself for airports, and the sibling file in the same directory for the
+// others. For OpenFlights datasets source is the directory holding the
+// .dat files.
+func datasetURL(source string, ds dataset) string {
+	if ds.name == "airports" {
+		return source
+	}
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+	if ds.openFlights() {
+		parsed.Path = path.Join(parsed.Path, ds.remote)
+	} else {
+		parsed.Path = path.Join(path.Dir(parsed.Path), ds.file+".csv")
+	}
+	return parsed.String()
+}
+
+// sources lists the URLs ds may be fetched from, in order.
+func (u *updater) sources(ds dataset) []string {
+	if ds.openFlights() {
+		return []string{u.openFlightsURL}
+	}
+	return u.urls
+}
+
+// countRows returns the number of data rows in a file of ds.
+func countRows(ds dataset, path string) (int, error) {
+	if ds.fields > 0 {
+		rows, _, err := countDatRows(path, ds.fields)
+		return rows, err
+	}
+	return countCSVRows(path)
+}
+
+// countCSVRows returns the number of data rows in a CSV file, which may be
+// compressed.
+func countCSVRows(path string) (int, error) {
+	f, err := openCSV(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	if _, err := r.Read(); err != nil {
+		return 0, fmt.Errorf("read header: %w", err)
+	}
+	n := 0
+	for {
+		_, err := r.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// countDatRows returns the number of records in a headerless OpenFlights
+// file, which may be compressed, and how many of them don't have the
+// expected number of fields.
+func countDatRows(path string, fields int) (rows, bad int, err error) {
+	f, err := openCSV(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.ReuseRecord = true
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return rows, bad, nil
+		}
+		if err != nil {
+			return rows, bad, err
+		}
+		rows++
+		if len(rec) != fields {
+			bad++
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseDatasets(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{"airports", []string{"airports"}, false},
+		{"runways, frequencies,runways", []string{"runways", "frequencies"}, false},
+		{"airports,openflights-routes", []string{"airports", "openflights-routes"}, false},
+		{"airports,heliports", nil, true},
+		{" , ", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseDatasets(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDatasets(%q) error = %v", tt.list, err)
+			continue
+		}
+		var names []string
+		for _, ds := range got {
+			names = append(names, ds.name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("parseDatasets(%q) = %v, want %v", tt.list, names, tt.want)
+		}
+	}
+}
+
+func TestDatasetURL(t *testing.T) {
+	frequencies, _ := parseDatasets("frequencies")
+	routes, _ := parseDatasets("openflights-routes")
+	tests := []struct {
+		source string
+		ds     dataset
+		want   string
+	}{
+		{"https://example.com/data/airports.csv?v=1", knownDatasets[0], "https://example.com/data/airports.csv?v=1"},
+		{"https://example.com/data/airports.csv?v=1", frequencies[0], "https://example.com/data/airport-frequencies.csv?v=1"},
+		{"https://example.com/of/", routes[0], "https://example.com/of/routes.dat"},
+	}
+	for _, tt := range tests {
+		if got := datasetURL(tt.source, tt.ds); got != tt.want {
+			t.Errorf("datasetURL(%q, %s) = %q, want %q", tt.source, tt.ds.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunDatasets(t *testing.T) {
+	const runways = "id,airport_ref,airport_ident,length_ft\n1,2434,EGLL,12799\n2,2434,EGLL,12001\n"
+	m := newMirror(t, map[string]string{
+		"/airports.csv": testAirports,
+		"/runways.csv":  runways,
+	})
+	datasets, err := parseDatasets("airports,runways,countries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := newTestUpdater(t, m, datasets...)
+	err = u.run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "countries:") {
+		t.Fatalf("run = %v, want a countries failure", err)
+	}
+
+	// One dataset failing doesn't stop the others.
+	var statuses []string
+	for _, res := range u.results {
+		statuses = append(statuses, res.Name+"="+res.Status)
+	}
+	if want := []string{"airports=updated", "runways=updated", "countries=failed"}; !slices.Equal(statuses, want) {
+		t.Errorf("results %v, want %v", statuses, want)
+	}
+	if u.results[1].Rows != 2 || u.results[1].Source != m.URL+"/runways.csv" {
+		t.Errorf("runways result %+v", u.results[1])
+	}
+	for _, name := range []string{"airports-latest.csv", "runways-latest.csv"} {
+		if !fileExists(filepath.Join(u.outDir, name)) {
+			t.Errorf("%s missing", name)
+		}
+	}
+	if fileExists(filepath.Join(u.outDir, "countries-latest.csv")) {
+		t.Error("countries-latest.csv written")
+	}
+}
+
+func TestRunOpenFlights(t *testing.T) {
+	const airportsDat = `507,"London Heathrow Airport","London","United Kingdom","LHR","EGLL",51.4706,-0.461941,83,0,"E","Europe/London","airport","OurAirports"
+502,"London Gatwick Airport","London","United Kingdom","LGW","EGKK",51.148102,-0.190278,202,0,"E","Europe/London","airport","OurAirports"
+3797,"John F Kennedy International Airport","New York","United States","JFK","KJFK",40.63980103,-73.77890015,13,-5,"A","America/New_York","airport","OurAirports"
+`
+	m := newMirror(t, map[string]string{"/openflights/airports.dat": airportsDat})
+	datasets, _ := parseDatasets("openflights-airports")
+	u := newTestUpdater(t, m, datasets...)
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	res := u.results[0]
+	if res.Rows != 3 || res.Source != m.URL+"/openflights/airports.dat" || !strings.HasSuffix(res.Snapshot, ".dat") {
+		t.Errorf("result %+v", res)
+	}
+	if !fileExists(filepath.Join(u.outDir, "openflights-airports-latest.dat")) {
+		t.Error("openflights-airports-latest.dat missing")
+	}
+
+	// A record with the wrong number of fields is over the 1% limit.
+	m.set("/openflights/airports.dat", airportsDat+`9999,"Broken"`+"\n")
+	err := u.run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 rows (25.0%) don't have 14 fields") {
+		t.Errorf("run with a short record = %v", err)
+	}
+}
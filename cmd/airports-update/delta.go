@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// deltaSuffix names the delta file written next to a snapshot with -delta.
+const deltaSuffix = ".delta.json.gz"
+
+// deltaFile is the gzip-compressed JSON written with -delta: the airports
+// added or changed since the previous snapshot, in full, and the codes
+// removed. It decodes directly into an iataplaces.Delta for ApplyDelta;
+// the checksums let a node check it holds the snapshot the delta starts
+// from, and that applying it leads to the new one.
+type deltaFile struct {
+	From       string `json:"from"`
+	FromSHA256 string `json:"from_sha256,omitempty"`
+	To         string `json:"to"`
+	ToSHA256   string `json:"to_sha256"`
+	iataplaces.Delta
+}
+
+// writeDelta writes the delta from the snapshot named prev to the one
+// named next, whose checksum is sum, and returns the delta file's name.
+func (u *updater) writeDelta(prev, next, sum string) (string, error) {
+	d := deltaFile{
+		From:     prev,
+		To:       next,
+		ToSHA256: sum,
+		Delta:    u.fullDiff.Delta(),
+	}
+	if b, err := os.ReadFile(filepath.Join(u.outDir, prev+".sha256")); err == nil {
+		if fields := strings.Fields(string(b)); len(fields) > 0 {
+			d.FromSHA256 = fields[0]
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(d); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	name := next + deltaSuffix
+	return name, writeFileAtomic(filepath.Join(u.outDir, name), buf.Bytes())
+}
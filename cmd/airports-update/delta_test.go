@@ -0,0 +1,87 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func loadSnapshot(t *testing.T, path string) *iataplaces.Store {
+	t.Helper()
+	s, err := iataplaces.LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWriteDelta(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.delta = true
+	ctx := context.Background()
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	first := u.results[0]
+	if first.Delta != "" {
+		t.Errorf("first snapshot has delta %s", first.Delta)
+	}
+
+	// Rename LHR, drop LGW and add STN; snapshots are named by the second.
+	time.Sleep(time.Second)
+	next := strings.Replace(testAirports, "London Heathrow Airport", "Heathrow Airport", 1)
+	next = strings.Replace(next, "2,EGKK,large_airport,London Gatwick Airport,51.15,-0.19,GB,LGW\n", "", 1)
+	m.set("/airports.csv", next+"4,EGSS,large_airport,London Stansted Airport,51.88,0.23,GB,STN\n")
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	second := u.results[0]
+	if second.Delta != second.Snapshot+deltaSuffix {
+		t.Fatalf("delta %q for %s", second.Delta, second.Snapshot)
+	}
+
+	f, err := os.Open(filepath.Join(u.outDir, second.Delta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d deltaFile
+	if err := json.NewDecoder(zr).Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+	if d.From != first.Snapshot || d.FromSHA256 != first.SHA256 || d.To != second.Snapshot || d.ToSHA256 != second.SHA256 {
+		t.Errorf("delta header %s (%s) -> %s (%s)", d.From, d.FromSHA256, d.To, d.ToSHA256)
+	}
+	if !slices.Equal(d.Remove, []string{"LGW"}) || len(d.Upsert) != 2 {
+		t.Errorf("delta upserts %d, removes %v", len(d.Upsert), d.Remove)
+	}
+
+	// Applying it to the first snapshot gives the second.
+	store := loadSnapshot(t, filepath.Join(u.outDir, first.Snapshot))
+	if _, err := store.ApplyDelta(d.Delta); err != nil {
+		t.Fatal(err)
+	}
+	want := loadSnapshot(t, filepath.Join(u.outDir, second.Snapshot))
+	if diff := iataplaces.DiffStores(want, store); !diff.Empty() {
+		t.Errorf("applied delta differs from the new snapshot: %+v", diff)
+	}
+
+	var man snapshotManifest
+	readJSON(t, filepath.Join(u.outDir, second.Snapshot+manifestSuffix), &man)
+	if man.Delta != second.Delta {
+		t.Errorf("manifest names delta %q", man.Delta)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// diffSummary reports how a new airports snapshot differs from the
+// previous latest file, by IATA code.
+type diffSummary struct {
+	Previous     string   `json:"previous"`
+	Current      string   `json:"current"`
+	Added        int      `json:"added"`
+	Removed      int      `json:"removed"`
+	Changed      int      `json:"changed"`
+	AddedCodes   []string `json:"added_codes"`
+	RemovedCodes []string `json:"removed_codes"`
+	ChangedCodes []string `json:"changed_codes"`
+}
+
+// summarizeDiff compares two airports CSV files. It also returns the full
+// diff, for delta files.
+func summarizeDiff(prevPath, newPath string) (*diffSummary, *iataplaces.StoreDiff, error) {
+	before, err := loadAirports(prevPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := loadAirports(newPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := iataplaces.DiffStores(before, after)
+
+	s := &diffSummary{
+		Previous:     filepath.Base(prevPath),
+		Current:      filepath.Base(newPath),
+		Added:        len(d.Added),
+		Removed:      len(d.Removed),
+		Changed:      len(d.Changed),
+		AddedCodes:   []string{},
+		RemovedCodes: []string{},
+		ChangedCodes: []string{},
+	}
+	for _, a := range d.Added {
+		s.AddedCodes = append(s.AddedCodes, a.IATACode)
+	}
+	for _, a := range d.Removed {
+		s.RemovedCodes = append(s.RemovedCodes, a.IATACode)
+	}
+	for _, c := range d.Changed {
+		s.ChangedCodes = append(s.ChangedCodes, c.IATACode)
+	}
+	return s, d, nil
+}
+
+// loadAirports loads an airports CSV, which may be compressed.
+func loadAirports(path string) (*iataplaces.Store, error) {
+	f, err := openCSV(path)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	defer f.Close()
+
+	store, err := iataplaces.LoadFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// writeDiffSummary writes s as JSON to dest, or to stdout for "-".
+func writeDiffSummary(dest string, s *diffSummary) error {
+	var w io.Writer = os.Stdout
+	if dest != "-" {
+		f, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
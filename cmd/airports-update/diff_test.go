@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const diffHeader = "id,ident,type,name,latitude_deg,longitude_deg,iso_country,iata_code\n"
+
+func TestReportDiff(t *testing.T) {
+	prev := writeFile(t, "airports-latest.csv", diffHeader+
+		"1,EGLL,large_airport,London Heathrow Airport,51.47,-0.46,GB,LHR\n"+
+		"2,EGKK,large_airport,London Gatwick Airport,51.15,-0.19,GB,LGW\n")
+	next := writeFile(t, "airports-download.csv.tmp", diffHeader+
+		"1,EGLL,large_airport,Heathrow Airport,51.47,-0.46,GB,LHR\n"+
+		"3,EGSS,large_airport,London Stansted Airport,51.88,0.23,GB,STN\n")
+	out := filepath.Join(t.TempDir(), "changes.json")
+
+	u := &updater{diffOut: out, delta: true}
+	if err := u.reportDiff(prev, next, "airports-20240501-100000.csv.gz"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got diffSummary
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Previous != "airports-latest.csv" || got.Current != "airports-20240501-100000.csv.gz" {
+		t.Errorf("previous %q, current %q", got.Previous, got.Current)
+	}
+	if !slices.Equal(got.AddedCodes, []string{"STN"}) || !slices.Equal(got.RemovedCodes, []string{"LGW"}) || !slices.Equal(got.ChangedCodes, []string{"LHR"}) {
+		t.Errorf("added %v, removed %v, changed %v", got.AddedCodes, got.RemovedCodes, got.ChangedCodes)
+	}
+	if got.Added != 1 || got.Removed != 1 || got.Changed != 1 {
+		t.Errorf("counts %d/%d/%d, want 1/1/1", got.Added, got.Removed, got.Changed)
+	}
+	if u.diff.Current != got.Current {
+		t.Errorf("logged summary names %q", u.diff.Current)
+	}
+	if u.fullDiff == nil {
+		t.Error("-delta didn't keep the full diff")
+	}
+}
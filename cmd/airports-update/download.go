@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultUserAgent identifies the updater to upstream servers.
+const defaultUserAgent = "airports-update (+https://github.com/achamwada/iata-lookup-places)"
+
+// tlsOptions configure the client side of TLS, for internal mirrors.
+type tlsOptions struct {
+	caFile             string // extra CA bundle (PEM) to trust
+	certFile, keyFile  string // client certificate for mutual TLS
+	insecureSkipVerify bool
+}
+
+// newHTTPClient returns a client whose every stage is bounded: connecting,
+// the TLS handshake, waiting for headers, and the whole request including
+// the body.
+func newHTTPClient(timeout time.Duration, opts tlsOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = 15 * time.Second
+	transport.ResponseHeaderTimeout = 60 * time.Second
+
+	tlsConfig, err := opts.config()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+func (o tlsOptions) config() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: o.insecureSkipVerify}
+	if o.caFile != "" {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.certFile != "" || o.keyFile != "" {
+		if o.certFile == "" || o.keyFile == "" {
+			return nil, fmt.Errorf("-client-cert and -client-key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// parseHeaders turns "Name: value" flag values into a header set.
+func parseHeaders(values []string) (http.Header, error) {
+	h := http.Header{}
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid -header %q (want \"Name: value\")", v)
+		}
+		h.Add(name, strings.TrimSpace(value))
+	}
+	return h, nil
+}
+
+// transientError marks a failure worth retrying: network errors, timeouts
+// and 408/429/5xx responses.
+type transientError struct {
+	err        error
+	retryAfter time.Duration // from a Retry-After header, if any
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// fetch downloads url into path, retrying transient failures with
+// exponential backoff. No wait is longer than maxRetryWait: a server
+// asking to be retried later than that gets no retry. The returned
+// response's body has already been consumed and closed; it is returned for
+// its headers.
+func (u *updater) fetch(ctx context.Context, ds dataset, url, path string, header http.Header) (*http.Response, int64, error) {
+	for attempt := 0; ; attempt++ {
+		resp, n, err := u.fetchOnce(ctx, ds, url, path, header)
+		var transient *transientError
+		if err == nil || !errors.As(err, &transient) || attempt >= u.retries {
+			return resp, n, err
+		}
+
+		wait := max(u.backoff(attempt), transient.retryAfter)
+		if u.maxRetryWait > 0 {
+			if transient.retryAfter > u.maxRetryWait {
+				return nil, 0, fmt.Errorf("%w (not retrying: Retry-After %s is longer than %s)",
+					err, transient.retryAfter, u.maxRetryWait)
+			}
+			wait = min(wait, u.maxRetryWait)
+		}
+		slog.Warn("download failed, retrying", "url", url, "err", err,
+			"wait", wait.Round(time.Millisecond).String(), "retry", attempt+1, "of", u.retries)
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the delay before retry number attempt+1: retryBackoff
+// doubled per attempt, with up to 50% jitter so that many hosts on the same
+// schedule don't retry in lockstep.
+func (u *updater) backoff(attempt int) time.Duration {
+	d := u.retryBackoff << attempt
+	return d + time.Duration(rand.Int64N(int64(d)/2+1))
+}
+
+func (u *updater) fetchOnce(ctx context.Context, ds dataset, url, path string, header http.Header) (*http.Response, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header = u.headers.Clone()
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	var offset int64
+	if u.resume {
+		var ifRange string
+		if offset, ifRange = resumeOffset(path, url); offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", ifRange)
+		}
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, 0, &transientError{err: fmt.Errorf("failed to download %s: %w", ds.name, err)}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		removePartial(path)
+		return resp, 0, errNotModified
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		start, err := contentRangeStart(resp.Header.Get("Content-Range"))
+		if err != nil || start != offset {
+			removePartial(path)
+			return nil, 0, &transientError{err: fmt.Errorf("server resumed at the wrong offset (%s)", resp.Header.Get("Content-Range"))}
+		}
+		slog.Info("resuming download", "url", url, "offset", offset)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file no longer matches; start again from zero.
+		removePartial(path)
+		return nil, 0, &transientError{err: fmt.Errorf("cannot resume %s at byte %d", url, offset)}
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return nil, 0, &transientError{
+			err:        fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	default:
+		return nil, 0, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	} else if u.resume {
+		if err := savePartialMeta(path, url, resp); err != nil {
+			return nil, 0, fmt.Errorf("failed to record download metadata: %w", err)
+		}
+	}
+	outFile, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temp file %s: %w", path, err)
+	}
+
+	var body io.Reader = resp.Body
+	if u.limiter != nil {
+		body = &limitedReader{ctx: ctx, r: body, lim: u.limiter}
+	}
+	if u.progressEvery > 0 {
+		body = newProgressReader(body, url, u.progressEvery, offset, resp.ContentLength)
+	}
+	n, err := io.Copy(outFile, body)
+	closeErr := outFile.Close()
+	if err != nil {
+		return nil, 0, &transientError{err: fmt.Errorf("failed to write %s to %s: %w", ds.name, path, err)}
+	}
+	if closeErr != nil {
+		return nil, 0, fmt.Errorf("failed to close temp file %s: %w", path, closeErr)
+	}
+	// A connection closed early can look like a clean end of body. The
+	// partial file stays, so the retry resumes where this one stopped.
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return nil, 0, &transientError{err: fmt.Errorf("truncated download from %s: got %d of %d bytes", url, n, resp.ContentLength)}
+	}
+	return resp, offset + n, nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
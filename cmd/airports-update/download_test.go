@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{"Wed, 01 May 2024 10:00:00 GMT", 0}, // in the past
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v, want about an hour", future, got)
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	const body = "id,ident\n1,EGLL\n"
+	tests := []struct {
+		name         string
+		failures     int    // 503 responses before the body
+		retryAfter   string // sent with each 503
+		retries      int
+		maxRetryWait time.Duration
+		wantRequests int32
+		wantErr      string
+	}{
+		{name: "first try", wantRequests: 1},
+		{name: "retried", failures: 2, retries: 3, maxRetryWait: time.Millisecond, wantRequests: 3},
+		{name: "out of retries", failures: 5, retries: 2, maxRetryWait: time.Millisecond, wantRequests: 3, wantErr: "status code 503"},
+		{name: "not transient", failures: -1, retries: 3, wantRequests: 1, wantErr: "status code 404"},
+		{name: "Retry-After too long", failures: 1, retryAfter: "3600", retries: 3, maxRetryWait: time.Second, wantRequests: 1, wantErr: "Retry-After 1h0m0s is longer than 1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := requests.Add(1)
+				switch {
+				case tt.failures < 0:
+					http.NotFound(w, r)
+				case int(n) <= tt.failures:
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					http.Error(w, "busy", http.StatusServiceUnavailable)
+				default:
+					w.Write([]byte(body))
+				}
+			}))
+			defer srv.Close()
+
+			// The backoff alone would wait an hour; maxRetryWait cuts it short.
+			u := &updater{
+				client:       srv.Client(),
+				headers:      http.Header{},
+				retries:      tt.retries,
+				retryBackoff: time.Hour,
+				maxRetryWait: tt.maxRetryWait,
+			}
+			path := filepath.Join(t.TempDir(), "airports.csv.tmp")
+			_, n, err := u.fetch(context.Background(), knownDatasets[0], srv.URL, path, nil)
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("%d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(len(body)) {
+				t.Errorf("downloaded %d bytes, want %d", n, len(body))
+			}
+		})
+	}
+}
+
+func TestFetchOnceNamesDataset(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close() // refuse connections
+
+	runways, err := parseDatasets("runways")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &updater{client: &http.Client{}, headers: http.Header{}}
+	_, _, err = u.fetchOnce(context.Background(), runways[0], url, filepath.Join(t.TempDir(), "runways.csv.tmp"), nil)
+	var te *transientError
+	if !errors.As(err, &te) {
+		t.Fatalf("err = %v, want a transient error", err)
+	}
+	if !strings.Contains(err.Error(), "failed to download runways") {
+		t.Errorf("err = %v, want it to name the dataset", err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := parseHeaders([]string{"Authorization: Bearer abc:def", "X-Mirror:eu", "x-mirror: us"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Get("Authorization") != "Bearer abc:def" || !slices.Equal(h.Values("X-Mirror"), []string{"eu", "us"}) {
+		t.Errorf("parseHeaders = %v", h)
+	}
+	for _, bad := range []string{"Authorization", ": value"} {
+		if _, err := parseHeaders([]string{bad}); err == nil {
+			t.Errorf("parseHeaders(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestRunSendsHeaders(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.headers = http.Header{"User-Agent": {"airports-test/1.0"}, "Authorization": {"Bearer abc"}}
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	req := m.lastRequest(t, "/airports.csv")
+	if req.UserAgent() != "airports-test/1.0" || req.Header.Get("Authorization") != "Bearer abc" {
+		t.Errorf("request headers %v", req.Header)
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "no client certificate", http.StatusForbidden)
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	serverCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, serverCert, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := writeClientCert(t, dir)
+
+	get := func(opts tlsOptions) (int, error) {
+		client, err := newHTTPClient(10*time.Second, opts)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	tests := []struct {
+		name string
+		opts tlsOptions
+		want int // 0 for an error
+	}{
+		{"untrusted", tlsOptions{}, 0},
+		{"CA bundle", tlsOptions{caFile: caFile}, http.StatusForbidden},
+		{"insecure", tlsOptions{insecureSkipVerify: true}, http.StatusForbidden},
+		{"client certificate", tlsOptions{caFile: caFile, certFile: certFile, keyFile: keyFile}, http.StatusOK},
+		{"certificate without key", tlsOptions{caFile: caFile, certFile: certFile}, 0},
+		{"CA bundle without certificates", tlsOptions{caFile: keyFile}, 0},
+		{"missing CA bundle", tlsOptions{caFile: filepath.Join(dir, "missing.pem")}, 0},
+	}
+	for _, tt := range tests {
+		got, err := get(tt.opts)
+		if got != tt.want {
+			t.Errorf("%s: status %d, %v; want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and its key
+// as PEM files in dir.
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "airports-update test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// Files written by -emit-go into the target package directory.
+const (
+	emitGoCSV    = "airports.csv"
+	emitGoSource = "airports_data.go"
+)
+
+var emitGoTemplate = template.Must(template.New("go").Parse(`// Code generated by airports-update; DO NOT EDIT.
+
+// Package {{.Package}} embeds a snapshot of the OurAirports airports
+// dataset. Regenerate it with
+//
+//	go run github.com/achamwada/iata-lookup-places/cmd/airports-update -emit-go <dir>
+package {{.Package}}
+
+import (
+	"bytes"
+	_ "embed"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// CSV is the embedded airports.csv.
+//
+//go:embed {{.File}}
+var CSV []byte
+
+// Snapshot metadata.
+const (
+	Source   = {{printf "%q" .Source}}
+	Snapshot = {{printf "%q" .Snapshot}}
+	SHA256   = {{printf "%q" .SHA256}}
+	Rows     = {{.Rows}}
+{{- if .Subset}}
+	Subset   = {{printf "%q" .Subset}} // rows kept, see iataplaces.ParseSubset
+{{- end}}
+)
+
+// Load parses the embedded snapshot into a store.
+func Load() (*iataplaces.Store, error) {
+	return iataplaces.LoadFromReader(bytes.NewReader(CSV))
+}
+`))
+
+// emitGo writes the current airports latest file into dir as a Go package
+// that embeds it, so projects that vendor the data can refresh it with
+// go:generate. Files are only rewritten when their content changes.
+func (u *updater) emitGo(dir, pkg string) error {
+	ds := knownDatasets[0]
+	latestPath := filepath.Join(u.outDir, ds.file+"-latest.csv"+u.latestExt())
+	state, err := loadState(u.outDir, ds)
+	if err != nil {
+		return err
+	}
+
+	r, err := openCSV(latestPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", latestPath, err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", latestPath, err)
+	}
+	rows := state.Rows
+	if u.emitGoSubset != nil {
+		var trimmed bytes.Buffer
+		if rows, err = iataplaces.WriteSubset(&trimmed, bytes.NewReader(data), *u.emitGoSubset); err != nil {
+			return fmt.Errorf("failed to trim %s: %w", latestPath, err)
+		}
+		data = trimmed.Bytes()
+	}
+	sum := sha256.Sum256(data)
+
+	if pkg == "" {
+		pkg = packageName(dir)
+	}
+	var src bytes.Buffer
+	err = emitGoTemplate.Execute(&src, map[string]any{
+		"Package":  pkg,
+		"File":     emitGoCSV,
+		"Source":   state.URL,
+		"Snapshot": state.Snapshot,
+		"SHA256":   hex.EncodeToString(sum[:]),
+		"Rows":     rows,
+		"Subset":   subsetSpec(u.emitGoSubset),
+	})
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not parse: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for name, content := range map[string][]byte{emitGoCSV: data, emitGoSource: formatted} {
+		path := filepath.Join(dir, name)
+		changed, err := writeIfChanged(path, content)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if changed {
+			slog.Info("generated", "path", path)
+		}
+	}
+	return nil
+}
+
+func subsetSpec(s *iataplaces.Subset) string {
+	if s == nil {
+		return ""
+	}
+	return s.String()
+}
+
+// packageName derives a Go package name from the last element of dir.
+func packageName(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		dir = abs
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			return unicode.ToLower(r)
+		case r == '_':
+			return r
+		}
+		return -1
+	}, filepath.Base(dir))
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "airports" + name
+	}
+	return name
+}
+
+// writeIfChanged replaces path with content unless it already holds exactly
+// that, and reports whether it wrote.
+func writeIfChanged(path string, content []byte) (bool, error) {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, content) {
+		return false, nil
+	}
+	if err := writeFileAtomic(path, content); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"internal/airportdata": "airportdata",
+		"gen/Airport-Data.v2":  "airportdatav2",
+		"data/2024":            "airports2024",
+		"x/___":                "___",
+	}
+	for dir, want := range tests {
+		if got := packageName(dir); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+// emittedConsts parses the generated source and returns its constants.
+func emittedConsts(t *testing.T, dir string) (pkg string, consts map[string]string) {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, emitGoSource), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consts = map[string]string{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			lit := vs.Values[0].(*ast.BasicLit)
+			value := lit.Value
+			if lit.Kind == token.STRING {
+				value, _ = strconv.Unquote(value)
+			}
+			consts[vs.Names[0].Name] = value
+		}
+	}
+	return f.Name.Name, consts
+}
+
+func TestEmitGo(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.emitGoDir = filepath.Join(t.TempDir(), "airportdata")
+	ctx := context.Background()
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	csv, err := os.ReadFile(filepath.Join(u.emitGoDir, emitGoCSV))
+	if err != nil || string(csv) != testAirports {
+		t.Fatalf("embedded CSV %q, %v", csv, err)
+	}
+	pkg, consts := emittedConsts(t, u.emitGoDir)
+	if pkg != "airportdata" {
+		t.Errorf("package %s", pkg)
+	}
+	res := u.results[0]
+	want := map[string]string{"Source": res.Source, "Snapshot": res.Snapshot, "SHA256": res.SHA256, "Rows": "3"}
+	for k, v := range want {
+		if consts[k] != v {
+			t.Errorf("%s = %q, want %q", k, consts[k], v)
+		}
+	}
+	if _, ok := consts["Subset"]; ok {
+		t.Error("Subset emitted without -emit-go-subset")
+	}
+
+	// Nothing new upstream: the package is still regenerated, but files
+	// whose content is the same aren't rewritten.
+	src := filepath.Join(u.emitGoDir, emitGoSource)
+	before, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(u.emitGoDir, emitGoCSV)); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.run(ctx); !errors.Is(err, errNotModified) {
+		t.Fatalf("second run = %v", err)
+	}
+	if !fileExists(filepath.Join(u.emitGoDir, emitGoCSV)) {
+		t.Error("embedded CSV not restored")
+	}
+	if after, err := os.Stat(src); err != nil || !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("unchanged source rewritten: %v", err)
+	}
+}
+
+func TestEmitGoSubset(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	sub, err := iataplaces.ParseSubset("country=GB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.emitGoDir, u.emitGoPkg, u.emitGoSubset = t.TempDir(), "ukairports", &sub
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pkg, consts := emittedConsts(t, u.emitGoDir)
+	if pkg != "ukairports" || consts["Rows"] != "2" || consts["Subset"] != sub.String() {
+		t.Errorf("package %s with %v", pkg, consts)
+	}
+	csv, err := os.ReadFile(filepath.Join(u.emitGoDir, emitGoCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(csv), "KJFK") || !strings.Contains(string(csv), "EGKK") {
+		t.Errorf("subset CSV %q", csv)
+	}
+	if consts["SHA256"] == u.results[0].SHA256 {
+		t.Error("SHA256 is the full snapshot's, not the embedded subset's")
+	}
+}
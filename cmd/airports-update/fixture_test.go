@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAirports is an airports file with just the required columns.
+const testAirports = diffHeader +
+	"1,EGLL,large_airport,London Heathrow Airport,51.47,-0.46,GB,LHR\n" +
+	"2,EGKK,large_airport,London Gatwick Airport,51.15,-0.19,GB,LGW\n" +
+	"3,KJFK,large_airport,John F Kennedy International Airport,40.64,-73.78,US,JFK\n"
+
+// mirror is a test upstream serving files by URL path. Each file's ETag
+// is derived from its content, so conditional requests work.
+type mirror struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	files    map[string]string
+	requests []*http.Request
+}
+
+func newMirror(t *testing.T, files map[string]string) *mirror {
+	t.Helper()
+	m := &mirror{files: files}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		content, ok := m.files[r.URL.Path]
+		m.requests = append(m.requests, r)
+		m.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		sum := sha256.Sum256([]byte(content))
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:8])+`"`)
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+// set replaces or adds the file served at path.
+func (m *mirror) set(path, content string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[path] = content
+}
+
+// lastRequest returns the most recent request for path.
+func (m *mirror) lastRequest(t *testing.T, path string) *http.Request {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.requests) - 1; i >= 0; i-- {
+		if m.requests[i].URL.Path == path {
+			return m.requests[i]
+		}
+	}
+	t.Fatalf("no request for %s", path)
+	return nil
+}
+
+// newTestUpdater returns an updater that fetches datasets (default:
+// airports) from m into a fresh output directory, with the default
+// validation limits except -min-bytes.
+func newTestUpdater(t *testing.T, m *mirror, datasets ...dataset) *updater {
+	t.Helper()
+	if len(datasets) == 0 {
+		datasets = knownDatasets[:1]
+	}
+	return &updater{
+		outDir:         t.TempDir(),
+		urls:           []string{m.URL + "/airports.csv"},
+		openFlightsURL: m.URL + "/openflights/",
+		datasets:       datasets,
+		client:         m.Client(),
+		headers:        http.Header{"User-Agent": {defaultUserAgent}},
+		minRowRatio:    0.9,
+		maxBadRatio:    0.01,
+		latestMode:     "copy",
+	}
+}
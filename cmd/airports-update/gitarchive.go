@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// checkGitRepo makes sure dir is a Git work tree and git is installed, so
+// a misconfigured -git-archive fails at startup rather than after the
+// first download.
+func checkGitRepo(ctx context.Context, dir string) error {
+	if _, err := gitOutput(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return fmt.Errorf("-git-archive %s: %w", dir, err)
+	}
+	return nil
+}
+
+// archiveToGit copies the newest snapshot of each dataset and its
+// manifest into the -git-archive work tree under fixed names, e.g.
+// airports.csv and airports.manifest.json, so every data change becomes a
+// commit with history and blame. It commits when anything changed and,
+// with -git-push, pushes the current branch; the push is retried on every
+// run, so one that failed goes out with the next.
+func (u *updater) archiveToGit(ctx context.Context) error {
+	var files, names, lines []string
+	for _, ds := range u.datasets {
+		state, err := loadState(u.outDir, ds)
+		if err != nil {
+			return err
+		}
+		if state.Snapshot == "" {
+			continue
+		}
+		r, err := openCSV(filepath.Join(u.outDir, state.Snapshot))
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", state.Snapshot, err)
+		}
+		name := ds.file + ds.ext()
+		changed, err := writeIfChanged(filepath.Join(u.gitDir, name), data)
+		if err != nil {
+			return err
+		}
+		files = append(files, name)
+
+		manifest, err := os.ReadFile(filepath.Join(u.outDir, state.Snapshot+manifestSuffix))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return err
+		default:
+			manifestName := ds.file + manifestSuffix
+			manifestChanged, err := writeIfChanged(filepath.Join(u.gitDir, manifestName), manifest)
+			if err != nil {
+				return err
+			}
+			files = append(files, manifestName)
+			changed = changed || manifestChanged
+		}
+		if changed {
+			names = append(names, ds.name)
+			lines = append(lines, archiveLine(ds, state, manifest))
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	if _, err := gitOutput(ctx, u.gitDir, append([]string{"add", "--"}, files...)...); err != nil {
+		return err
+	}
+	staged, err := gitOutput(ctx, u.gitDir, "diff", "--cached", "--name-only")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(staged) != "" {
+		msg := "Update airports data"
+		if len(names) > 0 {
+			msg = "Update " + strings.Join(names, ", ") + "\n\n" + strings.Join(lines, "\n")
+		}
+		if _, err := gitOutput(ctx, u.gitDir, "commit", "--quiet", "-m", msg); err != nil {
+			return err
+		}
+		head, _ := gitOutput(ctx, u.gitDir, "rev-parse", "--short", "HEAD")
+		slog.Info("committed to git archive", "dir", u.gitDir, "commit", strings.TrimSpace(head))
+	}
+	if u.gitPush {
+		if _, err := gitOutput(ctx, u.gitDir, "push", "--quiet"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveLine describes one updated dataset in the commit message.
+func archiveLine(ds dataset, state fetchState, manifest []byte) string {
+	line := fmt.Sprintf("%s: %s, %d rows", ds.name, state.Snapshot, state.Rows)
+	var m snapshotManifest
+	if json.Unmarshal(manifest, &m) != nil {
+		return line
+	}
+	if m.Source != "" {
+		line += " from " + m.Source
+	}
+	if d := m.Diff; d != nil {
+		line += fmt.Sprintf(" (%d added, %d removed, %d changed)", d.Added, d.Removed, d.Changed)
+	}
+	return line
+}
+
+// gitOutput runs git in dir and returns its standard output. Errors carry
+// git's own message.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.String(), nil
+}
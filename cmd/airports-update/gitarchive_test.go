@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs a git command for the test, failing it on error.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := gitOutput(context.Background(), dir, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(out)
+}
+
+// newGitArchive returns a work tree with one commit, tracking a bare
+// remote.
+func newGitArchive(t *testing.T) (work, remote string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "airports-update")
+	t.Setenv("GIT_AUTHOR_EMAIL", "airports-update@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "airports-update")
+	t.Setenv("GIT_COMMITTER_EMAIL", "airports-update@example.com")
+
+	remote, work = t.TempDir(), t.TempDir()
+	git(t, remote, "init", "--quiet", "--bare")
+	git(t, work, "init", "--quiet")
+	touch(t, work, "README")
+	git(t, work, "add", "README")
+	git(t, work, "commit", "--quiet", "-m", "Start")
+	git(t, work, "remote", "add", "origin", remote)
+	git(t, work, "push", "--quiet", "-u", "origin", "HEAD")
+	return work, remote
+}
+
+func TestArchiveToGit(t *testing.T) {
+	work, remote := newGitArchive(t)
+	ctx := context.Background()
+	if err := checkGitRepo(ctx, work); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkGitRepo(ctx, t.TempDir()); err == nil {
+		t.Error("checkGitRepo accepted a plain directory")
+	}
+
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.gitDir, u.gitPush = work, true
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(work, "airports.csv")); err != nil || string(b) != testAirports {
+		t.Errorf("archived airports.csv: %q, %v", b, err)
+	}
+	if !fileExists(filepath.Join(work, "airports"+manifestSuffix)) {
+		t.Error("manifest not archived")
+	}
+
+	// The commit was pushed, and describes the snapshot.
+	msg := git(t, remote, "log", "-1", "--format=%B")
+	want := "Update airports\n\nairports: " + u.results[0].Snapshot + ", 3 rows from " + m.URL + "/airports.csv"
+	if msg != want {
+		t.Errorf("commit message %q, want %q", msg, want)
+	}
+
+	// Nothing new: no commit.
+	if err := u.run(ctx); !errors.Is(err, errNotModified) {
+		t.Fatalf("second run = %v", err)
+	}
+	if n := git(t, work, "rev-list", "--count", "HEAD"); n != "2" {
+		t.Errorf("%s commits after an unchanged run, want 2", n)
+	}
+}
+
+func TestArchiveLine(t *testing.T) {
+	state := fetchState{Snapshot: "airports-20240501-100000.csv", Rows: 3}
+	manifest := []byte(`{"source":"https://example.com/airports.csv","diff":{"previous":"x","added":1,"removed":2,"changed":3}}`)
+	got := archiveLine(knownDatasets[0], state, manifest)
+	want := "airports: airports-20240501-100000.csv, 3 rows from https://example.com/airports.csv (1 added, 2 removed, 3 changed)"
+	if got != want {
+		t.Errorf("archiveLine = %q, want %q", got, want)
+	}
+	if got := archiveLine(knownDatasets[0], state, nil); got != "airports: airports-20240501-100000.csv, 3 rows" {
+		t.Errorf("archiveLine without a manifest = %q", got)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// runPostHook runs the -post-hook command through the shell after a
+// dataset has been updated. The new files are described in environment
+// variables:
+//
+//	AIRPORTS_UPDATE_DATASET        airports, runways, ...
+//	AIRPORTS_UPDATE_SOURCE         URL the data was downloaded from
+//	AIRPORTS_UPDATE_FILE           path of the new snapshot
+//	AIRPORTS_UPDATE_LATEST         path of the refreshed latest file
+//	AIRPORTS_UPDATE_SHA256         SHA-256 of the snapshot
+//	AIRPORTS_UPDATE_ROWS           data rows in the snapshot
+//	AIRPORTS_UPDATE_PREVIOUS_ROWS  data rows in the previous latest file
+//	AIRPORTS_UPDATE_BYTES          downloaded size
+func (u *updater) runPostHook(ctx context.Context, res *datasetResult, snapshotPath, latestPath string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.postHookTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", u.postHook)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", u.postHook)
+	}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"AIRPORTS_UPDATE_DATASET="+res.Name,
+		"AIRPORTS_UPDATE_SOURCE="+res.Source,
+		"AIRPORTS_UPDATE_FILE="+snapshotPath,
+		"AIRPORTS_UPDATE_LATEST="+latestPath,
+		"AIRPORTS_UPDATE_SHA256="+res.SHA256,
+		"AIRPORTS_UPDATE_ROWS="+strconv.Itoa(res.Rows),
+		"AIRPORTS_UPDATE_PREVIOUS_ROWS="+strconv.Itoa(res.PrevRows),
+		"AIRPORTS_UPDATE_BYTES="+strconv.FormatInt(res.Bytes, 10),
+	)
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("post-hook failed: %w", err)
+	}
+	slog.Info("post-hook finished", "dataset", res.Name, "duration", time.Since(start).Round(time.Millisecond).String())
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test hooks are sh scripts")
+	}
+	out := filepath.Join(t.TempDir(), "env")
+	t.Setenv("HOOK_OUT", out)
+
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.postHook = `env | grep '^AIRPORTS_UPDATE_' | sort > "$HOOK_OUT"`
+	u.postHookTimeout = time.Minute
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		k, v, _ := strings.Cut(line, "=")
+		env[k] = v
+	}
+	res := u.results[0]
+	want := map[string]string{
+		"AIRPORTS_UPDATE_DATASET":       "airports",
+		"AIRPORTS_UPDATE_SOURCE":        m.URL + "/airports.csv",
+		"AIRPORTS_UPDATE_FILE":          filepath.Join(u.outDir, res.Snapshot),
+		"AIRPORTS_UPDATE_LATEST":        filepath.Join(u.outDir, "airports-latest.csv"),
+		"AIRPORTS_UPDATE_SHA256":        res.SHA256,
+		"AIRPORTS_UPDATE_ROWS":          "3",
+		"AIRPORTS_UPDATE_PREVIOUS_ROWS": "0",
+		"AIRPORTS_UPDATE_BYTES":         strconv.Itoa(len(testAirports)),
+	}
+	if len(env) != len(want) {
+		t.Errorf("hook saw %v", env)
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+
+	// A failing or hung hook fails the run.
+	u.postHook = "exit 3"
+	u.force = true
+	if err := u.run(context.Background()); err == nil || !strings.Contains(err.Error(), "post-hook failed") {
+		t.Errorf("run with a failing hook = %v", err)
+	}
+	u.postHook = "exec sleep 10"
+	u.postHookTimeout = 50 * time.Millisecond
+	start := time.Now()
+	if err := u.run(context.Background()); err == nil {
+		t.Error("run with a hung hook succeeded")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("hung hook ran for %v", d)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockName is the lockfile in the output directory held during a run.
+const lockName = ".airports-update.lock"
+
+// lockInfo identifies the run holding the lock.
+type lockInfo struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+// acquireLock creates the lockfile in dir, first clearing a stale one: left
+// by a process on this host that has exited, or older than staleAfter.
+// Several runs may find the same stale lock; clearStaleLock makes sure only
+// one of them replaces it. The returned function releases the lock.
+func acquireLock(dir string, staleAfter time.Duration) (func(), error) {
+	path := filepath.Join(dir, lockName)
+	host, _ := os.Hostname()
+	me := lockInfo{PID: os.Getpid(), Host: host, Started: time.Now().UTC()}
+	b, err := json.Marshal(me)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = f.Write(b)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lockfile: %w", err)
+			}
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lockfile: %w", err)
+		}
+
+		holder, seen, stale := inspectLock(path, host, staleAfter)
+		if !stale {
+			return nil, fmt.Errorf("another update is running (pid %d on %s since %s); lockfile %s",
+				holder.PID, holder.Host, holder.Started.Format(time.RFC3339), path)
+		}
+		if seen != nil {
+			slog.Warn("removing stale lockfile", "path", path, "pid", holder.PID, "host", holder.Host)
+		}
+		if err := clearStaleLock(path, seen); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("could not acquire lockfile %s", path)
+}
+
+// inspectLock reads the lockfile and reports whether it is stale, along
+// with the contents it judged.
+func inspectLock(path, host string, staleAfter time.Duration) (lockInfo, []byte, bool) {
+	var holder lockInfo
+	b, err := os.ReadFile(path)
+	if err != nil {
+		// Released between our create and read; try again.
+		return holder, nil, errors.Is(err, fs.ErrNotExist)
+	}
+	if json.Unmarshal(b, &holder) != nil {
+		return holder, b, true // half-written by a crashed run
+	}
+	if holder.Host == host && !processAlive(holder.PID) {
+		return holder, b, true
+	}
+	return holder, b, staleAfter > 0 && time.Since(holder.Started) > staleAfter
+}
+
+// clearStaleLock removes the lockfile inspectLock judged stale from its
+// contents seen. Removing path directly could delete a lock another run
+// created after clearing the same stale one, so the file is first renamed
+// aside, which only one run can do, and checked: a lockfile that no longer
+// holds seen is put back.
+func clearStaleLock(path string, seen []byte) error {
+	aside := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // cleared by another run; try again
+		}
+		return fmt.Errorf("failed to remove stale lockfile: %w", err)
+	}
+	defer os.Remove(aside)
+	if b, err := os.ReadFile(aside); err == nil && bytes.Equal(b, seen) {
+		return nil
+	}
+	// Link rather than rename back, so a lock created in the meantime
+	// isn't overwritten.
+	if err := os.Link(aside, path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("failed to restore lockfile taken over by another run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// processAlive reports whether a process with the given pid may exist.
+// There is no signal 0 here: on Windows FindProcess opens the process and
+// fails when there is none, and elsewhere it always succeeds, leaving
+// locks of exited runs to -lock-stale.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exitedPID returns the pid of a process that has exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func writeLock(t *testing.T, dir string, info lockInfo) []byte {
+	t.Helper()
+	b, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, lockName), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestAcquireLock(t *testing.T) {
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+	tests := []struct {
+		name  string
+		lock  func(t *testing.T, dir string)
+		stale time.Duration
+		busy  bool
+	}{
+		{name: "free", lock: func(*testing.T, string) {}},
+		{
+			name: "running here",
+			lock: func(t *testing.T, dir string) {
+				writeLock(t, dir, lockInfo{PID: os.Getpid(), Host: host, Started: now})
+			},
+			stale: time.Hour,
+			busy:  true,
+		},
+		{
+			name: "exited here",
+			lock: func(t *testing.T, dir string) {
+				writeLock(t, dir, lockInfo{PID: exitedPID(t), Host: host, Started: now})
+			},
+			stale: time.Hour,
+		},
+		{
+			name: "other host",
+			lock: func(t *testing.T, dir string) {
+				writeLock(t, dir, lockInfo{PID: exitedPID(t), Host: "elsewhere", Started: now})
+			},
+			stale: time.Hour,
+			busy:  true,
+		},
+		{
+			name: "too old",
+			lock: func(t *testing.T, dir string) {
+				writeLock(t, dir, lockInfo{PID: os.Getpid(), Host: "elsewhere", Started: now.Add(-2 * time.Hour)})
+			},
+			stale: time.Hour,
+		},
+		{
+			name: "old, no -lock-stale",
+			lock: func(t *testing.T, dir string) {
+				writeLock(t, dir, lockInfo{PID: os.Getpid(), Host: "elsewhere", Started: now.Add(-2 * time.Hour)})
+			},
+			busy: true,
+		},
+		{
+			name: "half written",
+			lock: func(t *testing.T, dir string) {
+				if err := os.WriteFile(filepath.Join(dir, lockName), []byte(`{"pid":`), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.lock(t, dir)
+			release, err := acquireLock(dir, tt.stale)
+			if tt.busy {
+				if err == nil || !strings.Contains(err.Error(), "another update is running") {
+					t.Fatalf("err = %v, want the lock to be busy", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(filepath.Join(dir, lockName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var info lockInfo
+			if err := json.Unmarshal(b, &info); err != nil || info.PID != os.Getpid() {
+				t.Errorf("lockfile holds %s", b)
+			}
+			if _, err := acquireLock(dir, tt.stale); err == nil {
+				t.Error("lock acquired twice")
+			}
+			release()
+			if got := dirNames(t, dir); len(got) != 0 {
+				t.Errorf("left %v after release", got)
+			}
+		})
+	}
+}
+
+func TestClearStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, lockName)
+	stale := writeLock(t, dir, lockInfo{PID: 1, Host: "elsewhere"})
+
+	// Another run cleared the stale lock and took it over before us: its
+	// lockfile must survive.
+	fresh := writeLock(t, dir, lockInfo{PID: 2, Host: "elsewhere", Started: time.Now().UTC()})
+	if err := clearStaleLock(path, stale); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != string(fresh) {
+		t.Fatalf("lockfile = %s, %v; want the other run's", b, err)
+	}
+
+	if err := clearStaleLock(path, fresh); err != nil {
+		t.Fatal(err)
+	}
+	if got := dirNames(t, dir); len(got) != 0 {
+		t.Errorf("left %v", got)
+	}
+	// Already cleared by another run.
+	if err := clearStaleLock(path, fresh); err != nil {
+		t.Error(err)
+	}
+}
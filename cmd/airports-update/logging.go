@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// newLogger returns the logger for -log-format, at Warn level with -quiet
+// and Debug with -verbose.
+func newLogger(format string, quiet, verbose bool) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	switch {
+	case quiet && verbose:
+		return nil, fmt.Errorf("-quiet and -verbose are mutually exclusive")
+	case quiet:
+		level = slog.LevelWarn
+	case verbose:
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown -log-format %q (want text or json)", format)
+}
+
+// fatal logs err and exits with status 1.
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		format         string
+		quiet, verbose bool
+		level          slog.Level // lowest enabled level
+	}{
+		{"text", false, false, slog.LevelInfo},
+		{"json", false, false, slog.LevelInfo},
+		{"text", true, false, slog.LevelWarn},
+		{"json", false, true, slog.LevelDebug},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		logger, err := newLogger(tt.format, tt.quiet, tt.verbose)
+		if err != nil {
+			t.Errorf("newLogger(%q, %v, %v): %v", tt.format, tt.quiet, tt.verbose, err)
+			continue
+		}
+		if !logger.Enabled(ctx, tt.level) || logger.Enabled(ctx, tt.level-1) {
+			t.Errorf("newLogger(%q, %v, %v) isn't at level %v", tt.format, tt.quiet, tt.verbose, tt.level)
+		}
+		switch h := logger.Handler().(type) {
+		case *slog.TextHandler:
+			if tt.format != "text" {
+				t.Errorf("format %q gave a text handler", tt.format)
+			}
+		case *slog.JSONHandler:
+			if tt.format != "json" {
+				t.Errorf("format %q gave a JSON handler", tt.format)
+			}
+		default:
+			t.Errorf("format %q gave %T", tt.format, h)
+		}
+	}
+	if _, err := newLogger("text", true, true); err == nil {
+		t.Error("-quiet with -verbose accepted")
+	}
+	if _, err := newLogger("logfmt", false, false); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
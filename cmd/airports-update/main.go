@@ -1,97 +1,257 @@
+// Command airports-update downloads the OurAirports CSV into a timestamped
+// snapshot and refreshes airports-latest.csv. With -datasets it also fetches
+// the runways, countries, regions, navaids and frequencies files the same
+// way, and the OpenFlights airports.dat, airlines.dat and routes.dat.
+//
+// It exits 0 after saving a new snapshot, 3 when the server reports every
+// dataset unchanged since the last run, and 1 on failure. With -emit-go an
+// unchanged dataset exits 0 too, so it can run under go:generate. With -daemon it
+// instead keeps running, updating on a schedule until SIGINT or SIGTERM.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"slices"
+	"syscall"
 	"time"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
 )
 
 const defaultAirportsURL = "https://ourairports.com/airports.csv"
 
+// exitNotModified is the exit status when there was nothing to download.
+const exitNotModified = 3
+
 func main() {
+	configPath := flag.String("config", os.Getenv("AIRPORTS_UPDATE_CONFIG"), "YAML `FILE` of settings keyed by flag name; flags on the command line win")
 	outDir := flag.String("out", "data", "output directory for airports CSV files")
-	url := flag.String("url", defaultAirportsURL, "OurAirports CSV URL")
+	var urls, headerFlags stringList
+	flag.Var(&urls, "url", "OurAirports CSV `URL`; repeat to list mirrors, tried in order (default "+defaultAirportsURL+")")
+	mirrorsFile := flag.String("mirrors", "", "`FILE` of further airports CSV URLs to fall back to, one per line")
+	datasetList := flag.String("datasets", "airports", "comma-separated datasets to fetch: airports, runways, countries, regions, navaids, frequencies, openflights-airports, openflights-airlines, openflights-routes")
+	openFlightsURL := flag.String("openflights-url", defaultOpenFlightsURL, "`URL` of the directory holding the OpenFlights .dat files")
+	force := flag.Bool("force", false, "download even if the server reports no change since the last run")
+	retries := flag.Int("retries", 3, "retries after a network error, timeout or 408/429/5xx response")
+	retryBackoff := flag.Duration("retry-backoff", 2*time.Second, "delay before the first retry, doubled for each further retry")
+	timeout := flag.Duration("timeout", 5*time.Minute, "limit for each download attempt, including the body")
+	userAgent := flag.String("user-agent", defaultUserAgent, "User-Agent header for downloads")
+	flag.Var(&headerFlags, "header", "extra download request header as `\"Name: value\"`; repeatable, e.g. for mirror auth")
+	caCert := flag.String("ca-cert", "", "PEM `FILE` of extra CA certificates to trust")
+	clientCert := flag.String("client-cert", "", "PEM client certificate `FILE` for mutual TLS")
+	clientKey := flag.String("client-key", "", "PEM private key `FILE` for -client-cert")
+	insecure := flag.Bool("insecure-skip-verify", false, "don't verify server certificates (internal mirrors only)")
+	limitRate := flag.String("limit-rate", "", "cap the combined download rate, in bytes per second with optional k, m or g suffix, e.g. 500k")
+	progress := flag.Duration("progress", 0, "log download progress (bytes, percent, rate) this often, e.g. 10s (0 disables)")
+	resume := flag.Bool("resume", true, "resume an interrupted download with a Range request instead of starting over")
+	minRowRatio := flag.Float64("min-row-ratio", 0.9, "refuse a download with fewer rows than this fraction of the current latest file (0 disables)")
+	maxBadRatio := flag.Float64("max-bad-ratio", 0.01, "refuse a download where more than this fraction of rows fail to parse (0 disables)")
+	minBytes := flag.Int64("min-bytes", 1024, "refuse a download smaller than this many bytes")
+	maxStaleness := flag.Duration("max-staleness", 0, "refuse airports data whose newest last_updated is older than this, e.g. 336h (0 disables)")
+	staleAction := flag.String("stale-action", "fail", "what -max-staleness does with stale data: fail or warn")
+	keep := flag.Int("keep", 0, "keep only the newest `N` snapshots (0 keeps all)")
+	maxAge := flag.Duration("max-age", 0, "delete snapshots older than this, e.g. 720h (0 keeps all)")
+	compress := flag.String("compress", "", "store snapshots compressed: gzip or zstd")
+	compressLatest := flag.Bool("compress-latest", false, "with -compress, compress the latest file too (airports-latest.csv.gz)")
+	latestMode := flag.String("latest-mode", "copy", "how to refresh the latest file: copy, or symlink to the new snapshot")
+	binary := flag.Bool("binary", false, "also write "+binaryLatestName+", the parsed airports store for iataplaces.LoadFromGob")
+	diffOut := flag.String("diff", "", "write a JSON summary of added, removed and changed airports to `FILE` (\"-\" for stdout)")
+	delta := flag.Bool("delta", false, "also write a gzipped JSON delta (added, changed and removed airports) against the previous snapshot, for iataplaces ApplyDelta")
+	upload := flag.String("upload", "", "also publish snapshots and latest files to `s3://bucket/prefix` or gs://bucket/prefix")
+	gitArchive := flag.String("git-archive", "", "also commit the newest file and manifest of each dataset to the Git work tree `DIR`, e.g. airports.csv")
+	gitPush := flag.Bool("git-push", true, "with -git-archive, push the current branch after committing")
+	notifyURL := flag.String("notify-url", "", "POST a JSON run summary to this webhook `URL` (Slack incoming webhooks show its text)")
+	notifyOn := flag.String("notify-on", "always", "when to notify: always, change (skip runs with nothing new) or failure")
+	pushgateway := flag.String("metrics-pushgateway", "", "push run metrics to this Prometheus Pushgateway `URL`")
+	statsdAddr := flag.String("metrics-statsd", "", "send run metrics as statsd gauges to `host:port` over UDP")
+	metricsJob := flag.String("metrics-job", "airports_update", "Pushgateway job name")
+	lockStale := flag.Duration("lock-stale", 6*time.Hour, "treat a lockfile older than this as left by a crashed run (0: only when its process is gone)")
+	postHook := flag.String("post-hook", "", "shell `command` to run after each updated dataset; AIRPORTS_UPDATE_* variables describe the new file")
+	postHookTimeout := flag.Duration("post-hook-timeout", 5*time.Minute, "kill -post-hook after this long")
+	emitGo := flag.String("emit-go", "", "also write airports-latest.csv into `DIR` as a Go package that embeds it, for go:generate")
+	emitGoPkg := flag.String("emit-go-package", "", "package name for -emit-go (default: derived from DIR)")
+	emitGoSubset := flag.String("emit-go-subset", "", "embed only the rows matching `SPEC` with -emit-go, e.g. \"continent=EU;scheduled\" (terms: continent=, country=, type=, scheduled, iata)")
+	dryRun := flag.Bool("dry-run", false, "download and validate into a temporary directory and report what would change, leaving -out untouched")
+	daemon := flag.Bool("daemon", false, "keep running and update on a schedule instead of once")
+	interval := flag.Duration("interval", 24*time.Hour, "time between updates in -daemon mode")
+	scheduleExpr := flag.String("schedule", "", "cron expression for -daemon mode, e.g. \"0 3 * * *\"; overrides -interval")
+	jitter := flag.Duration("jitter", 0, "random delay of up to this much added to each scheduled update")
+	verify := flag.String("verify", "", "check `FILE` against FILE.sha256 and exit instead of downloading")
+	logFormat := flag.String("log-format", "text", "log format: text or json")
+	quiet := flag.Bool("quiet", false, "only log warnings and errors")
+	verbose := flag.Bool("verbose", false, "also log debug detail")
 	flag.Parse()
-
-	if err := os.MkdirAll(*outDir, 0o755); err != nil {
-		log.Fatalf("failed to create output dir %s: %v", *outDir, err)
+	if *configPath != "" {
+		if err := applyConfigFile(flag.CommandLine, *configPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
 	}
 
-	ts := time.Now().UTC().Format("20060102-150405")
-	filename := fmt.Sprintf("airports-%s.csv", ts)
-	fullPath := filepath.Join(*outDir, filename)
-	latestPath := filepath.Join(*outDir, "airports-latest.csv")
-
-	log.Printf("Downloading airports data from %s", *url)
-
-	resp, err := http.Get(*url)
+	logger, err := newLogger(*logFormat, *quiet, *verbose)
 	if err != nil {
-		log.Fatalf("failed to download airports CSV: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	defer resp.Body.Close()
+	slog.SetDefault(logger)
 
-	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("unexpected status code %d from %s", resp.StatusCode, *url)
+	if *verify != "" {
+		if err := verifyChecksum(*verify); err != nil {
+			fatal(err)
+		}
+		slog.Info("checksum OK", "path", *verify)
+		return
 	}
 
-	tempPath := fullPath + ".tmp"
-	outFile, err := os.Create(tempPath)
-	if err != nil {
-		log.Fatalf("failed to create temp file %s: %v", tempPath, err)
+	if *mirrorsFile != "" {
+		mirrors, err := readMirrors(*mirrorsFile)
+		if err != nil {
+			fatal(err)
+		}
+		urls = append(urls, mirrors...)
+	}
+	if len(urls) == 0 {
+		urls = stringList{defaultAirportsURL}
 	}
 
-	n, err := io.Copy(outFile, resp.Body)
-	closeErr := outFile.Close()
+	headers, err := parseHeaders(headerFlags)
 	if err != nil {
-		log.Fatalf("failed to write CSV to %s: %v", tempPath, err)
+		fatal(err)
 	}
-	if closeErr != nil {
-		log.Fatalf("failed to close temp file %s: %v", tempPath, closeErr)
+	if headers.Get("User-Agent") == "" {
+		headers.Set("User-Agent", *userAgent)
 	}
-
-	if err := os.Rename(tempPath, fullPath); err != nil {
-		log.Fatalf("failed to move temp file to final path: %v", err)
+	client, err := newHTTPClient(*timeout, tlsOptions{
+		caFile:             *caCert,
+		certFile:           *clientCert,
+		keyFile:            *clientKey,
+		insecureSkipVerify: *insecure,
+	})
+	if err != nil {
+		fatal(err)
 	}
 
-	log.Printf("Saved airports CSV to %s (%d bytes)", fullPath, n)
-
-	// Also keep a stable "airports-latest.csv" for your scripts.
-	if err := copyFile(fullPath, latestPath); err != nil {
-		log.Fatalf("failed to update %s: %v", latestPath, err)
+	bytesPerSec, err := parseRate(*limitRate)
+	if err != nil {
+		fatal(err)
 	}
-	log.Printf("Updated %s", latestPath)
-}
 
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
+	datasets, err := parseDatasets(*datasetList)
 	if err != nil {
-		return fmt.Errorf("open src: %w", err)
+		fatal(err)
 	}
-	defer in.Close()
-
-	tmp := dst + ".tmp"
-	out, err := os.Create(tmp)
+	if *emitGo != "" && !slices.Contains(datasets, knownDatasets[0]) {
+		fatal(errors.New("-emit-go needs the airports dataset"))
+	}
+	var subset *iataplaces.Subset
+	if *emitGoSubset != "" {
+		sub, err := iataplaces.ParseSubset(*emitGoSubset)
+		if err != nil {
+			fatal(err)
+		}
+		subset = &sub
+	}
+	ext, err := compressExt(*compress)
 	if err != nil {
-		return fmt.Errorf("create dst: %w", err)
+		fatal(err)
+	}
+	if *compress == "none" {
+		*compress = ""
+	}
+	switch {
+	case *latestMode != "copy" && *latestMode != "symlink":
+		fatal(fmt.Errorf("invalid -latest-mode %q (want copy or symlink)", *latestMode))
+	case *latestMode == "symlink" && ext != "" && !*compressLatest:
+		fatal(fmt.Errorf("-latest-mode symlink with -compress needs -compress-latest, so latest and snapshot share a format"))
+	}
+	switch *notifyOn {
+	case "always", "change", "failure":
+	default:
+		fatal(fmt.Errorf("invalid -notify-on %q (want always, change or failure)", *notifyOn))
+	}
+	if *staleAction != "fail" && *staleAction != "warn" {
+		fatal(fmt.Errorf("invalid -stale-action %q (want fail or warn)", *staleAction))
 	}
 
-	if _, err := io.Copy(out, in); err != nil {
-		out.Close()
-		return fmt.Errorf("copy: %w", err)
+	u := &updater{
+		outDir:          *outDir,
+		urls:            urls,
+		openFlightsURL:  *openFlightsURL,
+		datasets:        datasets,
+		force:           *force,
+		client:          client,
+		headers:         headers,
+		retries:         *retries,
+		retryBackoff:    *retryBackoff,
+		maxRetryWait:    *timeout,
+		resume:          *resume,
+		limiter:         newLimiter(bytesPerSec),
+		progressEvery:   *progress,
+		minRowRatio:     *minRowRatio,
+		maxBadRatio:     *maxBadRatio,
+		minBytes:        *minBytes,
+		maxStaleness:    *maxStaleness,
+		staleAction:     *staleAction,
+		keep:            *keep,
+		maxAge:          *maxAge,
+		compress:        *compress,
+		compressExt:     ext,
+		compressLatest:  *compressLatest && ext != "",
+		latestMode:      *latestMode,
+		binary:          *binary,
+		diffOut:         *diffOut,
+		delta:           *delta,
+		notifyURL:       *notifyURL,
+		notifyOn:        *notifyOn,
+		pushgateway:     *pushgateway,
+		statsdAddr:      *statsdAddr,
+		metricsJob:      *metricsJob,
+		dryRun:          *dryRun,
+		lockStale:       *lockStale,
+		postHook:        *postHook,
+		postHookTimeout: *postHookTimeout,
+		emitGoDir:       *emitGo,
+		emitGoPkg:       *emitGoPkg,
+		emitGoSubset:    subset,
 	}
+	if *upload != "" && !*dryRun {
+		if u.uploader, err = newUploader(*upload); err != nil {
+			fatal(err)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := out.Close(); err != nil {
-		return fmt.Errorf("close dst: %w", err)
+	if *gitArchive != "" && !*dryRun {
+		if err := checkGitRepo(ctx, *gitArchive); err != nil {
+			fatal(err)
+		}
+		u.gitDir, u.gitPush = *gitArchive, *gitPush
 	}
 
-	if err := os.Rename(tmp, dst); err != nil {
-		return fmt.Errorf("rename: %w", err)
+	if *daemon {
+		next, err := parseSchedule(*scheduleExpr, *interval)
+		if err != nil {
+			fatal(err)
+		}
+		u.daemon(ctx, next, *jitter)
+		return
 	}
 
-	return nil
+	err = u.run(ctx)
+	switch {
+	case errors.Is(err, errNotModified):
+		slog.Info("nothing to do")
+		if *emitGo == "" {
+			os.Exit(exitNotModified)
+		}
+	case err != nil:
+		fatal(err)
+	}
 }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Manifest files: one next to each snapshot, and a rolling index of all
+// of them in the output directory.
+const (
+	manifestSuffix = ".manifest.json"
+	manifestIndex  = "manifest.json"
+)
+
+// snapshotManifest describes one saved snapshot, for automation that
+// would otherwise have to parse logs.
+type snapshotManifest struct {
+	Dataset  string    `json:"dataset"`
+	Snapshot string    `json:"snapshot"`
+	Source   string    `json:"source"`
+	TakenAt  time.Time `json:"taken_at"`
+	Bytes    int64     `json:"bytes"` // as downloaded, before any compression
+	SHA256   string    `json:"sha256"`
+	Rows     int       `json:"rows"`
+	Delta    string    `json:"delta,omitempty"` // delta file against the previous snapshot
+
+	// Airports only.
+	NewestLastUpdated *time.Time    `json:"newest_last_updated,omitempty"`
+	Diff              *manifestDiff `json:"diff,omitempty"`
+}
+
+// manifestDiff is the diffSummary without its code lists.
+type manifestDiff struct {
+	Previous string `json:"previous"`
+	Added    int    `json:"added"`
+	Removed  int    `json:"removed"`
+	Changed  int    `json:"changed"`
+}
+
+// manifestIndexFile is the rolling index: every snapshot with a manifest
+// still in the output directory, newest first, and the newest snapshot of
+// each dataset.
+type manifestIndexFile struct {
+	Updated   time.Time          `json:"updated"`
+	Latest    map[string]string  `json:"latest"`
+	Snapshots []snapshotManifest `json:"snapshots"`
+}
+
+// writeManifest writes the manifest of the snapshot at fullPath, described
+// by res, and returns its file name. prev names the snapshot the diff was
+// taken against, if known.
+func (u *updater) writeManifest(ds dataset, fullPath, ts, prev string, res *datasetResult) (string, error) {
+	taken, err := time.Parse(snapshotTimeLayout, ts)
+	if err != nil {
+		return "", err
+	}
+	m := snapshotManifest{
+		Dataset:  ds.name,
+		Snapshot: res.Snapshot,
+		Source:   res.Source,
+		TakenAt:  taken,
+		Bytes:    res.Bytes,
+		SHA256:   res.SHA256,
+		Rows:     res.Rows,
+		Delta:    res.Delta,
+	}
+	if ds.name == "airports" {
+		if newest, err := newestUpdate(fullPath); err == nil {
+			m.NewestLastUpdated = &newest
+		}
+		if d := u.diff; d != nil {
+			if prev == "" {
+				prev = d.Previous
+			}
+			m.Diff = &manifestDiff{Previous: prev, Added: d.Added, Removed: d.Removed, Changed: d.Changed}
+		}
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := res.Snapshot + manifestSuffix
+	return name, writeFileAtomic(filepath.Join(u.outDir, name), append(b, '\n'))
+}
+
+// writeManifestIndex rebuilds the index from the manifests in the output
+// directory, so snapshots removed by retention drop out of it too.
+func (u *updater) writeManifestIndex() error {
+	entries, err := os.ReadDir(u.outDir)
+	if err != nil {
+		return err
+	}
+	index := manifestIndexFile{
+		Updated:   time.Now().UTC(),
+		Latest:    map[string]string{},
+		Snapshots: []snapshotManifest{},
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), manifestSuffix) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(u.outDir, e.Name()))
+		if err != nil {
+			return err
+		}
+		var m snapshotManifest
+		if err := json.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("parse %s: %w", e.Name(), err)
+		}
+		index.Snapshots = append(index.Snapshots, m)
+	}
+	sort.SliceStable(index.Snapshots, func(i, j int) bool {
+		a, b := index.Snapshots[i], index.Snapshots[j]
+		if !a.TakenAt.Equal(b.TakenAt) {
+			return a.TakenAt.After(b.TakenAt)
+		}
+		return a.Dataset < b.Dataset
+	})
+	for _, m := range index.Snapshots {
+		if _, ok := index.Latest[m.Dataset]; !ok {
+			index.Latest[m.Dataset] = m.Snapshot
+		}
+	}
+
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(u.outDir, manifestIndex), append(b, '\n'))
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readJSON(t *testing.T, path string, v any) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifests(t *testing.T) {
+	airports := strings.TrimSuffix(diffHeader, "\n") + ",last_updated\n" +
+		"1,EGLL,large_airport,London Heathrow Airport,51.47,-0.46,GB,LHR,2024-03-01T10:00:00+00:00\n" +
+		"2,EGKK,large_airport,London Gatwick Airport,51.15,-0.19,GB,LGW,2024-04-02T08:30:00+00:00\n"
+	m := newMirror(t, map[string]string{
+		"/airports.csv": airports,
+		"/runways.csv":  "id,airport_ident\n1,EGLL\n",
+	})
+	datasets, _ := parseDatasets("airports,runways")
+	u := newTestUpdater(t, m, datasets...)
+	ctx := context.Background()
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	first, runways := u.results[0].Snapshot, u.results[1].Snapshot
+
+	var man snapshotManifest
+	readJSON(t, filepath.Join(u.outDir, first+manifestSuffix), &man)
+	res := u.results[0]
+	if man.Dataset != "airports" || man.Snapshot != first || man.Source != res.Source ||
+		man.SHA256 != res.SHA256 || man.Rows != 2 || man.Bytes != int64(len(airports)) {
+		t.Errorf("manifest %+v", man)
+	}
+	if man.TakenAt.Format(snapshotTimeLayout) != strings.TrimSuffix(strings.TrimPrefix(first, "airports-"), ".csv") {
+		t.Errorf("taken_at %v for %s", man.TakenAt, first)
+	}
+	if want := time.Date(2024, 4, 2, 8, 30, 0, 0, time.UTC); man.NewestLastUpdated == nil || !man.NewestLastUpdated.Equal(want) {
+		t.Errorf("newest_last_updated %v, want %v", man.NewestLastUpdated, want)
+	}
+	if man.Diff != nil {
+		t.Errorf("first snapshot has a diff %+v", man.Diff)
+	}
+
+	// The second snapshot's manifest records the diff against the first.
+	time.Sleep(time.Until(man.TakenAt.Add(time.Second)))
+	m.set("/airports.csv", airports+"3,EGSS,large_airport,London Stansted Airport,51.88,0.23,GB,STN,2024-01-01\n")
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	second := u.results[0].Snapshot
+	readJSON(t, filepath.Join(u.outDir, second+manifestSuffix), &man)
+	if man.Diff == nil || *man.Diff != (manifestDiff{Previous: first, Added: 1}) {
+		t.Errorf("second manifest diff %+v", man.Diff)
+	}
+
+	var index manifestIndexFile
+	readJSON(t, filepath.Join(u.outDir, manifestIndex), &index)
+	var listed []string
+	for _, s := range index.Snapshots {
+		listed = append(listed, s.Snapshot)
+	}
+	if !slices.Equal(listed, []string{second, first, runways}) {
+		t.Errorf("index lists %v", listed)
+	}
+	if index.Latest["airports"] != second || index.Latest["runways"] != runways {
+		t.Errorf("index latest %v", index.Latest)
+	}
+
+	// Pruned snapshots drop out of the index.
+	u.keep = 1
+	if err := u.run(ctx); !errors.Is(err, errNotModified) {
+		t.Fatalf("third run = %v", err)
+	}
+	readJSON(t, filepath.Join(u.outDir, manifestIndex), &index)
+	if len(index.Snapshots) != 2 || index.Latest["airports"] != second {
+		t.Errorf("index after pruning %+v", index)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metric is one gauge value describing a run.
+type metric struct {
+	name   string
+	help   string
+	label  string // optional label name, e.g. "dataset"
+	values map[string]float64
+}
+
+func gauge(name, help string, v float64) metric {
+	return metric{name: name, help: help, values: map[string]float64{"": v}}
+}
+
+func labeled(name, help, label string) metric {
+	return metric{name: name, help: help, label: label, values: map[string]float64{}}
+}
+
+// runMetrics describes the outcome of a run. Series without a label have
+// their value under the "" key.
+func (u *updater) runMetrics(started time.Time, runErr error) []metric {
+	now := time.Now()
+	success := 0.0
+	if runErr == nil || errors.Is(runErr, errNotModified) {
+		success = 1
+	}
+	ms := []metric{
+		gauge("airports_update_duration_seconds", "Duration of the last run.", now.Sub(started).Seconds()),
+		gauge("airports_update_success", "Whether the last run succeeded (1) or failed (0).", success),
+		gauge("airports_update_last_run_timestamp_seconds", "Unix time the last run finished.", float64(now.Unix())),
+	}
+	if success == 1 {
+		// Left out on failure, so the pushed value keeps the time of the
+		// previous success and staleness can be alerted on.
+		ms = append(ms, gauge("airports_update_last_success_timestamp_seconds",
+			"Unix time of the last successful run.", float64(now.Unix())))
+	}
+
+	updated := labeled("airports_update_dataset_updated", "Whether the last run saved a new snapshot.", "dataset")
+	bytesM := labeled("airports_update_dataset_bytes", "Bytes downloaded in the last run.", "dataset")
+	rows := labeled("airports_update_dataset_rows", "Data rows in the last downloaded file.", "dataset")
+	for _, res := range u.results {
+		updated.values[res.Name] = 0
+		if res.Status == "updated" {
+			updated.values[res.Name] = 1
+		}
+		bytesM.values[res.Name] = float64(res.Bytes)
+		if res.Rows > 0 {
+			rows.values[res.Name] = float64(res.Rows)
+		}
+	}
+	ms = append(ms, updated, bytesM, rows)
+
+	if u.diff != nil {
+		changes := labeled("airports_update_airports_changes", "Airports added, removed and changed by the last update.", "change")
+		changes.values["added"] = float64(u.diff.Added)
+		changes.values["removed"] = float64(u.diff.Removed)
+		changes.values["changed"] = float64(u.diff.Changed)
+		ms = append(ms, changes)
+	}
+	return ms
+}
+
+// pushMetrics sends the run metrics to the configured Pushgateway and
+// statsd server.
+func (u *updater) pushMetrics(started time.Time, runErr error) error {
+	ms := u.runMetrics(started, runErr)
+	var errs []error
+	if u.pushgateway != "" {
+		if err := u.pushPrometheus(ms); err != nil {
+			errs = append(errs, fmt.Errorf("pushgateway: %w", err))
+		}
+	}
+	if u.statsdAddr != "" {
+		if err := sendStatsd(u.statsdAddr, ms); err != nil {
+			errs = append(errs, fmt.Errorf("statsd: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pushPrometheus POSTs ms in the text exposition format to the
+// Pushgateway group of u.metricsJob. POST replaces only the metrics being
+// pushed, which keeps the last success timestamp across failed runs.
+func (u *updater) pushPrometheus(ms []metric) error {
+	var b bytes.Buffer
+	for _, m := range ms {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, key := range sortedKeys(m.values) {
+			if m.label == "" {
+				fmt.Fprintf(&b, "%s %s\n", m.name, formatValue(m.values[key]))
+			} else {
+				fmt.Fprintf(&b, "%s{%s=%q} %s\n", m.name, m.label, key, formatValue(m.values[key]))
+			}
+		}
+	}
+
+	endpoint := strings.TrimSuffix(u.pushgateway, "/") + "/metrics/job/" + url.PathEscape(u.metricsJob)
+	resp, err := u.client.Post(endpoint, "text/plain; version=0.0.4", &b)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d from %s", resp.StatusCode, endpoint)
+	}
+	return nil
+}
+
+// sendStatsd writes ms as statsd gauges over UDP, with the label value
+// appended to the metric name (airports_update_dataset_rows.airports).
+func sendStatsd(addr string, ms []metric) error {
+	conn, err := net.DialTimeout("udp", addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// One line per datagram keeps each packet well under the usual MTU.
+	for _, m := range ms {
+		for _, key := range sortedKeys(m.values) {
+			name := m.name
+			if key != "" {
+				name += "." + key
+			}
+			if _, err := fmt.Fprintf(conn, "%s:%s|g", name, formatValue(m.values[key])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
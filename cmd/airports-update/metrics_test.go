@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushPrometheus(t *testing.T) {
+	var path, body string
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing/") {
+			http.NotFound(w, r)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		path, body = r.URL.EscapedPath(), string(b)
+	}))
+	defer gw.Close()
+
+	u := &updater{
+		client:      gw.Client(),
+		pushgateway: gw.URL + "/",
+		metricsJob:  "airports update",
+		results: []datasetResult{
+			{Name: "airports", Status: "updated", Bytes: 2048, Rows: 3},
+			{Name: "runways", Status: "not_modified"},
+		},
+		diff: &diffSummary{Added: 1, Removed: 2, Changed: 3},
+	}
+	if err := u.pushMetrics(time.Now().Add(-time.Second), nil); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/metrics/job/airports%20update" {
+		t.Errorf("pushed to %s", path)
+	}
+	for _, want := range []string{
+		"# TYPE airports_update_success gauge\nairports_update_success 1\n",
+		"airports_update_last_success_timestamp_seconds ",
+		`airports_update_dataset_updated{dataset="airports"} 1`,
+		`airports_update_dataset_updated{dataset="runways"} 0`,
+		`airports_update_dataset_bytes{dataset="airports"} 2048`,
+		`airports_update_dataset_rows{dataset="airports"} 3`,
+		`airports_update_airports_changes{change="removed"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("pushed metrics lack %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `airports_update_dataset_rows{dataset="runways"}`) {
+		t.Error("rows pushed for a dataset that wasn't downloaded")
+	}
+
+	// A failed run keeps the previous success timestamp.
+	u.diff = nil
+	if err := u.pushMetrics(time.Now(), errors.New("boom")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body, "airports_update_success 0\n") || strings.Contains(body, "last_success") {
+		t.Errorf("failed run pushed:\n%s", body)
+	}
+	u.pushgateway = gw.URL + "/missing"
+	if err := u.pushMetrics(time.Now(), nil); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("push to a failing gateway = %v", err)
+	}
+}
+
+func TestSendStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	u := &updater{results: []datasetResult{{Name: "airports", Status: "updated", Bytes: 10, Rows: 3}}}
+	ms := u.runMetrics(time.Now(), nil)
+	if err := sendStatsd(conn.LocalAddr().String(), ms); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	buf := make([]byte, 1500)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(got) < 7 {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("after %d datagrams: %v", len(got), err)
+		}
+		got[string(buf[:n])] = true
+	}
+	for _, want := range []string{"airports_update_success:1|g", "airports_update_dataset_rows.airports:3|g", "airports_update_dataset_bytes.airports:10|g"} {
+		if !got[want] {
+			t.Errorf("no %q in %v", want, got)
+		}
+	}
+}
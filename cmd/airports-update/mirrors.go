@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// stringList is a flag that may be repeated, collecting each value.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// readMirrors reads a mirrors file: one airports CSV URL per line, with
+// blank lines and # comments ignored.
+func readMirrors(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open mirrors file: %w", err)
+	}
+	defer f.Close()
+
+	var urls []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			urls = append(urls, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read mirrors file: %w", err)
+	}
+	return urls, nil
+}
+
+// fetchFromSources downloads ds into tempPath from the first source that
+// works, trying each -url (and mirror) in order once the previous one has
+// used up its retries. It returns the URL actually used.
+func (u *updater) fetchFromSources(ctx context.Context, ds dataset, state fetchState, latestPath, tempPath string) (string, *http.Response, int64, error) {
+	var errs []error
+	sources := u.sources(ds)
+	for i, base := range sources {
+		url := datasetURL(base, ds)
+
+		// Only ask for a conditional response when the file it would stand
+		// in for is still there, and it came from this same source.
+		header := http.Header{}
+		if !u.force && state.URL == url && fileExists(latestPath) {
+			if state.ETag != "" {
+				header.Set("If-None-Match", state.ETag)
+			}
+			if state.LastModified != "" {
+				header.Set("If-Modified-Since", state.LastModified)
+			}
+		}
+
+		slog.Info("downloading", "dataset", ds.name, "url", url)
+		resp, n, err := u.fetch(ctx, ds, url, tempPath, header)
+		if err == nil || errors.Is(err, errNotModified) || ctx.Err() != nil {
+			return url, resp, n, err
+		}
+		errs = append(errs, err)
+		if i < len(sources)-1 {
+			slog.Warn("source failed, trying the next one", "dataset", ds.name, "url", url, "err", err)
+		}
+	}
+	return "", nil, 0, errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadMirrors(t *testing.T) {
+	path := writeFile(t, "mirrors.txt", `# primary is given with -url
+https://mirror-a.example/airports.csv
+
+  https://mirror-b.example/airports.csv  # behind the VPN
+#https://retired.example/airports.csv
+`)
+	got, err := readMirrors(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://mirror-a.example/airports.csv", "https://mirror-b.example/airports.csv"}
+	if !slices.Equal(got, want) {
+		t.Errorf("readMirrors = %v, want %v", got, want)
+	}
+	if _, err := readMirrors(path + ".missing"); err == nil {
+		t.Error("missing mirrors file read")
+	}
+}
+
+func TestFetchFallback(t *testing.T) {
+	broken := newMirror(t, map[string]string{})
+	good := newMirror(t, map[string]string{"/data/airports.csv": testAirports, "/data/runways.csv": "id,airport_ident\n1,EGLL\n"})
+	datasets, _ := parseDatasets("airports,runways")
+	u := newTestUpdater(t, good, datasets...)
+	u.urls = []string{broken.URL + "/airports.csv", good.URL + "/data/airports.csv"}
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Each dataset falls back separately, to its sibling on the mirror.
+	for i, file := range []string{"airports.csv", "runways.csv"} {
+		if want := good.URL + "/data/" + file; u.results[i].Source != want {
+			t.Errorf("%s came from %s, want %s", u.results[i].Name, u.results[i].Source, want)
+		}
+		broken.lastRequest(t, "/"+file)
+	}
+	state, err := loadState(u.outDir, knownDatasets[0])
+	if err != nil || state.URL != good.URL+"/data/airports.csv" {
+		t.Errorf("state %+v, %v", state, err)
+	}
+
+	// When every source fails, the error lists each of them.
+	u.urls = []string{broken.URL + "/airports.csv", broken.URL + "/other/airports.csv"}
+	u.datasets = knownDatasets[:1]
+	err = u.run(context.Background())
+	if err == nil || strings.Count(err.Error(), "status code 404") != 2 {
+		t.Errorf("run with no working source = %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// notification is the JSON body POSTed to -notify-url. Text carries a
+// one-line summary, which is all a Slack incoming webhook displays.
+type notification struct {
+	Text     string          `json:"text"`
+	Status   string          `json:"status"` // "success", "not_modified" or "failed"
+	Host     string          `json:"host,omitempty"`
+	Started  time.Time       `json:"started"`
+	Duration string          `json:"duration"`
+	Datasets []datasetResult `json:"datasets"`
+	Diff     *diffCounts     `json:"diff,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// diffCounts is the airports diff without the code lists, which can run to
+// thousands of entries.
+type diffCounts struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+}
+
+// notify posts the outcome of a run to u.notifyURL, subject to u.notifyOn.
+func (u *updater) notify(started time.Time, runErr error) error {
+	n := notification{
+		Status:   "success",
+		Started:  started.UTC(),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+		Datasets: u.results,
+	}
+	n.Host, _ = os.Hostname()
+	switch {
+	case errors.Is(runErr, errNotModified):
+		n.Status = "not_modified"
+	case runErr != nil:
+		n.Status, n.Error = "failed", runErr.Error()
+	}
+	if u.diff != nil {
+		n.Diff = &diffCounts{Added: u.diff.Added, Removed: u.diff.Removed, Changed: u.diff.Changed}
+	}
+
+	switch {
+	case u.notifyOn == "failure" && n.Status != "failed",
+		u.notifyOn == "change" && n.Status == "not_modified":
+		return nil
+	}
+	n.Text = n.summary()
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	resp, err := u.client.Post(u.notifyURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// summary renders n as one line of text.
+func (n *notification) summary() string {
+	var b strings.Builder
+	switch n.Status {
+	case "failed":
+		fmt.Fprintf(&b, "airports-update FAILED on %s: %s", n.Host, n.Error)
+		return b.String()
+	case "not_modified":
+		fmt.Fprintf(&b, "airports-update on %s: no upstream changes", n.Host)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "airports-update on %s:", n.Host)
+	for _, d := range n.Datasets {
+		if d.Status != "updated" {
+			continue
+		}
+		fmt.Fprintf(&b, " %s %d rows", d.Name, d.Rows)
+		if d.PrevRows > 0 {
+			fmt.Fprintf(&b, " (%+d)", d.Rows-d.PrevRows)
+		}
+		if d.Name == "airports" && n.Diff != nil {
+			fmt.Fprintf(&b, ", %d added, %d removed, %d changed", n.Diff.Added, n.Diff.Removed, n.Diff.Changed)
+		}
+		b.WriteByte(';')
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
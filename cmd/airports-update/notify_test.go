@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	var got []notification
+	status := http.StatusOK
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("webhook body: %v", err)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type %q", ct)
+		}
+		got = append(got, n)
+		w.WriteHeader(status)
+	}))
+	defer hook.Close()
+
+	results := []datasetResult{
+		{Name: "airports", Status: "updated", Rows: 105, PrevRows: 100},
+		{Name: "runways", Status: "updated", Rows: 40},
+		{Name: "countries", Status: "not_modified"},
+	}
+	tests := []struct {
+		on       string
+		err      error
+		wantSent bool
+		status   string
+	}{
+		{"always", nil, true, "success"},
+		{"always", errNotModified, true, "not_modified"},
+		{"change", errNotModified, false, ""},
+		{"change", nil, true, "success"},
+		{"failure", nil, false, ""},
+		{"failure", errors.New("airports: boom"), true, "failed"},
+	}
+	for _, tt := range tests {
+		got = nil
+		u := &updater{client: hook.Client(), notifyURL: hook.URL, notifyOn: tt.on, results: results,
+			diff: &diffSummary{Added: 6, Removed: 1, Changed: 12}}
+		if err := u.notify(time.Now(), tt.err); err != nil {
+			t.Errorf("%s, %v: notify = %v", tt.on, tt.err, err)
+			continue
+		}
+		if (len(got) == 1) != tt.wantSent {
+			t.Errorf("%s, %v: sent %d notifications", tt.on, tt.err, len(got))
+			continue
+		}
+		if tt.wantSent && got[0].Status != tt.status {
+			t.Errorf("%s, %v: status %q, want %q", tt.on, tt.err, got[0].Status, tt.status)
+		}
+	}
+
+	got = nil
+	u := &updater{client: hook.Client(), notifyURL: hook.URL, notifyOn: "always", results: results,
+		diff: &diffSummary{Added: 6, Removed: 1, Changed: 12}}
+	if err := u.notify(time.Now(), nil); err != nil {
+		t.Fatal(err)
+	}
+	n := got[0]
+	if !strings.HasSuffix(n.Text, ": airports 105 rows (+5), 6 added, 1 removed, 12 changed; runways 40 rows") {
+		t.Errorf("text %q", n.Text)
+	}
+	if len(n.Datasets) != 3 || n.Diff == nil || n.Diff.Added != 6 {
+		t.Errorf("notification %+v", n)
+	}
+
+	got = nil
+	if err := u.notify(time.Now(), errors.New("airports: boom")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got[0].Text, "FAILED") || !strings.HasSuffix(got[0].Text, ": airports: boom") || got[0].Error != "airports: boom" {
+		t.Errorf("failure notification %+v", got[0])
+	}
+
+	status = http.StatusInternalServerError
+	if err := u.notify(time.Now(), nil); err == nil || !strings.Contains(err.Error(), "status 500") {
+		t.Errorf("notify to a failing webhook = %v", err)
+	}
+}
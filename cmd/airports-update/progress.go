@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// parseRate parses a -limit-rate value: bytes per second with an optional
+// k, m or g suffix (powers of 1024), as in curl's --limit-rate.
+func parseRate(value string) (int, error) {
+	s := strings.TrimSpace(strings.ToLower(value))
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	mult := 1
+	switch s[len(s)-1] {
+	case 'k':
+		mult = 1 << 10
+	case 'm':
+		mult = 1 << 20
+	case 'g':
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid -limit-rate %q (want bytes per second, e.g. 500k or 2m)", value)
+	}
+	return int(v * float64(mult)), nil
+}
+
+// newLimiter returns a limiter for bytesPerSec shared by all downloads of a
+// run, or nil for no limit. Bursts are capped at 64 KiB so the link is
+// never saturated for long.
+func newLimiter(bytesPerSec int) *rate.Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSec), min(bytesPerSec, 64<<10))
+}
+
+// limitedReader throttles reads to a shared limiter.
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	lim *rate.Limiter
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > l.lim.Burst() {
+		p = p[:l.lim.Burst()]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if werr := l.lim.WaitN(l.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// progressReader logs how far a download has got every interval.
+type progressReader struct {
+	r        io.Reader
+	url      string
+	interval time.Duration
+	offset   int64 // bytes already on disk when resuming
+	total    int64 // expected final size, or -1 when unknown
+
+	start, last time.Time
+	n           int64
+}
+
+func newProgressReader(r io.Reader, url string, interval time.Duration, offset, contentLength int64) *progressReader {
+	total := int64(-1)
+	if contentLength >= 0 {
+		total = offset + contentLength
+	}
+	now := time.Now()
+	return &progressReader{r: r, url: url, interval: interval, offset: offset, total: total, start: now, last: now}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if now := time.Now(); now.Sub(p.last) >= p.interval {
+		p.last = now
+		p.log(now)
+	}
+	return n, err
+}
+
+func (p *progressReader) log(now time.Time) {
+	done := p.offset + p.n
+	attrs := []any{"url", p.url, "bytes", done}
+	if p.total > 0 {
+		attrs = append(attrs, "total", p.total, "percent", fmt.Sprintf("%.1f", float64(done)*100/float64(p.total)))
+	}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		attrs = append(attrs, "rate", formatBytes(float64(p.n)/elapsed)+"/s")
+	}
+	slog.Info("download progress", attrs...)
+}
+
+// formatBytes renders a byte count with a binary unit.
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 0},
+		{"0", 0},
+		{"2048", 2048},
+		{"500k", 500 << 10},
+		{"1.5M", 3 << 19},
+		{" 1g ", 1 << 30},
+	}
+	for _, tt := range tests {
+		if got, err := parseRate(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseRate(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"fast", "-1k", "10kb"} {
+		if _, err := parseRate(bad); err == nil {
+			t.Errorf("parseRate(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	if newLimiter(0) != nil {
+		t.Error("newLimiter(0) isn't nil")
+	}
+	// The first burst is free; the next 10000 bytes take 200ms at 50000/s.
+	lim := newLimiter(50000)
+	if lim.Burst() != 50000 {
+		t.Errorf("burst %d", lim.Burst())
+	}
+	data := bytes.Repeat([]byte("x"), 60000)
+	r := &limitedReader{ctx: context.Background(), r: bytes.NewReader(data), lim: lim}
+	start := time.Now()
+	got, err := io.ReadAll(r)
+	if err != nil || len(got) != len(data) {
+		t.Fatalf("read %d bytes, %v", len(got), err)
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("read took %v, want about 200ms", d)
+	}
+
+	// Cancelling the context stops a throttled read.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = &limitedReader{ctx: ctx, r: bytes.NewReader(data), lim: newLimiter(1000)}
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("read with a cancelled context succeeded")
+	}
+}
+
+func TestProgressReader(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	// Resuming at byte 1000 of 3000; a zero interval logs every read.
+	p := newProgressReader(strings.NewReader(strings.Repeat("x", 2000)), "https://example.com/airports.csv", 0, 1000, 2000)
+	buf := make([]byte, 1000)
+	for i := 0; i < 2; i++ {
+		if _, err := p.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %q", lines)
+	}
+	for _, want := range []string{"bytes=3000", "total=3000", "percent=100.0", "rate="} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("progress line %q lacks %s", lines[1], want)
+		}
+	}
+	if !strings.Contains(lines[0], "percent=66.7") {
+		t.Errorf("first progress line %q", lines[0])
+	}
+
+	logs.Reset()
+	p = newProgressReader(strings.NewReader("xx"), "u", 0, 0, -1)
+	p.Read(buf)
+	if strings.Contains(logs.String(), "total=") || strings.Contains(logs.String(), "percent=") {
+		t.Errorf("unknown size logged %q", logs.String())
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for b, want := range map[float64]string{0: "0.0 B", 1023: "1023.0 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 40: "3072.0 GiB"} {
+		if got := formatBytes(b); got != want {
+			t.Errorf("formatBytes(%v) = %q, want %q", b, got, want)
+		}
+	}
+}
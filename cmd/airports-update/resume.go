@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// partialMeta is kept next to an in-progress download as <path>.json. It
+// holds the validator the partial bytes came with, so a later attempt can
+// ask for the rest with If-Range and get the whole file again if upstream
+// has changed in between.
+type partialMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ifRange returns the If-Range value for m, or "" when the partial bytes
+// can't be safely resumed. Weak ETags aren't allowed in If-Range.
+func (m partialMeta) ifRange() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// resumeOffset returns how many bytes of path can be resumed for url and
+// the If-Range validator to send with them.
+func resumeOffset(path, url string) (int64, string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+	b, err := os.ReadFile(path + ".json")
+	if err != nil {
+		return 0, ""
+	}
+	var m partialMeta
+	if json.Unmarshal(b, &m) != nil || m.URL != url || m.ifRange() == "" {
+		return 0, ""
+	}
+	return info.Size(), m.ifRange()
+}
+
+// savePartialMeta records the validators of a response whose body is
+// about to be written to path.
+func savePartialMeta(path, url string, resp *http.Response) error {
+	b, err := json.Marshal(partialMeta{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".json", b, 0o644)
+}
+
+// removePartial deletes an in-progress download and its metadata.
+func removePartial(path string) {
+	os.Remove(path)
+	os.Remove(path + ".json")
+}
+
+// contentRangeStart returns the first byte position of a
+// "bytes first-last/length" Content-Range header.
+func contentRangeStart(v string) (int64, error) {
+	var first, last int64
+	var length string
+	if _, err := fmt.Sscanf(v, "bytes %d-%d/%s", &first, &last, &length); err != nil {
+		return 0, fmt.Errorf("bad Content-Range %q", v)
+	}
+	return first, nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPartialMetaIfRange(t *testing.T) {
+	lastMod := "Wed, 01 May 2024 10:00:00 GMT"
+	tests := []struct {
+		meta partialMeta
+		want string
+	}{
+		{partialMeta{ETag: `"abc"`, LastModified: lastMod}, `"abc"`},
+		{partialMeta{ETag: `W/"abc"`, LastModified: lastMod}, lastMod},
+		{partialMeta{ETag: `W/"abc"`}, ""},
+		{partialMeta{LastModified: lastMod}, lastMod},
+		{partialMeta{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.meta.ifRange(); got != tt.want {
+			t.Errorf("%+v.ifRange() = %q, want %q", tt.meta, got, tt.want)
+		}
+	}
+}
+
+func TestResumeOffset(t *testing.T) {
+	const url = "https://example.com/airports.csv"
+	tests := []struct {
+		name       string
+		partial    string // "" for no partial file
+		meta       string // "" for no metadata file
+		wantOffset int64
+		wantIf     string
+	}{
+		{"no partial file", "", `{"url":"` + url + `","etag":"\"v1\""}`, 0, ""},
+		{"empty partial file", "-", `{"url":"` + url + `","etag":"\"v1\""}`, 0, ""},
+		{"no metadata", "id,ident\n", "", 0, ""},
+		{"bad metadata", "id,ident\n", "{", 0, ""},
+		{"other URL", "id,ident\n", `{"url":"https://mirror.example/airports.csv","etag":"\"v1\""}`, 0, ""},
+		{"weak ETag only", "id,ident\n", `{"url":"` + url + `","etag":"W/\"v1\""}`, 0, ""},
+		{"ETag", "id,ident\n", `{"url":"` + url + `","etag":"\"v1\""}`, 9, `"v1"`},
+		{"Last-Modified", "id,ident\n", `{"url":"` + url + `","last_modified":"Wed, 01 May 2024 10:00:00 GMT"}`, 9, "Wed, 01 May 2024 10:00:00 GMT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "airports.csv.part")
+			switch tt.partial {
+			case "":
+			case "-":
+				touch(t, filepath.Dir(path), filepath.Base(path))
+			default:
+				if err := os.WriteFile(path, []byte(tt.partial), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.meta != "" {
+				if err := os.WriteFile(path+".json", []byte(tt.meta), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			offset, ifRange := resumeOffset(path, url)
+			if offset != tt.wantOffset || ifRange != tt.wantIf {
+				t.Errorf("resumeOffset = %d, %q; want %d, %q", offset, ifRange, tt.wantOffset, tt.wantIf)
+			}
+		})
+	}
+}
+
+func TestContentRangeStart(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, true},
+		{"bytes 0-0/*", 0, true},
+		{"bytes 4096-8191/12000", 4096, true},
+		{"bytes */200", 0, false},
+		{"100-199/200", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := contentRangeStart(tt.header)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("contentRangeStart(%q) = %d, %v; want %d, ok = %v", tt.header, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+// rangeServer serves a file with http.ServeContent, which honours Range
+// and If-Range, and records the Range header of each request.
+type rangeServer struct {
+	mu       sync.Mutex
+	body     []byte
+	etag     string
+	truncate int // when > 0, the next response stops after this many bytes
+	ranges   []string
+}
+
+func (rs *rangeServer) set(body, etag string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.body, rs.etag = []byte(body), etag
+}
+
+func (rs *rangeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rs.mu.Lock()
+	body, etag, truncate := rs.body, rs.etag, rs.truncate
+	rs.truncate = 0
+	rs.ranges = append(rs.ranges, r.Header.Get("Range"))
+	rs.mu.Unlock()
+
+	w.Header().Set("ETag", etag)
+	if truncate > 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body[:truncate])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler) // drop the connection mid-body
+	}
+	http.ServeContent(w, r, "airports.csv", time.Time{}, bytes.NewReader(body))
+}
+
+func (rs *rangeServer) lastRange() string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.ranges[len(rs.ranges)-1]
+}
+
+func TestFetchOnceResume(t *testing.T) {
+	const (
+		v1 = "id,ident,name\n1,EGLL,London Heathrow Airport\n2,EGKK,London Gatwick Airport\n"
+		v2 = "id,ident,name\n1,EGLL,Heathrow\n2,EGKK,Gatwick\n3,EGSS,Stansted\n"
+	)
+	tests := []struct {
+		name      string
+		partial   string // bytes already downloaded, with v1's validator
+		serve     string
+		etag      string
+		resume    bool
+		wantRange string
+		want      string
+	}{
+		{"fresh download", "", v1, `"v1"`, true, "", v1},
+		{"resume", v1[:20], v1, `"v1"`, true, "bytes=20-", v1},
+		{"upstream changed", v1[:20], v2, `"v2"`, true, "bytes=20-", v2},
+		{"resume disabled", v1[:20], v1, `"v1"`, false, "", v1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &rangeServer{}
+			rs.set(tt.serve, tt.etag)
+			srv := httptest.NewServer(rs)
+			defer srv.Close()
+			url := srv.URL + "/airports.csv"
+
+			path := filepath.Join(t.TempDir(), "airports.csv.part")
+			if tt.partial != "" {
+				if err := os.WriteFile(path, []byte(tt.partial), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path+".json", []byte(`{"url":"`+url+`","etag":"\"v1\""}`), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			u := &updater{client: srv.Client(), headers: http.Header{}, resume: tt.resume}
+			_, n, err := u.fetchOnce(context.Background(), knownDatasets[0], url, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rs.lastRange(); got != tt.wantRange {
+				t.Errorf("Range = %q, want %q", got, tt.wantRange)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("downloaded %q, want %q", got, tt.want)
+			}
+			if n != int64(len(tt.want)) {
+				t.Errorf("size = %d, want %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestFetchOnceTruncated(t *testing.T) {
+	body := strings.Repeat("1,EGLL,London Heathrow Airport\n", 200)
+	rs := &rangeServer{truncate: 1000}
+	rs.set(body, `"v1"`)
+	srv := httptest.NewServer(rs)
+	defer srv.Close()
+	url := srv.URL + "/airports.csv"
+	path := filepath.Join(t.TempDir(), "airports.csv.part")
+	u := &updater{client: srv.Client(), headers: http.Header{}, resume: true}
+
+	_, _, err := u.fetchOnce(context.Background(), knownDatasets[0], url, path, nil)
+	var te *transientError
+	if !errors.As(err, &te) {
+		t.Fatalf("err = %v, want a transient error", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("partial download removed: %v", err)
+	}
+	if info.Size() == 0 || info.Size() >= int64(len(body)) {
+		t.Fatalf("partial download has %d of %d bytes", info.Size(), len(body))
+	}
+
+	_, n, err := u.fetchOnce(context.Background(), knownDatasets[0], url, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rs.lastRange(), fmt.Sprintf("bytes=%d-", info.Size()); got != want {
+		t.Errorf("Range = %q, want %q", got, want)
+	}
+	if got, _ := os.ReadFile(path); string(got) != body || n != int64(len(body)) {
+		t.Errorf("resumed download has %d bytes (reported %d), want %d", len(got), n, len(body))
+	}
+}
+
+func TestFetchOnceBadResume(t *testing.T) {
+	const body = "id,ident\n1,EGLL\n"
+	tests := []struct {
+		name    string
+		partial string
+		handler http.HandlerFunc
+	}{
+		{
+			// The partial file is longer than upstream's, so the range
+			// can't be satisfied.
+			name:    "range not satisfiable",
+			partial: body + body,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "airports.csv", time.Time{}, strings.NewReader(body))
+			},
+		},
+		{
+			name:    "wrong offset",
+			partial: body[:4],
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(body)-1, len(body)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(body))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			url := srv.URL + "/airports.csv"
+			path := filepath.Join(t.TempDir(), "airports.csv.part")
+			if err := os.WriteFile(path, []byte(tt.partial), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path+".json", []byte(`{"url":"`+url+`","etag":"\"v1\""}`), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			u := &updater{client: srv.Client(), headers: http.Header{}, resume: true}
+			_, _, err := u.fetchOnce(context.Background(), knownDatasets[0], url, path, nil)
+			var te *transientError
+			if !errors.As(err, &te) {
+				t.Fatalf("err = %v, want a transient error", err)
+			}
+			for _, p := range []string{path, path + ".json"} {
+				if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("%s kept after a failed resume", filepath.Base(p))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotTimeLayout is the timestamp in airports-<timestamp>.csv.
+const snapshotTimeLayout = "20060102-150405"
+
+// snapshotFile is a timestamped download of one dataset in the output
+// directory.
+type snapshotFile struct {
+	name     string
+	taken    time.Time
+	rejected bool // failed validation; never became latest
+}
+
+// listSnapshots returns the snapshots of ds in dir, newest first.
+func listSnapshots(dir string, ds dataset) ([]snapshotFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snaps []snapshotFile
+	for _, e := range entries {
+		name := e.Name()
+		rejected := strings.HasSuffix(name, ds.ext()+".rejected")
+		stem := strings.TrimSuffix(name, ".rejected")
+		for _, ext := range []string{".gz", ".zst"} {
+			stem = strings.TrimSuffix(stem, ext)
+		}
+		if !strings.HasSuffix(stem, ds.ext()) || !strings.HasPrefix(stem, ds.file+"-") {
+			continue
+		}
+		stem = strings.TrimSuffix(stem, ds.ext())
+		taken, err := time.Parse(snapshotTimeLayout, strings.TrimPrefix(stem, ds.file+"-"))
+		if err != nil {
+			continue // the latest file, and other datasets sharing the prefix
+		}
+		snaps = append(snaps, snapshotFile{name: name, taken: taken, rejected: rejected})
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].taken.After(snaps[j].taken) })
+	return snaps, nil
+}
+
+// prune deletes snapshots beyond the newest u.keep and those older than
+// u.maxAge, along with their checksum, manifest and delta files. The
+// newest good snapshot is always kept. Rejected downloads only expire by
+// age.
+func (u *updater) prune() error {
+	if u.keep <= 0 && u.maxAge <= 0 {
+		return nil
+	}
+	for _, ds := range u.datasets {
+		if err := u.pruneDataset(ds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *updater) pruneDataset(ds dataset) error {
+	snaps, err := listSnapshots(u.outDir, ds)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	now := time.Now().UTC()
+	kept := 0
+	for _, s := range snaps {
+		tooOld := u.maxAge > 0 && now.Sub(s.taken) > u.maxAge
+		if s.rejected {
+			if !tooOld {
+				continue
+			}
+		} else {
+			kept++
+			tooMany := u.keep > 0 && kept > u.keep
+			if kept == 1 || !tooMany && !tooOld {
+				continue
+			}
+		}
+
+		path := filepath.Join(u.outDir, s.name)
+		if u.dryRun {
+			slog.Info("dry run: would prune", "path", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to prune %s: %w", path, err)
+		}
+		os.Remove(path + ".sha256")
+		os.Remove(path + manifestSuffix)
+		os.Remove(path + deltaSuffix)
+		slog.Info("pruned", "path", path)
+	}
+	return nil
+}
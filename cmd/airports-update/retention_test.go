@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// snapshotName returns the name of an airports snapshot taken age ago.
+func snapshotName(age time.Duration, suffix string) string {
+	return "airports-" + time.Now().UTC().Add(-age).Format(snapshotTimeLayout) + ".csv" + suffix
+}
+
+// touch creates empty files in dir.
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestListSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	newest := snapshotName(time.Hour, "")
+	gz := snapshotName(48*time.Hour, ".gz")
+	rejected := snapshotName(2*time.Hour, ".rejected")
+	oldest := snapshotName(240*time.Hour, ".zst")
+	touch(t, dir, oldest, newest, gz, rejected,
+		"airports-latest.csv",
+		"airports-latest.bin",
+		"airports-20240101-000000.csv.sha256",
+		"airport-frequencies-20240101-000000.csv",
+		"openflights-airports-20240101-000000.dat",
+		"notes.txt",
+	)
+
+	snaps, err := listSnapshots(dir, knownDatasets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range snaps {
+		names = append(names, s.name)
+		if s.rejected != (s.name == rejected) {
+			t.Errorf("%s: rejected = %v", s.name, s.rejected)
+		}
+	}
+	if want := []string{newest, rejected, gz, oldest}; !slices.Equal(names, want) {
+		t.Errorf("listSnapshots = %v, want %v", names, want)
+	}
+
+	// Each dataset only sees its own snapshots, even with a similar name.
+	others, err := parseDatasets("openflights-airports,frequencies,runways")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int{1, 1, 0} {
+		snaps, err := listSnapshots(dir, others[i])
+		if err != nil || len(snaps) != want {
+			t.Errorf("listSnapshots(%s) = %+v, %v; want %d snapshots", others[i].name, snaps, err, want)
+		}
+	}
+
+	if snaps, err := listSnapshots(filepath.Join(dir, "missing"), knownDatasets[0]); err != nil || snaps != nil {
+		t.Errorf("missing directory: %+v, %v", snaps, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	day := 24 * time.Hour
+	var (
+		good1h   = snapshotName(time.Hour, "")
+		good2d   = snapshotName(2*day, "")
+		good3d   = snapshotName(3*day, ".gz")
+		good10d  = snapshotName(10*day, "")
+		good20d  = snapshotName(20*day, ".zst")
+		rejected = snapshotName(30*time.Minute, ".rejected")
+		rej40d   = snapshotName(40*day, ".rejected")
+	)
+	all := []string{good1h, good2d, good3d, good10d, good20d, rejected, rej40d}
+
+	tests := []struct {
+		name   string
+		keep   int
+		maxAge time.Duration
+		dryRun bool
+		pruned []string
+	}{
+		{name: "no limits"},
+		{name: "keep", keep: 2, pruned: []string{good3d, good10d, good20d}},
+		{name: "max age", maxAge: 7 * day, pruned: []string{good10d, good20d, rej40d}},
+		{name: "keep and max age", keep: 2, maxAge: 7 * day, pruned: []string{good3d, good10d, good20d, rej40d}},
+		{name: "newest is always kept", keep: 1, maxAge: time.Minute, pruned: []string{good2d, good3d, good10d, good20d, rejected, rej40d}},
+		{name: "dry run", keep: 1, dryRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var want []string
+			for _, name := range all {
+				files := []string{name, name + ".sha256", name + manifestSuffix, name + deltaSuffix}
+				touch(t, dir, files...)
+				if !slices.Contains(tt.pruned, name) {
+					want = append(want, files...)
+				}
+			}
+			touch(t, dir, "airports-latest.csv", "runways-20000101-000000.csv")
+			want = append(want, "airports-latest.csv", "runways-20000101-000000.csv")
+
+			u := &updater{
+				outDir:   dir,
+				datasets: knownDatasets[:1],
+				keep:     tt.keep,
+				maxAge:   tt.maxAge,
+				dryRun:   tt.dryRun,
+			}
+			if err := u.prune(); err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(want)
+			if got := dirNames(t, dir); !slices.Equal(got, want) {
+				t.Errorf("left %v\nwant %v", got, want)
+			}
+		})
+	}
+}
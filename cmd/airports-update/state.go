@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// stateFile names the file in the output directory that remembers the HTTP
+// validators of the last successful download of ds.
+func stateFile(ds dataset) string {
+	return "." + ds.file + "-update.json"
+}
+
+type fetchState struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Snapshot     string `json:"snapshot,omitempty"` // file name of that download
+	Rows         int    `json:"rows,omitempty"`     // its data rows
+}
+
+// loadState reads the saved state; a missing file is an empty state.
+func loadState(dir string, ds dataset) (fetchState, error) {
+	var st fetchState
+	b, err := os.ReadFile(filepath.Join(dir, stateFile(ds)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, fmt.Errorf("failed to read download state: %w", err)
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, fmt.Errorf("failed to parse %s: %w", stateFile(ds), err)
+	}
+	return st, nil
+}
+
+func saveState(dir string, ds dataset, st fetchState) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, stateFile(ds)), append(b, '\n'))
+}
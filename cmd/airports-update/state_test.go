@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConditionalDownload(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	ctx := context.Background()
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	state, err := loadState(u.outDir, knownDatasets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.URL != m.URL+"/airports.csv" || state.ETag == "" || state.Rows != 3 {
+		t.Errorf("saved state %+v", state)
+	}
+
+	// Unchanged upstream: the saved ETag gets a 304.
+	if err := u.run(ctx); !errors.Is(err, errNotModified) {
+		t.Fatalf("second run = %v, want errNotModified", err)
+	}
+	if got := m.lastRequest(t, "/airports.csv").Header.Get("If-None-Match"); got != state.ETag {
+		t.Errorf("If-None-Match = %q, want %q", got, state.ETag)
+	}
+
+	// -force ignores the validators.
+	u.force = true
+	if err := u.run(ctx); err != nil {
+		t.Fatalf("forced run = %v", err)
+	}
+	if got := m.lastRequest(t, "/airports.csv").Header.Get("If-None-Match"); got != "" {
+		t.Errorf("forced run sent If-None-Match %q", got)
+	}
+	u.force = false
+
+	// Without the latest file there's nothing to stand in for, so the
+	// download is unconditional.
+	if err := os.Remove(filepath.Join(u.outDir, "airports-latest.csv")); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.run(ctx); err != nil {
+		t.Fatalf("run without latest = %v", err)
+	}
+	if !fileExists(filepath.Join(u.outDir, "airports-latest.csv")) {
+		t.Error("latest file not restored")
+	}
+}
+
+func TestLoadState(t *testing.T) {
+	dir := t.TempDir()
+	ds := knownDatasets[1]
+	if st, err := loadState(dir, ds); err != nil || st != (fetchState{}) {
+		t.Errorf("missing state = %+v, %v", st, err)
+	}
+	want := fetchState{URL: "https://example.com/runways.csv", LastModified: "Wed, 01 May 2024 10:00:00 GMT", Rows: 5}
+	if err := saveState(dir, ds, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loadState(dir, ds); err != nil || got != want {
+		t.Errorf("loadState = %+v, %v; want %+v", got, err, want)
+	}
+	if err := os.WriteFile(filepath.Join(dir, stateFile(ds)), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(dir, ds); err == nil {
+		t.Error("corrupt state loaded")
+	}
+}
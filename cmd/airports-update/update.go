@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// errNotModified is returned by run when a conditional request finds the
+// upstream file unchanged.
+var errNotModified = errors.New("not modified")
+
+// updater downloads one snapshot of each dataset per run.
+type updater struct {
+	outDir         string
+	urls           []string // airports URLs tried in order; the other datasets are their siblings
+	openFlightsURL string   // directory of the OpenFlights .dat files
+	datasets       []dataset
+	force          bool // ignore saved validators
+
+	client       *http.Client
+	headers      http.Header   // sent with every download, including User-Agent
+	retries      int           // extra attempts after a transient failure
+	retryBackoff time.Duration // delay before the first retry
+	maxRetryWait time.Duration // longest wait between attempts; 0 for no limit
+	resume       bool          // continue a partial download with a Range request
+
+	limiter       *rate.Limiter // caps the combined download rate; nil for no limit
+	progressEvery time.Duration // log download progress this often; 0 disables
+
+	minRowRatio float64 // reject a dataset with fewer rows than this share of the previous one
+	maxBadRatio float64 // reject a dataset with more than this share of unparseable rows
+	minBytes    int64   // reject a download smaller than this
+
+	maxStaleness time.Duration // newest last_updated in airports may be at most this old; 0 disables
+	staleAction  string        // "fail" or "warn" when it is older
+
+	keep   int           // snapshots to retain; 0 keeps all
+	maxAge time.Duration // delete snapshots older than this; 0 keeps all
+
+	compress       string // "gzip" or "zstd" to store snapshots compressed; "" keeps them plain
+	compressExt    string // file extension for compress
+	compressLatest bool   // compress the latest files too
+	latestMode     string // "copy" or "symlink"
+	binary         bool   // also write airports-latest.bin for iataplaces.LoadFromGob
+
+	lockStale time.Duration // treat a lockfile older than this as abandoned
+
+	postHook        string // shell command run after each dataset update
+	postHookTimeout time.Duration
+
+	diffOut string // where to write the airports diff summary as JSON; "-" is stdout
+	delta   bool   // write a delta file against the previous airports snapshot
+
+	uploader *uploader // publishes each new snapshot; nil when -upload is unset
+
+	gitDir  string // Git work tree the latest files are committed to; "" disables
+	gitPush bool   // push after committing
+
+	emitGoDir    string             // regenerate an embedding Go package here after each run
+	emitGoPkg    string             // its package name; "" derives it from emitGoDir
+	emitGoSubset *iataplaces.Subset // embed only these rows; nil embeds all
+
+	// dryRun downloads and validates into scratch, a temporary directory,
+	// and only reports what would change in outDir.
+	dryRun  bool
+	scratch string
+
+	notifyURL string // webhook to POST a run summary to
+	notifyOn  string // "always", "change" or "failure"
+
+	pushgateway string // Prometheus Pushgateway base URL
+	statsdAddr  string // statsd host:port (UDP)
+	metricsJob  string // Pushgateway job name
+
+	// Filled in by update for the notification.
+	results  []datasetResult
+	diff     *diffSummary
+	fullDiff *iataplaces.StoreDiff // kept for the delta file when delta is set
+}
+
+// datasetResult records what a run did with one dataset.
+type datasetResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`           // "updated", "not_modified" or "failed"
+	Source   string `json:"source,omitempty"` // URL the data came from
+	Snapshot string `json:"snapshot,omitempty"`
+	Bytes    int64  `json:"bytes,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Rows     int    `json:"rows,omitempty"`
+	PrevRows int    `json:"previous_rows,omitempty"`
+	Delta    string `json:"delta,omitempty"` // delta file against the previous snapshot
+	Error    string `json:"error,omitempty"`
+}
+
+// run downloads a new snapshot if there is one, then applies the retention
+// policy.
+func (u *updater) run(ctx context.Context) error {
+	started := time.Now()
+	u.results, u.diff, u.fullDiff = nil, nil, nil
+
+	// Dry runs don't write to the output directory, so they need no lock.
+	if !u.dryRun {
+		if err := os.MkdirAll(u.outDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output dir %s: %w", u.outDir, err)
+		}
+		release, err := acquireLock(u.outDir, u.lockStale)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	if u.dryRun {
+		scratch, err := os.MkdirTemp("", "airports-update-")
+		if err != nil {
+			return fmt.Errorf("failed to create scratch dir: %w", err)
+		}
+		defer os.RemoveAll(scratch)
+		u.scratch = scratch
+	}
+
+	err := u.update(ctx)
+	if err == nil || errors.Is(err, errNotModified) {
+		if pruneErr := u.prune(); pruneErr != nil {
+			err = pruneErr
+		}
+	}
+	if u.dryRun {
+		return err
+	}
+	if indexErr := u.writeManifestIndex(); indexErr != nil {
+		slog.Error("failed to write manifest index", "err", indexErr)
+	} else if u.uploader != nil {
+		if uploadErr := u.uploader.upload(ctx, u.outDir, manifestIndex); uploadErr != nil {
+			slog.Error("failed to upload manifest index", "err", uploadErr)
+		}
+	}
+	if u.gitDir != "" && (err == nil || errors.Is(err, errNotModified)) {
+		if gitErr := u.archiveToGit(ctx); gitErr != nil {
+			err = fmt.Errorf("failed to archive to git: %w", gitErr)
+		}
+	}
+	// The package is regenerated even when nothing was downloaded, so a
+	// fresh checkout gets its files from the existing latest snapshot.
+	if u.emitGoDir != "" && (err == nil || errors.Is(err, errNotModified)) {
+		if emitErr := u.emitGo(u.emitGoDir, u.emitGoPkg); emitErr != nil {
+			err = fmt.Errorf("failed to emit Go package: %w", emitErr)
+		}
+	}
+	if u.pushgateway != "" || u.statsdAddr != "" {
+		if metricsErr := u.pushMetrics(started, err); metricsErr != nil {
+			slog.Error("failed to push metrics", "err", metricsErr)
+		}
+	}
+	if u.notifyURL != "" {
+		if notifyErr := u.notify(started, err); notifyErr != nil {
+			slog.Error("failed to send notification", "err", notifyErr)
+		}
+	}
+	return err
+}
+
+// update fetches every configured dataset concurrently, all under the same
+// timestamp. It returns errNotModified only when none of them changed.
+func (u *updater) update(ctx context.Context) error {
+	if err := os.MkdirAll(u.workDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create output dir %s: %w", u.workDir(), err)
+	}
+	ts := time.Now().UTC().Format(snapshotTimeLayout)
+
+	errs := make([]error, len(u.datasets))
+	u.results = make([]datasetResult, len(u.datasets))
+	var wg sync.WaitGroup
+	for i, ds := range u.datasets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.results[i].Name = ds.name
+			errs[i] = u.updateDataset(ctx, ds, ts, &u.results[i])
+		}()
+	}
+	wg.Wait()
+
+	var failed []error
+	unchanged := 0
+	for i, err := range errs {
+		res := &u.results[i]
+		switch {
+		case err == nil:
+			res.Status = "updated"
+		case errors.Is(err, errNotModified):
+			res.Status = "not_modified"
+			unchanged++
+			slog.Info("not modified since the last download", "dataset", u.datasets[i].name)
+		default:
+			res.Status, res.Error = "failed", err.Error()
+			failed = append(failed, fmt.Errorf("%s: %w", u.datasets[i].name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+	if unchanged == len(u.datasets) {
+		return errNotModified
+	}
+	return nil
+}
+
+func (u *updater) updateDataset(ctx context.Context, ds dataset, ts string, res *datasetResult) error {
+	base := fmt.Sprintf("%s-%s%s", ds.file, ts, ds.ext())
+	filename := base + u.compressExt
+	fullPath := filepath.Join(u.outDir, filename)
+	latestPath := filepath.Join(u.outDir, ds.file+"-latest"+ds.ext()+u.latestExt())
+
+	state, err := loadState(u.outDir, ds)
+	if err != nil {
+		return err
+	}
+
+	// The in-progress file has a fixed name so that a later run can resume
+	// it after an interrupted download.
+	tempPath := filepath.Join(u.workDir(), ds.file+"-download"+ds.ext()+".tmp")
+	url, resp, n, err := u.fetchFromSources(ctx, ds, state, latestPath, tempPath)
+	if err != nil {
+		return err
+	}
+	res.Source = url
+
+	os.Remove(tempPath + ".json")
+
+	// A bad upstream response must not replace a good latest file. The
+	// rejected download is kept for inspection.
+	prevRows := state.Rows
+	if prevRows == 0 && fileExists(latestPath) {
+		prevRows, _ = countRows(ds, latestPath)
+	}
+	rows, err := u.checkDataset(ds, tempPath, prevRows)
+	res.Bytes, res.Rows, res.PrevRows = n, rows, prevRows
+	if err != nil {
+		rejected := filepath.Join(u.workDir(), base+".rejected")
+		if u.dryRun {
+			return fmt.Errorf("would refuse to update %s: %w", latestPath, err)
+		}
+		if renameErr := os.Rename(tempPath, rejected); renameErr != nil {
+			rejected = tempPath
+		}
+		return fmt.Errorf("refusing to update %s: %w (download kept as %s)", latestPath, err, rejected)
+	}
+
+	if u.dryRun {
+		slog.Info("dry run: would save snapshot", "dataset", ds.name, "path", fullPath,
+			"bytes", n, "rows", rows, "previous_rows", prevRows, "latest", latestPath)
+	}
+	if ds.name == "airports" && fileExists(latestPath) {
+		if err := u.reportDiff(latestPath, tempPath, filename); err != nil {
+			return fmt.Errorf("failed to diff against %s: %w", latestPath, err)
+		}
+	}
+	if u.dryRun {
+		return nil
+	}
+
+	if u.compress == "" {
+		err = publish(tempPath, fullPath)
+	} else {
+		err = compressFile(tempPath, fullPath, u.compress)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", fullPath, err)
+	}
+	res.Snapshot = filename
+
+	sum, err := sha256File(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %w", fullPath, err)
+	}
+	res.SHA256 = sum
+	if err := writeChecksum(fullPath, sum); err != nil {
+		return fmt.Errorf("failed to write checksum for %s: %w", fullPath, err)
+	}
+	var extra []string
+	if u.fullDiff != nil && ds.name == "airports" && state.Snapshot != "" {
+		name, err := u.writeDelta(state.Snapshot, filename, sum)
+		if err != nil {
+			return fmt.Errorf("failed to write delta for %s: %w", fullPath, err)
+		}
+		res.Delta = name
+		extra = append(extra, name)
+	}
+	manifest, err := u.writeManifest(ds, fullPath, ts, state.Snapshot, res)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest for %s: %w", fullPath, err)
+	}
+
+	slog.Info("saved snapshot", "dataset", ds.name, "path", fullPath, "source", url,
+		"bytes", n, "rows", rows, "sha256", sum)
+
+	// Also keep a stable "<name>-latest.csv" for your scripts. A plain
+	// latest next to compressed snapshots comes straight from the download.
+	latestSum := sum
+	switch {
+	case u.latestExt() != u.compressExt:
+		err = publish(tempPath, latestPath)
+		if err == nil {
+			latestSum, err = sha256File(latestPath)
+		}
+	case u.latestMode == "symlink":
+		if err = symlinkLatest(filename, latestPath); err != nil {
+			slog.Warn("cannot symlink latest, copying instead", "path", latestPath, "err", err)
+			err = copyFile(fullPath, latestPath)
+		}
+	default:
+		err = copyFile(fullPath, latestPath)
+	}
+	os.Remove(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", latestPath, err)
+	}
+	if err := writeChecksum(latestPath, latestSum); err != nil {
+		return fmt.Errorf("failed to write checksum for %s: %w", latestPath, err)
+	}
+	slog.Info("updated latest", "dataset", ds.name, "path", latestPath)
+
+	if u.binary && ds.name == "airports" {
+		binPath := filepath.Join(u.outDir, binaryLatestName)
+		if err := writeBinary(fullPath, binPath); err != nil {
+			return fmt.Errorf("failed to write %s: %w", binPath, err)
+		}
+		binSum, err := sha256File(binPath)
+		if err == nil {
+			err = writeChecksum(binPath, binSum)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write checksum for %s: %w", binPath, err)
+		}
+		extra = append(extra, binaryLatestName, binaryLatestName+".sha256")
+		slog.Info("updated binary store", "dataset", ds.name, "path", binPath)
+	}
+
+	if u.uploader != nil {
+		// The latest file goes last, so readers never see it ahead of the
+		// snapshot it names.
+		latestName := filepath.Base(latestPath)
+		names := append([]string{filename, filename + ".sha256", manifest}, extra...)
+		names = append(names, latestName, latestName+".sha256")
+		if err := u.uploader.upload(ctx, u.outDir, names...); err != nil {
+			return err
+		}
+	}
+
+	state = fetchState{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Snapshot:     filename,
+		Rows:         rows,
+	}
+	if err := saveState(u.outDir, ds, state); err != nil {
+		return fmt.Errorf("failed to save download state: %w", err)
+	}
+
+	if u.postHook != "" {
+		return u.runPostHook(ctx, res, fullPath, latestPath)
+	}
+	return nil
+}
+
+// workDir is where downloads are written: the output directory, or the
+// scratch directory in a dry run.
+func (u *updater) workDir() string {
+	if u.dryRun {
+		return u.scratch
+	}
+	return u.outDir
+}
+
+// latestExt is the compression extension of the latest files.
+func (u *updater) latestExt() string {
+	if u.compressLatest {
+		return u.compressExt
+	}
+	return ""
+}
+
+// reportDiff logs how the new airports snapshot differs from the current
+// latest file and writes the JSON summary requested with -diff. newPath is
+// still the download's temporary file, so the summary names the snapshot
+// it will be saved as instead.
+func (u *updater) reportDiff(prevPath, newPath, snapshot string) error {
+	summary, full, err := summarizeDiff(prevPath, newPath)
+	if err != nil {
+		return err
+	}
+	summary.Current = snapshot
+	slog.Info("changes since previous dataset", "dataset", "airports",
+		"added", summary.Added, "removed", summary.Removed, "changed", summary.Changed)
+	u.diff = summary
+	if u.delta {
+		u.fullDiff = full
+	}
+	if u.diffOut == "" {
+		return nil
+	}
+	return writeDiffSummary(u.diffOut, summary)
+}
+
+// symlinkLatest atomically points latestPath at target, a file name in the
+// same directory, by renaming a fresh symlink over it.
+func symlinkLatest(target, latestPath string) error {
+	tmp := latestPath + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, latestPath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(latestPath))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open src: %w", err)
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create dst: %w", err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("close dst: %w", err)
+	}
+
+	if err := publish(tmp, dst); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	ctx := context.Background()
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	before := dirNames(t, u.outDir)
+	latest := filepath.Join(u.outDir, "airports-latest.csv")
+
+	m.set("/airports.csv", testAirports+"4,EGSS,large_airport,London Stansted Airport,51.88,0.23,GB,STN\n")
+	u.dryRun = true
+	u.diffOut = filepath.Join(t.TempDir(), "changes.json")
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := dirNames(t, u.outDir); !slices.Equal(got, before) {
+		t.Errorf("dry run changed the output dir: %v, was %v", got, before)
+	}
+	if b, err := os.ReadFile(latest); err != nil || string(b) != testAirports {
+		t.Errorf("dry run changed the latest file: %v", err)
+	}
+	if u.diff == nil || u.diff.Added != 1 || !slices.Equal(u.diff.AddedCodes, []string{"STN"}) {
+		t.Errorf("dry run diff %+v", u.diff)
+	}
+	if !fileExists(u.diffOut) {
+		t.Error("dry run didn't write -diff")
+	}
+	if res := u.results[0]; res.Status != "updated" || res.Rows != 4 || res.Snapshot != "" {
+		t.Errorf("dry run result %+v", res)
+	}
+	if fileExists(u.scratch) {
+		t.Error("scratch dir left behind")
+	}
+
+	// A download that would be refused says so, and leaves nothing behind.
+	m.set("/airports.csv", diffHeader+"1,EGLL,large_airport,London Heathrow Airport,51.47,-0.46,GB,LHR\n")
+	err := u.run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "would refuse to update") {
+		t.Errorf("dry run of a shrunken file = %v", err)
+	}
+	if got := dirNames(t, u.outDir); !slices.Equal(got, before) {
+		t.Errorf("refused dry run changed the output dir: %v", got)
+	}
+}
+
+func TestSymlinkLatest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks needs extra privileges on Windows")
+	}
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.latestMode = "symlink"
+	ctx := context.Background()
+	if err := u.run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	latest := filepath.Join(u.outDir, "airports-latest.csv")
+	target, err := os.Readlink(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The link is relative, so the directory can be moved or mounted
+	// elsewhere.
+	if target != u.results[0].Snapshot {
+		t.Errorf("latest links to %q, want %q", target, u.results[0].Snapshot)
+	}
+	if b, err := os.ReadFile(latest); err != nil || string(b) != testAirports {
+		t.Errorf("reading through the link: %q, %v", b, err)
+	}
+
+	// Repointing replaces the link in place.
+	touch(t, u.outDir, "airports-20000101-000000.csv")
+	if err := symlinkLatest("airports-20000101-000000.csv", latest); err != nil {
+		t.Fatal(err)
+	}
+	if target, _ := os.Readlink(latest); target != "airports-20000101-000000.csv" {
+		t.Errorf("latest links to %q after repointing", target)
+	}
+	if fileExists(latest + ".tmp") {
+		t.Error("temporary link left behind")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// uploader publishes snapshots to an S3 bucket, or to a GCS bucket through
+// its S3-compatible XML API.
+type uploader struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	dest   string // as given to -upload, for log messages
+}
+
+// newUploader parses an s3://bucket/prefix or gs://bucket/prefix
+// destination.
+//
+// S3 credentials come from the usual AWS sources: AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY, ~/.aws/credentials, or the instance role.
+// AWS_REGION selects the region and AWS_ENDPOINT_URL an S3-compatible
+// endpoint. GCS needs an HMAC key in GS_ACCESS_KEY_ID and
+// GS_SECRET_ACCESS_KEY.
+func newUploader(dest string) (*uploader, error) {
+	parsed, err := url.Parse(dest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload destination %q: %w", dest, err)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("upload destination %q has no bucket", dest)
+	}
+
+	var endpoint string
+	var opts minio.Options
+	switch parsed.Scheme {
+	case "s3":
+		endpoint = "s3.amazonaws.com"
+		opts = minio.Options{
+			Creds: credentials.NewChainCredentials([]credentials.Provider{
+				&credentials.EnvAWS{},
+				&credentials.FileAWSCredentials{},
+				&credentials.IAM{},
+			}),
+			Secure: true,
+			Region: os.Getenv("AWS_REGION"),
+		}
+		if custom := os.Getenv("AWS_ENDPOINT_URL"); custom != "" {
+			u, err := url.Parse(custom)
+			if err != nil || u.Host == "" {
+				return nil, fmt.Errorf("invalid AWS_ENDPOINT_URL %q", custom)
+			}
+			endpoint = u.Host
+			opts.Secure = u.Scheme != "http"
+			opts.BucketLookup = minio.BucketLookupPath
+		}
+	case "gs":
+		id, secret := os.Getenv("GS_ACCESS_KEY_ID"), os.Getenv("GS_SECRET_ACCESS_KEY")
+		if id == "" || secret == "" {
+			return nil, fmt.Errorf("gs:// uploads need GS_ACCESS_KEY_ID and GS_SECRET_ACCESS_KEY (a GCS HMAC key)")
+		}
+		endpoint = "storage.googleapis.com"
+		opts = minio.Options{
+			Creds:  credentials.NewStaticV4(id, secret, ""),
+			Secure: true,
+		}
+	default:
+		return nil, fmt.Errorf("upload destination %q must start with s3:// or gs://", dest)
+	}
+
+	client, err := minio.New(endpoint, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return &uploader{
+		client: client,
+		bucket: parsed.Host,
+		prefix: strings.Trim(parsed.Path, "/"),
+		dest:   strings.TrimRight(dest, "/"),
+	}, nil
+}
+
+// upload copies the named files from dir to the bucket, under the prefix.
+func (up *uploader) upload(ctx context.Context, dir string, names ...string) error {
+	for _, name := range names {
+		contentType := "text/csv"
+		switch {
+		case strings.HasSuffix(name, ".sha256"):
+			contentType = "text/plain"
+		case strings.HasSuffix(name, deltaSuffix):
+			contentType = "application/gzip"
+		case strings.HasSuffix(name, ".json"):
+			contentType = "application/json"
+		case strings.HasSuffix(name, ".gz"):
+			contentType = "application/gzip"
+		case strings.HasSuffix(name, ".zst"):
+			contentType = "application/zstd"
+		case strings.HasSuffix(name, ".bin"):
+			contentType = "application/octet-stream"
+		}
+		key := path.Join(up.prefix, name)
+		_, err := up.client.FPutObject(ctx, up.bucket, key, path.Join(dir, name), minio.PutObjectOptions{
+			ContentType: contentType,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload %s to %s: %w", name, up.dest, err)
+		}
+		slog.Info("uploaded", "dest", up.dest+"/"+name)
+	}
+	return nil
+}
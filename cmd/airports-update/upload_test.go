@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// bucket is a fake S3 endpoint that records PUT objects. When denied is
+// set it refuses every request.
+type bucket struct {
+	mu      sync.Mutex
+	keys    []string // in upload order
+	objects map[string][]byte
+	types   map[string]string
+	denied  bool
+}
+
+func newBucket(t *testing.T) (*bucket, *httptest.Server) {
+	t.Helper()
+	b := &bucket{objects: map[string][]byte{}, types: map[string]string{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.denied || r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err == nil && r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+			body, err = decodeAWSChunked(body)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b.keys = append(b.keys, r.URL.Path)
+		b.objects[r.URL.Path] = body
+		b.types[r.URL.Path] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"0"`)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	return b, srv
+}
+
+// decodeAWSChunked strips the signed chunk framing minio-go uses for
+// uploads over plain HTTP.
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	var out []byte
+	r := bufio.NewReader(bytes.NewReader(body))
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return out, nil
+		}
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		out = append(out, chunk[:n]...)
+	}
+}
+
+func TestNewUploader(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("GS_ACCESS_KEY_ID", "")
+	up, err := newUploader("s3://airports/snapshots/daily/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.bucket != "airports" || up.prefix != "snapshots/daily" || up.dest != "s3://airports/snapshots/daily" {
+		t.Errorf("uploader %+v", up)
+	}
+	if got := up.client.EndpointURL().Host; got != "s3.amazonaws.com" {
+		t.Errorf("endpoint %s", got)
+	}
+
+	for _, dest := range []string{"ftp://airports/x", "s3:///x", "gs://airports/x"} {
+		if _, err := newUploader(dest); err == nil {
+			t.Errorf("newUploader(%q) succeeded", dest)
+		}
+	}
+	t.Setenv("GS_ACCESS_KEY_ID", "id")
+	t.Setenv("GS_SECRET_ACCESS_KEY", "secret")
+	if up, err := newUploader("gs://airports"); err != nil || up.client.EndpointURL().Host != "storage.googleapis.com" {
+		t.Errorf("gs:// uploader: %v", err)
+	}
+	t.Setenv("AWS_ENDPOINT_URL", "not a url")
+	if _, err := newUploader("s3://airports"); err == nil {
+		t.Error("invalid AWS_ENDPOINT_URL accepted")
+	}
+}
+
+func TestRunUploads(t *testing.T) {
+	b, _ := newBucket(t)
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	var err error
+	if u.uploader, err = newUploader("s3://airports/daily"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := u.results[0].Snapshot
+	manifest := snapshot + manifestSuffix
+	want := []string{snapshot, snapshot + ".sha256", manifest, "airports-latest.csv", "airports-latest.csv.sha256", manifestIndex}
+	for i := range want {
+		want[i] = "/airports/daily/" + want[i]
+	}
+	if !slices.Equal(b.keys, want) {
+		t.Errorf("uploaded %v, want %v", b.keys, want)
+	}
+	for key, wantType := range map[string]string{
+		want[0]: "text/csv",
+		want[1]: "text/plain",
+		want[2]: "application/json",
+	} {
+		if b.types[key] != wantType {
+			t.Errorf("%s has Content-Type %q, want %q", key, b.types[key], wantType)
+		}
+	}
+	for i, name := range []string{snapshot, snapshot + ".sha256"} {
+		local, err := os.ReadFile(filepath.Join(u.outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.objects[want[i]], local) {
+			t.Errorf("%s uploaded as %q", name, b.objects[want[i]])
+		}
+	}
+
+	// A refused upload fails the dataset.
+	b.mu.Lock()
+	b.denied = true
+	b.mu.Unlock()
+	u.force = true
+	if err := u.run(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to upload") {
+		t.Errorf("run with a refused upload = %v", err)
+	}
+}
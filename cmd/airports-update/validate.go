@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// checkDataset validates a downloaded file before it may replace the
+// dataset's latest file. prevRows is the row count of the current latest
+// file, or 0 when unknown. It returns the new row count.
+//
+// Airports get the library's full checks; the other datasets are only
+// required to parse as CSV and keep their size, and OpenFlights records
+// to have the expected number of fields.
+func (u *updater) checkDataset(ds dataset, path string, prevRows int) (int, error) {
+	if err := u.checkSize(path); err != nil {
+		return 0, err
+	}
+	if ds.fields > 0 {
+		rows, bad, err := countDatRows(path, ds.fields)
+		if err != nil {
+			return rows, err
+		}
+		if rows == 0 {
+			return 0, fmt.Errorf("no data rows")
+		}
+		if ratio := float64(bad) / float64(rows); u.maxBadRatio > 0 && ratio > u.maxBadRatio {
+			return rows, fmt.Errorf("%d of %d rows (%.1f%%) don't have %d fields, over the %.1f%% limit",
+				bad, rows, ratio*100, ds.fields, u.maxBadRatio*100)
+		}
+		return rows, u.checkRowDrop(prevRows, rows)
+	}
+	if ds.name != "airports" {
+		rows, err := countCSVRows(path)
+		if err != nil {
+			return rows, err
+		}
+		if rows == 0 {
+			return 0, fmt.Errorf("no data rows")
+		}
+		return rows, u.checkRowDrop(prevRows, rows)
+	}
+
+	report, err := iataplaces.ValidateFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(report.MissingColumns) > 0 {
+		return report.Rows, fmt.Errorf("header is missing required columns: %s", strings.Join(report.MissingColumns, ", "))
+	}
+	if report.Rows == 0 {
+		return 0, fmt.Errorf("no data rows")
+	}
+
+	badLines := make(map[int]bool)
+	for _, is := range report.Issues {
+		if is.Severity == iataplaces.SeverityError {
+			badLines[is.Line] = true
+		}
+	}
+	if ratio := float64(len(badLines)) / float64(report.Rows); u.maxBadRatio > 0 && ratio > u.maxBadRatio {
+		return report.Rows, fmt.Errorf("%d of %d rows (%.1f%%) failed to parse, over the %.1f%% limit",
+			len(badLines), report.Rows, ratio*100, u.maxBadRatio*100)
+	}
+	if err := u.checkRowDrop(prevRows, report.Rows); err != nil {
+		return report.Rows, err
+	}
+	if u.maxStaleness > 0 {
+		if err := u.checkFreshness(path); err != nil {
+			return report.Rows, err
+		}
+	}
+
+	slog.Debug("validated", "dataset", ds.name, "rows", report.Rows, "error_rows", len(badLines), "warnings", report.Warnings())
+	return report.Rows, nil
+}
+
+// checkSize rejects files below u.minBytes and HTML pages, such as a
+// captive portal or an error page served with status 200.
+func (u *updater) checkSize(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < u.minBytes {
+		return fmt.Errorf("download is only %d bytes, under the %d byte minimum", info.Size(), u.minBytes)
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	if ct := http.DetectContentType(head[:n]); strings.HasPrefix(ct, "text/html") {
+		return errors.New("download is an HTML page, not CSV")
+	}
+	return nil
+}
+
+func (u *updater) checkRowDrop(prevRows, rows int) error {
+	if prevRows > 0 && u.minRowRatio > 0 && float64(rows) < float64(prevRows)*u.minRowRatio {
+		return fmt.Errorf("row count dropped from %d to %d, below %.0f%% of the previous dataset",
+			prevRows, rows, u.minRowRatio*100)
+	}
+	return nil
+}
+
+// checkFreshness compares the newest last_updated value in an airports
+// file with u.maxStaleness. OurAirports edits some airport every day, so
+// a file whose newest edit is weeks old points at a mirror that has stopped
+// updating. Depending on u.staleAction it fails or only warns.
+func (u *updater) checkFreshness(path string) error {
+	newest, err := newestUpdate(path)
+	if err != nil {
+		return err
+	}
+	age := time.Since(newest)
+	if age <= u.maxStaleness {
+		return nil
+	}
+	msg := fmt.Sprintf("data looks stale: newest last_updated is %s (%s ago), over the %s limit",
+		newest.UTC().Format(time.RFC3339), age.Round(time.Hour), u.maxStaleness)
+	if u.staleAction == "warn" {
+		slog.Warn(msg, "dataset", "airports")
+		return nil
+	}
+	return errors.New(msg)
+}
+
+// newestUpdate returns the latest last_updated timestamp in an airports CSV,
+// in any of the layouts the library loads.
+func newestUpdate(path string) (time.Time, error) {
+	r, err := openCSV(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer r.Close()
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("read header: %w", err)
+	}
+	col := -1
+	for i, name := range header {
+		if strings.TrimSpace(name) == "last_updated" {
+			col = i
+		}
+	}
+	if col < 0 {
+		return time.Time{}, errors.New("no last_updated column to check freshness with")
+	}
+
+	var newest time.Time
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("read record: %w", err)
+		}
+		if col >= len(rec) {
+			continue
+		}
+		t, err := iataplaces.ParseTimestamp(strings.TrimSpace(rec[col]))
+		if err == nil && t.After(newest) {
+			newest = t
+		}
+	}
+	if newest.IsZero() {
+		return time.Time{}, errors.New("no valid last_updated values to check freshness with")
+	}
+	return newest, nil
+}
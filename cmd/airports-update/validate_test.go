@@ -0,0 +1,150 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if strings.HasSuffix(name, ".gz") {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zw := gzip.NewWriter(f)
+		zw.Write([]byte(content))
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewestUpdate(t *testing.T) {
+	rows := func(values ...string) string {
+		var b strings.Builder
+		b.WriteString("id,iata_code,last_updated\n")
+		for i, v := range values {
+			fmt.Fprintf(&b, "%d,AA%c,%s\n", i+1, 'A'+i, v)
+		}
+		return b.String()
+	}
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string // RFC 3339; "" for an error
+	}{
+		{"RFC 3339", "a.csv", rows("2024-01-02T03:04:05+00:00", "2023-12-31T23:59:59Z"), "2024-01-02T03:04:05Z"},
+		{"offset", "a.csv", rows("2024-01-02T03:04:05+02:00", "2024-01-02T02:00:00Z"), "2024-01-02T02:00:00Z"},
+		{"space separated", "a.csv", rows("2024-05-01 10:00:00", "2024-04-30T12:00:00Z"), "2024-05-01T10:00:00Z"},
+		{"space separated with zone", "a.csv", rows("2024-05-01 10:00:00+0200", "2024-05-01 07:00:00"), "2024-05-01T08:00:00Z"},
+		{"no seconds", "a.csv", rows("2024-05-01T10:00", "2024-05-01 09:00"), "2024-05-01T10:00:00Z"},
+		{"date only", "a.csv", rows("2024-06-01", "2024-05-31T23:00:00Z"), "2024-06-01T00:00:00Z"},
+		{"unparseable values skipped", "a.csv", rows("yesterday", "", "2020-02-02"), "2020-02-02T00:00:00Z"},
+		{"gzip", "a.csv.gz", rows("2024-01-02 03:04:05"), "2024-01-02T03:04:05Z"},
+		{"no valid values", "a.csv", rows("soon", ""), ""},
+		{"no column", "a.csv", "id,iata_code\n1,AAA\n", ""},
+		{"empty", "a.csv", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newestUpdate(writeFile(t, tt.file, tt.content))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("newestUpdate = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := time.Parse(time.RFC3339, tt.want)
+			if !got.Equal(want) {
+				t.Errorf("newestUpdate = %v, want %v", got.UTC(), want)
+			}
+		})
+	}
+}
+
+func TestCheckFreshness(t *testing.T) {
+	recent := time.Now().UTC().Add(-time.Hour).Format("2006-01-02 15:04:05")
+	old := time.Now().UTC().Add(-90 * 24 * time.Hour).Format(time.DateOnly)
+	tests := []struct {
+		name    string
+		newest  string
+		action  string
+		wantErr bool
+	}{
+		{"fresh", recent, "fail", false},
+		{"stale", old, "fail", true},
+		{"stale, warn only", old, "warn", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "airports.csv", "id,last_updated\n1,"+tt.newest+"\n")
+			u := &updater{maxStaleness: 30 * 24 * time.Hour, staleAction: tt.action}
+			err := u.checkFreshness(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkFreshness = %v, want error = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"big enough", testAirports, ""},
+		{"too small", "id,ident\n", "under the 100 byte minimum"},
+		{"HTML", "<!DOCTYPE html><html><body>Please sign in to the network" + strings.Repeat(" ", 100) + "</body></html>", "HTML page"},
+	}
+	u := &updater{minBytes: 100}
+	for _, tt := range tests {
+		err := u.checkSize(writeFile(t, "airports.csv", tt.content))
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: checkSize = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunRejectsSmallDownload(t *testing.T) {
+	m := newMirror(t, map[string]string{"/airports.csv": testAirports})
+	u := newTestUpdater(t, m)
+	u.minBytes = 1024
+	err := u.run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "under the 1024 byte minimum") {
+		t.Fatalf("run = %v", err)
+	}
+	if fileExists(filepath.Join(u.outDir, "airports-latest.csv")) {
+		t.Error("latest file written")
+	}
+	// The refused download is kept for inspection.
+	var rejected []string
+	for _, name := range dirNames(t, u.outDir) {
+		if strings.HasSuffix(name, ".rejected") {
+			rejected = append(rejected, name)
+		}
+	}
+	if len(rejected) != 1 {
+		t.Errorf("rejected downloads %v", rejected)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// requestInfo is filled in by handlers during a request and reported by
+// the access log afterwards.
+type requestInfo struct {
+	results int
+}
+
+type requestInfoKey struct{}
+
+// setResultCount records how many airports a handler returned.
+func setResultCount(r *http.Request, n int) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.results = n
+	}
+}
+
+// accessLog writes one structured record per request to logger. Any
+// slog.Handler can sit behind it, so logs can go straight to a pipeline.
+func accessLog(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{results: -1}
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes", rec.bytes),
+			slog.String("remote", r.RemoteAddr),
+		}
+		if info.results >= 0 {
+			attrs = append(attrs, slog.Int("results", info.results))
+		}
+		if key := clientKey(r); key != "" {
+			attrs = append(attrs, slog.String("client_key", key))
+		}
+		logger.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+	})
+}
+
+// clientKey identifies the caller by a fingerprint of its X-API-Key, so
+// requests can be attributed without writing the key itself to logs.
+func clientKey(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *statusRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordWriter passes each log record written to it down a channel.
+type recordWriter chan []byte
+
+func (w recordWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func nextRecord(t *testing.T, records recordWriter) map[string]any {
+	t.Helper()
+	select {
+	case b := <-records:
+		var rec map[string]any
+		if err := json.Unmarshal(b, &rec); err != nil {
+			t.Fatalf("%v: %s", err, b)
+		}
+		return rec
+	case <-time.After(5 * time.Second):
+		t.Fatal("no access log record")
+		return nil
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	s, _ := newTestServer(t, nil)
+	records := make(recordWriter, 10)
+	s.accessLogger = slog.New(slog.NewJSONHandler(records, nil))
+	ts := httptest.NewServer(s.routes())
+	defer ts.Close()
+
+	do(t, http.MethodGet, ts.URL+"/v1/search?q=london", http.Header{"X-API-Key": {"k-123"}}, nil)
+	rec := nextRecord(t, records)
+	want := map[string]any{"msg": "request", "method": "GET", "path": "/v1/search", "status": 200.0, "results": 2.0}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("%s = %v, want %v", k, rec[k], v)
+		}
+	}
+	if rec["bytes"].(float64) <= 0 || rec["latency_ms"] == nil || rec["remote"] == "" {
+		t.Errorf("record %v", rec)
+	}
+	// The API key is fingerprinted, never logged.
+	if key, _ := rec["client_key"].(string); len(key) != 12 || strings.Contains(key, "k-123") {
+		t.Errorf("client_key %v", rec["client_key"])
+	}
+
+	do(t, http.MethodGet, ts.URL+"/v1/airports/XXX", nil, nil)
+	rec = nextRecord(t, records)
+	if rec["status"] != 404.0 || rec["results"] != 0.0 || rec["client_key"] != nil {
+		t.Errorf("not found record %v", rec)
+	}
+	do(t, http.MethodGet, ts.URL+"/healthz", nil, nil)
+	if rec = nextRecord(t, records); rec["results"] != nil {
+		t.Errorf("healthz record has results: %v", rec)
+	}
+}
+
+func TestNewAccessLogger(t *testing.T) {
+	for _, dest := range []string{"", "off"} {
+		if logger, err := newAccessLogger(dest, "json"); logger != nil || err != nil {
+			t.Errorf("newAccessLogger(%q) = %v, %v", dest, logger, err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "access.log")
+	logger, err := newAccessLogger(path, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("request", "path", "/healthz")
+	if b, _ := os.ReadFile(path); !strings.Contains(string(b), "msg=request path=/healthz") {
+		t.Errorf("log file holds %q", b)
+	}
+	if _, err := newAccessLogger("stdout", "xml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// handleAirlinesAt serves GET /v1/airports/{code}/airlines from the
+// routes loaded with -routes.
+func (s *server) handleAirlinesAt(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Data.Routes == "" {
+		writeError(w, http.StatusNotFound, "no routes loaded")
+		return
+	}
+	store := s.data.Load().store
+	if _, ok := store.LookupIATA(r.PathValue("code")); !ok {
+		setResultCount(r, 0)
+		writeError(w, http.StatusNotFound, "airport not found")
+		return
+	}
+	airlines := store.AirlinesAt(r.PathValue("code"))
+	if airlines == nil {
+		airlines = []iataplaces.Airline{}
+	}
+	setResultCount(r, len(airlines))
+	writeJSON(w, http.StatusOK, map[string]any{"data": airlines})
+}
+
+// handleServedBy serves GET /v1/airlines/{code}/airports, the airports an
+// airline flies to or from.
+func (s *server) handleServedBy(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Data.Routes == "" {
+		writeError(w, http.StatusNotFound, "no routes loaded")
+		return
+	}
+	store := s.data.Load().store
+	rd, err := newRenderer(w, r, store)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	out := []any{}
+	for _, a := range store.AirportsServedBy(r.PathValue("code")) {
+		out = append(out, rd.render(a))
+	}
+	setResultCount(r, len(out))
+	writeJSON(w, http.StatusOK, map[string]any{"data": out})
+}
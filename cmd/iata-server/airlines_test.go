@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// testRoutes is in the OpenFlights routes.dat format.
+const testRoutes = `BA,1355,LHR,507,JFK,3797,,0,777
+BA,1355,LGW,502,CDG,1382,,0,320
+AF,137,CDG,1382,HND,2359,,0,77W
+JL,2822,HND,2359,JFK,3797,Y,0,77W
+`
+
+const testAirlines = `1355,"British Airways",\N,"BA","BAW","SPEEDBIRD","United Kingdom","Y"
+137,"Air France",\N,"AF","AFR","AIRFRANS","France","Y"
+`
+
+func newRoutesServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	_, ts := newTestServer(t, func(cfg *config) {
+		dir := t.TempDir()
+		cfg.Data.Routes = filepath.Join(dir, "routes.dat")
+		cfg.Data.Airlines = filepath.Join(dir, "airlines.dat")
+		for path, content := range map[string]string{cfg.Data.Routes: testRoutes, cfg.Data.Airlines: testAirlines} {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	return ts
+}
+
+func TestAirlinesAt(t *testing.T) {
+	ts := newRoutesServer(t)
+
+	var resp struct{ Data []iataplaces.Airline }
+	getJSON(t, ts.URL+"/v1/airports/cdg/airlines", http.StatusOK, &resp)
+	if len(resp.Data) != 2 || resp.Data[0].Name != "Air France" || resp.Data[1].ICAO != "BAW" {
+		t.Errorf("airlines at CDG %+v", resp.Data)
+	}
+	// JL has no airlines.dat entry, so only its code is known.
+	var hnd struct{ Data []iataplaces.Airline }
+	getJSON(t, ts.URL+"/v1/airports/HND/airlines", http.StatusOK, &hnd)
+	if len(hnd.Data) != 2 || hnd.Data[1] != (iataplaces.Airline{IATA: "JL"}) {
+		t.Errorf("airlines at HND %+v", hnd.Data)
+	}
+	getJSON(t, ts.URL+"/v1/airports/XXX/airlines", http.StatusNotFound, nil)
+}
+
+func TestServedBy(t *testing.T) {
+	ts := newRoutesServer(t)
+
+	for _, airline := range []string{"BA", "baw"} {
+		var p page
+		getJSON(t, ts.URL+"/v1/airlines/"+airline+"/airports?fields=iata_code", http.StatusOK, &p)
+		if got := codes(p); len(got) != 4 || got[0] != "CDG" || got[3] != "LHR" {
+			t.Errorf("airports served by %s: %v", airline, got)
+		}
+	}
+	var p page
+	getJSON(t, ts.URL+"/v1/airlines/ZZ/airports", http.StatusOK, &p)
+	if len(p.Data) != 0 {
+		t.Errorf("unknown airline serves %v", codes(p))
+	}
+	getJSON(t, ts.URL+"/v1/airlines/BA/airports?fields=nope", http.StatusBadRequest, nil)
+}
+
+func TestAirlinesWithoutRoutes(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+	getJSON(t, ts.URL+"/v1/airports/LHR/airlines", http.StatusNotFound, nil)
+	getJSON(t, ts.URL+"/v1/airlines/BA/airports", http.StatusNotFound, nil)
+}
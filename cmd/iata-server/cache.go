@@ -0,0 +1,231 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheMaxBody is the largest response body kept in the cache; bigger
+// pages are served uncached rather than evicting many small entries.
+const cacheMaxBody = 1 << 20
+
+// responseCache holds rendered GET responses keyed by normalized request.
+// Entries remember the snapshot they were rendered from and are dropped
+// once it is no longer live, so the TTL only bounds staleness within one
+// dataset version. Concurrent misses for the same key share one render.
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element // of *cachedResponse
+	lru      *list.List               // most recently used first
+	inflight map[string]*cacheCall
+}
+
+type cachedResponse struct {
+	key      string
+	snapshot *snapshotInfo
+	expires  time.Time
+
+	status  int
+	header  http.Header
+	body    []byte
+	results int
+}
+
+// cacheCall is a render in progress that later requests for the same key
+// wait on.
+type cacheCall struct {
+	done chan struct{}
+	resp *cachedResponse
+}
+
+// newResponseCache returns nil, which disables caching, when ttl or
+// maxEntries is not positive.
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	if ttl <= 0 || maxEntries <= 0 {
+		return nil
+	}
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		inflight:   make(map[string]*cacheCall),
+	}
+}
+
+// purge drops every entry. It is called after a new dataset is installed.
+func (c *responseCache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.lru.Init()
+}
+
+// get returns the live entry for key, or joins (or starts) the render of
+// it. Exactly one of resp and call is non-nil; leader reports whether the
+// caller must render and then call finish.
+func (c *responseCache) get(key string, snap *snapshotInfo) (resp *cachedResponse, call *cacheCall, leader bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cachedResponse)
+		if e.snapshot == snap && time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			return e, nil, false
+		}
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+	if call, ok := c.inflight[key]; ok {
+		return nil, call, false
+	}
+	call = &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	return nil, call, true
+}
+
+// finish publishes the leader's response to waiters and stores it when
+// it is worth keeping.
+func (c *responseCache) finish(call *cacheCall, resp *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inflight, resp.key)
+	call.resp = resp
+	close(call.done)
+
+	if !cacheable(resp) {
+		return
+	}
+	resp.expires = time.Now().Add(c.ttl)
+	if el, ok := c.entries[resp.key]; ok {
+		c.lru.Remove(el)
+	}
+	c.entries[resp.key] = c.lru.PushFront(resp)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// cacheable keeps successful responses and not-found answers, which are
+// just as repetitive, but nothing that might be transient.
+func cacheable(resp *cachedResponse) bool {
+	return (resp.status == http.StatusOK || resp.status == http.StatusNotFound) &&
+		len(resp.body) <= cacheMaxBody
+}
+
+// cacheKey normalizes r so that equivalent requests share an entry: query
+// parameters are sorted and Accept-Language is reduced to its ordered
+// language tags.
+func cacheKey(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(r.URL.Query().Encode())
+	b.WriteByte('\n')
+	b.WriteString(strings.Join(parseAcceptLanguage(r.Header.Get("Accept-Language")), ","))
+	return b.String()
+}
+
+// cached serves GET responses from s.cache, rendering through next on a
+// miss. With no cache configured next is returned unchanged.
+func (s *server) cached(next http.HandlerFunc) http.Handler {
+	if s.cache == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snap := s.data.Load().info
+		key := cacheKey(r)
+		resp, call, leader := s.cache.get(key, snap)
+		switch {
+		case resp != nil:
+			cacheHits.Add(1)
+			resp.write(w, r, "HIT")
+			return
+		case leader:
+			cacheMisses.Add(1)
+			resp = s.render(next, r, key, snap)
+			s.cache.finish(call, resp)
+			resp.write(w, r, "MISS")
+			return
+		}
+
+		cacheHits.Add(1)
+		select {
+		case <-call.done:
+			call.resp.write(w, r, "HIT")
+		case <-r.Context().Done():
+		}
+	})
+}
+
+// render runs next into a buffer. The handler's result count is captured
+// through its own request info so cached replays can log it too.
+func (s *server) render(next http.HandlerFunc, r *http.Request, key string, snap *snapshotInfo) *cachedResponse {
+	info := &requestInfo{results: -1}
+	// Detached from the client so a disconnect doesn't cut short a
+	// render that other requests are waiting on.
+	ctx := context.WithValue(context.WithoutCancel(r.Context()), requestInfoKey{}, info)
+	rec := &cacheRecorder{header: http.Header{}}
+	next(rec, r.WithContext(ctx))
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return &cachedResponse{
+		key:      key,
+		snapshot: snap,
+		status:   rec.status,
+		header:   rec.header,
+		body:     rec.body,
+		results:  info.results,
+	}
+}
+
+func (resp *cachedResponse) write(w http.ResponseWriter, r *http.Request, state string) {
+	h := w.Header()
+	for k, vs := range resp.header {
+		for _, v := range vs {
+			h.Add(k, v)
+		}
+	}
+	h.Set("X-Cache", state)
+	if resp.results >= 0 {
+		setResultCount(r, resp.results)
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// cacheRecorder buffers a handler's response.
+type cacheRecorder struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (rec *cacheRecorder) Header() http.Header { return rec.header }
+
+func (rec *cacheRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *cacheRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body = append(rec.body, p...)
+	return len(p), nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCacheKey(t *testing.T) {
+	req := func(target, acceptLanguage string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Accept-Language", acceptLanguage)
+		return r
+	}
+	same := [][2]*http.Request{
+		{req("/v1/search?q=london&limit=5", ""), req("/v1/search?limit=5&q=london", "")},
+		{req("/v1/airports/LHR", "de;q=0.5, fr"), req("/v1/airports/LHR", "fr,de;q=0.4")},
+	}
+	for _, pair := range same {
+		if a, b := cacheKey(pair[0]), cacheKey(pair[1]); a != b {
+			t.Errorf("keys differ: %q and %q", a, b)
+		}
+	}
+	different := [][2]*http.Request{
+		{req("/v1/search?q=london", ""), req("/v1/search?q=paris", "")},
+		{req("/v1/airports/LHR", "de"), req("/v1/airports/LHR", "fr")},
+		{req("/v1/airports/LHR", "de, fr"), req("/v1/airports/LHR", "fr, de")},
+	}
+	for _, pair := range different {
+		if a, b := cacheKey(pair[0]), cacheKey(pair[1]); a == b {
+			t.Errorf("requests share key %q", a)
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	s, ts := newTestServer(t, func(cfg *config) { cfg.Cache.TTL = time.Minute })
+
+	get := func(path string, want int) string {
+		t.Helper()
+		resp, body := do(t, http.MethodGet, ts.URL+path, nil, nil)
+		if resp.StatusCode != want {
+			t.Fatalf("GET %s: status %d, want %d: %s", path, resp.StatusCode, want, body)
+		}
+		return resp.Header.Get("X-Cache")
+	}
+	for _, tt := range []struct {
+		path  string
+		want  int
+		cache string
+	}{
+		{"/v1/search?q=london&limit=5", http.StatusOK, "MISS"},
+		{"/v1/search?q=london&limit=5", http.StatusOK, "HIT"},
+		{"/v1/search?limit=5&q=london", http.StatusOK, "HIT"},
+		{"/v1/airports/XXX", http.StatusNotFound, "MISS"},
+		{"/v1/airports/XXX", http.StatusNotFound, "HIT"},
+		// Client errors aren't cached.
+		{"/v1/search?limit=0", http.StatusBadRequest, "MISS"},
+		{"/v1/search?limit=0", http.StatusBadRequest, "MISS"},
+		// Routes outside the cache have no X-Cache header.
+		{"/healthz", http.StatusOK, ""},
+	} {
+		if got := get(tt.path, tt.want); got != tt.cache {
+			t.Errorf("GET %s: X-Cache %q, want %q", tt.path, got, tt.cache)
+		}
+	}
+
+	// A reload drops everything rendered from the old dataset.
+	get("/v1/airports/HND", http.StatusOK)
+	if err := os.WriteFile(s.cfg.Data.Path, []byte(dropLine(testCSV, "HND")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/v1/airports/HND", http.StatusNotFound); got != "MISS" {
+		t.Errorf("after reload: X-Cache %q", got)
+	}
+}
+
+func TestResponseCacheDisabled(t *testing.T) {
+	if c := newResponseCache(0, 100); c != nil {
+		t.Error("cache with no TTL")
+	}
+	if c := newResponseCache(time.Minute, 0); c != nil {
+		t.Error("cache with no entries")
+	}
+	_, ts := newTestServer(t, nil)
+	if resp, _ := do(t, http.MethodGet, ts.URL+"/v1/airports/LHR", nil, nil); resp.Header.Get("X-Cache") != "" {
+		t.Errorf("X-Cache %q with caching disabled", resp.Header.Get("X-Cache"))
+	}
+}
+
+// fill renders key through c as the leader, with an OK response.
+func fill(t *testing.T, c *responseCache, key string, snap *snapshotInfo) {
+	t.Helper()
+	resp, call, leader := c.get(key, snap)
+	if resp != nil || !leader {
+		t.Fatalf("get(%q): cached %v, leader %v", key, resp != nil, leader)
+	}
+	c.finish(call, &cachedResponse{key: key, snapshot: snap, status: http.StatusOK, body: []byte(key)})
+}
+
+func cachedBody(c *responseCache, key string, snap *snapshotInfo) string {
+	resp, call, leader := c.get(key, snap)
+	if leader {
+		// Release the render slot so later calls aren't left waiting.
+		c.finish(call, &cachedResponse{key: key, status: http.StatusInternalServerError})
+	}
+	if resp == nil {
+		return ""
+	}
+	return string(resp.body)
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	snap := &snapshotInfo{}
+	c := newResponseCache(time.Minute, 2)
+	fill(t, c, "a", snap)
+	fill(t, c, "b", snap)
+	cachedBody(c, "a", snap) // a is now the most recently used
+	fill(t, c, "c", snap)
+
+	for key, want := range map[string]string{"a": "a", "b": "", "c": "c"} {
+		if got := cachedBody(c, key, snap); got != want {
+			t.Errorf("%s: cached %q, want %q", key, got, want)
+		}
+	}
+	if got := cachedBody(c, "a", &snapshotInfo{}); got != "" {
+		t.Errorf("entry from another snapshot served: %q", got)
+	}
+
+	c = newResponseCache(time.Nanosecond, 2)
+	fill(t, c, "a", snap)
+	time.Sleep(time.Millisecond)
+	if got := cachedBody(c, "a", snap); got != "" {
+		t.Errorf("expired entry served: %q", got)
+	}
+}
+
+func TestResponseCacheCoalesces(t *testing.T) {
+	snap := &snapshotInfo{}
+	c := newResponseCache(time.Minute, 10)
+	_, call, leader := c.get("a", snap)
+	if !leader {
+		t.Fatal("first miss isn't the leader")
+	}
+	resp, waiting, leader := c.get("a", snap)
+	if resp != nil || leader || waiting != call {
+		t.Fatal("second miss doesn't wait for the first render")
+	}
+	c.finish(call, &cachedResponse{key: "a", snapshot: snap, status: http.StatusOK, body: []byte("a")})
+	<-waiting.done
+	if string(waiting.resp.body) != "a" {
+		t.Errorf("waiter got %q", waiting.resp.body)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest body worth compressing; below this the
+// encoding overhead outweighs the savings.
+const compressMinSize = 1024
+
+var (
+	gzipPool   = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	brotliPool = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, 5) }}
+)
+
+// compress negotiates br or gzip from Accept-Encoding and compresses
+// response bodies of at least compressMinSize bytes. A compressed body is a
+// different representation, so its ETag gets a "-br" or "-gzip" suffix;
+// the suffix is taken off If-None-Match again before the handler compares
+// it.
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		if inm := r.Header.Get("If-None-Match"); inm != "" {
+			if plain, ok := stripETagSuffix(inm, encoding); ok {
+				r = r.Clone(r.Context())
+				r.Header.Set("If-None-Match", plain)
+				cw.encodedMatch = true
+			}
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// encodedETag returns the ETag of etag's representation in encoding.
+func encodedETag(etag, encoding string) string {
+	if len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// stripETagSuffix undoes encodedETag for each tag in an If-None-Match
+// list, reporting whether any tag had the suffix.
+func stripETagSuffix(list, encoding string) (string, bool) {
+	suffix := "-" + encoding + `"`
+	tags := strings.Split(list, ",")
+	stripped := false
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if t, ok := strings.CutSuffix(tag, suffix); ok {
+			tag, stripped = t+`"`, true
+		}
+		tags[i] = tag
+	}
+	return strings.Join(tags, ", "), stripped
+}
+
+// negotiateEncoding picks "br" or "gzip" (in that order of preference)
+// from an Accept-Encoding header, honouring q=0 exclusions.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, enc := range []string{"br", "gzip"} {
+		if ok, listed := accepted[enc]; ok || (!listed && accepted["*"]) {
+			return enc
+		}
+	}
+	return ""
+}
+
+// compressWriter buffers the start of the body so small responses can be
+// sent uncompressed, then switches to streaming through the encoder.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	// encodedMatch is set when If-None-Match named the encoded
+	// representation, whose ETag a 304 then carries.
+	encodedMatch bool
+
+	status    int
+	buf       []byte
+	committed bool
+	enc       io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.committed {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends buffered data immediately, which streaming handlers rely on.
+func (cw *compressWriter) Flush() {
+	if !cw.committed {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.commit(true)
+	}
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) commit(compress bool) error {
+	cw.committed = true
+
+	h := cw.Header()
+	// Ranges are of the unencoded body, and gzip files are already
+	// compressed.
+	if compress && h.Get("Content-Encoding") == "" && h.Get("Content-Type") != "application/gzip" &&
+		cw.status != http.StatusPartialContent && bodyAllowed(cw.status) {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.enc = newEncoder(cw.encoding, cw.ResponseWriter)
+	}
+	if etag := h.Get("ETag"); etag != "" && (cw.enc != nil || cw.status == http.StatusNotModified && cw.encodedMatch) {
+		h.Set("ETag", encodedETag(etag, cw.encoding))
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+func (cw *compressWriter) close() {
+	if !cw.committed {
+		if cw.status == 0 {
+			// Nothing written; let net/http send its implicit 200.
+			return
+		}
+		cw.commit(false)
+	}
+	if cw.enc == nil {
+		return
+	}
+	cw.enc.Close()
+	switch e := cw.enc.(type) {
+	case *gzip.Writer:
+		gzipPool.Put(e)
+	case *brotli.Writer:
+		brotliPool.Put(e)
+	}
+}
+
+func newEncoder(encoding string, w io.Writer) io.WriteCloser {
+	if encoding == "br" {
+		bw := brotliPool.Get().(*brotli.Writer)
+		bw.Reset(w)
+		return bw
+	}
+	gw := gzipPool.Get().(*gzip.Writer)
+	gw.Reset(w)
+	return gw
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
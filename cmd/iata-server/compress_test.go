@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0, gzip", "gzip"},
+		{"BR;q=0.5", "br"},
+		{"gzip;q=0", ""},
+		{"*", "br"},
+		{"*, br;q=0", "gzip"},
+		{"identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestETagSuffix(t *testing.T) {
+	if got := encodedETag(`"abc"`, "gzip"); got != `"abc-gzip"` {
+		t.Errorf("encodedETag = %s", got)
+	}
+	if got := encodedETag(`W/"abc"`, "br"); got != `W/"abc-br"` {
+		t.Errorf("encodedETag of a weak tag = %s", got)
+	}
+	tests := []struct {
+		list     string
+		want     string
+		stripped bool
+	}{
+		{`"abc-gzip"`, `"abc"`, true},
+		{`"x","abc-gzip"`, `"x", "abc"`, true},
+		{`W/"abc-gzip"`, `W/"abc"`, true},
+		{`"abc-br"`, `"abc-br"`, false},
+		{`"a","b"`, `"a", "b"`, false},
+		{`*`, `*`, false},
+	}
+	for _, tt := range tests {
+		got, stripped := stripETagSuffix(tt.list, "gzip")
+		if got != tt.want || stripped != tt.stripped {
+			t.Errorf("stripETagSuffix(%s) = %s, %v; want %s, %v", tt.list, got, stripped, tt.want, tt.stripped)
+		}
+	}
+}
+
+func TestCompressETags(t *testing.T) {
+	s, ts := newTestServer(t, nil)
+	url := ts.URL + "/v1/airports.json"
+	etag := `"` + s.data.Load().info.Checksum + `"`
+	// Without an Accept-Encoding the transport asks for gzip itself.
+	identity := http.Header{"Accept-Encoding": {"identity"}}
+	_, plain := do(t, http.MethodGet, url, identity, nil)
+
+	tests := []struct {
+		encoding string
+		etag     string
+		decode   func(io.Reader) (io.Reader, error)
+	}{
+		{"identity", etag, nil},
+		{"gzip", strings.TrimSuffix(etag, `"`) + `-gzip"`, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{"br", strings.TrimSuffix(etag, `"`) + `-br"`, func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			header := http.Header{"Accept-Encoding": {tt.encoding}}
+			resp, body := do(t, http.MethodGet, url, header, nil)
+			if got, want := resp.Header.Get("Content-Encoding"), strings.TrimPrefix(tt.encoding, "identity"); got != want {
+				t.Errorf("Content-Encoding = %q, want %q", got, want)
+			}
+			if got := resp.Header.Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %s, want %s", got, tt.etag)
+			}
+			if got := resp.Header.Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q", got)
+			}
+			if tt.decode != nil {
+				r, err := tt.decode(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(body, plain) {
+				t.Error("decoded body differs from the identity one")
+			}
+
+			// Revalidating with the ETag just received gives a 304 carrying it.
+			header.Set("If-None-Match", tt.etag)
+			resp, _ = do(t, http.MethodGet, url, header, nil)
+			if resp.StatusCode != http.StatusNotModified || resp.Header.Get("ETag") != tt.etag {
+				t.Errorf("revalidation: status %d, ETag %s", resp.StatusCode, resp.Header.Get("ETag"))
+			}
+		})
+	}
+
+	// Another encoding's ETag doesn't validate this one.
+	header := http.Header{"Accept-Encoding": {"br"}, "If-None-Match": {tests[1].etag}}
+	if resp, _ := do(t, http.MethodGet, url, header, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("br request validated by the gzip ETag: status %d", resp.StatusCode)
+	}
+	header = http.Header{"Accept-Encoding": {"identity"}, "If-None-Match": {tests[1].etag}}
+	if resp, _ := do(t, http.MethodGet, url, header, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("identity request validated by the gzip ETag: status %d", resp.StatusCode)
+	}
+	header = http.Header{"Accept-Encoding": {"gzip"}, "If-None-Match": {`"other", ` + tests[1].etag}}
+	if resp, _ := do(t, http.MethodGet, url, header, nil); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("ETag list: status %d, want 304", resp.StatusCode)
+	}
+
+	// The .gz dump is already compressed, and small bodies aren't worth it.
+	for _, path := range []string{"/v1/airports.json.gz", "/healthz"} {
+		resp, _ := do(t, http.MethodGet, ts.URL+path, http.Header{"Accept-Encoding": {"gzip"}}, nil)
+		if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+			t.Errorf("%s: Content-Encoding %q", path, enc)
+		}
+		if got := resp.Header.Get("ETag"); strings.Contains(got, "-gzip") {
+			t.Errorf("%s: ETag %s", path, got)
+		}
+	}
+}
+
+// compressRecorder runs h behind compress for a request with the given
+// Accept-Encoding.
+func compressRecorder(h http.HandlerFunc, method, acceptEncoding string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	compress(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCompressWriter(t *testing.T) {
+	large := strings.Repeat("airport ", 1000)
+	// Written in pieces, so the switch to the encoder happens mid-body.
+	streamed := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(large)))
+		for i := 0; i < len(large); i += 100 {
+			w.Write([]byte(large[i : i+100]))
+		}
+	}
+
+	rec := compressRecorder(streamed, http.MethodGet, "gzip", nil)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("headers %v", rec.Header())
+	}
+	if got := gunzip(t, rec.Body.Bytes()); got != large {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(large))
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		header  http.Header
+	}{
+		{"small", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) }, http.MethodGet, nil},
+		{"HEAD", streamed, http.MethodHead, nil},
+		{"already encoded", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "identity")
+			w.Write([]byte(large))
+		}, http.MethodGet, nil},
+		{"gzip file", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write([]byte(large))
+		}, http.MethodGet, nil},
+		{"range", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "", time.Time{}, strings.NewReader(large))
+		}, http.MethodGet, http.Header{"Range": {"bytes=0-1999"}}},
+		{"no content", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }, http.MethodGet, nil},
+	}
+	for _, tt := range tests {
+		rec := compressRecorder(tt.handler, tt.method, "gzip, br", tt.header)
+		if enc := rec.Header().Get("Content-Encoding"); enc == "gzip" || enc == "br" {
+			t.Errorf("%s: compressed with %s", tt.name, enc)
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: Vary %q", tt.name, rec.Header().Get("Vary"))
+		}
+	}
+	if rec := compressRecorder(tests[4].handler, http.MethodGet, "gzip", tests[4].header); rec.Code != http.StatusPartialContent || rec.Body.Len() != 2000 {
+		t.Errorf("range: status %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	// A streaming handler's first event goes out compressed straight away,
+	// though it's under the minimum size.
+	rec := compressRecorder(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {}\n\n")
+		http.NewResponseController(w).Flush()
+	}, http.MethodGet, "gzip", nil)
+	if !rec.Flushed || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("flushed %v, headers %v", rec.Flushed, rec.Header())
+	}
+	if got := gunzip(t, rec.Body.Bytes()); got != "data: {}\n\n" {
+		t.Errorf("decoded %q", got)
+	}
+}
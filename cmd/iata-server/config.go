@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is the complete server configuration. Values are resolved in
+// increasing order of precedence: defaults, the YAML config file,
+// environment variables, then command-line flags.
+type config struct {
+	Listen string `yaml:"listen"`
+
+	Data struct {
+		Path            string        `yaml:"path"`
+		URL             string        `yaml:"url"` // fetched instead of Path when set
+		Names           string        `yaml:"names"`
+		Popularity      string        `yaml:"popularity"`
+		Routes          string        `yaml:"routes"`
+		Airlines        string        `yaml:"airlines"`
+		RefreshInterval time.Duration `yaml:"refresh_interval"`
+	} `yaml:"data"`
+
+	Auth struct {
+		AdminToken string `yaml:"admin_token"`
+	} `yaml:"auth"`
+
+	TLS tlsOptions `yaml:"tls"`
+
+	Limits struct {
+		DefaultPageSize   int           `yaml:"default_page_size"`
+		MaxPageSize       int           `yaml:"max_page_size"`
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	} `yaml:"limits"`
+
+	Cache struct {
+		TTL        time.Duration `yaml:"ttl"` // 0 disables the response cache
+		MaxEntries int           `yaml:"max_entries"`
+	} `yaml:"cache"`
+
+	AccessLog struct {
+		Dest   string `yaml:"dest"`
+		Format string `yaml:"format"`
+	} `yaml:"access_log"`
+
+	UI struct {
+		Enabled bool `yaml:"enabled"` // serve the demo map at /ui
+	} `yaml:"ui"`
+
+	Telemetry struct {
+		Expvar bool `yaml:"expvar"` // serve counters at /debug/vars
+	} `yaml:"telemetry"`
+}
+
+func defaultConfig() config {
+	var cfg config
+	cfg.Listen = ":8080"
+	cfg.Data.Path = defaultDataPath()
+	cfg.TLS.ACMECacheDir = "acme-cache"
+	cfg.TLS.ACMEHTTPAddr = ":80"
+	cfg.Limits.DefaultPageSize = 50
+	cfg.Limits.MaxPageSize = 500
+	cfg.Limits.ReadHeaderTimeout = 10 * time.Second
+	cfg.Limits.ShutdownTimeout = 15 * time.Second
+	cfg.Cache.MaxEntries = 10000
+	cfg.AccessLog.Dest = "stdout"
+	cfg.AccessLog.Format = "json"
+	return cfg
+}
+
+// loadConfig resolves the configuration from all sources. The config file
+// is named by -config or IATA_SERVER_CONFIG.
+func loadConfig(fs *flag.FlagSet, args []string) (config, error) {
+	cfg := defaultConfig()
+
+	configPath := fs.String("config", os.Getenv("IATA_SERVER_CONFIG"), "path to a YAML config file")
+	fs.StringVar(&cfg.Listen, "addr", cfg.Listen, "listen address")
+	fs.StringVar(&cfg.Data.Path, "data", cfg.Data.Path, "path to the airports CSV served by the API")
+	fs.StringVar(&cfg.Data.URL, "data-url", cfg.Data.URL, "URL to fetch the airports CSV from instead of -data")
+	fs.StringVar(&cfg.Data.Names, "names", cfg.Data.Names, "optional CSV of localized names (iata_code,lang,name,municipality)")
+	fs.StringVar(&cfg.Data.Routes, "routes", cfg.Data.Routes, "optional OpenFlights routes.dat, enables the airline endpoints")
+	fs.StringVar(&cfg.Data.Airlines, "airlines", cfg.Data.Airlines, "optional OpenFlights airlines.dat naming the carriers in -routes")
+	fs.StringVar(&cfg.Data.Popularity, "popularity", cfg.Data.Popularity, "optional CSV of passenger traffic or PageRank by iata_code/icao_code, used to rank search")
+	fs.DurationVar(&cfg.Data.RefreshInterval, "refresh-interval", cfg.Data.RefreshInterval, "reload the dataset periodically (0 disables)")
+	fs.StringVar(&cfg.Auth.AdminToken, "admin-token", cfg.Auth.AdminToken, "bearer token for /admin endpoints (admin API disabled if empty)")
+	fs.DurationVar(&cfg.Cache.TTL, "cache-ttl", cfg.Cache.TTL, "cache GET responses for this long within a dataset snapshot (0 disables)")
+	fs.IntVar(&cfg.Cache.MaxEntries, "cache-entries", cfg.Cache.MaxEntries, "maximum number of cached responses")
+	fs.StringVar(&cfg.AccessLog.Dest, "access-log", cfg.AccessLog.Dest, "access log destination: stdout, stderr, off, or a file path")
+	fs.StringVar(&cfg.AccessLog.Format, "access-log-format", cfg.AccessLog.Format, "access log format: json or text")
+	fs.BoolVar(&cfg.UI.Enabled, "ui", cfg.UI.Enabled, "serve a demo map for browsing and searching the dataset at /ui")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert", cfg.TLS.CertFile, "TLS certificate file (PEM)")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key", cfg.TLS.KeyFile, "TLS private key file (PEM)")
+	fs.StringVar(&cfg.TLS.ACMEDomains, "acme-domains", cfg.TLS.ACMEDomains, "comma-separated domains to obtain Let's Encrypt certificates for")
+	fs.StringVar(&cfg.TLS.ACMEEmail, "acme-email", cfg.TLS.ACMEEmail, "contact email for the ACME account")
+	fs.StringVar(&cfg.TLS.ACMECacheDir, "acme-cache", cfg.TLS.ACMECacheDir, "directory to cache ACME certificates in")
+	fs.StringVar(&cfg.TLS.ACMEHTTPAddr, "acme-http-addr", cfg.TLS.ACMEHTTPAddr, "listen address for ACME HTTP-01 challenges and HTTPS redirects")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	// Remember explicit flags so they can win over the file and env.
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = f.Value.String() })
+
+	if *configPath != "" {
+		raw, err := os.ReadFile(*configPath)
+		if err != nil {
+			return cfg, fmt.Errorf("read config: %w", err)
+		}
+		if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(raw))), &cfg); err != nil {
+			return cfg, fmt.Errorf("parse config %s: %w", *configPath, err)
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	for name, v := range explicit {
+		fs.Set(name, v)
+	}
+
+	if cfg.Limits.DefaultPageSize < 1 || cfg.Limits.MaxPageSize < cfg.Limits.DefaultPageSize {
+		return cfg, fmt.Errorf("invalid page size limits: default %d, max %d", cfg.Limits.DefaultPageSize, cfg.Limits.MaxPageSize)
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides settings from IATA_SERVER_* variables. The older
+// IATA_ADMIN_TOKEN is still honoured.
+func (cfg *config) applyEnv() error {
+	vars := []struct {
+		name   string
+		target any
+	}{
+		{"IATA_SERVER_LISTEN", &cfg.Listen},
+		{"IATA_SERVER_DATA_PATH", &cfg.Data.Path},
+		{"IATA_SERVER_DATA_URL", &cfg.Data.URL},
+		{"IATA_SERVER_NAMES", &cfg.Data.Names},
+		{"IATA_SERVER_POPULARITY", &cfg.Data.Popularity},
+		{"IATA_SERVER_ROUTES", &cfg.Data.Routes},
+		{"IATA_SERVER_AIRLINES", &cfg.Data.Airlines},
+		{"IATA_SERVER_REFRESH_INTERVAL", &cfg.Data.RefreshInterval},
+		{"IATA_ADMIN_TOKEN", &cfg.Auth.AdminToken},
+		{"IATA_SERVER_ADMIN_TOKEN", &cfg.Auth.AdminToken},
+		{"IATA_SERVER_TLS_CERT", &cfg.TLS.CertFile},
+		{"IATA_SERVER_TLS_KEY", &cfg.TLS.KeyFile},
+		{"IATA_SERVER_ACME_DOMAINS", &cfg.TLS.ACMEDomains},
+		{"IATA_SERVER_ACME_EMAIL", &cfg.TLS.ACMEEmail},
+		{"IATA_SERVER_DEFAULT_PAGE_SIZE", &cfg.Limits.DefaultPageSize},
+		{"IATA_SERVER_MAX_PAGE_SIZE", &cfg.Limits.MaxPageSize},
+		{"IATA_SERVER_CACHE_TTL", &cfg.Cache.TTL},
+		{"IATA_SERVER_CACHE_ENTRIES", &cfg.Cache.MaxEntries},
+		{"IATA_SERVER_ACCESS_LOG", &cfg.AccessLog.Dest},
+		{"IATA_SERVER_ACCESS_LOG_FORMAT", &cfg.AccessLog.Format},
+		{"IATA_SERVER_EXPVAR", &cfg.Telemetry.Expvar},
+		{"IATA_SERVER_UI", &cfg.UI.Enabled},
+	}
+
+	for _, v := range vars {
+		raw, ok := os.LookupEnv(v.name)
+		if !ok || raw == "" {
+			continue
+		}
+		var err error
+		switch t := v.target.(type) {
+		case *string:
+			*t = raw
+		case *int:
+			*t, err = strconv.Atoi(raw)
+		case *bool:
+			*t, err = strconv.ParseBool(raw)
+		case *time.Duration:
+			*t, err = time.ParseDuration(raw)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", v.name, err)
+		}
+	}
+	return nil
+}
+
+// defaultDataPath mirrors the library's default: AIRPORTS_CSV_PATH, then
+// "data/airports-latest.csv".
+func defaultDataPath() string {
+	if p := os.Getenv("AIRPORTS_CSV_PATH"); p != "" {
+		return p
+	}
+	return "data/airports-latest.csv"
+}
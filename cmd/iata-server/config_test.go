@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func parseConfig(t *testing.T, args ...string) (config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("iata-server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return loadConfig(fs, args)
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	for _, name := range []string{"IATA_SERVER_CONFIG", "IATA_ADMIN_TOKEN", "AIRPORTS_CSV_PATH"} {
+		t.Setenv(name, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	file := `listen: ":9000"
+data:
+  path: /srv/airports.csv
+  refresh_interval: 1h
+auth:
+  admin_token: ${TEST_ADMIN_TOKEN}
+limits:
+  default_page_size: 20
+cache:
+  ttl: 30s
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_ADMIN_TOKEN", "from-file")
+	t.Setenv("IATA_SERVER_CONFIG", path)
+	t.Setenv("IATA_SERVER_LISTEN", ":9100")
+	t.Setenv("IATA_SERVER_MAX_PAGE_SIZE", "100")
+	t.Setenv("IATA_SERVER_EXPVAR", "true")
+
+	cfg, err := parseConfig(t, "-addr", ":9200", "-cache-ttl", "1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"listen (flag over env over file)", cfg.Listen, ":9200"},
+		{"data path (file)", cfg.Data.Path, "/srv/airports.csv"},
+		{"refresh interval (file)", cfg.Data.RefreshInterval, time.Hour},
+		{"admin token (expanded in file)", cfg.Auth.AdminToken, "from-file"},
+		{"default page size (file)", cfg.Limits.DefaultPageSize, 20},
+		{"max page size (env)", cfg.Limits.MaxPageSize, 100},
+		{"expvar (env)", cfg.Telemetry.Expvar, true},
+		{"cache ttl (flag over file)", cfg.Cache.TTL, time.Minute},
+		{"cache entries (default)", cfg.Cache.MaxEntries, 10000},
+		{"access log (default)", cfg.AccessLog.Dest, "stdout"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestLoadConfigLegacyToken(t *testing.T) {
+	t.Setenv("IATA_SERVER_CONFIG", "")
+	t.Setenv("IATA_ADMIN_TOKEN", "old")
+	t.Setenv("IATA_SERVER_ADMIN_TOKEN", "")
+	cfg, err := parseConfig(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Auth.AdminToken != "old" {
+		t.Errorf("admin token %q, want IATA_ADMIN_TOKEN", cfg.Auth.AdminToken)
+	}
+	t.Setenv("IATA_SERVER_ADMIN_TOKEN", "new")
+	if cfg, _ := parseConfig(t); cfg.Auth.AdminToken != "new" {
+		t.Errorf("admin token %q, want IATA_SERVER_ADMIN_TOKEN", cfg.Auth.AdminToken)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Setenv("IATA_SERVER_CONFIG", "")
+	bad := filepath.Join(t.TempDir(), "bad.yaml")
+	if err := os.WriteFile(bad, []byte("limits: [1, 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{"missing file", nil, []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}},
+		{"bad YAML", nil, []string{"-config", bad}},
+		{"bad env int", map[string]string{"IATA_SERVER_MAX_PAGE_SIZE": "lots"}, nil},
+		{"bad env duration", map[string]string{"IATA_SERVER_CACHE_TTL": "10"}, nil},
+		{"bad page sizes", nil, []string{"-config", writeConfig(t, "limits:\n  default_page_size: 50\n  max_page_size: 10\n")}},
+		{"unknown flag", nil, []string{"-verbose"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, v := range tt.env {
+				t.Setenv(name, v)
+			}
+			if _, err := parseConfig(t, tt.args...); err == nil {
+				t.Error("loadConfig succeeded")
+			}
+		})
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExampleConfig(t *testing.T) {
+	t.Setenv("IATA_SERVER_CONFIG", "config.example.yaml")
+	if _, err := parseConfig(t); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// countrySummary is one entry of GET /v1/countries.
+type countrySummary struct {
+	IsoCountry  string `json:"iso_country"`
+	CountryName string `json:"country_name"`
+	Continent   string `json:"continent"`
+	Airports    int    `json:"airports"`
+}
+
+// handleCountries serves GET /v1/countries, ordered by ISO code and
+// filtered by ?continent= and ?type= (counting only airports of that
+// type). The cursor is the last ISO code of the previous page.
+func (s *server) handleCountries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, err := s.parseLimit(q.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	continent, typ := q.Get("continent"), q.Get("type")
+	byISO := map[string]*countrySummary{}
+	for _, a := range s.data.Load().store.All() {
+		if a.IsoCountry == "" {
+			continue
+		}
+		if continent != "" && !strings.EqualFold(a.Continent, continent) {
+			continue
+		}
+		if typ != "" && a.Type != typ {
+			continue
+		}
+		c, ok := byISO[a.IsoCountry]
+		if !ok {
+			c = &countrySummary{IsoCountry: a.IsoCountry, CountryName: a.CountryName, Continent: a.Continent}
+			byISO[a.IsoCountry] = c
+		}
+		c.Airports++
+	}
+	countries := make([]*countrySummary, 0, len(byISO))
+	for _, c := range byISO {
+		countries = append(countries, c)
+	}
+	sort.Slice(countries, func(i, j int) bool { return countries[i].IsoCountry < countries[j].IsoCountry })
+
+	start := sort.Search(len(countries), func(i int) bool { return countries[i].IsoCountry > after })
+	out := page{Data: []any{}}
+	for _, c := range countries[start:] {
+		if len(out.Data) == limit {
+			out.NextCursor = encodeCursor(countries[start+limit-1].IsoCountry)
+			break
+		}
+		out.Data = append(out.Data, c)
+	}
+
+	setResultCount(r, len(out.Data))
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleCountryAirports serves GET /v1/countries/{iso}/airports, paged
+// and filtered like /v1/airports.
+func (s *server) handleCountryAirports(w http.ResponseWriter, r *http.Request) {
+	iso := r.PathValue("iso")
+	store := s.data.Load().store
+	airports := store.Filter(func(a *iataplaces.Airport) bool {
+		return strings.EqualFold(a.IsoCountry, iso)
+	})
+	if len(airports) == 0 {
+		setResultCount(r, 0)
+		writeError(w, http.StatusNotFound, "country not found")
+		return
+	}
+	s.listAirports(w, r, store, airports, "")
+}
+
+// handleCityAirports serves GET /v1/cities/{name}/airports: the airports
+// whose municipality is name, ignoring case. ?country= tells apart cities
+// that share a name; paging and ?type= work as for /v1/airports.
+func (s *server) handleCityAirports(w http.ResponseWriter, r *http.Request) {
+	city := strings.TrimSpace(r.PathValue("name"))
+	store := s.data.Load().store
+	airports := store.Filter(func(a *iataplaces.Airport) bool {
+		return strings.EqualFold(a.Municipality, city)
+	})
+	if len(airports) == 0 {
+		setResultCount(r, 0)
+		writeError(w, http.StatusNotFound, "city not found")
+		return
+	}
+	s.listAirports(w, r, store, airports, r.URL.Query().Get("country"))
+}
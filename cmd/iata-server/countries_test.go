@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestCountries(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	type countriesPage struct {
+		Data       []countrySummary `json:"data"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	var first, second countriesPage
+	getJSON(t, ts.URL+"/v1/countries?limit=3", http.StatusOK, &first)
+	if len(first.Data) != 3 || first.NextCursor == "" {
+		t.Fatalf("first page %+v", first)
+	}
+	want := countrySummary{IsoCountry: "GB", CountryName: "United Kingdom", Continent: "EU", Airports: 2}
+	if first.Data[0].IsoCountry != "FR" || first.Data[1] != want {
+		t.Errorf("first page %+v", first.Data)
+	}
+	getJSON(t, ts.URL+"/v1/countries?limit=3&cursor="+url.QueryEscape(first.NextCursor), http.StatusOK, &second)
+	if len(second.Data) != 1 || second.Data[0].IsoCountry != "US" || second.NextCursor != "" {
+		t.Errorf("second page %+v", second)
+	}
+
+	var europe countriesPage
+	getJSON(t, ts.URL+"/v1/countries?continent=eu", http.StatusOK, &europe)
+	if len(europe.Data) != 2 || europe.Data[0].IsoCountry != "FR" || europe.Data[1].IsoCountry != "GB" {
+		t.Errorf("European countries %+v", europe.Data)
+	}
+	var none countriesPage
+	getJSON(t, ts.URL+"/v1/countries?type=heliport", http.StatusOK, &none)
+	if len(none.Data) != 0 {
+		t.Errorf("countries with heliports %+v", none.Data)
+	}
+	getJSON(t, ts.URL+"/v1/countries?cursor=!!", http.StatusBadRequest, nil)
+}
+
+func TestCountryAndCityAirports(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	for _, tt := range []struct {
+		path string
+		want []string
+	}{
+		{"/v1/countries/gb/airports", []string{"LGW", "LHR"}},
+		{"/v1/countries/GB/airports?limit=1", []string{"LGW"}},
+		{"/v1/cities/london/airports", []string{"LGW", "LHR"}},
+		{"/v1/cities/New%20York/airports", []string{"JFK"}},
+		{"/v1/cities/london/airports?country=us", nil},
+	} {
+		if got := codes(getPage(t, ts.URL+tt.path)); !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s: %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	getJSON(t, ts.URL+"/v1/countries/ZZ/airports", http.StatusNotFound, nil)
+	getJSON(t, ts.URL+"/v1/cities/atlantis/airports", http.StatusNotFound, nil)
+}
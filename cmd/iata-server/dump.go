@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// datasetDump is the full dataset rendered for GET /v1/airports.json, in
+// plain and gzip form, built once per snapshot on first request.
+type datasetDump struct {
+	snapshot *snapshotInfo
+	json     []byte
+	gz       []byte
+}
+
+// dumpBody is the document clients sync: the snapshot it was taken from
+// and every airport, in the library's JSON form.
+type dumpBody struct {
+	Checksum string                `json:"checksum"`
+	LoadedAt time.Time             `json:"loaded_at"`
+	Data     []*iataplaces.Airport `json:"data"`
+}
+
+type dumpCache struct {
+	mu   sync.Mutex
+	last *datasetDump
+}
+
+// get returns the dump of d, rebuilding it when the dataset has been
+// reloaded since the last one.
+func (c *dumpCache) get(d *dataset) (*datasetDump, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snap := d.info
+	if c.last != nil && c.last.snapshot == snap {
+		return c.last, nil
+	}
+
+	body, err := json.Marshal(dumpBody{Checksum: snap.Checksum, LoadedAt: snap.LoadedAt, Data: d.store.All()})
+	if err != nil {
+		return nil, err
+	}
+	var gz bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	zw.Write(body)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	c.last = &datasetDump{snapshot: snap, json: body, gz: gz.Bytes()}
+	return c.last, nil
+}
+
+// handleDump serves GET /v1/airports.json and /v1/airports.json.gz: the
+// whole current dataset, for clients that keep an offline copy. The
+// snapshot checksum is the ETag and is repeated in X-Dataset-Checksum, so
+// a client can poll with If-None-Match and only download on change; range
+// requests let an interrupted download resume.
+func (s *server) handleDump(gzipped bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d := s.data.Load()
+		dump, err := s.dump.get(d)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "render dataset")
+			return
+		}
+
+		h := w.Header()
+		snap := d.info
+		h.Set("ETag", strconv.Quote(snap.Checksum))
+		h.Set("X-Dataset-Checksum", snap.Checksum)
+		h.Set("X-Dataset-Airports", strconv.Itoa(snap.Airports))
+		h.Set("Cache-Control", "no-cache")
+		body, name := dump.json, "airports.json"
+		if gzipped {
+			body, name = dump.gz, "airports.json.gz"
+			h.Set("Content-Type", "application/gzip")
+		} else {
+			h.Set("Content-Type", "application/json")
+		}
+		setResultCount(r, snap.Airports)
+		http.ServeContent(w, r, name, snap.LoadedAt, bytes.NewReader(body))
+	}
+}
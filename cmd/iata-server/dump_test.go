@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	s, ts := newTestServer(t, nil)
+	identity := http.Header{"Accept-Encoding": {"identity"}}
+
+	resp, body := do(t, http.MethodGet, ts.URL+"/v1/airports.json", identity, nil)
+	var dump dumpBody
+	if err := json.Unmarshal(body, &dump); err != nil {
+		t.Fatalf("%v: %s", err, body)
+	}
+	checksum := s.data.Load().info.Checksum
+	if dump.Checksum != checksum || len(dump.Data) != 5 || dump.Data[0].IATACode != "CDG" {
+		t.Errorf("dump has checksum %s and %d airports", dump.Checksum, len(dump.Data))
+	}
+	for name, want := range map[string]string{
+		"Content-Type":       "application/json",
+		"ETag":               strconv.Quote(checksum),
+		"X-Dataset-Checksum": checksum,
+		"X-Dataset-Airports": "5",
+		"Accept-Ranges":      "bytes",
+	} {
+		if got := resp.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	resp, gz := do(t, http.MethodGet, ts.URL+"/v1/airports.json.gz", identity, nil)
+	if resp.Header.Get("Content-Type") != "application/gzip" || gunzip(t, gz) != string(body) {
+		t.Errorf(".gz dump: Content-Type %q, doesn't decompress to the JSON dump", resp.Header.Get("Content-Type"))
+	}
+
+	// An interrupted download resumes with a range request.
+	header := http.Header{"Accept-Encoding": {"gzip"}, "Range": {"bytes=100-"}}
+	resp, rest := do(t, http.MethodGet, ts.URL+"/v1/airports.json", header, nil)
+	if resp.StatusCode != http.StatusPartialContent || string(rest) != string(body[100:]) {
+		t.Errorf("range request: status %d with %d bytes", resp.StatusCode, len(rest))
+	}
+
+	// After a reload the dump is rebuilt and the old ETag no longer matches.
+	if err := os.WriteFile(s.cfg.Data.Path, []byte(dropLine(testCSV, "HND")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	header = http.Header{"Accept-Encoding": {"identity"}, "If-None-Match": {strconv.Quote(checksum)}}
+	resp, body = do(t, http.MethodGet, ts.URL+"/v1/airports.json", header, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Dataset-Airports") != "4" {
+		t.Fatalf("after reload: status %d, %s airports", resp.StatusCode, resp.Header.Get("X-Dataset-Airports"))
+	}
+	if err := json.Unmarshal(body, &dump); err != nil || len(dump.Data) != 4 || dump.Checksum == checksum {
+		t.Errorf("dump after reload has checksum %s and %d airports", dump.Checksum, len(dump.Data))
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testCSV is a small extract of the OurAirports data.
+const testCSV = `id,ident,type,name,latitude_deg,longitude_deg,elevation_ft,continent,country_name,iso_country,region_name,iso_region,local_region,municipality,scheduled_service,gps_code,icao_code,iata_code,local_code,home_link,wikipedia_link,keywords,score,last_updated
+2434,EGLL,large_airport,London Heathrow Airport,51.4706,-0.461941,83,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGLL,EGLL,LHR,,http://www.heathrowairport.com/,https://en.wikipedia.org/wiki/Heathrow_Airport,"LON, Londres",1251675,2022-10-18T18:48:50+00:00
+2429,EGKK,large_airport,London Gatwick Airport,51.148771,-0.192089,202,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGKK,EGKK,LGW,,http://www.gatwickairport.com/,https://en.wikipedia.org/wiki/Gatwick_Airport,"LON, Crawley, Charlwood",1049275,2025-02-27T12:47:43+00:00
+3622,KJFK,large_airport,John F Kennedy International Airport,40.639447,-73.779317,13,NA,United States,US,New York,US-NY,NY,New York,1,KJFK,KJFK,JFK,JFK,https://www.jfkairport.com/,https://en.wikipedia.org/wiki/John_F._Kennedy_International_Airport,"Manhattan, New York City, NYC, Idlewild, IDL, KIDL",1052075,2022-10-18T18:49:55+00:00
+4185,LFPG,large_airport,Charles de Gaulle International Airport,49.012798,2.55,392,EU,France,FR,Île-de-France,FR-IDF,IDF,"Paris (Roissy-en-France, Val-d'Oise)",1,LFPG,LFPG,CDG,,http://www.aeroportsdeparis.fr/,https://en.wikipedia.org/wiki/Charles_de_Gaulle_Airport,"PAR, Aéroport Roissy-Charles de Gaulle, Roissy Airport",1127475,2024-06-22T13:11:28+00:00
+5627,RJTT,large_airport,Tokyo Haneda International Airport,35.552299,139.779999,35,AS,Japan,JP,Tōkyō Prefecture,JP-13,13,Tokyo,1,RJTT,RJTT,HND,,http://www.haneda-airport.jp/,https://en.wikipedia.org/wiki/Tokyo_International_Airport,"TYO, Haneda",1168475,2021-04-09T01:56:38+00:00
+`
+
+const testToken = "secret"
+
+// newTestServer serves testCSV from a data file, with the admin API
+// enabled. configure, if not nil, adjusts the configuration first.
+func newTestServer(t *testing.T, configure func(*config)) (*server, *httptest.Server) {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.Data.Path = filepath.Join(t.TempDir(), "airports.csv")
+	cfg.Auth.AdminToken = testToken
+	if err := os.WriteFile(cfg.Data.Path, []byte(testCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if configure != nil {
+		configure(&cfg)
+	}
+	s := newServer(cfg)
+	if err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(func() {
+		s.closeStreams()
+		ts.Close()
+	})
+	return s, ts
+}
+
+// do sends a request and returns the response with its body read.
+func do(t *testing.T, method, url string, header http.Header, body []byte) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, b
+}
+
+// getJSON fetches url and decodes its JSON body into v, failing unless
+// the status is want.
+func getJSON(t *testing.T, url string, want int, v any) {
+	t.Helper()
+	resp, body := do(t, http.MethodGet, url, nil, nil)
+	if resp.StatusCode != want {
+		t.Fatalf("GET %s: status %d, want %d: %s", url, resp.StatusCode, want, body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			t.Fatalf("GET %s: %v: %s", url, err, body)
+		}
+	}
+}
+
+func adminHeader() http.Header {
+	return http.Header{"Authorization": {"Bearer " + testToken}}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// page is the envelope for list and search responses. NextCursor is empty
+// on the last page.
+type page struct {
+	Data       []any  `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// handleList serves GET /v1/airports, ordered by IATA code and filtered by
+// ?country= and ?type=. The cursor is the last code of the previous page,
+// so pages stay consistent across dataset reloads.
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	store := s.data.Load().store
+	s.listAirports(w, r, store, store.All(), r.URL.Query().Get("country"))
+}
+
+// listAirports writes one page of all, which must come from store ordered
+// by IATA code, keeping airports in country (any when empty) and of the
+// ?type= asked for.
+func (s *server) listAirports(w http.ResponseWriter, r *http.Request, store *iataplaces.Store, all []*iataplaces.Airport, country string) {
+	q := r.URL.Query()
+	rd, err := newRenderer(w, r, store)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := s.parseLimit(q.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	typ := q.Get("type")
+	start := sort.Search(len(all), func(i int) bool { return all[i].IATACode > after })
+
+	out := page{Data: []any{}}
+	var last string
+	for _, a := range all[start:] {
+		if country != "" && !strings.EqualFold(a.IsoCountry, country) {
+			continue
+		}
+		if typ != "" && a.Type != typ {
+			continue
+		}
+		if len(out.Data) == limit {
+			out.NextCursor = encodeCursor(last)
+			break
+		}
+		out.Data = append(out.Data, rd.render(a))
+		last = a.IATACode
+	}
+
+	setResultCount(r, len(out.Data))
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleSearch serves GET /v1/search?q=...&city=&country=&type=. Results
+// are ranked, so the cursor is an offset into the ranked list. With
+// highlight=1 each result is {"airport": ..., "matches": [...]}.
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	store := s.data.Load().store
+	rd, err := newRenderer(w, r, store)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := s.parseLimit(q.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offset := 0
+	if c := q.Get("cursor"); c != "" {
+		raw, err := decodeCursor(c)
+		if err == nil {
+			offset, err = strconv.Atoi(raw)
+		}
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+	}
+
+	results := store.Search(iataplaces.SearchQuery{
+		Text:    q.Get("q"),
+		City:    q.Get("city"),
+		Country: q.Get("country"),
+		Type:    q.Get("type"),
+		Limit:   offset + limit + 1, // one extra to detect a further page
+	})
+
+	highlight, _ := strconv.ParseBool(q.Get("highlight"))
+	out := page{Data: []any{}}
+	for i := offset; i < len(results) && len(out.Data) < limit; i++ {
+		res := results[i]
+		if !highlight {
+			out.Data = append(out.Data, rd.render(res.Airport))
+			continue
+		}
+		matches := res.Matches
+		if matches == nil {
+			matches = []iataplaces.Match{}
+		}
+		out.Data = append(out.Data, map[string]any{"airport": rd.render(res.Airport), "matches": matches})
+	}
+	if len(results) > offset+limit {
+		out.NextCursor = encodeCursor(strconv.Itoa(offset + limit))
+	}
+
+	setResultCount(r, len(out.Data))
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *server) parseLimit(v string) (int, error) {
+	if v == "" {
+		return s.cfg.Limits.DefaultPageSize, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid limit %q", v)
+	}
+	return min(n, s.cfg.Limits.MaxPageSize), nil
+}
+
+func encodeCursor(v string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(v))
+}
+
+func decodeCursor(c string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	return string(b), err
+}
+
+// airportFields maps each JSON field name of Airport to its struct index,
+// for ?fields= projections.
+var airportFields = func() map[string]int {
+	t := reflect.TypeOf(iataplaces.Airport{})
+	m := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		m[name] = i
+	}
+	return m
+}()
+
+// parseFields validates a comma-separated ?fields= list. A nil result
+// means "all fields".
+func parseFields(v string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if _, ok := airportFields[f]; !ok {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// project returns a with only the requested fields, or a itself when no
+// projection was asked for.
+func project(a *iataplaces.Airport, fields []string) any {
+	if fields == nil {
+		return a
+	}
+	v := reflect.ValueOf(a).Elem()
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		out[f] = v.Field(airportFields[f]).Interface()
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// codes returns the IATA codes of a page of airports.
+func codes(p page) []string {
+	var out []string
+	for _, a := range p.Data {
+		out = append(out, a.(map[string]any)["iata_code"].(string))
+	}
+	return out
+}
+
+// getPage fetches a list or search page decoded into generic values.
+func getPage(t *testing.T, url string) page {
+	t.Helper()
+	var p page
+	getJSON(t, url, http.StatusOK, &p)
+	return p
+}
+
+func TestListPagination(t *testing.T) {
+	_, ts := newTestServer(t, func(cfg *config) { cfg.Limits.MaxPageSize = 3 })
+
+	var got [][]string
+	next := ts.URL + "/v1/airports?limit=2"
+	for next != "" {
+		p := getPage(t, next)
+		got = append(got, codes(p))
+		next = ""
+		if p.NextCursor != "" {
+			next = ts.URL + "/v1/airports?limit=2&cursor=" + url.QueryEscape(p.NextCursor)
+		}
+	}
+	want := [][]string{{"CDG", "HND"}, {"JFK", "LGW"}, {"LHR"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("pages %v, want %v", got, want)
+	}
+
+	if p := getPage(t, ts.URL+"/v1/airports?limit=100"); len(p.Data) != 3 {
+		t.Errorf("limit above the maximum gave %d airports", len(p.Data))
+	}
+	if got := codes(getPage(t, ts.URL+"/v1/airports?country=gb")); !slices.Equal(got, []string{"LGW", "LHR"}) {
+		t.Errorf("country=gb gave %v", got)
+	}
+	if got := codes(getPage(t, ts.URL+"/v1/airports?type=small_airport")); len(got) != 0 {
+		t.Errorf("type=small_airport gave %v", got)
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	first := getPage(t, ts.URL+"/v1/search?q=london&limit=1")
+	if len(first.Data) != 1 || first.NextCursor == "" {
+		t.Fatalf("first page %+v", first)
+	}
+	second := getPage(t, ts.URL+"/v1/search?q=london&limit=1&cursor="+url.QueryEscape(first.NextCursor))
+	if len(second.Data) != 1 || second.NextCursor != "" {
+		t.Fatalf("second page %+v", second)
+	}
+	got := append(codes(first), codes(second)...)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"LGW", "LHR"}) {
+		t.Errorf("pages hold %v", got)
+	}
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	var a map[string]any
+	getJSON(t, ts.URL+"/v1/airports/LHR?fields=iata_code,%20name", http.StatusOK, &a)
+	if len(a) != 2 || a["iata_code"] != "LHR" || a["name"] != "London Heathrow Airport" {
+		t.Errorf("projected airport %v", a)
+	}
+	for _, a := range getPage(t, ts.URL+"/v1/search?q=london&fields=iata_code").Data {
+		if len(a.(map[string]any)) != 1 {
+			t.Errorf("projected search result %v", a)
+		}
+	}
+}
+
+func TestListBadParameters(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+	for _, path := range []string{
+		"/v1/airports?limit=0",
+		"/v1/airports?limit=ten",
+		"/v1/airports?cursor=!!",
+		"/v1/airports?fields=iata_code,runways",
+		"/v1/search?q=london&cursor=" + encodeCursor("-1"),
+		"/v1/search?q=london&cursor=" + encodeCursor("x"),
+	} {
+		getJSON(t, ts.URL+path, http.StatusBadRequest, nil)
+	}
+}
+
+func TestSearchHighlight(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	var resp struct {
+		Data []struct {
+			Airport map[string]any     `json:"airport"`
+			Matches []iataplaces.Match `json:"matches"`
+		} `json:"data"`
+	}
+	getJSON(t, ts.URL+"/v1/search?q=gatwick&highlight=1&fields=name", http.StatusOK, &resp)
+	if len(resp.Data) != 1 {
+		t.Fatalf("%d results", len(resp.Data))
+	}
+	name := resp.Data[0].Airport["name"].(string)
+	found := false
+	for _, m := range resp.Data[0].Matches {
+		if m.Field == "name" && name[m.Start:m.End] == "Gatwick" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("matches %+v don't mark Gatwick in %q", resp.Data[0].Matches, name)
+	}
+
+	// Without highlight=1 results are plain airports.
+	for _, a := range getPage(t, ts.URL+"/v1/search?q=gatwick").Data {
+		if _, ok := a.(map[string]any)["matches"]; ok {
+			t.Errorf("unhighlighted result %v", a)
+		}
+	}
+}
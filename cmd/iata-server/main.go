@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	cfg, err := loadConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	srv := newServer(cfg)
+	accessLogger, err := newAccessLogger(cfg.AccessLog.Dest, cfg.AccessLog.Format)
+	if err != nil {
+		log.Fatalf("failed to set up access log: %v", err)
+	}
+	srv.accessLogger = accessLogger
+
+	if err := srv.reload(); err != nil {
+		log.Fatalf("failed to load airports: %v", err)
+	}
+
+	httpSrv := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           srv.routes(),
+		ReadHeaderTimeout: cfg.Limits.ReadHeaderTimeout,
+	}
+	httpSrv.RegisterOnShutdown(srv.closeStreams)
+
+	var challengeSrv *http.Server
+	if cfg.TLS.enabled() {
+		var err error
+		challengeSrv, err = configureTLS(httpSrv, cfg.TLS)
+		if err != nil {
+			log.Fatalf("failed to configure TLS: %v", err)
+		}
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		if httpSrv.TLSConfig != nil {
+			log.Printf("Listening on %s (TLS)", cfg.Listen)
+			errc <- httpSrv.ListenAndServeTLS("", "")
+			return
+		}
+		log.Printf("Listening on %s", cfg.Listen)
+		errc <- httpSrv.ListenAndServe()
+	}()
+	if challengeSrv != nil {
+		go func() {
+			log.Printf("Serving ACME challenges on %s", challengeSrv.Addr)
+			errc <- challengeSrv.ListenAndServe()
+		}()
+	}
+
+	if cfg.Data.RefreshInterval > 0 {
+		go srv.refreshEvery(cfg.Data.RefreshInterval)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+	for {
+		select {
+		case err := <-errc:
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("server error: %v", err)
+			}
+			return
+		case sig := <-sigs:
+			if sig == syscall.SIGHUP {
+				// Reload in place; requests already running keep the store
+				// they started with.
+				if err := srv.reload(); err != nil {
+					log.Printf("reload failed, keeping previous dataset: %v", err)
+				}
+				continue
+			}
+
+			log.Printf("Received %s, shutting down", sig)
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Limits.ShutdownTimeout)
+			if challengeSrv != nil {
+				challengeSrv.Shutdown(ctx)
+			}
+			err := httpSrv.Shutdown(ctx)
+			cancel()
+			if err != nil {
+				log.Fatalf("shutdown: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// newAccessLogger builds the slog logger used for access logs, or nil when
+// access logging is off.
+func newAccessLogger(dest, format string) (*slog.Logger, error) {
+	var w io.Writer
+	switch dest {
+	case "off", "":
+		return nil, nil
+	case "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+
+	switch format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", format)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// cluster is a Clusters result with the representative airport rendered
+// like any other airport response.
+type cluster struct {
+	Lat     float64         `json:"lat"`
+	Lon     float64         `json:"lon"`
+	Count   int             `json:"count"`
+	Bounds  iataplaces.BBox `json:"bounds"`
+	Airport any             `json:"airport"`
+}
+
+// handleClusters serves GET /v1/clusters?bbox=minLon,minLat,maxLon,maxLat&zoom=N
+// for web maps that show every airport without clustering on the client.
+// bbox defaults to the whole world.
+func (s *server) handleClusters(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	store := s.data.Load().store
+	rd, err := newRenderer(w, r, store)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bbox := iataplaces.BBox{MinLon: -180, MinLat: -90, MaxLon: 180, MaxLat: 90}
+	if v := q.Get("bbox"); v != "" {
+		if bbox, err = parseBBox(v); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	zoom, err := strconv.Atoi(q.Get("zoom"))
+	if err != nil || zoom < 0 || zoom > 22 {
+		writeError(w, http.StatusBadRequest, "zoom must be an integer from 0 to 22")
+		return
+	}
+
+	clusters := store.Clusters(bbox, zoom)
+	out := make([]cluster, len(clusters))
+	for i, c := range clusters {
+		out[i] = cluster{Lat: c.Lat, Lon: c.Lon, Count: c.Count, Bounds: c.Bounds, Airport: rd.render(c.Top)}
+	}
+	setResultCount(r, len(out))
+	writeJSON(w, http.StatusOK, map[string]any{"data": out})
+}
+
+// handleTile serves GET /v1/tiles/{z}/{x}/{y}.mvt, the airport layer as
+// Mapbox Vector Tiles for map overlays.
+func (s *server) handleTile(w http.ResponseWriter, r *http.Request) {
+	yStr, ok := strings.CutSuffix(r.PathValue("y"), ".mvt")
+	z, errZ := strconv.Atoi(r.PathValue("z"))
+	x, errX := strconv.Atoi(r.PathValue("x"))
+	y, errY := strconv.Atoi(yStr)
+	if !ok || errZ != nil || errX != nil || errY != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	tile, err := s.data.Load().store.VectorTile(z, x, y)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	w.Write(tile)
+}
+
+// parseBBox reads "minLon,minLat,maxLon,maxLat".
+func parseBBox(v string) (iataplaces.BBox, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return iataplaces.BBox{}, fmt.Errorf("invalid bbox %q: want minLon,minLat,maxLon,maxLat", v)
+	}
+	var f [4]float64
+	for i, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return iataplaces.BBox{}, fmt.Errorf("invalid bbox %q: %v", v, err)
+		}
+		f[i] = n
+	}
+	b := iataplaces.BBox{MinLon: f[0], MinLat: f[1], MaxLon: f[2], MaxLat: f[3]}
+	if b.MinLat > b.MaxLat || b.MinLat < -90 || b.MaxLat > 90 || b.MinLon < -180 || b.MaxLon > 180 {
+		return iataplaces.BBox{}, fmt.Errorf("invalid bbox %q", v)
+	}
+	return b, nil
+}
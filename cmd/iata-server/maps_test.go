@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+type clusterResponse struct {
+	Data []struct {
+		Count   int            `json:"count"`
+		Airport map[string]any `json:"airport"`
+	} `json:"data"`
+}
+
+func TestClusters(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	total := func(r clusterResponse) int {
+		n := 0
+		for _, c := range r.Data {
+			n += c.Count
+		}
+		return n
+	}
+
+	var world clusterResponse
+	getJSON(t, ts.URL+"/v1/clusters?zoom=0", http.StatusOK, &world)
+	if total(world) != 5 || len(world.Data) >= 5 {
+		t.Errorf("zoom 0: %d airports in %d clusters", total(world), len(world.Data))
+	}
+
+	var street clusterResponse
+	getJSON(t, ts.URL+"/v1/clusters?zoom=22", http.StatusOK, &street)
+	if len(street.Data) != 5 {
+		t.Errorf("zoom 22: %d clusters, want one per airport", len(street.Data))
+	}
+
+	// At zoom 5 Heathrow and Gatwick share a cluster, represented by the
+	// busier Heathrow.
+	var london clusterResponse
+	getJSON(t, ts.URL+"/v1/clusters?zoom=5&bbox=-1,51,0,52&fields=iata_code", http.StatusOK, &london)
+	if len(london.Data) != 1 || london.Data[0].Count != 2 || london.Data[0].Airport["iata_code"] != "LHR" || len(london.Data[0].Airport) != 1 {
+		t.Errorf("London clusters %+v", london.Data)
+	}
+
+	for _, query := range []string{
+		"",
+		"zoom=23",
+		"zoom=-1",
+		"zoom=3&bbox=1,2,3",
+		"zoom=3&bbox=0,60,10,50",
+		"zoom=3&bbox=-200,0,0,10",
+		"zoom=3&bbox=a,b,c,d",
+		"zoom=3&fields=nope",
+	} {
+		getJSON(t, ts.URL+"/v1/clusters?"+query, http.StatusBadRequest, nil)
+	}
+}
+
+func TestTiles(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+
+	resp, body := do(t, http.MethodGet, ts.URL+"/v1/tiles/0/0/0.mvt", http.Header{"Accept-Encoding": {"identity"}}, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/vnd.mapbox-vector-tile" {
+		t.Fatalf("tile 0/0/0: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for _, code := range []string{iataplaces.MVTLayer, "LHR", "HND"} {
+		if !bytes.Contains(body, []byte(code)) {
+			t.Errorf("tile 0/0/0 has no %q", code)
+		}
+	}
+
+	// Heathrow's tile at zoom 10 doesn't reach Tokyo.
+	_, body = do(t, http.MethodGet, ts.URL+"/v1/tiles/10/510/340.mvt", http.Header{"Accept-Encoding": {"identity"}}, nil)
+	if !bytes.Contains(body, []byte("LHR")) || bytes.Contains(body, []byte("HND")) {
+		t.Errorf("tile 10/510/340 has the wrong airports")
+	}
+
+	for _, path := range []string{"/v1/tiles/0/0/0", "/v1/tiles/0/0/0.png", "/v1/tiles/z/0/0.mvt", "/v1/tiles/23/0/0.mvt", "/v1/tiles/1/2/0.mvt"} {
+		if resp, _ := do(t, http.MethodGet, ts.URL+path, nil, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
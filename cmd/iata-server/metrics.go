@@ -0,0 +1,23 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Counters published at /debug/vars when telemetry.expvar is enabled.
+var (
+	requestsTotal  = expvar.NewInt("requests_total")
+	reloads        = expvar.NewInt("reloads_total")
+	reloadFailures = expvar.NewInt("reload_failures_total")
+	airportCount   = expvar.NewInt("airports")
+	cacheHits      = expvar.NewInt("cache_hits_total")
+	cacheMisses    = expvar.NewInt("cache_misses_total")
+)
+
+func countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsTotal.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestExpvar(t *testing.T) {
+	_, ts := newTestServer(t, func(cfg *config) { cfg.Telemetry.Expvar = true })
+
+	vars := func() map[string]any {
+		var v map[string]any
+		getJSON(t, ts.URL+"/debug/vars", http.StatusOK, &v)
+		return v
+	}
+	before := vars()
+	if before["airports"] != 5.0 {
+		t.Errorf("airports = %v, want 5", before["airports"])
+	}
+	getJSON(t, ts.URL+"/v1/airports/LHR", http.StatusOK, nil)
+	after := vars()
+	// The LHR lookup and the /debug/vars request itself.
+	if got := after["requests_total"].(float64) - before["requests_total"].(float64); got != 2 {
+		t.Errorf("requests_total grew by %v, want 2", got)
+	}
+	if resp, _ := do(t, http.MethodPost, ts.URL+"/admin/reload", adminHeader(), nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("reload: status %d", resp.StatusCode)
+	}
+	if got := vars()["reloads_total"].(float64) - after["reloads_total"].(float64); got != 1 {
+		t.Errorf("reloads_total grew by %v, want 1", got)
+	}
+
+	_, ts = newTestServer(t, nil)
+	getJSON(t, ts.URL+"/debug/vars", http.StatusNotFound, nil)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// renderer shapes airports for a response: localized names first, then
+// the ?fields= projection.
+type renderer struct {
+	store  *iataplaces.Store
+	fields []string
+	langs  []string // preferred languages, best first
+}
+
+// newRenderer reads ?fields=, ?lang= and Accept-Language from r.
+func newRenderer(w http.ResponseWriter, r *http.Request, store *iataplaces.Store) (renderer, error) {
+	fields, err := parseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		return renderer{}, err
+	}
+	rd := renderer{store: store, fields: fields}
+	if store.HasLocalizedNames() {
+		w.Header().Add("Vary", "Accept-Language")
+		if lang := r.URL.Query().Get("lang"); lang != "" {
+			rd.langs = []string{lang}
+		} else {
+			rd.langs = parseAcceptLanguage(r.Header.Get("Accept-Language"))
+		}
+	}
+	return rd, nil
+}
+
+func (rd renderer) render(a *iataplaces.Airport) any {
+	for _, lang := range rd.langs {
+		n, ok := rd.store.Localized(a.IATACode, lang)
+		if !ok {
+			continue
+		}
+		localized := *a
+		if n.Name != "" {
+			localized.Name = n.Name
+		}
+		if n.Municipality != "" {
+			localized.Municipality = n.Municipality
+		}
+		a = &localized
+		break
+	}
+	return project(a, rd.fields)
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language
+// header ordered by preference, dropping "*" and q=0 entries.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	langs := make([]string, len(tags))
+	for i, t := range tags {
+		langs[i] = t.tag
+	}
+	return langs
+}
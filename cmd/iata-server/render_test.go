@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"de", []string{"de"}},
+		{"fr;q=0.5, de-AT, de;q=0.8", []string{"de-AT", "de", "fr"}},
+		{"ja, *;q=0.1, en;q=0", []string{"ja"}},
+	}
+	for _, tt := range tests {
+		if got := parseAcceptLanguage(tt.header); !slices.Equal(got, tt.want) {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLocalizedNames(t *testing.T) {
+	_, ts := newTestServer(t, func(cfg *config) {
+		cfg.Data.Names = filepath.Join(t.TempDir(), "names.csv")
+		names := "iata_code,lang,name,municipality\n" +
+			"LHR,de,Flughafen London Heathrow,London\n" +
+			"CDG,de,Flughafen Paris-Charles de Gaulle,Paris\n" +
+			"LHR,ja,ロンドン・ヒースロー空港,ロンドン\n"
+		if err := os.WriteFile(cfg.Data.Names, []byte(names), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	get := func(path, acceptLanguage string) (map[string]any, http.Header) {
+		t.Helper()
+		resp, body := do(t, http.MethodGet, ts.URL+path, http.Header{"Accept-Language": {acceptLanguage}}, nil)
+		var a map[string]any
+		if err := json.Unmarshal(body, &a); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %d %v: %s", path, resp.StatusCode, err, body)
+		}
+		return a, resp.Header
+	}
+
+	tests := []struct {
+		path, acceptLanguage, want string
+	}{
+		{"/v1/airports/LHR", "de-AT, en;q=0.5", "Flughafen London Heathrow"},
+		{"/v1/airports/LHR", "fr, ja;q=0.9, de;q=0.8", "ロンドン・ヒースロー空港"},
+		{"/v1/airports/LHR", "fr", "London Heathrow Airport"},
+		{"/v1/airports/LHR?lang=ja", "de", "ロンドン・ヒースロー空港"},
+		{"/v1/airports/LGW", "de", "London Gatwick Airport"},
+	}
+	for _, tt := range tests {
+		a, header := get(tt.path, tt.acceptLanguage)
+		if a["name"] != tt.want {
+			t.Errorf("GET %s with Accept-Language %q: name %v, want %q", tt.path, tt.acceptLanguage, a["name"], tt.want)
+		}
+		if !slices.Contains(header.Values("Vary"), "Accept-Language") {
+			t.Errorf("GET %s: Vary %q", tt.path, header.Values("Vary"))
+		}
+	}
+
+	// Projections apply to the localized airport.
+	if a, _ := get("/v1/airports/CDG?fields=name,municipality", "de"); a["name"] != "Flughafen Paris-Charles de Gaulle" || a["municipality"] != "Paris" {
+		t.Errorf("projected localized airport %v", a)
+	}
+}
+
+func TestNoLocalizedNames(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+	resp, _ := do(t, http.MethodGet, ts.URL+"/v1/airports/LHR", http.Header{"Accept-Language": {"de"}}, nil)
+	if slices.Contains(resp.Header.Values("Vary"), "Accept-Language") {
+		t.Errorf("Vary %q without localized names", resp.Header.Values("Vary"))
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// server holds the live dataset behind an atomic pointer so it can be
+// swapped without blocking readers.
+type server struct {
+	cfg          config
+	accessLogger *slog.Logger // nil disables access logging
+
+	data     atomic.Pointer[dataset]
+	reloadMu sync.Mutex // serializes reloads
+	updates  broadcaster
+	cache    *responseCache // nil when response caching is disabled
+	dump     dumpCache
+
+	closing   chan struct{} // closed on shutdown to end streaming responses
+	closeOnce sync.Once
+}
+
+// dataset is a store with the snapshot describing it. They are published
+// together so a reader never pairs a store with another reload's snapshot.
+// It is set before the server starts listening.
+type dataset struct {
+	store *iataplaces.Store
+	info  *snapshotInfo
+}
+
+func newServer(cfg config) *server {
+	return &server{
+		cfg:     cfg,
+		cache:   newResponseCache(cfg.Cache.TTL, cfg.Cache.MaxEntries),
+		closing: make(chan struct{}),
+	}
+}
+
+// closeStreams ends long-lived streams such as /v1/updates so a graceful
+// shutdown doesn't wait on them.
+func (s *server) closeStreams() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+// reload loads the CSV from disk and swaps it in. On failure the
+// previous store stays live.
+func (s *server) reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	data, source, err := s.fetchDataset()
+	if err != nil {
+		reloadFailures.Add(1)
+		return err
+	}
+	store, err := iataplaces.LoadFromReader(bytes.NewReader(data))
+	if err != nil {
+		reloadFailures.Add(1)
+		return fmt.Errorf("load %s: %w", source, err)
+	}
+	return s.install(store, data, source)
+}
+
+// install loads the configured supplementary files into store, built
+// from data, and makes it live. s.reloadMu must be held.
+func (s *server) install(store *iataplaces.Store, data []byte, source string) error {
+	if names := s.cfg.Data.Names; names != "" {
+		if err := store.LoadLocalizedNamesFromFile(names); err != nil {
+			reloadFailures.Add(1)
+			return fmt.Errorf("load localized names from %s: %w", names, err)
+		}
+	}
+	if pop := s.cfg.Data.Popularity; pop != "" {
+		if err := store.LoadPopularityFromFile(pop); err != nil {
+			reloadFailures.Add(1)
+			return fmt.Errorf("load popularity from %s: %w", pop, err)
+		}
+	}
+	if routes := s.cfg.Data.Routes; routes != "" {
+		if err := store.LoadRoutesFromFile(routes); err != nil {
+			reloadFailures.Add(1)
+			return fmt.Errorf("load routes from %s: %w", routes, err)
+		}
+	}
+	if airlines := s.cfg.Data.Airlines; airlines != "" {
+		if err := store.LoadAirlinesFromFile(airlines); err != nil {
+			reloadFailures.Add(1)
+			return fmt.Errorf("load airlines from %s: %w", airlines, err)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	info := &snapshotInfo{
+		Checksum: "sha256:" + hex.EncodeToString(sum[:]),
+		Airports: store.Len(),
+		LoadedAt: time.Now().UTC(),
+	}
+	prev := s.data.Swap(&dataset{store: store, info: info})
+	s.cache.purge()
+	reloads.Add(1)
+	airportCount.Set(int64(info.Airports))
+	if prev == nil || prev.info.Checksum != info.Checksum {
+		s.updates.publish(*info)
+	}
+	log.Printf("Loaded %d airports from %s (%s)", info.Airports, source, info.Checksum)
+	return nil
+}
+
+// fetchDataset reads the airports CSV from the configured URL, or from
+// the data path when no URL is set.
+func (s *server) fetchDataset() ([]byte, string, error) {
+	if u := s.cfg.Data.URL; u != "" {
+		client := &http.Client{Timeout: 2 * time.Minute}
+		resp, err := client.Get(u)
+		if err != nil {
+			return nil, u, fmt.Errorf("download %s: %w", u, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, u, fmt.Errorf("download %s: unexpected status %d", u, resp.StatusCode)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, u, fmt.Errorf("download %s: %w", u, err)
+		}
+		return data, u, nil
+	}
+
+	path := s.cfg.Data.Path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("read %s: %w", path, err)
+	}
+	return data, path, nil
+}
+
+// refreshEvery reloads the dataset on a fixed interval until shutdown.
+// Subscribers are only notified when the checksum actually changes.
+func (s *server) refreshEvery(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-t.C:
+			if err := s.reload(); err != nil {
+				log.Printf("scheduled reload failed, keeping previous dataset: %v", err)
+			}
+		}
+	}
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.Handle("GET /v1/airports", s.cached(s.handleList))
+	mux.HandleFunc("GET /v1/airports.json", s.handleDump(false))
+	mux.HandleFunc("GET /v1/airports.json.gz", s.handleDump(true))
+	mux.Handle("GET /v1/airports/{code}", s.cached(s.handleAirport))
+	mux.Handle("GET /v1/airports/{code}/airlines", s.cached(s.handleAirlinesAt))
+	mux.Handle("GET /v1/airlines/{code}/airports", s.cached(s.handleServedBy))
+	mux.Handle("GET /v1/countries", s.cached(s.handleCountries))
+	mux.Handle("GET /v1/countries/{iso}/airports", s.cached(s.handleCountryAirports))
+	mux.Handle("GET /v1/cities/{name}/airports", s.cached(s.handleCityAirports))
+	mux.Handle("GET /v1/search", s.cached(s.handleSearch))
+	mux.Handle("GET /v1/clusters", s.cached(s.handleClusters))
+	mux.Handle("GET /v1/tiles/{z}/{x}/{y}", s.cached(s.handleTile))
+	mux.HandleFunc("GET /v1/updates", s.handleUpdates)
+	mux.Handle("POST /admin/reload", s.requireAdmin(http.HandlerFunc(s.handleReload)))
+	mux.Handle("POST /admin/data", s.requireAdmin(http.HandlerFunc(s.handleUpload)))
+	if s.cfg.UI.Enabled {
+		mux.HandleFunc("GET /ui", s.handleUI)
+	}
+	if s.cfg.Telemetry.Expvar {
+		mux.Handle("GET /debug/vars", expvar.Handler())
+	}
+
+	var h http.Handler = countRequests(compress(mux))
+	if s.accessLogger != nil {
+		h = accessLog(s.accessLogger, h)
+	}
+	return h
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":   "ok",
+		"snapshot": s.data.Load().info,
+	})
+}
+
+func (s *server) handleAirport(w http.ResponseWriter, r *http.Request) {
+	store := s.data.Load().store
+	rd, err := newRenderer(w, r, store)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a, ok := store.LookupIATA(r.PathValue("code"))
+	if !ok {
+		setResultCount(r, 0)
+		writeError(w, http.StatusNotFound, "airport not found")
+		return
+	}
+	setResultCount(r, 1)
+	writeJSON(w, http.StatusOK, rd.render(a))
+}
+
+func (s *server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.reload(); err != nil {
+		log.Printf("reload failed, keeping previous dataset: %v", err)
+		writeError(w, http.StatusInternalServerError, "reload failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.data.Load().info)
+}
+
+// requireAdmin checks for "Authorization: Bearer <admin token>". With no
+// token configured the admin API is disabled entirely.
+func (s *server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.cfg.Auth.AdminToken == "" {
+			writeError(w, http.StatusForbidden, "admin API disabled")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Auth.AdminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dropLine removes the testCSV row containing text.
+func dropLine(csv, text string) string {
+	var kept []string
+	for _, line := range strings.SplitAfter(csv, "\n") {
+		if !strings.Contains(line, text) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+func TestAdminReload(t *testing.T) {
+	s, ts := newTestServer(t, nil)
+	before := s.data.Load().info.Checksum
+
+	for _, tt := range []struct {
+		header http.Header
+		want   int
+	}{
+		{nil, http.StatusUnauthorized},
+		{http.Header{"Authorization": {"Bearer wrong"}}, http.StatusUnauthorized},
+		{http.Header{"Authorization": {testToken}}, http.StatusUnauthorized},
+	} {
+		if resp, _ := do(t, http.MethodPost, ts.URL+"/admin/reload", tt.header, nil); resp.StatusCode != tt.want {
+			t.Errorf("reload with %v: status %d, want %d", tt.header, resp.StatusCode, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(s.cfg.Data.Path, []byte(dropLine(testCSV, "HND")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resp, body := do(t, http.MethodPost, ts.URL+"/admin/reload", adminHeader(), nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"airports":4`) {
+		t.Fatalf("reload: status %d: %s", resp.StatusCode, body)
+	}
+	getJSON(t, ts.URL+"/v1/airports/HND", http.StatusNotFound, nil)
+	if s.data.Load().info.Checksum == before {
+		t.Error("checksum unchanged after reload")
+	}
+
+	// A failed reload keeps the previous dataset.
+	if err := os.Remove(s.cfg.Data.Path); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := do(t, http.MethodPost, ts.URL+"/admin/reload", adminHeader(), nil); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("failed reload: status %d", resp.StatusCode)
+	}
+	getJSON(t, ts.URL+"/v1/airports/LHR", http.StatusOK, nil)
+}
+
+func TestAdminDisabled(t *testing.T) {
+	_, ts := newTestServer(t, func(cfg *config) { cfg.Auth.AdminToken = "" })
+	resp, _ := do(t, http.MethodPost, ts.URL+"/admin/reload", http.Header{"Authorization": {"Bearer "}}, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("reload without an admin token configured: status %d", resp.StatusCode)
+	}
+}
+
+func TestRefreshEvery(t *testing.T) {
+	s, _ := newTestServer(t, nil)
+	before := s.data.Load().info.Checksum
+	go s.refreshEvery(10 * time.Millisecond)
+
+	if err := os.WriteFile(s.cfg.Data.Path, []byte(dropLine(testCSV, "HND")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for s.data.Load().info.Checksum == before {
+		if time.Now().After(deadline) {
+			t.Fatal("dataset not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := s.data.Load().store.LookupIATA("HND"); ok {
+		t.Error("refreshed store still has HND")
+	}
+}
+
+func TestPopularity(t *testing.T) {
+	search := func(ts string) []string {
+		var p page
+		getJSON(t, ts+"/v1/search?q=international&fields=iata_code", http.StatusOK, &p)
+		return codes(p)
+	}
+	_, plain := newTestServer(t, nil)
+	if got := search(plain.URL); len(got) != 3 || got[0] == "HND" {
+		t.Fatalf("search without popularity %v", got)
+	}
+
+	_, ts := newTestServer(t, func(cfg *config) {
+		cfg.Data.Popularity = filepath.Join(t.TempDir(), "popularity.csv")
+		if err := os.WriteFile(cfg.Data.Popularity, []byte("iata_code^pagerank\nHND^0.9\nCDG^0.01\nJFK^0.01\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := search(ts.URL); len(got) != 3 || got[0] != "HND" {
+		t.Errorf("search with popularity %v, want HND first", got)
+	}
+}
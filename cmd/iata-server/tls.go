@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsOptions configures HTTPS, either from a static certificate pair or
+// from Let's Encrypt via ACME.
+type tlsOptions struct {
+	CertFile string `yaml:"cert"`
+	KeyFile  string `yaml:"key"`
+
+	ACMEDomains  string `yaml:"acme_domains"` // comma-separated host names
+	ACMEEmail    string `yaml:"acme_email"`
+	ACMECacheDir string `yaml:"acme_cache"`
+	ACMEHTTPAddr string `yaml:"acme_http_addr"` // serves HTTP-01 challenges and redirects to HTTPS
+}
+
+func (o tlsOptions) enabled() bool {
+	return o.CertFile != "" || o.KeyFile != "" || o.ACMEDomains != ""
+}
+
+func (o tlsOptions) validate() error {
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("tls cert and key must be set together")
+	}
+	if o.CertFile != "" && o.ACMEDomains != "" {
+		return errors.New("tls cert and acme domains are mutually exclusive")
+	}
+	return nil
+}
+
+// configureTLS sets httpSrv.TLSConfig. For ACME it also returns a plain
+// HTTP server answering challenges, which the caller must run alongside.
+func configureTLS(httpSrv *http.Server, o tlsOptions) (*http.Server, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if o.ACMEDomains == "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+		httpSrv.TLSConfig = cfg
+		return nil, nil
+	}
+
+	var hosts []string
+	for _, h := range strings.Split(o.ACMEDomains, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(o.ACMECacheDir),
+		Email:      o.ACMEEmail,
+	}
+	cfg.GetCertificate = m.GetCertificate
+	cfg.NextProtos = []string{"h2", "http/1.1", "acme-tls/1"}
+	httpSrv.TLSConfig = cfg
+
+	challengeSrv := &http.Server{
+		Addr:              o.ACMEHTTPAddr,
+		Handler:           m.HTTPHandler(nil),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return challengeSrv, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for 127.0.0.1 and its key
+// as PEM files, returning them with the certificate itself.
+func writeCert(t *testing.T) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "iata-server test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestTLSOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    tlsOptions
+		enabled bool
+		valid   bool
+	}{
+		{tlsOptions{}, false, true},
+		{tlsOptions{CertFile: "c.pem", KeyFile: "k.pem"}, true, true},
+		{tlsOptions{CertFile: "c.pem"}, true, false},
+		{tlsOptions{KeyFile: "k.pem"}, true, false},
+		{tlsOptions{ACMEDomains: "airports.example.com"}, true, true},
+		{tlsOptions{CertFile: "c.pem", KeyFile: "k.pem", ACMEDomains: "airports.example.com"}, true, false},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.enabled(); got != tt.enabled {
+			t.Errorf("%+v enabled = %v", tt.opts, got)
+		}
+		if err := tt.opts.validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v validate = %v", tt.opts, err)
+		}
+	}
+}
+
+func TestConfigureTLSCertificate(t *testing.T) {
+	certFile, keyFile, cert := writeCert(t)
+	httpSrv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})}
+	challengeSrv, err := configureTLS(httpSrv, tlsOptions{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if challengeSrv != nil {
+		t.Error("static certificate came with a challenge server")
+	}
+	if httpSrv.TLSConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion %x", httpSrv.TLSConfig.MinVersion)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go httpSrv.ServeTLS(ln, "", "")
+	defer httpSrv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+		t.Errorf("status %d over TLS %v", resp.StatusCode, resp.TLS != nil)
+	}
+
+	if _, err := configureTLS(&http.Server{}, tlsOptions{CertFile: keyFile, KeyFile: keyFile}); err == nil {
+		t.Error("configureTLS accepted a key as the certificate")
+	}
+	if _, err := configureTLS(&http.Server{}, tlsOptions{CertFile: certFile}); err == nil {
+		t.Error("configureTLS accepted a certificate without a key")
+	}
+}
+
+func TestConfigureTLSACME(t *testing.T) {
+	httpSrv := &http.Server{}
+	challengeSrv, err := configureTLS(httpSrv, tlsOptions{
+		ACMEDomains:  "airports.example.com, api.example.com",
+		ACMECacheDir: t.TempDir(),
+		ACMEHTTPAddr: ":8080",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if httpSrv.TLSConfig.GetCertificate == nil || !slices.Contains(httpSrv.TLSConfig.NextProtos, "acme-tls/1") {
+		t.Errorf("TLS config %+v", httpSrv.TLSConfig)
+	}
+	if challengeSrv == nil || challengeSrv.Addr != ":8080" {
+		t.Fatalf("challenge server %+v", challengeSrv)
+	}
+
+	// Outside the challenge path, plain HTTP is redirected to HTTPS.
+	rec := httptest.NewRecorder()
+	challengeSrv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://airports.example.com/v1/airports/LHR", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://airports.example.com/v1/airports/LHR" {
+		t.Errorf("redirect %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// Hosts outside the list don't get certificates.
+	if _, err := httpSrv.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "evil.example.com"}); err == nil {
+		t.Error("certificate requested for an unlisted host")
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// uiPage is the demo map served at /ui: Leaflet with markers from
+// /v1/clusters and a search box over /v1/search. Leaflet and the base map
+// tiles load from their public CDNs.
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+func (s *server) handleUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(uiPage)
+}
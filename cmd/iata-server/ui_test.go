@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUI(t *testing.T) {
+	_, ts := newTestServer(t, func(cfg *config) { cfg.UI.Enabled = true })
+	resp, body := do(t, http.MethodGet, ts.URL+"/ui", nil, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("GET /ui: status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for _, api := range []string{"/v1/clusters", "/v1/search"} {
+		if !strings.Contains(string(body), api) {
+			t.Errorf("demo page doesn't use %s", api)
+		}
+	}
+
+	_, ts = newTestServer(t, nil)
+	if resp, _ := do(t, http.MethodGet, ts.URL+"/ui", nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /ui with the UI disabled: status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// snapshotInfo describes the dataset currently being served.
+type snapshotInfo struct {
+	Checksum string    `json:"checksum"`
+	Airports int       `json:"airports"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// broadcaster fans dataset reload events out to connected /v1/updates
+// clients.
+type broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan snapshotInfo]struct{}
+}
+
+func (b *broadcaster) subscribe() (<-chan snapshotInfo, func()) {
+	ch := make(chan snapshotInfo, 1)
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[chan snapshotInfo]struct{})
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// publish delivers info to every subscriber. A slow client that has not
+// consumed the previous event only ever sees the latest one.
+func (b *broadcaster) publish(info snapshotInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- info
+	}
+}
+
+// handleUpdates serves GET /v1/updates as a Server-Sent Events stream. The
+// current snapshot is sent on connect and again after every reload.
+func (s *server) handleUpdates(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	ch, unsubscribe := s.updates.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(info snapshotInfo) error {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: snapshot\nid: %s\ndata: %s\n\n", info.Checksum, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := send(*s.data.Load().info); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case info := <-ch:
+			if err := send(info); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads one Server-Sent Event, skipping comments.
+func readEvent(r *bufio.Reader) (map[string]string, error) {
+	event := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(event) > 0 {
+				return event, nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		event[name] = value
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	s, ts := newTestServer(t, nil)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/updates", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+
+	events := make(chan map[string]string, 10)
+	go func() {
+		defer close(events)
+		r := bufio.NewReader(resp.Body)
+		for {
+			event, err := readEvent(r)
+			if err != nil {
+				return
+			}
+			events <- event
+		}
+	}()
+	next := func() snapshotInfo {
+		t.Helper()
+		select {
+		case event, ok := <-events:
+			var info snapshotInfo
+			if !ok || event["event"] != "snapshot" {
+				t.Fatalf("event %v", event)
+			}
+			if err := json.Unmarshal([]byte(event["data"]), &info); err != nil {
+				t.Fatal(err)
+			}
+			if event["id"] != info.Checksum {
+				t.Errorf("event id %q, checksum %q", event["id"], info.Checksum)
+			}
+			return info
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return snapshotInfo{}
+		}
+	}
+
+	// The current snapshot is sent on connect.
+	if info := next(); info.Checksum != s.data.Load().info.Checksum || info.Airports != 5 {
+		t.Errorf("first event %+v", info)
+	}
+
+	if err := os.WriteFile(s.cfg.Data.Path, []byte(dropLine(testCSV, "HND")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if resp, body := do(t, http.MethodPost, ts.URL+"/admin/reload", adminHeader(), nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("reload: status %d: %s", resp.StatusCode, body)
+	}
+	if info := next(); info.Checksum != s.data.Load().info.Checksum || info.Airports != 4 {
+		t.Errorf("event after reload %+v", info)
+	}
+}
+
+func TestBroadcasterKeepsLatest(t *testing.T) {
+	var b broadcaster
+	ch, unsubscribe := b.subscribe()
+	b.publish(snapshotInfo{Checksum: "a"})
+	b.publish(snapshotInfo{Checksum: "b"})
+	if info := <-ch; info.Checksum != "b" {
+		t.Errorf("slow subscriber got %q, want the latest", info.Checksum)
+	}
+
+	unsubscribe()
+	b.publish(snapshotInfo{Checksum: "c"})
+	select {
+	case info := <-ch:
+		t.Errorf("unsubscribed channel got %q", info.Checksum)
+	default:
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// maxUploadBytes caps POST /admin/data bodies; the full OurAirports CSV
+// is about 12 MB.
+const maxUploadBytes = 256 << 20
+
+// binarySnapshotMagic starts files written by iataplaces.WriteGob.
+const binarySnapshotMagic = "IATAPLCS"
+
+// handleUpload serves POST /admin/data: the body is an airports CSV or a
+// binary snapshot, which is validated and swapped in like a reload, for
+// deployments that can't reach the upstream. When no data URL is
+// configured the upload is also written to the data path, so it survives
+// restarts and reloads; a binary snapshot is written as CSV, which is what
+// reloads read there. With a data URL the upload only lasts until the next
+// reload, and the response's "persisted" is false. With ?strict=1 a CSV
+// with any error-level quality issue is rejected; otherwise only one
+// missing required columns.
+func (s *server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload larger than %d bytes", tooBig.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "read upload: "+err.Error())
+		return
+	}
+	strict, _ := strconv.ParseBool(r.URL.Query().Get("strict"))
+
+	var store *iataplaces.Store
+	var report *iataplaces.QualityReport
+	binary := bytes.HasPrefix(data, []byte(binarySnapshotMagic))
+	if binary {
+		store, err = iataplaces.LoadFromGob(bytes.NewReader(data))
+	} else {
+		report, err = iataplaces.ValidateCSV(bytes.NewReader(data))
+		switch {
+		case err != nil:
+		case len(report.MissingColumns) > 0:
+			err = fmt.Errorf("missing columns %v", report.MissingColumns)
+		case strict && report.Errors() > 0:
+			err = fmt.Errorf("%d quality errors", report.Errors())
+		default:
+			store, err = iataplaces.LoadFromReader(bytes.NewReader(data))
+		}
+	}
+	if err == nil && store.Len() == 0 {
+		err = errors.New("no airports with IATA codes")
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid dataset: "+err.Error())
+		return
+	}
+
+	persist := s.cfg.Data.URL == ""
+	if binary && persist {
+		// Converted before install adds the supplementary files, so the
+		// checksum matches what the next reload reads back.
+		var buf bytes.Buffer
+		if err := iataplaces.WriteCSV(&buf, store.All()); err != nil {
+			log.Printf("upload could not be converted to CSV: %v", err)
+			writeError(w, http.StatusInternalServerError, "convert failed")
+			return
+		}
+		data = buf.Bytes()
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	if err := s.install(store, data, "upload"); err != nil {
+		log.Printf("upload failed, keeping previous dataset: %v", err)
+		writeError(w, http.StatusInternalServerError, "install failed")
+		return
+	}
+	persisted := false
+	if persist {
+		if err := writeFileAtomic(s.cfg.Data.Path, data); err != nil {
+			log.Printf("upload is live but could not be saved to %s: %v", s.cfg.Data.Path, err)
+		} else {
+			persisted = true
+		}
+	}
+
+	resp := map[string]any{"snapshot": s.data.Load().info, "persisted": persisted}
+	if report != nil {
+		resp["errors"], resp["warnings"] = report.Errors(), report.Warnings()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeFileAtomic replaces path with data via a temporary file and rename.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
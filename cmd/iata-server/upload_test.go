@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// uploadBody returns testCSV with QQQ added, as CSV or a binary snapshot.
+func uploadBody(t *testing.T, binary bool) []byte {
+	t.Helper()
+	store, err := iataplaces.LoadFromReader(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(&iataplaces.Airport{ID: 8000000, IATACode: "QQQ", Name: "Uploaded Field", Type: "small_airport", LatitudeDeg: 1, LongitudeDeg: 2, IsoCountry: "GB"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if binary {
+		err = iataplaces.WriteGob(&buf, store.All())
+	} else {
+		err = iataplaces.WriteCSV(&buf, store.All())
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type uploadResponse struct {
+	Snapshot  snapshotInfo `json:"snapshot"`
+	Persisted bool         `json:"persisted"`
+}
+
+func TestUpload(t *testing.T) {
+	for _, binary := range []bool{false, true} {
+		name := "CSV"
+		if binary {
+			name = "binary"
+		}
+		t.Run(name, func(t *testing.T) {
+			s, ts := newTestServer(t, nil)
+			resp, body := do(t, http.MethodPost, ts.URL+"/admin/data", adminHeader(), uploadBody(t, binary))
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status %d: %s", resp.StatusCode, body)
+			}
+			var up uploadResponse
+			if err := json.Unmarshal(body, &up); err != nil {
+				t.Fatal(err)
+			}
+			if !up.Persisted || up.Snapshot.Airports != 6 {
+				t.Errorf("response %s", body)
+			}
+			getJSON(t, ts.URL+"/v1/airports/QQQ", http.StatusOK, nil)
+
+			// The data file is a CSV holding the upload, so a reload keeps it
+			// and sees the same dataset.
+			saved, err := os.ReadFile(s.cfg.Data.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.HasPrefix(saved, []byte(binarySnapshotMagic)) || !bytes.Contains(saved, []byte("Uploaded Field")) {
+				t.Errorf("data file holds %.40q", saved)
+			}
+			if err := s.reload(); err != nil {
+				t.Fatal(err)
+			}
+			getJSON(t, ts.URL+"/v1/airports/QQQ", http.StatusOK, nil)
+			if got := s.data.Load().info.Checksum; got != up.Snapshot.Checksum {
+				t.Errorf("checksum after reload %s, upload %s", got, up.Snapshot.Checksum)
+			}
+		})
+	}
+}
+
+func TestUploadWithDataURL(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testCSV))
+	}))
+	defer upstream.Close()
+	s, ts := newTestServer(t, func(cfg *config) { cfg.Data.URL = upstream.URL })
+
+	resp, body := do(t, http.MethodPost, ts.URL+"/admin/data", adminHeader(), uploadBody(t, true))
+	var up uploadResponse
+	if err := json.Unmarshal(body, &up); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	if up.Persisted {
+		t.Error("upload reported as persisted with a data URL")
+	}
+	getJSON(t, ts.URL+"/v1/airports/QQQ", http.StatusOK, nil)
+	if saved, _ := os.ReadFile(s.cfg.Data.Path); string(saved) != testCSV {
+		t.Error("upload written to the data path although a data URL is set")
+	}
+
+	// The next reload fetches the URL again.
+	if err := s.reload(); err != nil {
+		t.Fatal(err)
+	}
+	getJSON(t, ts.URL+"/v1/airports/QQQ", http.StatusNotFound, nil)
+}
+
+func TestUploadRejected(t *testing.T) {
+	_, ts := newTestServer(t, nil)
+	_, noToken := newTestServer(t, func(cfg *config) { cfg.Auth.AdminToken = "" })
+	badHeader := strings.Replace(testCSV, "iata_code", "iata", 1)
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		body   string
+		want   int
+	}{
+		{"no token", ts.URL, nil, testCSV, http.StatusUnauthorized},
+		{"wrong token", ts.URL, http.Header{"Authorization": {"Bearer nope"}}, testCSV, http.StatusUnauthorized},
+		{"admin API disabled", noToken.URL, adminHeader(), testCSV, http.StatusForbidden},
+		{"missing column", ts.URL, adminHeader(), badHeader, http.StatusUnprocessableEntity},
+		{"no airports", ts.URL, adminHeader(), strings.SplitAfter(testCSV, "\n")[0], http.StatusUnprocessableEntity},
+		{"truncated snapshot", ts.URL, adminHeader(), binarySnapshotMagic + "xx", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, http.MethodPost, tt.url+"/admin/data", tt.header, []byte(tt.body))
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, resp.StatusCode, tt.want, body)
+		}
+	}
+	var health struct{ Snapshot snapshotInfo }
+	getJSON(t, ts.URL+"/healthz", http.StatusOK, &health)
+	if health.Snapshot.Airports != 5 {
+		t.Errorf("%d airports after rejected uploads, want 5", health.Snapshot.Airports)
+	}
+}
@@ -0,0 +1,26 @@
+// Loads iata.wasm and resolves to its API once the dataset is parsed:
+//
+//   <script src="wasm_exec.js"></script>
+//   <script src="iata.js"></script>
+//   <script>
+//     loadIataPlaces("iata.wasm").then((iata) => {
+//       console.log(iata.lookup("LHR").name);
+//       console.log(iata.search("heathrow", 5));
+//       console.log(iata.nearest(51.5, -0.12, 3));
+//     });
+//   </script>
+//
+// wasm_exec.js comes with Go: $(go env GOROOT)/lib/wasm/wasm_exec.js.
+async function loadIataPlaces(url = "iata.wasm") {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  if (globalThis.iataplacesError) {
+    throw new Error("iataplaces: " + globalThis.iataplacesError);
+  }
+  return globalThis.iataplaces;
+}
+
+if (typeof module !== "undefined") {
+  module.exports = { loadIataPlaces };
+}
@@ -0,0 +1,89 @@
+//go:build js && wasm
+
+// Command iata-wasm is the WebAssembly build of the library. It parses the
+// embedded dataset and installs a global iataplaces object with lookup,
+// search and nearest functions; iata.js wraps loading it. Build with
+//
+//	GOOS=js GOARCH=wasm go build -o iata.wasm ./cmd/iata-wasm
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+	"github.com/achamwada/iata-lookup-places/data"
+)
+
+func main() {
+	store, err := data.Load()
+	if err != nil {
+		js.Global().Set("iataplacesError", err.Error())
+		return
+	}
+
+	api := js.Global().Get("Object").New()
+
+	// lookup(code) returns the airport, or null for an unknown code.
+	api.Set("lookup", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) < 1 {
+			return js.Null()
+		}
+		a, ok := store.LookupIATA(args[0].String())
+		if !ok {
+			return js.Null()
+		}
+		return airportValue(a)
+	}))
+
+	// search(text, limit?) returns airports ranked by relevance.
+	api.Set("search", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		q := iataplaces.SearchQuery{Limit: 20}
+		if len(args) > 0 {
+			q.Text = args[0].String()
+		}
+		if len(args) > 1 && args[1].Type() == js.TypeNumber {
+			q.Limit = args[1].Int()
+		}
+		results := store.Search(q)
+		out := make([]any, len(results))
+		for i, r := range results {
+			out[i] = airportValue(r.Airport)
+		}
+		return out
+	}))
+
+	// nearest(lat, lon, n?) returns the closest airports with a
+	// distance_km field added, nearest first.
+	api.Set("nearest", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) < 2 {
+			return []any{}
+		}
+		n := 5
+		if len(args) > 2 && args[2].Type() == js.TypeNumber {
+			n = args[2].Int()
+		}
+		hits := store.Nearest(args[0].Float(), args[1].Float(), n)
+		out := make([]any, len(hits))
+		for i, h := range hits {
+			v := airportValue(h.Airport)
+			v.Set("distance_km", h.DistanceKm)
+			out[i] = v
+		}
+		return out
+	}))
+
+	js.Global().Set("iataplaces", api)
+	// Keep the Go runtime alive to serve calls.
+	select {}
+}
+
+// airportValue converts a to a plain JavaScript object with the same
+// field names as the library's JSON encoding.
+func airportValue(a *iataplaces.Airport) js.Value {
+	b, err := json.Marshal(a)
+	if err != nil {
+		return js.Null()
+	}
+	return js.Global().Get("JSON").Call("parse", string(b))
+}
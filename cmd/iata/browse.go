@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runBrowse(args []string) error {
+	fs, dataPath := newFlagSet("browse", "[flags] [FILTER]")
+	terms, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("browse needs an interactive terminal")
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// Alternate screen, hidden cursor; both undone on exit.
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	b := &browser{
+		store:  store,
+		filter: strings.Join(terms, " "),
+		out:    bufio.NewWriter(os.Stdout),
+	}
+	b.refilter()
+
+	buf := make([]byte, 64)
+	for {
+		b.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		if b.handle(buf[:n]) {
+			return nil
+		}
+	}
+}
+
+// browser is the state of the interactive table + detail view.
+type browser struct {
+	store *iataplaces.Store
+	out   *bufio.Writer
+
+	filter   string
+	airports []*iataplaces.Airport
+	selected int
+	offset   int // first visible row
+	rows     int // visible rows at the last draw
+}
+
+func (b *browser) refilter() {
+	if strings.TrimSpace(b.filter) == "" {
+		b.airports = b.store.All()
+	} else {
+		results := b.store.Search(iataplaces.SearchQuery{Text: b.filter})
+		b.airports = make([]*iataplaces.Airport, len(results))
+		for i, r := range results {
+			b.airports[i] = r.Airport
+		}
+	}
+	b.selected, b.offset = 0, 0
+}
+
+// handle applies one chunk of keyboard input and reports whether to quit.
+func (b *browser) handle(in []byte) bool {
+	page := max(b.rows-1, 1)
+	switch string(in) {
+	case "\x03": // Ctrl-C
+		return true
+	case "\x1b": // Esc: clear the filter, or quit when already clear
+		if b.filter == "" {
+			return true
+		}
+		b.filter = ""
+		b.refilter()
+		return false
+	case "\x1b[A", "\x1bOA":
+		b.move(-1)
+		return false
+	case "\x1b[B", "\x1bOB":
+		b.move(1)
+		return false
+	case "\x1b[5~":
+		b.move(-page)
+		return false
+	case "\x1b[6~":
+		b.move(page)
+		return false
+	case "\x1b[H", "\x1b[1~":
+		b.move(-len(b.airports))
+		return false
+	case "\x1b[F", "\x1b[4~":
+		b.move(len(b.airports))
+		return false
+	}
+	if in[0] == 0x1b {
+		return false // unhandled escape sequence
+	}
+
+	changed := false
+	for len(in) > 0 {
+		r, size := utf8.DecodeRune(in)
+		in = in[size:]
+		switch {
+		case r == 0x7f || r == 0x08: // backspace
+			if b.filter != "" {
+				_, last := utf8.DecodeLastRuneInString(b.filter)
+				b.filter = b.filter[:len(b.filter)-last]
+				changed = true
+			}
+		case unicode.IsPrint(r):
+			b.filter += string(r)
+			changed = true
+		}
+	}
+	if changed {
+		b.refilter()
+	}
+	return false
+}
+
+func (b *browser) move(delta int) {
+	b.selected = min(max(b.selected+delta, 0), max(len(b.airports)-1, 0))
+}
+
+func (b *browser) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width < 40 || height < 8 {
+		width, height = 80, 24
+	}
+	b.rows = height - 3
+	if b.selected < b.offset {
+		b.offset = b.selected
+	}
+	if b.selected >= b.offset+b.rows {
+		b.offset = b.selected - b.rows + 1
+	}
+
+	leftW := width * 3 / 5
+	rightW := width - leftW - 3
+
+	var detail []string
+	if len(b.airports) > 0 {
+		detail = detailLines(b.airports[b.selected], rightW)
+	}
+
+	w := b.out
+	w.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(w, "\x1b[1m Filter:\x1b[0m %s\x1b[7m \x1b[0m  %s\r\n",
+		b.filter, fmt.Sprintf("(%d airports)  ↑/↓ PgUp/PgDn move · Esc clear/quit", len(b.airports)))
+	fmt.Fprintf(w, "\x1b[1m%s\x1b[0m │\r\n", fit(" IATA ICAO  NAME", leftW))
+
+	for i := 0; i < b.rows; i++ {
+		left := ""
+		idx := b.offset + i
+		if idx < len(b.airports) {
+			a := b.airports[idx]
+			left = fit(fmt.Sprintf(" %-4s %-5s %s, %s", a.IATACode, a.ICAOCode, a.Name, a.IsoCountry), leftW)
+			if idx == b.selected {
+				left = "\x1b[7m" + left + "\x1b[0m"
+			}
+		} else {
+			left = fit("", leftW)
+		}
+		right := ""
+		if i < len(detail) {
+			right = fit(detail[i], rightW)
+		}
+		fmt.Fprintf(w, "%s │ %s\r\n", left, right)
+	}
+	w.Flush()
+}
+
+// detailLines renders the detail pane for a, including a tiny world grid
+// marking its position.
+func detailLines(a *iataplaces.Airport, width int) []string {
+	lines := []string{
+		fmt.Sprintf("\x1b[1m%s\x1b[0m  %s", a.IATACode, a.ICAOCode),
+		a.Name,
+		strings.Trim(a.Municipality+", "+a.CountryName+" ("+a.IsoCountry+")", ", "),
+		fmt.Sprintf("Region:    %s (%s)", a.RegionName, a.IsoRegion),
+		"Type:      " + a.Type,
+	}
+	if a.Scheduled {
+		lines = append(lines, "Service:   scheduled")
+	}
+	if a.ElevationFt != nil {
+		lines = append(lines, fmt.Sprintf("Elevation: %d ft", *a.ElevationFt))
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("Lat %11.6f  %s", a.LatitudeDeg, dms(a.LatitudeDeg, "N", "S")),
+		fmt.Sprintf("Lon %11.6f  %s", a.LongitudeDeg, dms(a.LongitudeDeg, "E", "W")),
+		"",
+	)
+	lines = append(lines, worldGrid(a.LatitudeDeg, a.LongitudeDeg, min(width, 48))...)
+	lines = append(lines, "")
+	if a.WikipediaLink != "" {
+		lines = append(lines, a.WikipediaLink)
+	}
+	if a.HomeLink != "" {
+		lines = append(lines, a.HomeLink)
+	}
+	if a.Keywords != "" {
+		lines = append(lines, "Keywords: "+a.Keywords)
+	}
+	return lines
+}
+
+// worldGrid draws an equirectangular dot grid with the point marked.
+func worldGrid(lat, lon float64, cols int) []string {
+	rows := max(cols/4, 3)
+	r := int(math.Round((90 - lat) / 180 * float64(rows-1)))
+	c := int(math.Round((lon + 180) / 360 * float64(cols-1)))
+
+	grid := make([]string, rows)
+	for y := 0; y < rows; y++ {
+		line := make([]rune, cols)
+		for x := range line {
+			line[x] = '·'
+			if y == rows/2 {
+				line[x] = '-' // equator
+			}
+		}
+		if y == r && c >= 0 && c < cols {
+			line[c] = '●'
+		}
+		grid[y] = string(line)
+	}
+	return grid
+}
+
+// dms formats decimal degrees as degrees, minutes and seconds.
+func dms(deg float64, pos, neg string) string {
+	hemi := pos
+	if deg < 0 {
+		hemi, deg = neg, -deg
+	}
+	d := math.Floor(deg)
+	m := math.Floor((deg - d) * 60)
+	s := ((deg-d)*60 - m) * 60
+	return fmt.Sprintf("%.0f°%02.0f'%04.1f\"%s", d, m, s, hemi)
+}
+
+// fit truncates or pads s to exactly n visible runes. Escape sequences
+// are not counted.
+func fit(s string, n int) string {
+	var b strings.Builder
+	visible := 0
+	inEscape := false
+	for _, r := range s {
+		switch {
+		case r == '\x1b':
+			inEscape = true
+		case inEscape:
+			if r >= '@' && r <= '~' && r != '[' {
+				inEscape = false
+			}
+		default:
+			if visible == n {
+				continue
+			}
+			visible++
+		}
+		b.WriteRune(r)
+	}
+	for ; visible < n; visible++ {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBrowserKeys(t *testing.T) {
+	store, err := loadStore(useData(t, testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &browser{store: store, rows: 3}
+	b.refilter()
+
+	steps := []struct {
+		keys     string
+		quit     bool
+		filter   string
+		count    int
+		selected int
+	}{
+		{"\x1b[B", false, "", 5, 1},
+		{"\x1b[B\x1b[B", false, "", 5, 1}, // a chunk of several escapes is ignored
+		{"\x1bOB", false, "", 5, 2},
+		{"\x1b[6~", false, "", 5, 4}, // a page is rows-1, clamped to the end
+		{"\x1b[A", false, "", 5, 3},
+		{"\x1b[H", false, "", 5, 0},
+		{"\x1b[F", false, "", 5, 4},
+		{"lon", false, "lon", 2, 0},
+		{"\x7f\x7f\x7fgatw", false, "gatw", 1, 0},
+		{"\x1b[A", false, "gatw", 1, 0},
+		{"\x1b", false, "", 5, 0},
+		{"\x1b", true, "", 5, 0},
+	}
+	for _, s := range steps {
+		quit := b.handle([]byte(s.keys))
+		if quit != s.quit || b.filter != s.filter || len(b.airports) != s.count || b.selected != s.selected {
+			t.Fatalf("after %q: quit %v, filter %q, %d airports, selected %d; want %v, %q, %d, %d",
+				s.keys, quit, b.filter, len(b.airports), b.selected, s.quit, s.filter, s.count, s.selected)
+		}
+	}
+	if !b.handle([]byte("\x03")) {
+		t.Error("Ctrl-C doesn't quit")
+	}
+}
+
+func TestBrowseNeedsTerminal(t *testing.T) {
+	useData(t, testCSV)
+	if _, _, err := run(t, "", "browse"); err == nil || !strings.Contains(err.Error(), "terminal") {
+		t.Errorf("err = %v, want a terminal error", err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"LHR", 5, "LHR  "},
+		{"Heathrow", 5, "Heath"},
+		{"Île-de-France", 4, "Île-"},
+		{"\x1b[7mLHR\x1b[0m", 4, "\x1b[7mLHR\x1b[0m "},
+		{"\x1b[1mHeathrow\x1b[0m", 2, "\x1b[1mHe\x1b[0m"},
+	}
+	for _, tt := range tests {
+		if got := fit(tt.s, tt.n); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDetailPane(t *testing.T) {
+	if got := dms(51.4706, "N", "S"); got != `51°28'14.2"N` {
+		t.Errorf("dms = %s", got)
+	}
+	if got := dms(-0.461941, "E", "W"); got != `0°27'43.0"W` {
+		t.Errorf("dms = %s", got)
+	}
+
+	// Heathrow is marked on the prime meridian, north of the equator.
+	grid := worldGrid(51.4706, -0.461941, 40)
+	if len(grid) != 10 || !strings.HasPrefix(grid[5], "----") {
+		t.Fatalf("grid:\n%s", strings.Join(grid, "\n"))
+	}
+	for y, line := range grid {
+		want := -1
+		if y == 2 {
+			want = 19
+		}
+		if marked := slices.Index([]rune(line), '●'); marked != want {
+			t.Errorf("row %d has the marker at %d, want %d", y, marked, want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+func runCompletion(args []string) error {
+	fs, _ := newFlagSet("completion", "bash|zsh|fish")
+	shells, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(shells) != 1 {
+		fs.Usage()
+		return errors.New("name one shell: bash, zsh or fish")
+	}
+
+	var script string
+	switch shells[0] {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = "autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("unsupported shell %q", shells[0])
+	}
+
+	var names []string
+	for _, c := range commands {
+		if !strings.HasPrefix(c.name, "__") {
+			names = append(names, c.name)
+		}
+	}
+	tmpl := template.Must(template.New(shells[0]).Parse(script))
+	return tmpl.Execute(os.Stdout, map[string]any{
+		"Commands": strings.Join(names, " "),
+		"Types":    strings.Join(airportTypes, " "),
+	})
+}
+
+// runComplete is the hidden "__complete codes|countries [PREFIX]" helper
+// the completion scripts call to list candidates from the dataset.
+func runComplete(args []string) error {
+	fs, dataPath := newFlagSet("__complete", "codes|countries [PREFIX]")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) == 0 {
+		return errReported
+	}
+	prefix := ""
+	if len(rest) > 1 {
+		prefix = strings.ToUpper(rest[1])
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return errReported
+	}
+
+	switch rest[0] {
+	case "codes":
+		for _, a := range store.All() {
+			if strings.HasPrefix(a.IATACode, prefix) {
+				fmt.Printf("%s\t%s\n", a.IATACode, a.Name)
+			}
+		}
+	case "countries":
+		names := map[string]string{}
+		for _, a := range store.All() {
+			names[a.IsoCountry] = a.CountryName
+		}
+		codes := make([]string, 0, len(names))
+		for c := range names {
+			if strings.HasPrefix(c, prefix) {
+				codes = append(codes, c)
+			}
+		}
+		sort.Strings(codes)
+		for _, c := range codes {
+			fmt.Printf("%s\t%s\n", c, names[c])
+		}
+	default:
+		return errReported
+	}
+	return nil
+}
+
+// airportTypes are the OurAirports type values, offered for --type.
+var airportTypes = []string{
+	"large_airport", "medium_airport", "small_airport",
+	"heliport", "seaplane_base", "balloonport", "closed",
+}
+
+const bashCompletion = `# bash completion for iata
+_iata_candidates() {
+    iata __complete "$1" "$2" 2>/dev/null | cut -f1
+}
+
+_iata() {
+    local cur prev cmd
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    cmd="${COMP_WORDS[1]}"
+
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "{{.Commands}}" -- "$cur"))
+        return
+    fi
+
+    case "$prev" in
+        -country|--country)
+            COMPREPLY=($(_iata_candidates countries "$cur")); return ;;
+        -type|--type)
+            COMPREPLY=($(compgen -W "{{.Types}}" -- "$cur")); return ;;
+        -format|--format|-to|--to)
+            COMPREPLY=($(compgen -W "json geojson csv gds gds-pipe gob sql sqlite arrow arrow-stream parquet" -- "$cur")); return ;;
+        -unit|--unit)
+            COMPREPLY=($(compgen -W "km mi nm" -- "$cur")); return ;;
+        -data|--data|-o)
+            COMPREPLY=($(compgen -f -- "$cur")); return ;;
+    esac
+
+    case "$cmd" in
+        lookup|distance|runways|freqs)
+            COMPREPLY=($(_iata_candidates codes "$cur")) ;;
+        completion)
+            COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")) ;;
+        validate|diff|convert)
+            COMPREPLY=($(compgen -f -- "$cur")) ;;
+    esac
+}
+complete -F _iata iata
+`
+
+const fishCompletion = `# fish completion for iata
+complete -c iata -f
+complete -c iata -n __fish_use_subcommand -a "{{.Commands}}"
+complete -c iata -n "__fish_seen_subcommand_from lookup distance runways freqs" -a "(iata __complete codes (commandline -ct) 2>/dev/null)"
+complete -c iata -n "__fish_seen_subcommand_from completion" -a "bash zsh fish"
+complete -c iata -n "__fish_seen_subcommand_from validate diff convert" -F
+complete -c iata -l country -x -a "(iata __complete countries (commandline -ct) 2>/dev/null)"
+complete -c iata -l type -x -a "{{.Types}}"
+complete -c iata -l format -x -a "json geojson csv gds gds-pipe"
+complete -c iata -l to -x -a "json geojson csv gds gds-pipe gob sql sqlite arrow arrow-stream parquet"
+complete -c iata -l unit -x -a "km mi nm"
+complete -c iata -l data -r -F
+`
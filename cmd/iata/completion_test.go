@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		stdout, _, err := run(t, "", "completion", shell)
+		if err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		if !strings.Contains(stdout, "lookup search nearest") || !strings.Contains(stdout, "large_airport medium_airport") {
+			t.Errorf("%s script doesn't list commands and types:\n%s", shell, stdout)
+		}
+		// The hidden helper is called by the scripts but not offered.
+		if !strings.Contains(stdout, "browse completion help") {
+			t.Errorf("%s script offers __complete", shell)
+		}
+	}
+	if stdout, _, _ := run(t, "", "completion", "zsh"); !strings.HasPrefix(stdout, "autoload -U +X bashcompinit") {
+		t.Error("zsh script doesn't load bashcompinit")
+	}
+	for _, args := range [][]string{{"completion"}, {"completion", "powershell"}} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	useData(t, testCSV)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"codes", "l"}, "LGW\tLondon Gatwick Airport\nLHR\tLondon Heathrow Airport\n"},
+		{[]string{"codes", "JFK"}, "JFK\tJohn F Kennedy International Airport\n"},
+		{[]string{"countries"}, "FR\tFrance\nGB\tUnited Kingdom\nJP\tJapan\nUS\tUnited States\n"},
+		{[]string{"countries", "u"}, "US\tUnited States\n"},
+	}
+	for _, tt := range tests {
+		stdout, _, err := run(t, "", append([]string{"__complete"}, tt.args...)...)
+		if err != nil || stdout != tt.want {
+			t.Errorf("__complete %q = %q, %v; want %q", tt.args, stdout, err, tt.want)
+		}
+	}
+
+	// Failures are silent so they don't disturb the shell.
+	for _, args := range [][]string{{"__complete"}, {"__complete", "cities"}, {"__complete", "-data", "/nonexistent.csv", "codes"}} {
+		stdout, stderr, err := run(t, "", args...)
+		if !errors.Is(err, errReported) || stdout != "" || stderr != "" {
+			t.Errorf("%q: %q, %q, %v", args, stdout, stderr, err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+var constsTemplate = template.Must(template.New("consts").Parse(`// Code generated by "iata consts{{.Args}}"; DO NOT EDIT.
+
+// Package {{.Package}} declares typed constants for IATA airport codes, so
+// code referring to an airport is checked by the compiler. Regenerate it
+// with the command above.
+{{- if .Selection}}
+//
+// Selected airports: {{.Selection}}.
+{{- end}}
+package {{.Package}}
+
+// {{.Type}} is an IATA airport code.
+type {{.Type}} string
+
+// String returns the code itself.
+func (c {{.Type}}) String() string { return string(c) }
+
+// Airport codes{{if .Prefixed}}; codes that don't start with a letter have
+// an X prefix{{end}}.
+const (
+{{- range .Airports}}
+	{{.Ident}} {{$.Type}} = {{printf "%q" .Code}} // {{.Comment}}
+{{- end}}
+)
+
+// All lists every code declared in this package, in order.
+var All = []{{.Type}}{
+{{- range .Airports}}
+	{{.Ident}},
+{{- end}}
+}
+`))
+
+type constAirport struct {
+	Ident, Code, Comment string
+}
+
+func runConsts(args []string) error {
+	fs, dataPath := newFlagSet("consts", "[flags]")
+	continent := fs.String("continent", "", "comma-separated continent codes to include, e.g. EU")
+	country := fs.String("country", "", "comma-separated ISO country codes to include")
+	typ := fs.String("type", "", "comma-separated airport types to include, e.g. large_airport")
+	scheduled := fs.Bool("scheduled", false, "only airports with scheduled service")
+	codes := fs.String("codes", "", "comma-separated codes to include on top of the filters, or alone")
+	pkg := fs.String("package", "iatacodes", "name of the generated package")
+	typeName := fs.String("type-name", "Code", "name of the generated code type")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", positional[0])
+	}
+	for _, name := range []string{*pkg, *typeName} {
+		if !token.IsIdentifier(name) {
+			return fmt.Errorf("%q is not a Go identifier", name)
+		}
+	}
+	if !token.IsExported(*typeName) {
+		return fmt.Errorf("type name %q must be exported", *typeName)
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	subset := iataplaces.Subset{
+		Continents: splitList(*continent),
+		Countries:  splitList(*country),
+		Types:      splitList(*typ),
+		Scheduled:  *scheduled,
+	}
+	explicit := splitList(*codes)
+	filtered := subset.String() != "" || len(explicit) == 0
+
+	selected := map[string]*iataplaces.Airport{}
+	if filtered {
+		for _, a := range store.All() {
+			if subset.Match(a) {
+				selected[a.IATACode] = a
+			}
+		}
+	}
+	for _, code := range explicit {
+		a, ok := store.LookupIATA(code)
+		if !ok {
+			return fmt.Errorf("%w %q", iataplaces.ErrUnknownCode, code)
+		}
+		selected[a.IATACode] = a
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no airports match %s", subset)
+	}
+
+	data := map[string]any{
+		"Args":      quoteArgs(args),
+		"Package":   *pkg,
+		"Type":      *typeName,
+		"Selection": selection(subset, explicit),
+	}
+	var airports []constAirport
+	prefixed := false
+	for code, a := range selected {
+		ident := code
+		if !token.IsExported(ident) || !token.IsIdentifier(ident) {
+			ident = "X" + code
+			prefixed = true
+		}
+		comment := a.Name
+		if place := strings.Join(nonEmpty(a.Municipality, a.IsoCountry), ", "); place != "" {
+			comment += ", " + place
+		}
+		airports = append(airports, constAirport{Ident: ident, Code: code, Comment: comment})
+	}
+	sort.Slice(airports, func(i, j int) bool { return airports[i].Code < airports[j].Code })
+	data["Airports"] = airports
+	data["Prefixed"] = prefixed
+
+	var src bytes.Buffer
+	if err := constsTemplate.Execute(&src, data); err != nil {
+		return err
+	}
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return writeOutput(*out, func(w io.Writer) error {
+		_, err := w.Write(formatted)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "generated %d constants\n", len(airports))
+		}
+		return err
+	})
+}
+
+// quoteArgs renders args for the generated header, quoting any that a
+// shell would split.
+func quoteArgs(args []string) string {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteByte(' ')
+		if arg == "" || strings.ContainsAny(arg, " \t\"'\\;$*") {
+			arg = fmt.Sprintf("%q", arg)
+		}
+		b.WriteString(arg)
+	}
+	return b.String()
+}
+
+// selection describes the chosen airports for the package comment.
+func selection(subset iataplaces.Subset, codes []string) string {
+	var parts []string
+	if spec := subset.String(); spec != "" {
+		parts = append(parts, spec)
+	}
+	if len(codes) > 0 {
+		parts = append(parts, strings.ToUpper(strings.Join(codes, ", ")))
+	}
+	return strings.Join(parts, ", plus ")
+}
+
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
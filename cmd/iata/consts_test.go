@@ -0,0 +1,51 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestConsts(t *testing.T) {
+	useData(t, testCSV)
+
+	out, stderr, err := run(t, "", "consts", "--country", "GB", "--codes", "hnd", "--package", "airports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "airports.go", out, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, out)
+	}
+	if f.Name.Name != "airports" {
+		t.Errorf("package %s", f.Name.Name)
+	}
+	for _, want := range []string{
+		`// Code generated by "iata consts --country GB --codes hnd --package airports"; DO NOT EDIT.`,
+		`LHR Code = "LHR" // London Heathrow Airport, London, GB`,
+		"var All = []Code{\n\tHND,\n\tLGW,\n\tLHR,\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "JFK") {
+		t.Errorf("output has JFK:\n%s", out)
+	}
+	if stderr != "generated 3 constants\n" {
+		t.Errorf("stderr %q", stderr)
+	}
+
+	for _, args := range [][]string{
+		{"consts", "--package", "iata-codes"},
+		{"consts", "--type-name", "code"},
+		{"consts", "--codes", "ZZZ"},
+		{"consts", "--country", "DE"},
+		{"consts", "LHR"},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%s succeeded", strings.Join(args, " "))
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runConvert(args []string) error {
+	fs, dataPath := newFlagSet("convert", "[flags] [FILE]")
+	to := fs.String("to", "", "target format: json, geojson, csv, gds, gds-pipe, gob, sql, sqlite, arrow, arrow-stream or parquet")
+	table := fs.String("table", "airports", "table name for --to sql and --to sqlite")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		fs.Usage()
+		return errors.New("convert takes at most one input file")
+	}
+	path := *dataPath
+	if len(files) == 1 {
+		path = files[0]
+	}
+
+	var write func(io.Writer, []*iataplaces.Airport) error
+	switch *to {
+	case "gob":
+		write = iataplaces.WriteGob
+	case "arrow":
+		write = iataplaces.WriteArrow
+	case "arrow-stream":
+		write = iataplaces.WriteArrowStream
+	case "sql":
+		write = func(w io.Writer, airports []*iataplaces.Airport) error {
+			return iataplaces.WriteSQL(w, airports, *table)
+		}
+	case "sqlite":
+		write = func(w io.Writer, airports []*iataplaces.Airport) error {
+			return iataplaces.WriteSQLite(w, airports, *table)
+		}
+	case "parquet":
+		write = iataplaces.WriteParquet
+	case "":
+		fs.Usage()
+		return errors.New("--to is required")
+	default:
+		if write, err = exporter(*to); err != nil {
+			return err
+		}
+	}
+
+	store, err := loadStore(path)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, func(w io.Writer) error {
+		return write(w, store.All())
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func TestConvert(t *testing.T) {
+	useData(t, testCSV)
+
+	out := filepath.Join(t.TempDir(), "airports.gob")
+	if _, _, err := run(t, "", "convert", "--to", "gob", "-o", out); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	store, err := iataplaces.LoadFromGob(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := store.LookupIATA("CDG"); !ok || a.ICAOCode != "LFPG" || store.Len() != 5 {
+		t.Errorf("gob round trip: %d airports, CDG %+v", store.Len(), a)
+	}
+
+	stdout, _, err := run(t, "", "convert", "--to", "sql", "--table", "places")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout, "BEGIN;\nCREATE TABLE \"places\"") || strings.Count(stdout, `INSERT INTO "places"`) != 5 {
+		t.Errorf("SQL script:\n%s", stdout)
+	}
+
+	for to, magic := range map[string]string{"sqlite": "SQLite format 3\x00", "parquet": "PAR1", "geojson": `{"type":"FeatureCollection"`} {
+		stdout, _, err := run(t, "", "convert", "--to", to)
+		if err != nil {
+			t.Fatalf("--to %s: %v", to, err)
+		}
+		if !strings.HasPrefix(stdout, magic) {
+			t.Errorf("--to %s output starts %q", to, stdout[:min(len(stdout), 32)])
+		}
+	}
+}
+
+func TestConvertFile(t *testing.T) {
+	useData(t, "")
+	in := filepath.Join(t.TempDir(), "airports.csv")
+	if err := os.WriteFile(in, []byte(testCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, err := run(t, "", "convert", in, "--to", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, ",HND,") {
+		t.Errorf("CSV output:\n%s", stdout)
+	}
+
+	for _, args := range [][]string{
+		{"convert", in},
+		{"convert", "--to", "yaml", in},
+		{"convert", "--to", "sql", "--table", "drop table", in},
+		{"convert", "--to", "csv", in, in},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
+
+func TestConvertArrow(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "convert", "--to", "arrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout, "ARROW1\x00\x00") || !strings.HasSuffix(stdout, "ARROW1") {
+		t.Error("--to arrow isn't an Arrow IPC file")
+	}
+	// The stream format has no file magic and starts with a continuation
+	// marker.
+	stdout, _, err = run(t, "", "convert", "--to", "arrow-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stdout, "\xff\xff\xff\xff") || strings.Contains(stdout, "ARROW1") {
+		t.Error("--to arrow-stream isn't an Arrow IPC stream")
+	}
+}
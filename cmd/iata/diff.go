@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runDiff(args []string) error {
+	fs, _ := newFlagSet("diff", "[flags] OLD.csv NEW.csv")
+	asJSON := fs.Bool("json", false, "print the diff as JSON")
+	summary := fs.Bool("summary", false, "only print the counts")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		fs.Usage()
+		return errors.New("need exactly two files")
+	}
+
+	before, err := loadStore(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := loadStore(files[1])
+	if err != nil {
+		return err
+	}
+	d := iataplaces.DiffStores(before, after)
+
+	if *asJSON {
+		return writeJSON(os.Stdout, d)
+	}
+	if !*summary {
+		printDiff(d)
+	}
+	fmt.Printf("%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	return nil
+}
+
+func printDiff(d *iataplaces.StoreDiff) {
+	for _, a := range d.Added {
+		fmt.Printf("+ %s  %s (%s)\n", a.IATACode, a.Name, a.IsoCountry)
+	}
+	for _, a := range d.Removed {
+		fmt.Printf("- %s  %s (%s)\n", a.IATACode, a.Name, a.IsoCountry)
+	}
+	for _, c := range d.Changed {
+		changes := make([]string, len(c.Fields))
+		for i, f := range c.Fields {
+			changes[i] = fmt.Sprintf("%s: %q → %q", f.Field, f.Old, f.New)
+		}
+		fmt.Printf("~ %s  %s\n", c.IATACode, strings.Join(changes, "; "))
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	before, after := filepath.Join(dir, "before.csv"), filepath.Join(dir, "after.csv")
+	changed := strings.Replace(testCSV, "London Gatwick Airport", "Gatwick Airport", 1)
+	changed = strings.Join(strings.SplitAfter(changed, "\n")[:5], "") // drop HND
+	changed += "26,EGLC,medium_airport,London City Airport,51.505299,0.055278,19,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGLC,EGLC,LCY,,,,,,\n"
+	for path, content := range map[string]string{before: testCSV, after: changed} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stdout, _, err := run(t, "", "diff", before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `+ LCY  London City Airport (GB)
+- HND  Tokyo Haneda International Airport (JP)
+~ LGW  name: "London Gatwick Airport" → "Gatwick Airport"
+1 added, 1 removed, 1 changed
+`
+	if stdout != want {
+		t.Errorf("output:\n%s\nwant:\n%s", stdout, want)
+	}
+
+	if stdout, _, _ := run(t, "", "diff", "--summary", before, after); stdout != "1 added, 1 removed, 1 changed\n" {
+		t.Errorf("--summary output %q", stdout)
+	}
+	if stdout, _, _ := run(t, "", "diff", before, before); stdout != "0 added, 0 removed, 0 changed\n" {
+		t.Errorf("identical files: %q", stdout)
+	}
+	if _, _, err := run(t, "", "diff", before); err == nil {
+		t.Error("diff with one file succeeded")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runDistance(args []string) error {
+	fs, dataPath := newFlagSet("distance", "[flags] FROM TO [TO...] | FROM-TO-TO...")
+	unit := fs.String("unit", "km", "distance unit: km, mi or nm")
+	bearing := fs.Bool("bearing", false, "also print the initial great-circle bearing")
+	flightTime := fs.Bool("time", false, "also print a rough gate-to-gate flight time estimate")
+	cruise := fs.Float64("cruise-kmh", iataplaces.DefaultCruiseKmh, "cruise speed assumed by --time")
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	codes, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 1 && strings.Contains(codes[0], "-") {
+		codes = strings.Split(codes[0], "-")
+	}
+	if len(codes) < 2 {
+		fs.Usage()
+		return errors.New("need at least two codes")
+	}
+	if _, err := convertKm(0, *unit); err != nil {
+		return err
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	if len(codes) > 2 {
+		return printItinerary(store, codes, *unit, *asJSON)
+	}
+	km, err := store.Distance(codes[0], codes[1])
+	if err != nil {
+		return err
+	}
+	d, _ := convertKm(km, *unit)
+	course, _ := store.Bearing(codes[0], codes[1])
+	est, _ := store.EstimateFlightTime(codes[0], codes[1], iataplaces.WithCruiseSpeed(*cruise))
+
+	if *asJSON {
+		out := map[string]any{
+			"from":     codes[0],
+			"to":       codes[1],
+			"distance": d,
+			"unit":     *unit,
+		}
+		if *bearing {
+			out["bearing_deg"] = course
+		}
+		if *flightTime {
+			out["flight_time_min"] = est.Minutes()
+		}
+		return writeJSON(os.Stdout, out)
+	}
+
+	fmt.Printf("%.1f %s\n", d, *unit)
+	if *bearing {
+		fmt.Printf("bearing %.1f°\n", course)
+	}
+	if *flightTime {
+		fmt.Printf("about %dh%02dm gate to gate\n", int(est.Hours()), int(est.Minutes())%60)
+	}
+	return nil
+}
+
+// printItinerary prints each leg of a multi-airport journey and the total.
+func printItinerary(store *iataplaces.Store, codes []string, unit string, asJSON bool) error {
+	it, err := store.ItineraryDistance(codes...)
+	if err != nil {
+		return err
+	}
+	total, _ := convertKm(it.TotalKm, unit)
+	if asJSON {
+		type leg struct {
+			From     string  `json:"from"`
+			To       string  `json:"to"`
+			Distance float64 `json:"distance"`
+		}
+		legs := make([]leg, len(it.Legs))
+		for i, l := range it.Legs {
+			d, _ := convertKm(l.DistanceKm, unit)
+			legs[i] = leg{From: l.From, To: l.To, Distance: d}
+		}
+		return writeJSON(os.Stdout, map[string]any{
+			"route": it.Route(),
+			"legs":  legs,
+			"total": total,
+			"unit":  unit,
+		})
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, l := range it.Legs {
+		d, _ := convertKm(l.DistanceKm, unit)
+		fmt.Fprintf(tw, "%s-%s\t%.1f %s\t\n", l.From, l.To, d, unit)
+	}
+	fmt.Fprintf(tw, "total\t%.1f %s\t\n", total, unit)
+	return tw.Flush()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// distanceJSON runs "iata distance --json" with args and decodes the output.
+func distanceJSON(t *testing.T, args ...string) map[string]any {
+	t.Helper()
+	stdout, _, err := run(t, "", append([]string{"distance", "--json"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	return out
+}
+
+func TestDistance(t *testing.T) {
+	useData(t, testCSV)
+
+	out := distanceJSON(t, "LHR", "JFK", "--bearing")
+	if d := out["distance"].(float64); math.Abs(d-5540) > 20 || out["unit"] != "km" {
+		t.Errorf("LHR-JFK is %v %v, want about 5540 km", d, out["unit"])
+	}
+	if b := out["bearing_deg"].(float64); math.Abs(b-288) > 2 {
+		t.Errorf("bearing %v, want about 288", b)
+	}
+
+	stdout, _, err := run(t, "", "distance", "LHR-CDG", "--unit", "nm", "--bearing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "187.5 nm\nbearing 140.7°\n" {
+		t.Errorf("output %q", stdout)
+	}
+
+	for _, args := range [][]string{
+		{"distance", "LHR"},
+		{"distance", "LHR", "XXX"},
+		{"distance", "LHR", "JFK", "--unit", "leagues"},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
+
+func TestDistanceFlightTime(t *testing.T) {
+	useData(t, testCSV)
+
+	normal := distanceJSON(t, "LHR", "JFK", "--time")["flight_time_min"].(float64)
+	if normal < 6*60 || normal > 9*60 {
+		t.Errorf("LHR-JFK takes %v minutes", normal)
+	}
+	if fast := distanceJSON(t, "LHR", "JFK", "--time", "--cruise-kmh", "1500")["flight_time_min"].(float64); fast >= normal {
+		t.Errorf("%v minutes at 1500 km/h, %v at the default speed", fast, normal)
+	}
+	if out := distanceJSON(t, "LHR", "JFK"); out["flight_time_min"] != nil {
+		t.Errorf("flight time without --time: %v", out)
+	}
+
+	stdout, _, err := run(t, "", "distance", "LHR", "JFK", "--time")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "5539.7 km\nabout 7h12m gate to gate\n" {
+		t.Errorf("output %q", stdout)
+	}
+}
+
+func TestDistanceItinerary(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "distance", "--json", "LHR-JFK-HND")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Route string
+		Legs  []struct {
+			From, To string
+			Distance float64
+		}
+		Total float64
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if out.Route != "LHR-JFK-HND" || len(out.Legs) != 2 || out.Legs[1].From != "JFK" || out.Legs[1].To != "HND" {
+		t.Fatalf("itinerary %+v", out)
+	}
+	if sum := out.Legs[0].Distance + out.Legs[1].Distance; math.Abs(sum-out.Total) > 0.01 {
+		t.Errorf("legs add up to %v, total %v", sum, out.Total)
+	}
+
+	stdout, _, err = run(t, "", "distance", "LHR", "CDG", "LGW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "  LHR-CDG  347.2 km\n  CDG-LGW  307.7 km\n    total  654.8 km\n"
+	if stdout != want {
+		t.Errorf("output %q, want %q", stdout, want)
+	}
+	if _, _, err := run(t, "", "distance", "LHR", "CDG", "XXX"); err == nil {
+		t.Error("itinerary with an unknown code succeeded")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// enrichAliases are short names --add accepts for dataset columns.
+var enrichAliases = map[string]string{
+	"lat":       "latitude_deg",
+	"lon":       "longitude_deg",
+	"country":   "iso_country",
+	"city":      "municipality",
+	"elevation": "elevation_ft",
+}
+
+func runEnrich(args []string) error {
+	fs, dataPath := newFlagSet("enrich", "--code-column COL [--input FILE] [--add FIELDS] [-o FILE]")
+	input := fs.String("input", "-", "CSV file to annotate (- for stdin)")
+	column := fs.String("code-column", "", "column holding IATA codes (name or 1-based index)")
+	add := fs.String("add", "name,municipality,iso_country,lat,lon", "comma-separated airport fields to append; lat, lon, city, country and elevation are short for dataset columns")
+	prefix := fs.String("prefix", "", "prefix for the appended column names (default: the code column and \"_\", e.g. origin_name)")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *column == "" {
+		fs.Usage()
+		return errors.New("--code-column is required")
+	}
+	prefixSet := false
+	fs.Visit(func(f *flag.Flag) { prefixSet = prefixSet || f.Name == "prefix" })
+
+	var names, fields []string
+	for _, name := range strings.Split(*add, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field := name
+		if col, ok := enrichAliases[name]; ok {
+			field = col
+		}
+		if _, ok := (&iataplaces.Airport{}).FieldValue(field); !ok {
+			return fmt.Errorf("unknown field %q (columns: %s)", name, strings.Join(iataplaces.CSVColumns, ", "))
+		}
+		names, fields = append(names, name), append(fields, field)
+	}
+	if len(fields) == 0 {
+		return errors.New("--add names no fields")
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(*out, func(w io.Writer) error {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if err != nil {
+			return fmt.Errorf("read header: %w", err)
+		}
+		idx, err := columnIndex(header, *column)
+		if err != nil {
+			return err
+		}
+		p := *prefix
+		if !prefixSet {
+			p = strings.TrimSpace(header[idx]) + "_"
+		}
+
+		cw := csv.NewWriter(w)
+		width := len(header)
+		outHeader := append([]string{}, header...)
+		for _, name := range names {
+			outHeader = append(outHeader, p+name)
+		}
+		if err := cw.Write(outHeader); err != nil {
+			return err
+		}
+
+		resolved, missing := 0, 0
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			// Pad short rows so the appended fields line up with the header.
+			for len(rec) < width {
+				rec = append(rec, "")
+			}
+			var a *iataplaces.Airport
+			if idx < len(rec) {
+				if code := strings.TrimSpace(rec[idx]); code != "" {
+					if found, ok := store.LookupIATA(code); ok {
+						a = found
+					}
+				}
+			}
+			if a != nil {
+				resolved++
+			} else {
+				missing++
+			}
+			for _, field := range fields {
+				v := ""
+				if a != nil {
+					v, _ = a.FieldValue(field)
+				}
+				rec = append(rec, v)
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		fmt.Fprintf(os.Stderr, "iata enrich: %d resolved, %d not found\n", resolved, missing)
+		return cw.Error()
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnrich(t *testing.T) {
+	useData(t, testCSV)
+
+	out, stderr, err := run(t, "flight,origin\nBA1,lhr\nXX9,ZZZ\nAF2\n", "enrich", "--code-column", "origin", "--add", "city,country")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unknown codes and short rows get empty fields.
+	if want := "flight,origin,origin_city,origin_country\nBA1,lhr,London,GB\nXX9,ZZZ,,\nAF2,,,\n"; out != want {
+		t.Errorf("enrich by name:\n%s\nwant:\n%s", out, want)
+	}
+	if want := "iata enrich: 1 resolved, 2 not found\n"; stderr != want {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+
+	input := filepath.Join(t.TempDir(), "in.csv")
+	if err := os.WriteFile(input, []byte("code\nHND\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err = run(t, "", "enrich", "--input", input, "--code-column", "1", "--prefix", "", "--add", "icao_code,elevation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "code,icao_code,elevation\nHND,RJTT,35\n"; out != want {
+		t.Errorf("enrich by index:\n%s\nwant:\n%s", out, want)
+	}
+
+	for _, args := range [][]string{
+		{"enrich"},
+		{"enrich", "--code-column", "origin", "--add", "runway"},
+		{"enrich", "--code-column", "destination"},
+	} {
+		if _, _, err := run(t, "flight,origin\n", args...); err == nil {
+			t.Errorf("%s succeeded", strings.Join(args, " "))
+		}
+	}
+}
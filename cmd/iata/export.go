@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runExport(args []string) error {
+	fs, dataPath := newFlagSet("export", "[flags]")
+	format := fs.String("format", "json", "output format: geojson, json, csv, gds (fixed-width) or gds-pipe")
+	country := fs.String("country", "", "only airports in this ISO country code")
+	continent := fs.String("continent", "", "only airports on this continent code, e.g. EU")
+	typ := fs.String("type", "", "only airports of this type, e.g. large_airport")
+	scheduled := fs.Bool("scheduled", false, "only airports with scheduled service")
+	within := fs.String("within", "", "only airports inside the polygons of this GeoJSON file")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	write, err := exporter(*format)
+	if err != nil {
+		return err
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	var inside map[string]bool
+	if *within != "" {
+		data, err := os.ReadFile(*within)
+		if err != nil {
+			return err
+		}
+		matches, err := store.WithinPolygon(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *within, err)
+		}
+		inside = make(map[string]bool, len(matches))
+		for _, a := range matches {
+			inside[a.IATACode] = true
+		}
+	}
+	airports := store.Filter(func(a *iataplaces.Airport) bool {
+		return (inside == nil || inside[a.IATACode]) &&
+			(*country == "" || strings.EqualFold(a.IsoCountry, *country)) &&
+			(*continent == "" || strings.EqualFold(a.Continent, *continent)) &&
+			(*typ == "" || a.Type == *typ) &&
+			(!*scheduled || a.Scheduled)
+	})
+
+	return writeOutput(*out, func(w io.Writer) error {
+		return write(w, airports)
+	})
+}
+
+// exporter returns the library writer for a --format value.
+func exporter(format string) (func(io.Writer, []*iataplaces.Airport) error, error) {
+	switch format {
+	case "geojson":
+		return iataplaces.WriteGeoJSON, nil
+	case "json":
+		return iataplaces.WriteJSON, nil
+	case "csv":
+		return iataplaces.WriteCSV, nil
+	case "gds", "gds-pipe":
+		var opts iataplaces.GDSOptions
+		if format == "gds-pipe" {
+			opts.Delimiter = '|'
+		}
+		return func(w io.Writer, airports []*iataplaces.Airport) error {
+			return iataplaces.WriteGDS(w, airports, opts)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// writeOutput runs write against stdout for "-", or against path, which is
+// only created once the writer succeeds.
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "-" || path == "" {
+		return write(os.Stdout)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "export", "--continent", "eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var airports []struct {
+		IATACode string `json:"iata_code"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &airports); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if len(airports) != 3 {
+		t.Errorf("%d European airports, want 3", len(airports))
+	}
+
+	stdout, _, err = run(t, "", "export", "--format", "geojson", "--country", "JP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fc struct {
+		Type     string
+		Features []struct {
+			Geometry struct{ Coordinates []float64 }
+		}
+	}
+	if err := json.Unmarshal([]byte(stdout), &fc); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 1 || fc.Features[0].Geometry.Coordinates[0] != 139.779999 {
+		t.Errorf("GeoJSON %+v", fc)
+	}
+
+	out := filepath.Join(t.TempDir(), "gb.csv")
+	if _, _, err := run(t, "", "export", "--format", "csv", "--country", "gb", "-o", out); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 3 || !strings.HasPrefix(lines[0], "id,ident,") {
+		t.Errorf("CSV export:\n%s", b)
+	}
+	if _, _, err := run(t, "", "export", "--format", "xml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestExportWithin(t *testing.T) {
+	useData(t, testCSV)
+
+	// A box around south-east England and northern France.
+	area := filepath.Join(t.TempDir(), "area.geojson")
+	polygon := `{"type":"Polygon","coordinates":[[[-1,48],[3,48],[3,52],[-1,52],[-1,48]]]}`
+	if err := os.WriteFile(area, []byte(polygon), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, err := run(t, "", "export", "--format", "csv", "--within", area, "--country", "GB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(stdout, "\n") != 3 || !strings.Contains(stdout, ",LGW,") || strings.Contains(stdout, ",CDG,") {
+		t.Errorf("export within the box:\n%s", stdout)
+	}
+
+	if err := os.WriteFile(area, []byte(`{"type":"Point","coordinates":[0,51]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := run(t, "", "export", "--within", area); err == nil {
+		t.Error("a Point was accepted as an area")
+	}
+}
+
+func TestExportGDS(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "export", "--format", "gds", "--country", "JP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "HND TOKYO HANEDA                   HND JP 3533N 13947E\n"; stdout != want {
+		t.Errorf("fixed-width GDS %q, want %q", stdout, want)
+	}
+	stdout, _, err = run(t, "", "export", "--format", "gds-pipe", "--country", "JP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "HND|TOKYO HANEDA|HND|JP|3533N|13947E\n"; stdout != want {
+		t.Errorf("pipe-delimited GDS %q, want %q", stdout, want)
+	}
+}
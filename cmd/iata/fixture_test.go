@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testCSV is a small extract of the OurAirports data.
+const testCSV = `id,ident,type,name,latitude_deg,longitude_deg,elevation_ft,continent,country_name,iso_country,region_name,iso_region,local_region,municipality,scheduled_service,gps_code,icao_code,iata_code,local_code,home_link,wikipedia_link,keywords,score,last_updated
+2434,EGLL,large_airport,London Heathrow Airport,51.4706,-0.461941,83,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGLL,EGLL,LHR,,http://www.heathrowairport.com/,https://en.wikipedia.org/wiki/Heathrow_Airport,"LON, Londres",1251675,2022-10-18T18:48:50+00:00
+2429,EGKK,large_airport,London Gatwick Airport,51.148771,-0.192089,202,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGKK,EGKK,LGW,,http://www.gatwickairport.com/,https://en.wikipedia.org/wiki/Gatwick_Airport,"LON, Crawley, Charlwood",1049275,2025-02-27T12:47:43+00:00
+3622,KJFK,large_airport,John F Kennedy International Airport,40.639447,-73.779317,13,NA,United States,US,New York,US-NY,NY,New York,1,KJFK,KJFK,JFK,JFK,https://www.jfkairport.com/,https://en.wikipedia.org/wiki/John_F._Kennedy_International_Airport,"Manhattan, New York City, NYC, Idlewild, IDL, KIDL",1052075,2022-10-18T18:49:55+00:00
+4185,LFPG,large_airport,Charles de Gaulle International Airport,49.012798,2.55,392,EU,France,FR,Île-de-France,FR-IDF,IDF,"Paris (Roissy-en-France, Val-d'Oise)",1,LFPG,LFPG,CDG,,http://www.aeroportsdeparis.fr/,https://en.wikipedia.org/wiki/Charles_de_Gaulle_Airport,"PAR, Aéroport Roissy-Charles de Gaulle, Roissy Airport",1127475,2024-06-22T13:11:28+00:00
+5627,RJTT,large_airport,Tokyo Haneda International Airport,35.552299,139.779999,35,AS,Japan,JP,Tōkyō Prefecture,JP-13,13,Tokyo,1,RJTT,RJTT,HND,,http://www.haneda-airport.jp/,https://en.wikipedia.org/wiki/Tokyo_International_Airport,"TYO, Haneda",1168475,2021-04-09T01:56:38+00:00
+`
+
+// useData writes csv to a temporary directory and makes it the default
+// -data for the rest of the test. It returns the file's path.
+func useData(t *testing.T, csv string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "airports-latest.csv")
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AIRPORTS_CSV_PATH", path)
+	return path
+}
+
+// run runs a subcommand with stdin as its standard input and returns what
+// it wrote to standard output and standard error.
+func run(t *testing.T, stdin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		t.Fatalf("no command %q", args[0])
+	}
+
+	dir := t.TempDir()
+	var files [3]*os.File
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	if _, err := files[0].WriteString(stdin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files[0].Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := [3]*os.File{os.Stdin, os.Stdout, os.Stderr}
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	err = cmd.run(args[1:])
+	os.Stdin, os.Stdout, os.Stderr = saved[0], saved[1], saved[2]
+
+	out, rerr := os.ReadFile(files[1].Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	errOut, rerr := os.ReadFile(files[2].Name())
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	return string(out), string(errOut), err
+}
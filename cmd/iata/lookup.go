@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runLookup(args []string) error {
+	fs, dataPath := newFlagSet("lookup", "[--json] CODE... | - [--column NAME] [--format ndjson|csv]")
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	out := addOutputFlags(fs)
+	column := fs.String("column", "", "with -: read codes from this column of a CSV on stdin (name or 1-based index)")
+	format := fs.String("format", "ndjson", "with -: output format, ndjson or csv")
+	fields := fs.String("fields", strings.Join(defaultStreamFields, ","), "with - and --format csv: output columns")
+	codes, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		fs.Usage()
+		return errors.New("no codes given")
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+
+	if len(codes) == 1 && codes[0] == "-" {
+		return streamLookup(store, os.Stdin, os.Stdout, streamOptions{
+			column: *column,
+			format: *format,
+			fields: strings.Split(*fields, ","),
+		})
+	}
+
+	found := make([]*iataplaces.Airport, 0, len(codes))
+	missing := 0
+	for _, code := range codes {
+		a, ok := store.LookupIATA(strings.TrimSpace(code))
+		if !ok {
+			fmt.Fprintf(os.Stderr, "iata lookup: %s: not found\n", code)
+			missing++
+			continue
+		}
+		found = append(found, a)
+	}
+
+	switch {
+	case *asJSON:
+		if err := writeJSON(os.Stdout, found); err != nil {
+			return err
+		}
+	case out.set():
+		if err := out.write(os.Stdout, found); err != nil {
+			return err
+		}
+	default:
+		for i, a := range found {
+			if i > 0 {
+				fmt.Println()
+			}
+			printAirport(os.Stdout, a)
+		}
+	}
+
+	if missing > 0 {
+		return errReported
+	}
+	return nil
+}
+
+// printAirport writes a short human-readable description of a.
+func printAirport(w io.Writer, a *iataplaces.Airport) {
+	fmt.Fprintf(w, "%s  %s\n", a.IATACode, a.Name)
+
+	var place []string
+	if a.Municipality != "" {
+		place = append(place, a.Municipality)
+	}
+	if a.CountryName != "" {
+		place = append(place, fmt.Sprintf("%s (%s)", a.CountryName, a.IsoCountry))
+	} else if a.IsoCountry != "" {
+		place = append(place, a.IsoCountry)
+	}
+	details := []string{strings.Join(place, ", ")}
+	if a.ICAOCode != "" {
+		details = append(details, "ICAO "+a.ICAOCode)
+	}
+	details = append(details, a.Type)
+	fmt.Fprintf(w, "     %s\n", strings.Join(details, " · "))
+
+	position := fmt.Sprintf("%.6f, %.6f", a.LatitudeDeg, a.LongitudeDeg)
+	if a.HasElevation() {
+		position += fmt.Sprintf(" · %d ft", a.ElevationFtOr(0))
+	}
+	fmt.Fprintf(w, "     %s\n", position)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
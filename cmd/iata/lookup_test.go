@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func TestLookup(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "lookup", "lhr", "HND")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `LHR  London Heathrow Airport
+     London, United Kingdom (GB) · ICAO EGLL · large_airport
+     51.470600, -0.461941 · 83 ft
+
+HND  Tokyo Haneda International Airport
+     Tokyo, Japan (JP) · ICAO RJTT · large_airport
+     35.552299, 139.779999 · 35 ft
+`
+	if stdout != want {
+		t.Errorf("output:\n%s\nwant:\n%s", stdout, want)
+	}
+
+	// Flags may follow the codes.
+	stdout, _, err = run(t, "", "lookup", "JFK", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var airports []iataplaces.Airport
+	if err := json.Unmarshal([]byte(stdout), &airports); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if len(airports) != 1 || airports[0].ICAOCode != "KJFK" {
+		t.Errorf("JSON output %+v", airports)
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	useData(t, testCSV)
+
+	// Found codes are still printed when others are missing.
+	stdout, stderr, err := run(t, "", "lookup", "LHR", "XXX")
+	if !errors.Is(err, errReported) {
+		t.Errorf("err = %v, want errReported", err)
+	}
+	if !strings.HasPrefix(stdout, "LHR  ") || !strings.Contains(stderr, "XXX: not found") {
+		t.Errorf("stdout %q, stderr %q", stdout, stderr)
+	}
+
+	if _, _, err := run(t, "", "lookup"); err == nil {
+		t.Error("lookup with no codes succeeded")
+	}
+	if _, _, err := run(t, "", "lookup", "-data", "/nonexistent/airports.csv", "LHR"); err == nil {
+		t.Error("lookup with a missing data file succeeded")
+	}
+}
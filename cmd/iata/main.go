@@ -0,0 +1,149 @@
+// Command iata queries the airports dataset from the terminal.
+//
+//	iata lookup LHR SIN
+//	iata lookup --json JFK
+//	iata search --city Berlin --country DE
+//	iata search --country IS --columns iata_code,name,elevation_ft
+//	iata lookup LHR --template '{{.Name}} ({{.ICAOCode}})'
+//	iata nearest --lat 51.5 --lon -0.12 --n 5 --major
+//	iata distance LHR JFK --unit nm
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"lookup", "resolve IATA codes to airports", runLookup},
+		{"search", "find airports by name, city or country", runSearch},
+		{"nearest", "list the airports closest to a point", runNearest},
+		{"distance", "great-circle distance between two airports", runDistance},
+		{"runways", "list an airport's runways: dimensions, surface, lighting", runRunways},
+		{"freqs", "list an airport's radio frequencies", runFreqs},
+		{"enrich", "append airport columns to a CSV, resolving a column of codes", runEnrich},
+		{"export", "write a filtered extract as GeoJSON, JSON or CSV", runExport},
+		{"validate", "check a dataset for schema and data problems", runValidate},
+		{"diff", "compare two datasets: added, removed and changed airports", runDiff},
+		{"stats", "summarize the dataset: counts, coverage and freshness", runStats},
+		{"subset", "write a trimmed CSV with only some regions or types", runSubset},
+		{"consts", "generate a Go package of typed airport code constants", runConsts},
+		{"site", "render per-airport and per-country JSON files for static hosting", runSite},
+		{"convert", "convert a CSV to JSON, GeoJSON, gob, SQL or Arrow", runConvert},
+		{"random", "pick random airports, e.g. for demos and fixtures", runRandom},
+		{"quiz", "guess the city for random IATA codes", runQuiz},
+		{"browse", "explore the dataset in an interactive terminal UI", runBrowse},
+		{"completion", "print a bash, zsh or fish completion script", runCompletion},
+		{"__complete", "list completion candidates", runComplete},
+		{"help", "show this help", runHelp},
+	}
+}
+
+// errReported signals a failure that has already been printed, so main
+// only needs to set the exit status.
+var errReported = errors.New("reported")
+
+func main() {
+	if len(os.Args) < 2 {
+		runHelp(nil)
+		os.Exit(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		err := c.run(args)
+		switch {
+		case err == nil:
+			return
+		case errors.Is(err, flag.ErrHelp):
+			os.Exit(0)
+		case errors.Is(err, errReported):
+			os.Exit(1)
+		default:
+			fmt.Fprintf(os.Stderr, "iata %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "iata: unknown command %q\n\n", name)
+	runHelp(nil)
+	os.Exit(2)
+}
+
+func runHelp(args []string) error {
+	fmt.Fprintln(os.Stderr, "Usage: iata <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, "__") {
+			continue // internal helpers
+		}
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "iata <command> -h" for command flags.`)
+	return nil
+}
+
+// newFlagSet returns a FlagSet for a subcommand along with the shared
+// -data flag.
+func newFlagSet(name, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: iata %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	data := fs.String("data", defaultDataPath(), "path to the airports CSV")
+	return fs, data
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments, as in "iata lookup LHR --json".
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func loadStore(path string) (*iataplaces.Store, error) {
+	store, err := iataplaces.LoadFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// defaultDataPath mirrors the library's default: AIRPORTS_CSV_PATH, then
+// "data/airports-latest.csv".
+func defaultDataPath() string {
+	if p := os.Getenv("AIRPORTS_CSV_PATH"); p != "" {
+		return p
+	}
+	return "data/airports-latest.csv"
+}
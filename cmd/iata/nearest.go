@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runNearest(args []string) error {
+	fs, dataPath := newFlagSet("nearest", "--lat LAT --lon LON [flags] | --airport CODE [flags] | --city NAME [--country CC] [flags]")
+	lat := fs.Float64("lat", 0, "latitude in decimal degrees")
+	lon := fs.Float64("lon", 0, "longitude in decimal degrees")
+	airport := fs.String("airport", "", "IATA code to search around instead of --lat/--lon; the airport itself is left out")
+	city := fs.String("city", "", "city to search around, located from the dataset's municipality column")
+	country := fs.String("country", "", "ISO country code of --city")
+	n := fs.Int("n", 5, "number of airports to show")
+	alternatives := fs.Bool("alternatives", false, "with --airport: rank scheduled airports within 200 km as fallbacks, favouring larger ones")
+	major := fs.Bool("major", false, "only large and medium airports with scheduled service")
+	typ := fs.String("type", "", "only airports of this type, e.g. large_airport")
+	in := fs.String("in", "", "only airports in these comma-separated ISO countries, e.g. FR")
+	notIn := fs.String("not-in", "", "leave out airports in these comma-separated ISO countries")
+	unit := fs.String("unit", "km", "distance unit: km, mi or nm")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	out := addOutputFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { seen[f.Name] = true })
+	if *airport == "" && *city == "" && (!seen["lat"] || !seen["lon"]) {
+		fs.Usage()
+		return errors.New("--lat and --lon, --airport or --city are required")
+	}
+	if _, err := convertKm(0, *unit); err != nil {
+		return err
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+
+	var opts []iataplaces.NearestOption
+	if *major {
+		opts = append(opts, iataplaces.MajorOnly())
+	}
+	if *typ != "" {
+		opts = append(opts, iataplaces.OfType(*typ))
+	}
+	if countries := splitList(*in); len(countries) > 0 {
+		opts = append(opts, iataplaces.InCountries(countries...))
+	}
+	if countries := splitList(*notIn); len(countries) > 0 {
+		opts = append(opts, iataplaces.NotInCountries(countries...))
+	}
+	var results []iataplaces.NearbyAirport
+	switch {
+	case *airport != "" && *alternatives:
+		if results, err = store.Alternatives(*airport, *n, opts...); err != nil {
+			return err
+		}
+	case *airport != "":
+		if results, err = store.NearestToAirport(*airport, *n, opts...); err != nil {
+			return err
+		}
+	case *city != "":
+		if results, err = store.AirportsNearCity(*city, *country, *n, opts...); err != nil {
+			return err
+		}
+	default:
+		results = store.Nearest(*lat, *lon, *n, opts...)
+	}
+
+	if *asJSON {
+		type row struct {
+			Distance float64             `json:"distance"`
+			Unit     string              `json:"unit"`
+			Airport  *iataplaces.Airport `json:"airport"`
+		}
+		rows := make([]row, len(results))
+		for i, r := range results {
+			d, _ := convertKm(r.DistanceKm, *unit)
+			rows[i] = row{Distance: d, Unit: *unit, Airport: r.Airport}
+		}
+		return writeJSON(os.Stdout, rows)
+	}
+	if out.set() {
+		airports := make([]*iataplaces.Airport, len(results))
+		for i, r := range results {
+			airports[i] = r.Airport
+		}
+		return out.write(os.Stdout, airports)
+	}
+	return printNearby(os.Stdout, results, *unit)
+}
+
+func printNearby(w io.Writer, results []iataplaces.NearbyAirport, unit string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%8s\tIATA\tNAME\tCITY\tCOUNTRY\n", unit)
+	for _, r := range results {
+		d, _ := convertKm(r.DistanceKm, unit)
+		a := r.Airport
+		fmt.Fprintf(tw, "%8.1f\t%s\t%s\t%s\t%s\n", d, a.IATACode, a.Name, a.Municipality, a.IsoCountry)
+	}
+	return tw.Flush()
+}
+
+// convertKm converts a distance in kilometres to unit.
+func convertKm(km float64, unit string) (float64, error) {
+	switch unit {
+	case "km":
+		return km, nil
+	case "mi":
+		return km / 1.609344, nil
+	case "nm":
+		return km / 1.852, nil
+	}
+	return 0, fmt.Errorf("unknown unit %q (want km, mi or nm)", unit)
+}
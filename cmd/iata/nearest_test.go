@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// nearestRow is one entry of "iata nearest --json".
+type nearestRow struct {
+	Distance float64 `json:"distance"`
+	Unit     string  `json:"unit"`
+	Airport  struct {
+		IATACode string `json:"iata_code"`
+	} `json:"airport"`
+}
+
+// nearest runs "iata nearest --json" with args and returns the rows.
+func nearest(t *testing.T, args ...string) []nearestRow {
+	t.Helper()
+	stdout, _, err := run(t, "", append([]string{"nearest", "--json"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []nearestRow
+	if err := json.Unmarshal([]byte(stdout), &rows); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	return rows
+}
+
+func nearestCodes(rows []nearestRow) string {
+	codes := make([]string, len(rows))
+	for i, r := range rows {
+		codes[i] = r.Airport.IATACode
+	}
+	return strings.Join(codes, " ")
+}
+
+func TestNearest(t *testing.T) {
+	useData(t, testCSV)
+
+	// Central London.
+	rows := nearest(t, "--lat", "51.5", "--lon", "-0.12", "--n", "3")
+	if got := nearestCodes(rows); got != "LHR LGW CDG" {
+		t.Errorf("nearest to London: %s", got)
+	}
+	if rows[0].Unit != "km" || math.Abs(rows[0].Distance-24.3) > 0.5 {
+		t.Errorf("LHR is %.1f %s away, want about 24 km", rows[0].Distance, rows[0].Unit)
+	}
+	miles := nearest(t, "--lat", "51.5", "--lon", "-0.12", "--n", "1", "--unit", "mi")
+	if math.Abs(miles[0].Distance*1.609344-rows[0].Distance) > 0.01 {
+		t.Errorf("%.2f mi for %.2f km", miles[0].Distance, rows[0].Distance)
+	}
+
+	stdout, _, err := run(t, "", "nearest", "--lat", "51.5", "--lon", "-0.12", "--n", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "LHR") {
+		t.Errorf("table:\n%s", stdout)
+	}
+}
+
+func TestNearestErrors(t *testing.T) {
+	useData(t, testCSV)
+	for _, args := range [][]string{
+		{"nearest"},
+		{"nearest", "--lat", "51.5"},
+		{"nearest", "--lat", "51.5", "--lon", "0", "--unit", "furlongs"},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
+
+func TestNearestToAirport(t *testing.T) {
+	useData(t, testCSV)
+
+	// The airport itself is left out.
+	if got := nearestCodes(nearest(t, "--airport", "lhr", "--n", "2")); got != "LGW CDG" {
+		t.Errorf("nearest to LHR: %s", got)
+	}
+	if _, _, err := run(t, "", "nearest", "--airport", "XXX"); err == nil {
+		t.Error("unknown airport accepted")
+	}
+}
+
+func TestNearestToCity(t *testing.T) {
+	useData(t, testCSV)
+
+	if got := nearestCodes(nearest(t, "--city", "new york", "--country", "US", "--n", "2")); got != "JFK LHR" {
+		t.Errorf("nearest to New York: %s", got)
+	}
+	for _, args := range [][]string{
+		{"nearest", "--city", "Atlantis"},
+		{"nearest", "--city", "London", "--country", "FR"},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
+
+func TestNearestAlternatives(t *testing.T) {
+	// London City is a little closer to Heathrow than Gatwick but smaller,
+	// and the strip next to Heathrow has no scheduled service.
+	useData(t, testCSV+
+		"26,EGLC,medium_airport,London City Airport,51.505299,0.055278,19,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGLC,EGLC,LCY,,,,,,\n"+
+		"27,GB-0001,small_airport,Heathrow Strip,51.47,-0.40,80,EU,United Kingdom,GB,England,GB-ENG,ENG,London,0,,,QQA,,,,,,\n")
+
+	if got := nearestCodes(nearest(t, "--airport", "LHR", "--n", "3")); got != "QQA LCY LGW" {
+		t.Errorf("nearest to LHR: %s", got)
+	}
+	if got := nearestCodes(nearest(t, "--airport", "LHR", "--alternatives")); got != "LGW LCY" {
+		t.Errorf("alternatives to LHR: %s", got)
+	}
+}
+
+func TestNearestCountries(t *testing.T) {
+	useData(t, testCSV)
+
+	if got := nearestCodes(nearest(t, "--airport", "LHR", "--in", "fr, us")); got != "CDG JFK" {
+		t.Errorf("--in fr,us: %s", got)
+	}
+	if got := nearestCodes(nearest(t, "--lat", "51.5", "--lon", "-0.12", "--not-in", "GB", "--n", "2")); got != "CDG JFK" {
+		t.Errorf("--not-in GB: %s", got)
+	}
+}
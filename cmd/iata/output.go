@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// outputFlags are the --template and --columns flags shared by commands
+// that print airports.
+type outputFlags struct {
+	template string
+	columns  string
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	o := &outputFlags{}
+	fs.StringVar(&o.template, "template", "", `Go text/template applied to each airport, e.g. '{{.IATACode}} {{.Name}}', '{{.ElevationFtOr 0}}' or '{{field "elevation_ft"}}'`)
+	fs.StringVar(&o.columns, "columns", "", "comma-separated columns for table output, e.g. iata_code,name,municipality")
+	return o
+}
+
+// set reports whether either flag was given.
+func (o *outputFlags) set() bool {
+	return o.template != "" || o.columns != ""
+}
+
+// write prints airports using --template, or else as a --columns table.
+func (o *outputFlags) write(w io.Writer, airports []*iataplaces.Airport) error {
+	if o.template != "" {
+		return writeTemplate(w, o.template, airports)
+	}
+	return writeColumns(w, strings.Split(o.columns, ","), airports)
+}
+
+// templateFuncs are available in --template in addition to the Airport
+// struct fields.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+}
+
+func writeTemplate(w io.Writer, text string, airports []*iataplaces.Airport) error {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	var cur *iataplaces.Airport
+	tmpl, err := template.New("airport").Funcs(templateFuncs).Funcs(template.FuncMap{
+		// field gives the CSV form of a column, which avoids printing
+		// pointers for optional values such as elevation_ft.
+		"field": func(col string) (string, error) {
+			v, ok := cur.FieldValue(col)
+			if !ok {
+				return "", fmt.Errorf("unknown field %q", col)
+			}
+			return v, nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("parse --template: %w", err)
+	}
+	for _, cur = range airports {
+		if err := tmpl.Execute(w, cur); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeColumns(w io.Writer, columns []string, airports []*iataplaces.Airport) error {
+	for i, c := range columns {
+		columns[i] = strings.TrimSpace(c)
+		if _, ok := (&iataplaces.Airport{}).FieldValue(columns[i]); !ok {
+			return fmt.Errorf("unknown column %q (columns: %s)", columns[i], strings.Join(iataplaces.CSVColumns, ", "))
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, a := range airports {
+		vals := make([]string, len(columns))
+		for i, c := range columns {
+			vals[i], _ = a.FieldValue(c)
+		}
+		fmt.Fprintln(tw, strings.Join(vals, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestOutputFlags(t *testing.T) {
+	useData(t, testCSV)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			[]string{"lookup", "LHR", "CDG", "--template", "{{.IATACode}} {{.Name | upper}}"},
+			"LHR LONDON HEATHROW AIRPORT\nCDG CHARLES DE GAULLE INTERNATIONAL AIRPORT\n",
+		},
+		{
+			[]string{"lookup", "HND", "--template", `{{field "elevation_ft"}} ft, {{.ElevationFtOr 0}}`},
+			"35 ft, 35\n",
+		},
+		{
+			[]string{"search", "--country", "gb", "--columns", "iata_code, elevation_ft,municipality"},
+			"IATA_CODE  ELEVATION_FT  MUNICIPALITY\nLGW        202           London\nLHR        83            London\n",
+		},
+	}
+	for _, tt := range tests {
+		stdout, _, err := run(t, "", tt.args...)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.args, err)
+		}
+		if stdout != tt.want {
+			t.Errorf("%q:\n%s\nwant:\n%s", tt.args, stdout, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"lookup", "LHR", "--template", "{{.IATACode"},
+		{"lookup", "LHR", "--template", `{{field "gates"}}`},
+		{"lookup", "LHR", "--columns", "iata_code,gates"},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
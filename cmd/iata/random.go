@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// addPoolFlags registers the filters shared by random and quiz and returns
+// a function that builds the matching pool of airports.
+func addPoolFlags(fs *flag.FlagSet) func(*iataplaces.Store) []*iataplaces.Airport {
+	country := fs.String("country", "", "only airports in this ISO country code")
+	typ := fs.String("type", "", "only airports of this type, e.g. large_airport")
+	major := fs.Bool("major", false, "only large and medium airports with scheduled service")
+	return func(store *iataplaces.Store) []*iataplaces.Airport {
+		return store.Filter(func(a *iataplaces.Airport) bool {
+			if *country != "" && !strings.EqualFold(a.IsoCountry, *country) {
+				return false
+			}
+			if *typ != "" && a.Type != *typ {
+				return false
+			}
+			if *major && !(a.Scheduled && (a.Type == "large_airport" || a.Type == "medium_airport")) {
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// newRand returns a generator seeded with seed, or randomly when seed is 0.
+func newRand(seed uint64) *rand.Rand {
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// sample picks up to n distinct airports from pool.
+func sample(r *rand.Rand, pool []*iataplaces.Airport, n int) []*iataplaces.Airport {
+	pool = append([]*iataplaces.Airport(nil), pool...)
+	r.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+	return pool[:min(n, len(pool))]
+}
+
+func runRandom(args []string) error {
+	fs, dataPath := newFlagSet("random", "[flags]")
+	pool := addPoolFlags(fs)
+	n := fs.Int("n", 1, "number of airports to pick")
+	seed := fs.Uint64("seed", 0, "random seed for repeatable picks (0: random)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	out := addOutputFlags(fs)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	candidates := pool(store)
+	if len(candidates) == 0 {
+		fmt.Fprintln(os.Stderr, "no airports match")
+		return errReported
+	}
+	picked := sample(newRand(*seed), candidates, *n)
+
+	switch {
+	case *asJSON:
+		return writeJSON(os.Stdout, picked)
+	case out.set():
+		return out.write(os.Stdout, picked)
+	}
+	return printTable(os.Stdout, picked)
+}
+
+func runQuiz(args []string) error {
+	fs, dataPath := newFlagSet("quiz", "[flags]")
+	pool := addPoolFlags(fs)
+	rounds := fs.Int("rounds", 10, "number of questions")
+	seed := fs.Uint64("seed", 0, "random seed for a repeatable quiz (0: random)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	var candidates []*iataplaces.Airport
+	for _, a := range pool(store) {
+		if a.Municipality != "" {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return errors.New("no airports with a city match the filters")
+	}
+	questions := sample(newRand(*seed), candidates, *rounds)
+
+	fmt.Printf("Name the city for each code (%d questions, empty answer to skip).\n\n", len(questions))
+	in := bufio.NewScanner(os.Stdin)
+	score := 0
+	for i, a := range questions {
+		fmt.Printf("%d/%d  %s? ", i+1, len(questions), a.IATACode)
+		if !in.Scan() {
+			fmt.Println()
+			break
+		}
+		if sameCity(in.Text(), a.Municipality) {
+			score++
+			fmt.Printf("  ✓ %s (%s)\n", a.Municipality, a.Name)
+		} else {
+			fmt.Printf("  ✗ %s (%s, %s)\n", a.Municipality, a.Name, a.IsoCountry)
+		}
+	}
+	fmt.Printf("\nScore: %d/%d\n", score, len(questions))
+	return in.Err()
+}
+
+// sameCity compares a quiz answer with the expected city, ignoring case,
+// surrounding space and anything after a comma or parenthesis in the
+// dataset's value ("London (Heathrow)" accepts "london").
+func sameCity(answer, city string) bool {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return false
+	}
+	if strings.EqualFold(answer, city) {
+		return true
+	}
+	if i := strings.IndexAny(city, ",("); i > 0 {
+		return strings.EqualFold(answer, strings.TrimSpace(city[:i]))
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRandom(t *testing.T) {
+	useData(t, testCSV)
+
+	pick := func(args ...string) []string {
+		t.Helper()
+		stdout, _, err := run(t, "", append([]string{"random", "--json"}, args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var airports []struct {
+			IATACode string `json:"iata_code"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &airports); err != nil {
+			t.Fatalf("%v: %s", err, stdout)
+		}
+		codes := make([]string, len(airports))
+		for i, a := range airports {
+			codes[i] = a.IATACode
+		}
+		return codes
+	}
+
+	first := pick("--n", "3", "--seed", "42")
+	if len(first) != 3 || first[0] == first[1] || first[1] == first[2] || first[0] == first[2] {
+		t.Errorf("--n 3 picked %v", first)
+	}
+	if again := pick("--n", "3", "--seed", "42"); strings.Join(again, " ") != strings.Join(first, " ") {
+		t.Errorf("same seed picked %v, then %v", first, again)
+	}
+	if got := pick("--n", "10", "--country", "gb"); len(got) != 2 {
+		t.Errorf("--country gb picked %v", got)
+	}
+
+	_, stderr, err := run(t, "", "random", "--type", "heliport")
+	if !errors.Is(err, errReported) || !strings.Contains(stderr, "no airports match") {
+		t.Errorf("empty pool: err %v, stderr %q", err, stderr)
+	}
+}
+
+func TestQuiz(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "paris\n", "quiz", "--country", "FR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "1/1  CDG? ") || !strings.Contains(stdout, "✓ Paris (Roissy-en-France") || !strings.HasSuffix(stdout, "Score: 1/1\n") {
+		t.Errorf("output:\n%s", stdout)
+	}
+
+	// Running out of answers ends the quiz early.
+	stdout, _, err = run(t, "wrong\n", "quiz", "--rounds", "3", "--seed", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(stdout, "✗") != 1 || !strings.Contains(stdout, "2/3  ") || strings.Contains(stdout, "3/3  ") || !strings.HasSuffix(stdout, "Score: 0/3\n") {
+		t.Errorf("output:\n%s", stdout)
+	}
+}
+
+func TestSameCity(t *testing.T) {
+	tests := []struct {
+		answer, city string
+		want         bool
+	}{
+		{"London", "London", true},
+		{" new york ", "New York", true},
+		{"paris", "Paris (Roissy-en-France, Val-d'Oise)", true},
+		{"Crawley", "Crawley, West Sussex", true},
+		{"", "London", false},
+		{"Roissy", "Paris (Roissy-en-France, Val-d'Oise)", false},
+		{"Lond", "London", false},
+	}
+	for _, tt := range tests {
+		if got := sameCity(tt.answer, tt.city); got != tt.want {
+			t.Errorf("sameCity(%q, %q) = %v", tt.answer, tt.city, got)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// OurAirports files written by airports-update next to airports-latest.csv.
+const (
+	runwaysFile     = "runways-latest.csv"
+	frequenciesFile = "airport-frequencies-latest.csv"
+)
+
+type runway struct {
+	LEIdent  string `json:"le_ident"`
+	HEIdent  string `json:"he_ident,omitempty"`
+	LengthFt *int64 `json:"length_ft"`
+	WidthFt  *int64 `json:"width_ft"`
+	Surface  string `json:"surface"`
+	Lighted  bool   `json:"lighted"`
+	Closed   bool   `json:"closed"`
+}
+
+type frequency struct {
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	MHz         float64 `json:"frequency_mhz"`
+}
+
+func runRunways(args []string) error {
+	ident, asJSON, path, err := parseAirportFileArgs("runways", runwaysFile, args)
+	if err != nil {
+		return err
+	}
+	var runways []runway
+	err = readAirportRows(path, ident, func(get func(string) string) {
+		runways = append(runways, runway{
+			LEIdent:  get("le_ident"),
+			HEIdent:  get("he_ident"),
+			LengthFt: parseOptionalInt(get("length_ft")),
+			WidthFt:  parseOptionalInt(get("width_ft")),
+			Surface:  get("surface"),
+			Lighted:  get("lighted") == "1",
+			Closed:   get("closed") == "1",
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if len(runways) == 0 {
+		return fmt.Errorf("%s: no runways in %s", ident, path)
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, runways)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUNWAY\tLENGTH FT\tWIDTH FT\tSURFACE\tLIGHTED\tSTATUS")
+	for _, r := range runways {
+		name := r.LEIdent
+		if r.HEIdent != "" {
+			name += "/" + r.HEIdent
+		}
+		status := "open"
+		if r.Closed {
+			status = "closed"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", name, optionalInt(r.LengthFt), optionalInt(r.WidthFt),
+			r.Surface, yesNo(r.Lighted), status)
+	}
+	return tw.Flush()
+}
+
+func runFreqs(args []string) error {
+	ident, asJSON, path, err := parseAirportFileArgs("freqs", frequenciesFile, args)
+	if err != nil {
+		return err
+	}
+	var freqs []frequency
+	err = readAirportRows(path, ident, func(get func(string) string) {
+		mhz, _ := strconv.ParseFloat(get("frequency_mhz"), 64)
+		freqs = append(freqs, frequency{Type: get("type"), Description: get("description"), MHz: mhz})
+	})
+	if err != nil {
+		return err
+	}
+	if len(freqs) == 0 {
+		return fmt.Errorf("%s: no frequencies in %s", ident, path)
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, freqs)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tMHZ\tDESCRIPTION")
+	for _, f := range freqs {
+		fmt.Fprintf(tw, "%s\t%.3f\t%s\n", f.Type, f.MHz, f.Description)
+	}
+	return tw.Flush()
+}
+
+// parseAirportFileArgs handles the flags shared by runways and freqs and
+// resolves the single airport argument, an IATA code, ICAO code or
+// OurAirports ident, to the ident used by the per-airport files. The file
+// defaults to name next to the -data CSV.
+func parseAirportFileArgs(cmd, name string, args []string) (ident string, asJSON *bool, path string, err error) {
+	fs, dataPath := newFlagSet(cmd, "[--json] [--file PATH] AIRPORT")
+	asJSON = fs.Bool("json", false, "print JSON instead of a table")
+	file := fs.String("file", "", "OurAirports "+name+" to read (default: next to -data)")
+	codes, err := parseArgs(fs, args)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(codes) != 1 {
+		fs.Usage()
+		return "", nil, "", errors.New("need exactly one airport code")
+	}
+	path = *file
+	if path == "" {
+		path = filepath.Join(filepath.Dir(*dataPath), name)
+	}
+
+	ident = strings.ToUpper(strings.TrimSpace(codes[0]))
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if a, ok := store.LookupIATA(ident); ok {
+		ident = a.Ident
+	} else if a, ok := store.LookupICAO(ident); ok {
+		ident = a.Ident
+	}
+	return ident, asJSON, path, nil
+}
+
+// readAirportRows calls fn for each row of the CSV at path whose
+// airport_ident is ident. get returns a column by header name.
+func readAirportRows(path, ident string, fn func(get func(string) string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w (fetch it with airports-update -datasets airports,runways,frequencies)", err)
+		}
+		return err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, col := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(col, "\ufeff"))] = i
+	}
+	identCol, ok := columns["airport_ident"]
+	if !ok {
+		return fmt.Errorf("%s: no airport_ident column", path)
+	}
+
+	var record []string
+	get := func(col string) string {
+		if i, ok := columns[col]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	for {
+		record, err = cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+		if identCol < len(record) && strings.EqualFold(record[identCol], ident) {
+			fn(get)
+		}
+	}
+}
+
+func parseOptionalInt(v string) *int64 {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func optionalInt(v *int64) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRunways = `"id","airport_ref","airport_ident","length_ft","width_ft","surface","lighted","closed","le_ident","he_ident"
+1,2434,"EGLL",12799,164,"ASP",1,0,"09L","27R"
+2,2434,"EGLL",12008,164,"ASP",1,0,"09R","27L"
+3,5627,"RJTT",9843,197,"ASP",1,0,"16L","34R"
+`
+
+const testFrequencies = `"id","airport_ref","airport_ident","type","description","frequency_mhz"
+1,2434,"EGLL","ATIS","ATIS",128.075
+2,2434,"EGLL","TWR","HEATHROW TWR",118.5
+`
+
+// useAirportFiles writes the runway and frequency files next to the data
+// file set up by useData.
+func useAirportFiles(t *testing.T) {
+	t.Helper()
+	dir := filepath.Dir(useData(t, testCSV))
+	for name, content := range map[string]string{runwaysFile: testRunways, frequenciesFile: testFrequencies} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunways(t *testing.T) {
+	useAirportFiles(t)
+
+	// IATA and ICAO codes both resolve to the airport ident.
+	for _, code := range []string{"lhr", "EGLL"} {
+		out, _, err := run(t, "", "runways", code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[1], "09L/27R  12799") || !strings.HasSuffix(lines[2], "yes      open") {
+			t.Errorf("runways %s:\n%s", code, out)
+		}
+	}
+
+	out, _, err := run(t, "", "runways", "--json", "HND")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runways []runway
+	if err := json.Unmarshal([]byte(out), &runways); err != nil {
+		t.Fatal(err)
+	}
+	if len(runways) != 1 || runways[0].LEIdent != "16L" || *runways[0].LengthFt != 9843 || !runways[0].Lighted {
+		t.Errorf("runways --json HND %+v", runways)
+	}
+
+	if _, _, err := run(t, "", "runways", "JFK"); err == nil || !strings.Contains(err.Error(), "KJFK: no runways") {
+		t.Errorf("runways JFK: %v", err)
+	}
+	if _, _, err := run(t, "", "runways", "--file", filepath.Join(t.TempDir(), runwaysFile), "LHR"); err == nil || !strings.Contains(err.Error(), "airports-update") {
+		t.Errorf("runways with a missing file: %v", err)
+	}
+	if _, _, err := run(t, "", "runways"); err == nil {
+		t.Error("runways with no airport succeeded")
+	}
+}
+
+func TestFreqs(t *testing.T) {
+	useAirportFiles(t)
+
+	out, _, err := run(t, "", "freqs", "LHR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "TYPE  MHZ      DESCRIPTION\nATIS  128.075  ATIS\nTWR   118.500  HEATHROW TWR\n"; out != want {
+		t.Errorf("freqs LHR:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, _, err = run(t, "", "freqs", "--json", "EGLL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var freqs []frequency
+	if err := json.Unmarshal([]byte(out), &freqs); err != nil {
+		t.Fatal(err)
+	}
+	if len(freqs) != 2 || freqs[1] != (frequency{"TWR", "HEATHROW TWR", 118.5}) {
+		t.Errorf("freqs --json EGLL %+v", freqs)
+	}
+
+	if _, _, err := run(t, "", "freqs", "HND"); err == nil || !strings.Contains(err.Error(), "no frequencies") {
+		t.Errorf("freqs HND: %v", err)
+	}
+}
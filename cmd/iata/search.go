@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runSearch(args []string) error {
+	fs, dataPath := newFlagSet("search", "[flags] [TEXT]")
+	city := fs.String("city", "", "only airports in this municipality")
+	country := fs.String("country", "", "only airports in this ISO country code")
+	typ := fs.String("type", "", "only airports of this type, e.g. large_airport")
+	limit := fs.Int("limit", 10, "maximum number of results (0 for all)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	out := addOutputFlags(fs)
+	terms, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	q := iataplaces.SearchQuery{
+		Text:    strings.Join(terms, " "),
+		City:    *city,
+		Country: *country,
+		Type:    *typ,
+		Limit:   *limit,
+	}
+	if q.Text == "" && q.City == "" && q.Country == "" && q.Type == "" {
+		fs.Usage()
+		return errors.New("give search text or at least one filter")
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+
+	results := store.Search(q)
+	airports := make([]*iataplaces.Airport, len(results))
+	for i, r := range results {
+		airports[i] = r.Airport
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, airports)
+	}
+	if len(airports) == 0 {
+		fmt.Fprintln(os.Stderr, "no matches")
+		return errReported
+	}
+	if out.set() {
+		return out.write(os.Stdout, airports)
+	}
+	return printTable(os.Stdout, airports)
+}
+
+// printTable writes airports as an aligned table, one per line.
+func printTable(w io.Writer, airports []*iataplaces.Airport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "IATA\tICAO\tNAME\tCITY\tCOUNTRY\tTYPE")
+	for _, a := range airports {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			a.IATACode, a.ICAOCode, a.Name, a.Municipality, a.IsoCountry, a.Type)
+	}
+	return tw.Flush()
+}
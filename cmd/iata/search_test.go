@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func TestSearch(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "search", "--country", "gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "IATA  ICAO") {
+		t.Fatalf("table:\n%s", stdout)
+	}
+	for _, code := range []string{"LHR", "LGW"} {
+		if !strings.Contains(stdout, code+"   EG") {
+			t.Errorf("table has no %s row:\n%s", code, stdout)
+		}
+	}
+
+	stdout, _, err = run(t, "", "search", "international", "--limit", "2", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var airports []iataplaces.Airport
+	if err := json.Unmarshal([]byte(stdout), &airports); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if len(airports) != 2 {
+		t.Errorf("--limit 2 gave %d airports", len(airports))
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	useData(t, testCSV)
+
+	if _, _, err := run(t, "", "search"); err == nil {
+		t.Error("search with no query succeeded")
+	}
+	_, stderr, err := run(t, "", "search", "--city", "Atlantis")
+	if !errors.Is(err, errReported) || !strings.Contains(stderr, "no matches") {
+		t.Errorf("no matches: err %v, stderr %q", err, stderr)
+	}
+	// JSON output is an empty list rather than an error.
+	if stdout, _, err := run(t, "", "search", "--city", "Atlantis", "--json"); err != nil || strings.TrimSpace(stdout) != "[]" {
+		t.Errorf("no matches as JSON: %q, %v", stdout, err)
+	}
+}
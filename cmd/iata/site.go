@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// siteAirport is the short form of an airport used in the site's indexes.
+type siteAirport struct {
+	IATACode     string `json:"iata_code"`
+	Name         string `json:"name"`
+	Municipality string `json:"municipality,omitempty"`
+	IsoCountry   string `json:"iso_country"`
+}
+
+type siteCountry struct {
+	IsoCountry  string `json:"iso_country"`
+	CountryName string `json:"country_name"`
+	Airports    int    `json:"airports"`
+}
+
+func runSite(args []string) error {
+	fs, dataPath := newFlagSet("site", "[flags] DIR")
+	geojson := fs.Bool("geojson", false, "also write airports/CODE.geojson")
+	dirs, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(dirs) != 1 {
+		fs.Usage()
+		return errors.New("site needs one output directory")
+	}
+	dir := dirs[0]
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	n, err := writeSite(dir, store.All(), *geojson)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d files to %s\n", n, dir)
+	return nil
+}
+
+// writeSite renders airports as static JSON files under dir:
+//
+//	index.json               every airport, short form
+//	airports/CODE.json       one airport (and CODE.geojson with geojson)
+//	countries.json           countries with airport counts
+//	countries/ISO.json       a country's airports, short form
+//	cities/ISO.json          a country's cities, each with its airport codes
+//
+// It returns the number of files written.
+func writeSite(dir string, airports []*iataplaces.Airport, geojson bool) (int, error) {
+	written := 0
+	write := func(name string, v any) error {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		var b bytes.Buffer
+		if raw, ok := v.([]byte); ok {
+			b.Write(raw)
+		} else if err := json.NewEncoder(&b).Encode(v); err != nil {
+			return err
+		}
+		written++
+		return os.WriteFile(path, b.Bytes(), 0o644)
+	}
+
+	index := make([]siteAirport, 0, len(airports))
+	byCountry := map[string][]siteAirport{}
+	countryNames := map[string]string{}
+	cities := map[string]map[string][]string{}
+	for _, a := range airports {
+		short := siteAirport{a.IATACode, a.Name, a.Municipality, a.IsoCountry}
+		index = append(index, short)
+		if err := write("airports/"+a.IATACode+".json", a); err != nil {
+			return written, err
+		}
+		if geojson {
+			var b bytes.Buffer
+			if err := iataplaces.WriteGeoJSON(&b, []*iataplaces.Airport{a}); err != nil {
+				return written, err
+			}
+			if err := write("airports/"+a.IATACode+".geojson", b.Bytes()); err != nil {
+				return written, err
+			}
+		}
+		if a.IsoCountry == "" {
+			continue
+		}
+		byCountry[a.IsoCountry] = append(byCountry[a.IsoCountry], short)
+		countryNames[a.IsoCountry] = a.CountryName
+		if a.Municipality != "" {
+			if cities[a.IsoCountry] == nil {
+				cities[a.IsoCountry] = map[string][]string{}
+			}
+			cities[a.IsoCountry][a.Municipality] = append(cities[a.IsoCountry][a.Municipality], a.IATACode)
+		}
+	}
+
+	countries := make([]siteCountry, 0, len(byCountry))
+	for iso, list := range byCountry {
+		countries = append(countries, siteCountry{iso, countryNames[iso], len(list)})
+		err := write("countries/"+iso+".json", map[string]any{
+			"iso_country":  iso,
+			"country_name": countryNames[iso],
+			"airports":     list,
+		})
+		if err != nil {
+			return written, err
+		}
+		if err := write("cities/"+iso+".json", cities[iso]); err != nil {
+			return written, err
+		}
+	}
+	sort.Slice(countries, func(i, j int) bool { return countries[i].IsoCountry < countries[j].IsoCountry })
+	if err := write("countries.json", countries); err != nil {
+		return written, err
+	}
+	return written, write("index.json", index)
+}
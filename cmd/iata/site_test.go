@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSite(t *testing.T) {
+	useData(t, testCSV)
+	dir := filepath.Join(t.TempDir(), "site")
+
+	_, stderr, err := run(t, "", "site", dir, "--geojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 5 airports with GeoJSON, 4 countries with cities, and the two indexes.
+	if want := "wrote 20 files to " + dir + "\n"; stderr != want {
+		t.Errorf("stderr %q, want %q", stderr, want)
+	}
+
+	read := func(name string, v any) {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, v); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	var index []siteAirport
+	read("index.json", &index)
+	if len(index) != 5 || index[0] != (siteAirport{"CDG", "Charles de Gaulle International Airport", "Paris (Roissy-en-France, Val-d'Oise)", "FR"}) {
+		t.Errorf("index %+v", index)
+	}
+	var lhr struct {
+		ICAOCode string `json:"icao_code"`
+	}
+	read("airports/LHR.json", &lhr)
+	if lhr.ICAOCode != "EGLL" {
+		t.Errorf("airports/LHR.json %+v", lhr)
+	}
+	var feature struct{ Type string }
+	read("airports/HND.geojson", &feature)
+	if feature.Type != "FeatureCollection" {
+		t.Errorf("airports/HND.geojson type %q", feature.Type)
+	}
+	var countries []siteCountry
+	read("countries.json", &countries)
+	if len(countries) != 4 || countries[1] != (siteCountry{"GB", "United Kingdom", 2}) {
+		t.Errorf("countries %+v", countries)
+	}
+	var gb struct {
+		Airports []siteAirport `json:"airports"`
+	}
+	read("countries/GB.json", &gb)
+	if len(gb.Airports) != 2 {
+		t.Errorf("countries/GB.json %+v", gb)
+	}
+	var cities map[string][]string
+	read("cities/GB.json", &cities)
+	if got := strings.Join(cities["London"], " "); len(cities) != 1 || (got != "LGW LHR" && got != "LHR LGW") {
+		t.Errorf("cities/GB.json %v", cities)
+	}
+
+	if _, _, err := run(t, "", "site"); err == nil {
+		t.Error("site with no directory succeeded")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+func runStats(args []string) error {
+	fs, dataPath := newFlagSet("stats", "[flags]")
+	top := fs.Int("top", 10, "number of countries to list")
+	asJSON := fs.Bool("json", false, "print JSON instead of text")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	store, err := loadStore(*dataPath)
+	if err != nil {
+		return err
+	}
+	st := store.Stats()
+	mem := store.MemoryFootprint()
+
+	var fileAge time.Duration
+	if fi, err := os.Stat(*dataPath); err == nil {
+		fileAge = time.Since(fi.ModTime()).Round(time.Minute)
+	}
+
+	if *asJSON {
+		return writeJSON(os.Stdout, map[string]any{
+			"file":          *dataPath,
+			"file_age_sec":  int64(fileAge.Seconds()),
+			"iata_coverage": st.IATACoverage(),
+			"stats":         st,
+			"memory":        mem,
+		})
+	}
+
+	fmt.Printf("File:          %s (modified %s ago)\n", *dataPath, fileAge)
+	fmt.Printf("Rows:          %d\n", st.SourceRows)
+	fmt.Printf("IATA airports: %d (%.1f%% of rows)\n", st.Airports, 100*st.IATACoverage())
+	fmt.Printf("Scheduled:     %d\n", st.Scheduled)
+	fmt.Printf("With ICAO:     %d\n", st.WithICAO)
+	if st.LoadIssues > 0 {
+		fmt.Printf("Load issues:   %d unparseable values\n", st.LoadIssues)
+	}
+	fmt.Printf("Memory:        ~%.1f MiB (records %.1f, indexes %.1f)\n", mib(mem.Total()),
+		mib(mem.Records+mem.Duplicates), mib(mem.Total()-mem.Records-mem.Duplicates))
+	if st.NewestUpdate != nil {
+		fmt.Printf("Last updated:  %s (newest), %s (oldest)\n",
+			st.NewestUpdate.Format(time.DateOnly), st.OldestUpdate.Format(time.DateOnly))
+	}
+
+	printCounts("By type", st.ByType, 0)
+	printCounts("By continent", st.ByContinent, 0)
+	printCounts(fmt.Sprintf("Top %d countries", *top), st.ByCountry, *top)
+	return nil
+}
+
+// printCounts lists a count map largest first, limited to n entries when
+// n > 0.
+func printCounts(title string, counts map[string]int, n int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+
+	fmt.Printf("\n%s:\n", title)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "  %s\t%d\n", k, counts[k])
+	}
+	tw.Flush()
+}
+
+func mib(n int64) float64 {
+	return float64(n) / (1 << 20)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// noIATARow is an OurAirports row without an IATA code.
+const noIATARow = "16795,EGLD,small_airport,Denham Aerodrome,51.5882987976,-0.513055980206,249,EU,United Kingdom,GB,England,GB-ENG,ENG,Gerrards Cross,0,EGLD,EGLD,,,,,,,\n"
+
+func TestStats(t *testing.T) {
+	useData(t, testCSV+noIATARow)
+
+	stdout, _, err := run(t, "", "stats", "--top", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Rows:          6\n",
+		"IATA airports: 5 (83.3% of rows)\n",
+		"Scheduled:     5\n",
+		"Last updated:  2025-02-27 (newest), 2021-04-09 (oldest)\n",
+		"By type:\n  large_airport  5\n",
+		"Top 2 countries:\n  GB  2\n  FR  1\n\n",
+	} {
+		if !strings.Contains(stdout+"\n", want) {
+			t.Errorf("output has no %q:\n%s", want, stdout)
+		}
+	}
+
+	stdout, _, err = run(t, "", "stats", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Coverage float64 `json:"iata_coverage"`
+		Stats    struct {
+			Airports  int            `json:"airports"`
+			ByCountry map[string]int `json:"by_country"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if out.Stats.Airports != 5 || out.Stats.ByCountry["GB"] != 2 || out.Coverage < 0.83 || out.Coverage > 0.84 {
+		t.Errorf("JSON stats %+v", out)
+	}
+}
+
+func TestStatsMemory(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "stats", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Memory map[string]int64 `json:"memory"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &out); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if out.Memory["records"] <= 0 || out.Memory["iata_index"] <= 0 {
+		t.Errorf("memory footprint %v", out.Memory)
+	}
+
+	stdout, _, err = run(t, "", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "\nMemory:        ~0.0 MiB (records 0.0, indexes 0.0)\n") {
+		t.Errorf("output has no memory line:\n%s", stdout)
+	}
+}
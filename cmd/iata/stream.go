@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// defaultStreamFields are the CSV output columns of "iata lookup -".
+var defaultStreamFields = []string{"iata_code", "name", "municipality", "iso_country", "latitude_deg", "longitude_deg"}
+
+type streamOptions struct {
+	column string   // input CSV column (name or 1-based index); empty means one code per line
+	format string   // ndjson or csv
+	fields []string // output columns for csv
+}
+
+// streamLookup resolves codes read from r and writes one result per input
+// code to w. Misses are emitted too, so output lines up with input.
+func streamLookup(store *iataplaces.Store, r io.Reader, w io.Writer, opts streamOptions) error {
+	for _, f := range opts.fields {
+		if _, ok := (&iataplaces.Airport{}).FieldValue(f); !ok {
+			return fmt.Errorf("unknown field %q", f)
+		}
+	}
+
+	var emit func(code string, a *iataplaces.Airport) error
+	var flush func() error
+	switch opts.format {
+	case "ndjson":
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		emit = func(code string, a *iataplaces.Airport) error {
+			return enc.Encode(struct {
+				Query   string              `json:"query"`
+				Found   bool                `json:"found"`
+				Airport *iataplaces.Airport `json:"airport,omitempty"`
+			}{code, a != nil, a})
+		}
+		flush = bw.Flush
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(append([]string{"query"}, opts.fields...)); err != nil {
+			return err
+		}
+		emit = func(code string, a *iataplaces.Airport) error {
+			rec := make([]string, 0, len(opts.fields)+1)
+			rec = append(rec, code)
+			for _, f := range opts.fields {
+				v := ""
+				if a != nil {
+					v, _ = a.FieldValue(f)
+				}
+				rec = append(rec, v)
+			}
+			return cw.Write(rec)
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return fmt.Errorf("unknown format %q (want ndjson or csv)", opts.format)
+	}
+
+	resolved, missing := 0, 0
+	err := readCodes(r, opts.column, func(code string) error {
+		a, ok := store.LookupIATA(code)
+		if ok {
+			resolved++
+		} else {
+			missing++
+		}
+		return emit(code, a)
+	})
+	if ferr := flush(); err == nil {
+		err = ferr
+	}
+	fmt.Fprintf(os.Stderr, "iata lookup: %d resolved, %d not found\n", resolved, missing)
+	return err
+}
+
+// readCodes calls fn for every code in r: one per line, or from the given
+// column of a CSV with a header row.
+func readCodes(r io.Reader, column string, fn func(code string) error) error {
+	if column == "" {
+		sc := bufio.NewScanner(r)
+		for sc.Scan() {
+			code := strings.TrimSpace(sc.Text())
+			if code == "" || strings.HasPrefix(code, "#") {
+				continue
+			}
+			if err := fn(code); err != nil {
+				return err
+			}
+		}
+		return sc.Err()
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	idx, err := columnIndex(header, column)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		code := ""
+		if idx < len(rec) {
+			code = strings.TrimSpace(rec[idx])
+		}
+		if err := fn(code); err != nil {
+			return err
+		}
+	}
+}
+
+// columnIndex finds column, a header name or a 1-based index, in header.
+func columnIndex(header []string, column string) (int, error) {
+	idx := -1
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), column) {
+			idx = i
+		}
+	}
+	if n, err := strconv.Atoi(column); idx < 0 && err == nil && n >= 1 && n <= len(header) {
+		idx = n - 1
+	}
+	if idx < 0 {
+		return 0, fmt.Errorf("column %q not in header", column)
+	}
+	return idx, nil
+}
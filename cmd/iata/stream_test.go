@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLookupStream(t *testing.T) {
+	useData(t, testCSV)
+
+	stdout, stderr, err := run(t, "lhr\n\n# comment\nXXX\n  JFK \n", "lookup", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		Query   string
+		Found   bool
+		Airport *struct {
+			ICAOCode string `json:"icao_code"`
+		}
+	}
+	var results []result
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		var r result
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		results = append(results, r)
+	}
+	if len(results) != 3 ||
+		results[0].Query != "lhr" || results[0].Airport.ICAOCode != "EGLL" ||
+		results[1].Query != "XXX" || results[1].Found || results[1].Airport != nil ||
+		results[2].Query != "JFK" || !results[2].Found {
+		t.Errorf("results:\n%s", stdout)
+	}
+	if stderr != "iata lookup: 2 resolved, 1 not found\n" {
+		t.Errorf("stderr %q", stderr)
+	}
+}
+
+func TestLookupStreamCSV(t *testing.T) {
+	useData(t, testCSV)
+	in := "flight,origin,dest\nBA117,LHR,JFK\nAF276,CDG,HND\nXX1,,ZZZ\n"
+
+	stdout, _, err := run(t, in, "lookup", "-", "--column", "dest", "--format", "csv", "--fields", "iata_code,municipality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "query,iata_code,municipality\nJFK,JFK,New York\nHND,HND,Tokyo\nZZZ,,\n"
+	if stdout != want {
+		t.Errorf("output %q, want %q", stdout, want)
+	}
+	// Columns can be given by 1-based index.
+	stdout, _, err = run(t, in, "lookup", "-", "--column", "2", "--format", "csv", "--fields", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "query,name\nLHR,London Heathrow Airport\nCDG,Charles de Gaulle International Airport\n,\n"
+	if stdout != want {
+		t.Errorf("output %q, want %q", stdout, want)
+	}
+
+	for _, args := range [][]string{
+		{"lookup", "-", "--column", "arrival"},
+		{"lookup", "-", "--format", "xml"},
+		{"lookup", "-", "--format", "csv", "--fields", "iata_code,runways"},
+	} {
+		if _, _, err := run(t, in, args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runSubset(args []string) error {
+	fs, dataPath := newFlagSet("subset", "[flags] [FILE]")
+	continent := fs.String("continent", "", "comma-separated continent codes to keep, e.g. EU")
+	country := fs.String("country", "", "comma-separated ISO country codes to keep")
+	typ := fs.String("type", "", "comma-separated airport types to keep, e.g. large_airport,medium_airport")
+	scheduled := fs.Bool("scheduled", false, "only airports with scheduled service")
+	iataOnly := fs.Bool("iata-only", false, "only rows with an IATA code")
+	out := fs.String("o", "-", "output file (- for stdout)")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		fs.Usage()
+		return errors.New("subset takes at most one input file")
+	}
+	path := *dataPath
+	if len(files) == 1 {
+		path = files[0]
+	}
+
+	subset := iataplaces.Subset{
+		Continents: splitList(*continent),
+		Countries:  splitList(*country),
+		Types:      splitList(*typ),
+		Scheduled:  *scheduled,
+		IATAOnly:   *iataOnly,
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return writeOutput(*out, func(w io.Writer) error {
+		kept, err := iataplaces.WriteSubset(w, f, subset)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "kept %d rows (%s)\n", kept, subset)
+		return nil
+	})
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubset(t *testing.T) {
+	path := useData(t, testCSV+noIATARow)
+
+	stdout, stderr, err := run(t, "", "subset", "--country", "gb,fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 5 || lines[0] != strings.SplitN(testCSV, "\n", 2)[0] || !strings.Contains(stdout, "Denham") {
+		t.Errorf("subset:\n%s", stdout)
+	}
+	if !strings.HasPrefix(stderr, "kept 4 rows (") {
+		t.Errorf("stderr %q", stderr)
+	}
+
+	out := filepath.Join(t.TempDir(), "eu.csv")
+	if _, _, err := run(t, "", "subset", path, "--continent", "EU", "--iata-only", "-o", out); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(b), "\n"); got != 4 || strings.Contains(string(b), "Denham") {
+		t.Errorf("--iata-only subset has %d lines:\n%s", got, b)
+	}
+
+	for _, args := range [][]string{
+		{"subset", path, path},
+		{"subset", filepath.Join(t.TempDir(), "missing.csv")},
+	} {
+		if _, _, err := run(t, "", args...); err == nil {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+func runValidate(args []string) error {
+	fs, dataPath := newFlagSet("validate", "[flags] [FILE]")
+	strict := fs.Bool("strict", false, "treat warnings as failures")
+	show := fs.Int("show", 20, "maximum number of issues to print (0 for all)")
+	asJSON := fs.Bool("json", false, "print the full report as JSON")
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		fs.Usage()
+		return errors.New("validate takes at most one file")
+	}
+	path := *dataPath
+	if len(files) == 1 {
+		path = files[0]
+	}
+
+	report, err := iataplaces.ValidateFile(path)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := writeJSON(os.Stdout, report); err != nil {
+			return err
+		}
+	} else {
+		printReport(path, report, *show)
+	}
+
+	if report.Errors() > 0 || (*strict && report.Warnings() > 0) {
+		return errReported
+	}
+	return nil
+}
+
+func printReport(path string, report *iataplaces.QualityReport, show int) {
+	fmt.Printf("%s: %d rows, %d with IATA codes\n", path, report.Rows, report.IATAAirports)
+	for _, col := range report.MissingColumns {
+		fmt.Printf("  error: missing required column %q\n", col)
+	}
+	for i, is := range report.Issues {
+		if show > 0 && i == show {
+			fmt.Printf("  ... and %d more\n", len(report.Issues)-show)
+			break
+		}
+		code := ""
+		if is.IATACode != "" {
+			code = " " + is.IATACode
+		}
+		fmt.Printf("  %s: line %d%s: %s: %s\n", is.Severity, is.Line, code, is.Field, is.Message)
+	}
+	fmt.Printf("%d errors, %d warnings\n", report.Errors(), report.Warnings())
+}
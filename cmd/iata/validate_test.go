@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	path := useData(t, testCSV)
+
+	stdout, _, err := run(t, "", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := path + ": 5 rows, 5 with IATA codes\n0 errors, 0 warnings\n"; stdout != want {
+		t.Errorf("output %q, want %q", stdout, want)
+	}
+
+	// A duplicate code is a warning, failing only with --strict; a
+	// latitude out of range is an error.
+	dir := t.TempDir()
+	warn := filepath.Join(dir, "warn.csv")
+	dup := strings.Replace(testCSV, ",LGW,", ",LHR,", 1)
+	bad := filepath.Join(dir, "bad.csv")
+	for file, content := range map[string]string{warn: dup, bad: strings.Replace(testCSV, "51.4706", "151.4706", 1)} {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stdout, _, err = run(t, "", "validate", warn)
+	if err != nil || !strings.Contains(stdout, "warning: line 3 LHR: iata_code: duplicate IATA code") {
+		t.Errorf("duplicate code: %v\n%s", err, stdout)
+	}
+	if _, _, err := run(t, "", "validate", "--strict", warn); !errors.Is(err, errReported) {
+		t.Errorf("--strict with a warning: err = %v", err)
+	}
+
+	stdout, _, err = run(t, "", "validate", "--json", bad)
+	if !errors.Is(err, errReported) {
+		t.Errorf("out-of-range latitude: err = %v", err)
+	}
+	var report struct {
+		Issues []struct {
+			Severity, Field string
+			Line            int
+		}
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("%v: %s", err, stdout)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Severity != "error" || report.Issues[0].Field != "latitude_deg" || report.Issues[0].Line != 2 {
+		t.Errorf("issues %+v", report.Issues)
+	}
+
+	if _, _, err := run(t, "", "validate", warn, bad); err == nil {
+		t.Error("two files accepted")
+	}
+}
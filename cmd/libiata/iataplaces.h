@@ -0,0 +1,46 @@
+/*
+ * C API of libiata, the iata-lookup-places store as a shared library.
+ * Build it with: go build -buildmode=c-shared -o libiata.so ./cmd/libiata
+ *
+ * Functions returning char* hand back a NUL-terminated UTF-8 JSON string
+ * owned by the caller, to be released with iata_free, or NULL when there is
+ * nothing to return. All functions are safe to call from several threads.
+ */
+#ifndef IATAPLACES_H
+#define IATAPLACES_H
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+/* Loads the airports CSV at path, replacing any loaded store. NULL or ""
+ * uses $AIRPORTS_CSV_PATH, then data/airports-latest.csv. Returns 0 on
+ * success and -1 on failure (see iata_last_error). */
+int iata_load(const char *path);
+
+/* The airport with the given IATA code as a JSON object, or NULL. */
+char *iata_lookup(const char *code);
+
+/* A JSON array of airports matching text, best first. limit <= 0 returns
+ * every match. */
+char *iata_search(const char *text, int limit);
+
+/* A JSON array of the n airports nearest to the point, each with an added
+ * distance_km field. n <= 0 returns every airport. */
+char *iata_nearest(double lat, double lon, int n);
+
+/* Great-circle distance in kilometres between two airports, or -1 if
+ * either code is unknown. */
+double iata_distance_km(const char *from, const char *to);
+
+/* A description of the last error, or NULL. */
+char *iata_last_error(void);
+
+/* Releases a string returned by this library. */
+void iata_free(char *p);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
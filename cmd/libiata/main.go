@@ -0,0 +1,155 @@
+// Command libiata builds the library as a C shared library, so services in
+// other languages can reuse the store through FFI:
+//
+//	go build -buildmode=c-shared -o libiata.so ./cmd/libiata
+//
+// iataplaces.h declares the stable API. Results are JSON strings in the
+// same shape as the HTTP API; the caller releases them with iata_free.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"unsafe"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+var (
+	mu      sync.RWMutex
+	store   *iataplaces.Store
+	lastErr string
+)
+
+func setError(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	lastErr = err.Error()
+}
+
+func currentStore() *iataplaces.Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return store
+}
+
+// jsonString returns v as a C string for the caller to free, or NULL.
+func jsonString(v any) *C.char {
+	b, err := json.Marshal(v)
+	if err != nil {
+		setError(err)
+		return nil
+	}
+	return C.CString(string(b))
+}
+
+//export iata_load
+func iata_load(path *C.char) C.int {
+	p := ""
+	if path != nil {
+		p = C.GoString(path)
+	}
+	if p == "" {
+		p = os.Getenv("AIRPORTS_CSV_PATH")
+	}
+	if p == "" {
+		p = "data/airports-latest.csv"
+	}
+	s, err := iataplaces.LoadFromFile(p)
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	mu.Lock()
+	store = s
+	mu.Unlock()
+	return 0
+}
+
+//export iata_lookup
+func iata_lookup(code *C.char) *C.char {
+	s := currentStore()
+	if s == nil || code == nil {
+		return nil
+	}
+	a, ok := s.LookupIATA(C.GoString(code))
+	if !ok {
+		return nil
+	}
+	return jsonString(a)
+}
+
+//export iata_search
+func iata_search(text *C.char, limit C.int) *C.char {
+	s := currentStore()
+	if s == nil {
+		return nil
+	}
+	q := iataplaces.SearchQuery{Limit: int(limit)}
+	if text != nil {
+		q.Text = C.GoString(text)
+	}
+	results := s.Search(q)
+	out := make([]*iataplaces.Airport, len(results))
+	for i, r := range results {
+		out[i] = r.Airport
+	}
+	return jsonString(out)
+}
+
+// nearbyJSON is an airport with its distance, flattened into one object.
+type nearbyJSON struct {
+	*iataplaces.Airport
+	DistanceKm float64 `json:"distance_km"`
+}
+
+//export iata_nearest
+func iata_nearest(lat, lon C.double, n C.int) *C.char {
+	s := currentStore()
+	if s == nil {
+		return nil
+	}
+	hits := s.Nearest(float64(lat), float64(lon), int(n))
+	out := make([]nearbyJSON, len(hits))
+	for i, h := range hits {
+		out[i] = nearbyJSON{h.Airport, h.DistanceKm}
+	}
+	return jsonString(out)
+}
+
+//export iata_distance_km
+func iata_distance_km(from, to *C.char) C.double {
+	s := currentStore()
+	if s == nil || from == nil || to == nil {
+		return -1
+	}
+	d, err := s.Distance(C.GoString(from), C.GoString(to))
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	return C.double(d)
+}
+
+//export iata_last_error
+func iata_last_error() *C.char {
+	mu.RLock()
+	defer mu.RUnlock()
+	if lastErr == "" {
+		return nil
+	}
+	return C.CString(lastErr)
+}
+
+//export iata_free
+func iata_free(p *C.char) {
+	C.free(unsafe.Pointer(p))
+}
+
+func main() {}
@@ -0,0 +1,25 @@
+// Package data embeds the airports-latest.csv snapshot committed with the
+// module, for programs that must work without the file on disk: the
+// WebAssembly build, mobile bindings and other self-contained binaries.
+// Importing it adds the whole CSV to the binary.
+//
+// The file must stay a regular file for go:embed, so don't refresh this
+// directory with airports-update -latest-mode symlink.
+package data
+
+import (
+	"bytes"
+	_ "embed"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// AirportsCSV is the embedded OurAirports airports.csv.
+//
+//go:embed airports-latest.csv
+var AirportsCSV []byte
+
+// Load parses the embedded dataset into a new store.
+func Load() (*iataplaces.Store, error) {
+	return iataplaces.LoadFromReader(bytes.NewReader(AirportsCSV))
+}
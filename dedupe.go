@@ -0,0 +1,86 @@
+package iataplaces
+
+import "sort"
+
+// duplicateSet collects airports sharing an IATA code while a store is
+// built, keeping the preferred one indexed and the rest aside.
+type duplicateSet struct {
+	byIATA map[string]*Airport
+	losers map[string][]*Airport
+}
+
+// add indexes a unless an airport already holding its code is preferred.
+func (d *duplicateSet) add(a *Airport) {
+	code := a.IATACode
+	existing, ok := d.byIATA[code]
+	if !ok {
+		d.byIATA[code] = a
+		return
+	}
+	if d.losers == nil {
+		d.losers = make(map[string][]*Airport)
+	}
+	if preferAirport(a, existing) {
+		d.byIATA[code] = a
+		a = existing
+	}
+	d.losers[code] = append(d.losers[code], a)
+}
+
+// store builds the store, with each code's losing rows best first.
+func (d *duplicateSet) store() *Store {
+	for _, losers := range d.losers {
+		sort.SliceStable(losers, func(i, j int) bool { return preferAirport(losers[i], losers[j]) })
+	}
+	s := newStore(d.byIATA)
+	s.duplicates = d.losers
+	return s
+}
+
+// preferAirport reports whether a should hold an IATA code over b: open
+// beats closed, then scheduled service, a higher score and a larger airport
+// type win in that order. Ties keep b, the row seen first.
+func preferAirport(a, b *Airport) bool {
+	if ao, bo := a.Type != "closed", b.Type != "closed"; ao != bo {
+		return ao
+	}
+	if a.Scheduled != b.Scheduled {
+		return a.Scheduled
+	}
+	if as, bs := a.ScoreOr(0), b.ScoreOr(0); as != bs {
+		return as > bs
+	}
+	return typeRank(a.Type) > typeRank(b.Type)
+}
+
+// typeRank orders airport types by size.
+func typeRank(t string) int {
+	switch t {
+	case "large_airport":
+		return 3
+	case "medium_airport":
+		return 2
+	case "small_airport":
+		return 1
+	}
+	return 0
+}
+
+// Duplicates returns the other rows that carried code in the source data
+// but lost to the indexed airport, best first.
+func (s *Store) Duplicates(code string) []*Airport {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	losers := s.duplicates[normalizeCode(code)]
+	if len(losers) == 0 {
+		return nil
+	}
+	out := make([]*Airport, len(losers))
+	for i, a := range losers {
+		out[i] = s.out(a)
+	}
+	return out
+}
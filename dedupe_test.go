@@ -0,0 +1,130 @@
+package iataplaces
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const dupHeader = "id,ident,type,name,latitude_deg,longitude_deg,scheduled_service,iata_code,score\n"
+
+// loadDupStore loads rows of dupHeader's columns.
+func loadDupStore(t *testing.T, rows ...string) *Store {
+	t.Helper()
+	s, err := LoadFromReader(strings.NewReader(dupHeader + strings.Join(rows, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func identsOf(airports []*Airport) []string {
+	var idents []string
+	for _, a := range airports {
+		idents = append(idents, a.Ident)
+	}
+	return idents
+}
+
+func TestDuplicateResolution(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       []string
+		winner     string
+		duplicates []string
+	}{
+		{
+			name: "open beats closed",
+			rows: []string{
+				"1,OLD1,closed,Old Field,1,1,1,QQA,5000",
+				"2,NEW1,small_airport,New Field,1,1,0,QQA,",
+			},
+			winner:     "NEW1",
+			duplicates: []string{"OLD1"},
+		},
+		{
+			name: "scheduled beats score",
+			rows: []string{
+				"1,BIG1,large_airport,Big Field,1,1,0,QQA,5000",
+				"2,SCH1,small_airport,Scheduled Field,1,1,1,QQA,10",
+			},
+			winner:     "SCH1",
+			duplicates: []string{"BIG1"},
+		},
+		{
+			name: "higher score",
+			rows: []string{
+				"1,LOW1,large_airport,Low Field,1,1,1,QQA,5",
+				"2,HIGH,small_airport,High Field,1,1,1,QQA,50",
+			},
+			winner:     "HIGH",
+			duplicates: []string{"LOW1"},
+		},
+		{
+			name: "larger type",
+			rows: []string{
+				"1,SML1,small_airport,Small Field,1,1,0,QQA,",
+				"2,HELI,heliport,Helipad,1,1,0,QQA,",
+				"3,MED1,medium_airport,Medium Field,1,1,0,QQA,",
+			},
+			winner:     "MED1",
+			duplicates: []string{"SML1", "HELI"},
+		},
+		{
+			name: "tie keeps the first row",
+			rows: []string{
+				"1,FST1,small_airport,First Field,1,1,0,QQA,7",
+				"2,SND1,small_airport,Second Field,1,1,0,QQA,7",
+				"3,TRD1,small_airport,Third Field,1,1,0,QQA,7",
+			},
+			winner:     "FST1",
+			duplicates: []string{"SND1", "TRD1"},
+		},
+		{
+			name: "losers best first",
+			rows: []string{
+				"1,CLS1,closed,Closed Field,1,1,0,QQA,",
+				"2,SML1,small_airport,Small Field,1,1,0,QQA,",
+				"3,WIN1,large_airport,Winner,1,1,1,QQA,100",
+				"4,SCH1,small_airport,Scheduled Field,1,1,1,QQA,",
+			},
+			winner:     "WIN1",
+			duplicates: []string{"SCH1", "SML1", "CLS1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := loadDupStore(t, append(tt.rows, "9,OTHR,small_airport,Other,1,1,0,QQB,")...)
+			a, ok := s.LookupIATA("QQA")
+			if !ok || a.Ident != tt.winner {
+				t.Fatalf("QQA is held by %v, want %s", a, tt.winner)
+			}
+			if got := identsOf(s.Duplicates("qqa ")); !slices.Equal(got, tt.duplicates) {
+				t.Errorf("Duplicates = %v, want %v", got, tt.duplicates)
+			}
+			if got := s.Duplicates("QQB"); got != nil {
+				t.Errorf("Duplicates(QQB) = %v, want nil", identsOf(got))
+			}
+			if s.Len() != 2 {
+				t.Errorf("Len = %d, want 2", s.Len())
+			}
+		})
+	}
+}
+
+func TestDuplicatesAfterChanges(t *testing.T) {
+	s := loadDupStore(t,
+		"1,WIN1,large_airport,Winner,1,1,1,QQA,100",
+		"2,LOS1,small_airport,Loser,1,1,0,QQA,",
+	)
+	snap := s.Snapshot()
+	if _, err := s.Remove("QQA"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Duplicates("QQA"); got != nil {
+		t.Errorf("Duplicates after Remove = %v", identsOf(got))
+	}
+	if got := identsOf(snap.Duplicates("QQA")); !slices.Equal(got, []string{"LOS1"}) {
+		t.Errorf("snapshot Duplicates = %v", got)
+	}
+}
@@ -0,0 +1,221 @@
+package iataplaces
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+)
+
+// Delta is a batch of changes to apply to a store: airports to insert or
+// replace, and IATA codes to remove.
+type Delta struct {
+	Upsert []*Airport `json:"upsert,omitempty"`
+	Remove []string   `json:"remove,omitempty"`
+}
+
+// ChangeSet lists the IATA codes affected by a change, each slice sorted.
+type ChangeSet struct {
+	Added   []string `json:"added"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// Empty reports whether nothing changed.
+func (c ChangeSet) Empty() bool {
+	return len(c.Added) == 0 && len(c.Changed) == 0 && len(c.Removed) == 0
+}
+
+// ApplyDelta applies d to the store in one step: readers see either none of
+// it or all of it. The delta is checked first and rejected as a whole when
+// a code is invalid or appears more than once. Upserts identical to the
+// stored airport and removals of unknown codes are no-ops, so replaying a
+// change feed is harmless.
+func (s *Store) ApplyDelta(d Delta) (ChangeSet, error) {
+	var cs ChangeSet
+	seen := make(map[string]bool, len(d.Upsert)+len(d.Remove))
+	upserts := make([]*Airport, 0, len(d.Upsert))
+	for _, a := range d.Upsert {
+		if a == nil {
+			return cs, fmt.Errorf("iataplaces: delta: nil airport")
+		}
+		code := toUpperASCII(a.IATACode)
+		if !isIATACode(code) {
+			return cs, fmt.Errorf("iataplaces: delta: invalid IATA code %q", a.IATACode)
+		}
+		if seen[code] {
+			return cs, fmt.Errorf("iataplaces: delta: %s appears more than once", code)
+		}
+		seen[code] = true
+		c := a.Clone()
+		c.IATACode = code
+		upserts = append(upserts, c)
+	}
+	removes := make([]string, 0, len(d.Remove))
+	for _, code := range d.Remove {
+		code = toUpperASCII(code)
+		if seen[code] {
+			return cs, fmt.Errorf("iataplaces: delta: %s appears more than once", code)
+		}
+		seen[code] = true
+		removes = append(removes, code)
+	}
+
+	s.mu.Lock()
+	if err := s.writable(); err != nil {
+		s.mu.Unlock()
+		return cs, err
+	}
+	for _, c := range upserts {
+		old, ok := s.byIATA[c.IATACode]
+		switch {
+		case !ok:
+			cs.Added = append(cs.Added, c.IATACode)
+		case !old.Equal(c):
+			cs.Changed = append(cs.Changed, c.IATACode)
+		default:
+			continue
+		}
+		s.put(c)
+	}
+	for _, code := range removes {
+		if s.remove(code) {
+			cs.Removed = append(cs.Removed, code)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(cs.Added)
+	sort.Strings(cs.Changed)
+	sort.Strings(cs.Removed)
+	s.notify(ChangeDelta, cs)
+	return cs, nil
+}
+
+// ChangeKind says what caused a ChangeEvent.
+type ChangeKind string
+
+const (
+	ChangeReload ChangeKind = "reload" // Replace swapped in a new dataset
+	ChangeDelta  ChangeKind = "delta"  // ApplyDelta, Put or Remove
+)
+
+// ChangeEvent is delivered to subscribers after the store changed.
+type ChangeEvent struct {
+	Kind ChangeKind `json:"kind"`
+	ChangeSet
+}
+
+// Subscribe registers fn to be called after every change to the store
+// that added, changed or removed an airport, so caches keyed by airport can
+// invalidate just those entries. fn runs in the goroutine that made the
+// change, after the store is unlocked, so it may read the store but should
+// return quickly. The returned function unsubscribes.
+func (s *Store) Subscribe(fn func(ChangeEvent)) (unsubscribe func()) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[int]func(ChangeEvent))
+	}
+	id := s.nextSub
+	s.nextSub++
+	s.subs[id] = fn
+	return func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+// notify delivers a change to the subscribers. s.mu must not be held.
+func (s *Store) notify(kind ChangeKind, cs ChangeSet) {
+	if cs.Empty() {
+		return
+	}
+	s.subMu.Lock()
+	ids := make([]int, 0, len(s.subs))
+	for id := range s.subs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	fns := make([]func(ChangeEvent), len(ids))
+	for i, id := range ids {
+		fns[i] = s.subs[id]
+	}
+	s.subMu.Unlock()
+
+	ev := ChangeEvent{Kind: kind, ChangeSet: cs}
+	for _, fn := range fns {
+		fn(ev)
+	}
+}
+
+// Replace swaps the contents of next into the store in one step, so a
+// long-lived store can take a full refresh while subscribers learn which
+// airports changed. next is not modified and may be discarded afterwards.
+func (s *Store) Replace(next *Store) (ChangeSet, error) {
+	var cs ChangeSet
+	if next == nil {
+		return cs, errors.New("iataplaces: nil store")
+	}
+	next.mu.RLock()
+	byIATA := make(map[string]*Airport, len(next.byIATA))
+	for code, a := range next.byIATA {
+		byIATA[code] = a
+	}
+	fresh := newStore(byIATA)
+	sourceRows, loadIssues := next.sourceRows, next.loadIssues
+	localized := cloneLocalized(next.localized)
+	duplicates := cloneDuplicates(next.duplicates)
+	cities := next.cities
+	lazy := next.lazy
+	keywords := next.keywords.clone()
+	popularity := maps.Clone(next.popularity)
+	routes, airlines := next.routes, next.airlines
+	next.mu.RUnlock()
+
+	s.mu.Lock()
+	if err := s.writable(); err != nil {
+		s.mu.Unlock()
+		return cs, err
+	}
+	for code, old := range s.byIATA {
+		a, ok := byIATA[code]
+		switch {
+		case !ok:
+			cs.Removed = append(cs.Removed, code)
+		case !old.Equal(a):
+			cs.Changed = append(cs.Changed, code)
+		}
+	}
+	for code := range byIATA {
+		if _, ok := s.byIATA[code]; !ok {
+			cs.Added = append(cs.Added, code)
+		}
+	}
+	s.byIATA, s.byICAO, s.bySlug, s.sorted = fresh.byIATA, fresh.byICAO, fresh.bySlug, fresh.sorted
+	s.sourceRows = sourceRows
+	s.loadIssues = loadIssues
+	s.localized = localized
+	s.duplicates = duplicates
+	s.cities = cities
+	s.lazy = lazy
+	s.keywords = keywords
+	for name, idx := range s.indexes {
+		s.indexes[name] = newKeyIndex(idx.keys, s.sorted)
+	}
+	s.spatial = s.spatial.rebuild(s.sorted)
+	s.icaoList = s.icaoList.rebuild(s.sorted)
+	if s.fullText != nil {
+		s.fullText = newKeyIndex(airportTrigrams, s.sorted)
+	}
+	s.popularity = popularity
+	s.routes, s.airlines = routes, airlines
+	s.mu.Unlock()
+
+	sort.Strings(cs.Added)
+	sort.Strings(cs.Changed)
+	sort.Strings(cs.Removed)
+	s.notify(ChangeReload, cs)
+	return cs, nil
+}
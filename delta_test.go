@@ -0,0 +1,275 @@
+package iataplaces
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestApplyDelta(t *testing.T) {
+	lhr := func(name string) *Airport {
+		a, _ := loadTestStore(t).LookupIATA("LHR")
+		a = a.Clone()
+		a.Name = name
+		return a
+	}
+	tests := []struct {
+		name  string
+		delta Delta
+		want  ChangeSet
+		err   string
+	}{
+		{
+			name:  "empty",
+			delta: Delta{},
+		},
+		{
+			name:  "add",
+			delta: Delta{Upsert: []*Airport{testAirport("qqq", "New")}},
+			want:  ChangeSet{Added: []string{"QQQ"}},
+		},
+		{
+			name:  "change",
+			delta: Delta{Upsert: []*Airport{lhr("Heathrow")}},
+			want:  ChangeSet{Changed: []string{"LHR"}},
+		},
+		{
+			name:  "identical upsert",
+			delta: Delta{Upsert: []*Airport{lhr("London Heathrow Airport")}},
+		},
+		{
+			name:  "remove",
+			delta: Delta{Remove: []string{"ory", "JFK"}},
+			want:  ChangeSet{Removed: []string{"JFK", "ORY"}},
+		},
+		{
+			name:  "remove unknown",
+			delta: Delta{Remove: []string{"QQQ"}},
+		},
+		{
+			name: "mixed",
+			delta: Delta{
+				Upsert: []*Airport{testAirport("QQB", "B"), testAirport("QQA", "A"), lhr("Heathrow")},
+				Remove: []string{"MUC"},
+			},
+			want: ChangeSet{Added: []string{"QQA", "QQB"}, Changed: []string{"LHR"}, Removed: []string{"MUC"}},
+		},
+		{
+			name:  "invalid code",
+			delta: Delta{Upsert: []*Airport{testAirport("QQQ", "New"), testAirport("Q1", "Bad")}},
+			err:   `invalid IATA code "Q1"`,
+		},
+		{
+			name:  "nil airport",
+			delta: Delta{Upsert: []*Airport{nil}},
+			err:   "nil airport",
+		},
+		{
+			name:  "upserted twice",
+			delta: Delta{Upsert: []*Airport{testAirport("QQQ", "A"), testAirport("qqq", "B")}},
+			err:   "QQQ appears more than once",
+		},
+		{
+			name:  "upserted and removed",
+			delta: Delta{Upsert: []*Airport{lhr("Heathrow")}, Remove: []string{"lhr"}},
+			err:   "LHR appears more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := loadTestStore(t)
+			var events []ChangeEvent
+			s.Subscribe(func(ev ChangeEvent) { events = append(events, ev) })
+
+			cs, err := s.ApplyDelta(tt.delta)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want one mentioning %q", err, tt.err)
+				}
+				if got := codesOf(s.All()); !slices.Equal(got, testCodes) {
+					t.Errorf("rejected delta changed the store: %v", got)
+				}
+				if len(events) != 0 {
+					t.Errorf("rejected delta notified %v", events)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cs, tt.want) {
+				t.Errorf("ChangeSet = %+v, want %+v", cs, tt.want)
+			}
+
+			switch {
+			case tt.want.Empty() && len(events) != 0:
+				t.Errorf("no-op delta notified %v", events)
+			case !tt.want.Empty() && (len(events) != 1 || !reflect.DeepEqual(events[0], ChangeEvent{Kind: ChangeDelta, ChangeSet: tt.want})):
+				t.Errorf("events = %+v, want one delta event with %+v", events, tt.want)
+			}
+
+			for _, code := range append(tt.want.Added, tt.want.Changed...) {
+				if _, ok := s.LookupIATA(code); !ok {
+					t.Errorf("%s missing after delta", code)
+				}
+			}
+			for _, code := range tt.want.Removed {
+				if _, ok := s.LookupIATA(code); ok {
+					t.Errorf("%s present after delta", code)
+				}
+			}
+			if got, want := s.Len(), len(testCodes)+len(tt.want.Added)-len(tt.want.Removed); got != want {
+				t.Errorf("Len() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := loadTestStore(t)
+	var got []ChangeEvent
+	unsubscribe := s.Subscribe(func(ev ChangeEvent) { got = append(got, ev) })
+
+	if err := s.Put(testAirport("QQQ", "New")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(testAirport("QQQ", "New")); err != nil { // unchanged
+		t.Fatal(err)
+	}
+	if _, err := s.Remove("QQQ"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Remove("QQQ"); err != nil { // already gone
+		t.Fatal(err)
+	}
+	unsubscribe()
+	if err := s.Put(testAirport("QQR", "New")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ChangeEvent{
+		{Kind: ChangeDelta, ChangeSet: ChangeSet{Added: []string{"QQQ"}}},
+		{Kind: ChangeDelta, ChangeSet: ChangeSet{Removed: []string{"QQQ"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	s := loadTestStore(t)
+	if err := s.WarmUp(context.Background(), IndexSpatial, IndexICAOPrefix, IndexFullText); err != nil {
+		t.Fatal(err)
+	}
+	next := loadTestStore(t)
+	if _, err := next.ApplyDelta(Delta{
+		Upsert: []*Airport{testAirport("QQQ", "New")},
+		Remove: []string{"NRT"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	hnd, _ := next.LookupIATA("HND")
+	hnd = hnd.Clone()
+	hnd.Name = "Haneda"
+	if err := next.Put(hnd); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []ChangeEvent
+	s.Subscribe(func(ev ChangeEvent) { events = append(events, ev) })
+	cs, err := s.Replace(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ChangeSet{Added: []string{"QQQ"}, Changed: []string{"HND"}, Removed: []string{"NRT"}}
+	if !reflect.DeepEqual(cs, want) {
+		t.Errorf("ChangeSet = %+v, want %+v", cs, want)
+	}
+	if len(events) != 1 || events[0].Kind != ChangeReload {
+		t.Errorf("events = %+v, want one reload", events)
+	}
+
+	if got, want := codesOf(s.All()), codesOf(next.All()); !slices.Equal(got, want) {
+		t.Errorf("codes = %v, want %v", got, want)
+	}
+	if got := searchCodes(s, "haneda"); !slices.Equal(got, []string{"HND"}) {
+		t.Errorf(`Search("haneda") = %v after Replace`, got)
+	}
+	if got := codesOf(s.ByICAOPrefix("RJ")); !slices.Equal(got, []string{"HND"}) {
+		t.Errorf(`ByICAOPrefix("RJ") = %v after Replace`, got)
+	}
+
+	// next is left alone and doesn't share indexes with s.
+	if _, err := s.Remove("HND"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := next.LookupIATA("HND"); !ok {
+		t.Error("removing from the replaced store removed from next")
+	}
+
+	events = nil
+	if _, err := s.Replace(nil); err == nil || !strings.Contains(err.Error(), "nil store") {
+		t.Errorf("Replace(nil) = %v, want an error", err)
+	}
+	if got, want := s.Len(), next.Len()-1; got != want || len(events) != 0 {
+		t.Errorf("Replace(nil) left %d airports and sent %d events", got, len(events))
+	}
+}
+
+// TestApplyDeltaConcurrent runs deltas against readers; run it with -race.
+// Readers must see each delta whole: QQA and QQB are always added and
+// removed together.
+func TestApplyDeltaConcurrent(t *testing.T) {
+	s := loadTestStore(t)
+	pair := Delta{Upsert: []*Airport{testAirport("QQA", "A"), testAirport("QQB", "B")}}
+	unpair := Delta{Remove: []string{"QQA", "QQB"}}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 4)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snap := s.Snapshot()
+				_, a := snap.LookupIATA("QQA")
+				_, b := snap.LookupIATA("QQB")
+				if a != b {
+					errs <- fmt.Errorf("saw half a delta: QQA %v, QQB %v", a, b)
+					return
+				}
+				s.Search(SearchQuery{Text: "london", Limit: 5})
+				s.Nearest(51.5, 0, 3)
+			}
+		}()
+	}
+	for i := 0; i < 500; i++ {
+		d := pair
+		if i%2 == 1 {
+			d = unpair
+		}
+		if _, err := s.ApplyDelta(d); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if got := codesOf(s.All()); !slices.Equal(got, testCodes) {
+		t.Errorf("codes = %v, want %v", got, testCodes)
+	}
+}
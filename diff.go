@@ -0,0 +1,109 @@
+package iataplaces
+
+// FieldChange is one column whose value differs between two versions of
+// an airport. Values use the CSV representation.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// AirportChange describes an airport present in both datasets with at
+// least one changed field.
+type AirportChange struct {
+	IATACode string        `json:"iata_code"`
+	Old      *Airport      `json:"-"`
+	New      *Airport      `json:"-"`
+	Fields   []FieldChange `json:"fields"`
+}
+
+// StoreDiff lists the differences between two stores, each slice ordered
+// by IATA code.
+type StoreDiff struct {
+	Added   []*Airport      `json:"added"`
+	Removed []*Airport      `json:"removed"`
+	Changed []AirportChange `json:"changed"`
+}
+
+// Empty reports whether the two stores were identical.
+func (d *StoreDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Delta returns the changes that turn the before store into the after
+// one, for ApplyDelta: added and changed airports are upserted in full,
+// removed ones are removed by code.
+func (d *StoreDiff) Delta() Delta {
+	var out Delta
+	for _, a := range d.Added {
+		out.Upsert = append(out.Upsert, a)
+	}
+	for _, c := range d.Changed {
+		out.Upsert = append(out.Upsert, c.New)
+	}
+	for _, a := range d.Removed {
+		out.Remove = append(out.Remove, a.IATACode)
+	}
+	return out
+}
+
+// DiffStores compares two stores by IATA code.
+func DiffStores(before, after *Store) *StoreDiff {
+	d := &StoreDiff{
+		Added:   []*Airport{},
+		Removed: []*Airport{},
+		Changed: []AirportChange{},
+	}
+
+	for _, a := range before.All() {
+		b, ok := after.LookupIATA(a.IATACode)
+		if !ok {
+			d.Removed = append(d.Removed, a)
+			continue
+		}
+		if fields := a.Diff(b); len(fields) > 0 {
+			d.Changed = append(d.Changed, AirportChange{
+				IATACode: a.IATACode,
+				Old:      a,
+				New:      b,
+				Fields:   fields,
+			})
+		}
+	}
+	for _, b := range after.All() {
+		if _, ok := before.LookupIATA(b.IATACode); !ok {
+			d.Added = append(d.Added, b)
+		}
+	}
+	return d
+}
+
+// Diff compares a with other column by column, in CSVColumns order, and
+// returns the columns that differ with a's value as Old. Values are
+// compared in their CSV form, so timestamps match to the second. A nil
+// airport compares like one with every field empty.
+func (a *Airport) Diff(other *Airport) []FieldChange {
+	if a == nil {
+		a = &Airport{}
+	}
+	if other == nil {
+		other = &Airport{}
+	}
+	ra, rb := csvRecord(a), csvRecord(other)
+	var out []FieldChange
+	for i, col := range CSVColumns {
+		if ra[i] != rb[i] {
+			out = append(out, FieldChange{Field: col, Old: ra[i], New: rb[i]})
+		}
+	}
+	return out
+}
+
+// Equal reports whether a and other have the same value in every CSV
+// column, as Diff compares them. Two nil airports are equal.
+func (a *Airport) Equal(other *Airport) bool {
+	if a == nil || other == nil {
+		return a == other
+	}
+	return len(a.Diff(other)) == 0
+}
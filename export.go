@@ -0,0 +1,172 @@
+package iataplaces
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVColumns is the OurAirports airports.csv header, in order. WriteCSV
+// emits exactly these columns, so its output loads back with
+// LoadFromReader.
+var CSVColumns = []string{
+	"id", "ident", "type", "name", "latitude_deg", "longitude_deg",
+	"elevation_ft", "continent", "country_name", "iso_country",
+	"region_name", "iso_region", "local_region", "municipality",
+	"scheduled_service", "gps_code", "icao_code", "iata_code", "local_code",
+	"home_link", "wikipedia_link", "keywords", "score", "last_updated",
+}
+
+// csvColumnIndex maps each CSVColumns name to its position.
+var csvColumnIndex = func() map[string]int {
+	m := make(map[string]int, len(CSVColumns))
+	for i, col := range CSVColumns {
+		m[col] = i
+	}
+	return m
+}()
+
+// FieldValue returns the CSV representation of the named column (one of
+// CSVColumns) for a.
+func (a *Airport) FieldValue(column string) (string, bool) {
+	i, ok := csvColumnIndex[column]
+	if !ok {
+		return "", false
+	}
+	return csvRecord(a)[i], true
+}
+
+// Filter returns the airports for which keep returns true, ordered by
+// IATA code.
+func (s *Store) Filter(keep func(*Airport) bool) []*Airport {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Airport
+	for _, a := range s.sorted {
+		if keep(a) {
+			out = append(out, s.out(a))
+		}
+	}
+	return out
+}
+
+// WriteCSV writes airports in the OurAirports CSV format.
+func WriteCSV(w io.Writer, airports []*Airport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, a := range airports {
+		if err := cw.Write(csvRecord(a)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRecord renders a in CSVColumns order.
+func csvRecord(a *Airport) []string {
+	sched := "0"
+	if a.Scheduled {
+		sched = "1"
+	}
+	return []string{
+		strconv.FormatInt(a.ID, 10),
+		a.Ident,
+		a.Type,
+		a.Name,
+		strconv.FormatFloat(a.LatitudeDeg, 'f', -1, 64),
+		strconv.FormatFloat(a.LongitudeDeg, 'f', -1, 64),
+		formatOptionalInt(a.ElevationFt),
+		a.Continent,
+		a.CountryName,
+		a.IsoCountry,
+		a.RegionName,
+		a.IsoRegion,
+		a.LocalRegion,
+		a.Municipality,
+		sched,
+		a.GPSCode,
+		a.ICAOCode,
+		a.IATACode,
+		a.LocalCode,
+		a.HomeLink,
+		a.WikipediaLink,
+		a.Keywords,
+		formatOptionalInt(a.Score),
+		formatOptionalTime(a.LastUpdateTime),
+	}
+}
+
+func formatOptionalInt(v *int64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(*v, 10)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// WriteJSON writes airports as a JSON array.
+func WriteJSON(w io.Writer, airports []*Airport) error {
+	if airports == nil {
+		airports = []*Airport{}
+	}
+	return json.NewEncoder(w).Encode(airports)
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties *Airport        `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON writes airports as a GeoJSON FeatureCollection of points,
+// with the airport fields as feature properties. Features are streamed,
+// so large selections don't need to be held in memory twice.
+func WriteGeoJSON(w io.Writer, airports []*Airport) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(`{"type":"FeatureCollection","features":[`); err != nil {
+		return err
+	}
+	for i, a := range airports {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		b, err := json.Marshal(geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONGeometry{
+				Type:        "Point",
+				Coordinates: [2]float64{a.LongitudeDeg, a.LatitudeDeg},
+			},
+			Properties: a,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := bw.Write(b); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.WriteString("]}\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,196 @@
+package iataplaces
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by LoadFromGob for files it can't use.
+var (
+	ErrSnapshotFormat   = errors.New("iataplaces: not an airports snapshot")
+	ErrSnapshotVersion  = errors.New("iataplaces: unsupported snapshot version")
+	ErrSnapshotChecksum = errors.New("iataplaces: snapshot checksum mismatch")
+)
+
+// The binary snapshot starts with a fixed header: snapshotMagic, the
+// format version and payload length as big-endian uint32 and uint64, and
+// the SHA-256 of the payload. The payload is a gob-encoded []Airport.
+// Bump snapshotVersion whenever the payload changes incompatibly.
+const (
+	snapshotMagic      = "IATAPLCS"
+	snapshotVersion    = 1
+	snapshotHeaderSize = len(snapshotMagic) + 4 + 8 + sha256.Size
+)
+
+// WriteGob writes airports as a versioned, checksummed binary snapshot,
+// a compact form that LoadFromGob reads back without CSV parsing.
+func WriteGob(w io.Writer, airports []*Airport) error {
+	vals := make([]Airport, len(airports))
+	for i, a := range airports {
+		vals[i] = *a
+	}
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(vals); err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, snapshotHeaderSize)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint32(header, snapshotVersion)
+	header = binary.BigEndian.AppendUint64(header, uint64(payload.Len()))
+	sum := sha256.Sum256(payload.Bytes())
+	header = append(header, sum[:]...)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := payload.WriteTo(w)
+	return err
+}
+
+// LoadFromGob builds a store from WriteGob output. Files from another
+// format version fail with ErrSnapshotVersion and truncated or corrupted
+// ones with ErrSnapshotChecksum, rather than loading partly.
+func LoadFromGob(r io.Reader) (*Store, error) {
+	header := make([]byte, snapshotHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: file too short", ErrSnapshotFormat)
+		}
+		return nil, err
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: bad magic (written before snapshots were versioned? regenerate it)", ErrSnapshotFormat)
+	}
+	rest := header[len(snapshotMagic):]
+	if v := binary.BigEndian.Uint32(rest); v != snapshotVersion {
+		return nil, fmt.Errorf("%w %d; this version of iataplaces reads %d", ErrSnapshotVersion, v, snapshotVersion)
+	}
+	size := binary.BigEndian.Uint64(rest[4:])
+	payload, err := io.ReadAll(io.LimitReader(r, int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(payload)) != size {
+		return nil, fmt.Errorf("%w: truncated to %d of %d bytes", ErrSnapshotChecksum, len(payload), size)
+	}
+	if sum := sha256.Sum256(payload); !bytes.Equal(sum[:], rest[12:]) {
+		return nil, ErrSnapshotChecksum
+	}
+
+	var vals []Airport
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&vals); err != nil {
+		return nil, fmt.Errorf("decode gob: %w", err)
+	}
+	set := duplicateSet{byIATA: make(map[string]*Airport, len(vals))}
+	cities := cityIndex{}
+	for i := range vals {
+		a := &vals[i]
+		cities.add(a.IsoCountry, a.Municipality, a.LatitudeDeg, a.LongitudeDeg)
+		if a.IATACode != "" {
+			set.add(a)
+		}
+	}
+	store := set.store()
+	store.cities = cities
+	store.sourceRows = len(vals)
+	return store, nil
+}
+
+// sqlColumnTypes gives the SQL type of each CSVColumns entry; anything
+// not listed is TEXT.
+var sqlColumnTypes = map[string]string{
+	"id":                "INTEGER PRIMARY KEY",
+	"latitude_deg":      "REAL",
+	"longitude_deg":     "REAL",
+	"elevation_ft":      "INTEGER",
+	"scheduled_service": "INTEGER",
+	"score":             "INTEGER",
+}
+
+// WriteSQL writes a SQL script creating table and inserting airports,
+// wrapped in a transaction. It targets SQLite but sticks to portable
+// syntax, e.g. sqlite3 airports.db < airports.sql. table must be a plain
+// identifier (letters, digits and underscores, not starting with a
+// digit); it is quoted in the script.
+func WriteSQL(w io.Writer, airports []*Airport, table string) error {
+	if !isSQLIdentifier(table) {
+		return fmt.Errorf("iataplaces: invalid SQL table name %q", table)
+	}
+	bw := bufio.NewWriter(w)
+
+	createTable, createIndex := sqlSchema(table)
+	fmt.Fprintf(bw, "BEGIN;\n%s;\n%s;\n", createTable, createIndex)
+
+	insert := fmt.Sprintf("INSERT INTO \"%s\" (%s) VALUES (", table, strings.Join(CSVColumns, ", "))
+	for _, a := range airports {
+		rec := csvRecord(a)
+		bw.WriteString(insert)
+		for i, v := range rec {
+			if i > 0 {
+				bw.WriteString(", ")
+			}
+			bw.WriteString(sqlLiteral(CSVColumns[i], v))
+		}
+		bw.WriteString(");\n")
+	}
+	bw.WriteString("COMMIT;\n")
+	return bw.Flush()
+}
+
+// sqlSchema returns the CREATE TABLE and CREATE UNIQUE INDEX statements
+// for table, without trailing semicolons.
+func sqlSchema(table string) (createTable, createIndex string) {
+	cols := make([]string, len(CSVColumns))
+	for i, col := range CSVColumns {
+		typ := sqlColumnTypes[col]
+		if typ == "" {
+			typ = "TEXT"
+		}
+		cols[i] = fmt.Sprintf("  %s %s", col, typ)
+	}
+	createTable = fmt.Sprintf("CREATE TABLE \"%s\" (\n%s\n)", table, strings.Join(cols, ",\n"))
+	createIndex = fmt.Sprintf("CREATE UNIQUE INDEX \"%s_iata_code\" ON \"%s\" (iata_code)", table, table)
+	return createTable, createIndex
+}
+
+// sqlLiteral quotes a CSV value for the given column. Empty values are
+// NULL, and so are NaN and infinite numbers, which SQL has no literal for.
+func sqlLiteral(col, v string) string {
+	if v == "" {
+		return "NULL"
+	}
+	if typ := sqlColumnTypes[col]; typ != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return "NULL"
+			}
+			return v
+		}
+	}
+	return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+}
+
+// isSQLIdentifier reports whether name is a plain SQL identifier.
+func isSQLIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+package iataplaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteSQLTableName(t *testing.T) {
+	tests := []struct {
+		table string
+		ok    bool
+	}{
+		{"airports", true},
+		{"Airports_2024", true},
+		{"_t", true},
+		{"order", true}, // a keyword, but quoted
+		{"", false},
+		{"2024", false},
+		{"air ports", false},
+		{"airports; DROP TABLE x", false},
+		{`air"ports`, false},
+		{"aéroports", false},
+	}
+	airports := loadTestStore(t).All()
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			for name, write := range map[string]func() error{
+				"sql":    func() error { return WriteSQL(&bytes.Buffer{}, airports, tt.table) },
+				"sqlite": func() error { return WriteSQLite(&bytes.Buffer{}, airports, tt.table) },
+			} {
+				if err := write(); (err == nil) != tt.ok {
+					t.Errorf("%s: err = %v, want ok = %v", name, err, tt.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		col, v, want string
+	}{
+		{"name", "", "NULL"},
+		{"name", "Heathrow", "'Heathrow'"},
+		{"name", "Val-d'Oise", "'Val-d''Oise'"},
+		{"name", "123", "'123'"},
+		{"elevation_ft", "83", "83"},
+		{"latitude_deg", "-0.461941", "-0.461941"},
+		{"latitude_deg", "NaN", "NULL"},
+		{"longitude_deg", "+Inf", "NULL"},
+		{"score", "-Inf", "NULL"},
+		{"elevation_ft", "high", "'high'"},
+	}
+	for _, tt := range tests {
+		if got := sqlLiteral(tt.col, tt.v); got != tt.want {
+			t.Errorf("sqlLiteral(%q, %q) = %s, want %s", tt.col, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestWriteSQL(t *testing.T) {
+	s := loadTestStore(t)
+	airports := s.All()
+	lhr, _ := s.LookupIATA("LHR")
+	odd := lhr.Clone()
+	odd.IATACode, odd.ID, odd.LatitudeDeg = "QQQ", 1, math.NaN()
+	airports = append(airports, odd)
+
+	var buf bytes.Buffer
+	if err := WriteSQL(&buf, airports, "order"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN;\nCREATE TABLE \"order\" (\n  id INTEGER PRIMARY KEY,",
+		"CREATE UNIQUE INDEX \"order_iata_code\" ON \"order\" (iata_code);\n",
+		"'Paris (Roissy-en-France, Val-d''Oise)'",
+		"VALUES (1, 'EGLL', 'large_airport', 'London Heathrow Airport', NULL, -0.461941, 83, ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+	if n := strings.Count(out, "INSERT INTO \"order\" "); n != len(airports) {
+		t.Errorf("%d inserts, want %d", n, len(airports))
+	}
+	if !strings.HasSuffix(out, "COMMIT;\n") {
+		t.Error("output doesn't end with COMMIT")
+	}
+}
+
+func TestWriteSQLiteRejectsDuplicates(t *testing.T) {
+	airports := loadTestStore(t).All()
+	sameID := airports[1].Clone()
+	sameID.ID, sameID.IATACode = airports[0].ID, "QQQ"
+	sameCode := airports[1].Clone()
+	sameCode.ID, sameCode.IATACode = 1, airports[0].IATACode
+	noCode := airports[1].Clone()
+	noCode.ID, noCode.IATACode = 2, ""
+	alsoNoCode := noCode.Clone()
+	alsoNoCode.ID = 3
+
+	tests := []struct {
+		name  string
+		extra []*Airport
+		err   string
+	}{
+		{"distinct", nil, ""},
+		{"same id", []*Airport{sameID}, "share id"},
+		{"same IATA code", []*Airport{sameCode}, "share IATA code"},
+		{"several without IATA code", []*Airport{noCode, alsoNoCode}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := append(slices.Clone(airports), tt.extra...)
+			err := WriteSQLite(&bytes.Buffer{}, list, "airports")
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("err = %v, want one mentioning %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestWriteSQLite(t *testing.T) {
+	airports := loadTestStore(t).All()
+	// Enough rows for interior pages, and keywords long enough to spill
+	// to overflow pages.
+	for i := 0; i < 3000; i++ {
+		a := airports[i%len(airports)].Clone()
+		a.ID = int64(10000 + i)
+		a.IATACode = ""
+		if i%2 == 0 {
+			a.IATACode = fmt.Sprintf("Q%03d", i/2)
+		}
+		if i%500 == 0 {
+			a.Keywords = strings.Repeat("keyword, ", 2000)
+		}
+		airports = append(airports, a)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSQLite(&buf, airports, "airports"); err != nil {
+		t.Fatal(err)
+	}
+	db := buf.Bytes()
+	if !bytes.HasPrefix(db, []byte("SQLite format 3\x00")) {
+		t.Fatalf("missing header magic: %q", db[:16])
+	}
+	if got := binary.BigEndian.Uint16(db[16:]); got != sqlitePageSize {
+		t.Errorf("page size %d, want %d", got, sqlitePageSize)
+	}
+	if len(db)%sqlitePageSize != 0 {
+		t.Fatalf("file size %d is not a whole number of pages", len(db))
+	}
+	if got, want := binary.BigEndian.Uint32(db[28:]), uint32(len(db)/sqlitePageSize); got != want {
+		t.Errorf("header says %d pages, file has %d", got, want)
+	}
+
+	sqlite3, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 not installed; skipping integrity check")
+	}
+	path := filepath.Join(t.TempDir(), "airports.db")
+	if err := os.WriteFile(path, db, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	queries := []struct{ sql, want string }{
+		{"PRAGMA integrity_check;", "ok"},
+		{"SELECT count(*), count(iata_code) FROM airports;", "3010|1510"},
+		{"SELECT name, elevation_ft, scheduled_service, typeof(latitude_deg) FROM airports WHERE iata_code = 'LHR';", "London Heathrow Airport|83|1|real"},
+		{"SELECT id, elevation_ft IS NULL, score IS NULL FROM airports WHERE iata_code = 'ZZV';", "9000001|1|1"},
+		{"SELECT length(keywords) FROM airports WHERE id = 10500;", "18000"},
+		{"SELECT keywords FROM airports WHERE iata_code = 'CDG';", "PAR, Aéroport Roissy-Charles de Gaulle, Roissy Airport"},
+	}
+	for _, q := range queries {
+		out, err := exec.Command(sqlite3, path, q.sql).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v: %s", q.sql, err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != q.want {
+			t.Errorf("%s = %q, want %q", q.sql, got, q.want)
+		}
+	}
+}
+
+func TestSqliteVarint(t *testing.T) {
+	tests := []struct {
+		v    uint64
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x00}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x81, 0x80, 0x00}},
+		{0x00ffffffffffffff, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{math.MaxUint64, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		if got := appendSqliteVarint(nil, tt.v); !bytes.Equal(got, tt.want) {
+			t.Errorf("appendSqliteVarint(%#x) = % x, want % x", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestSqliteRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		vals []any
+		want []byte
+	}{
+		{"null, one and text", []any{nil, int64(1), "ab"}, []byte{4, 0, 9, 17, 'a', 'b'}},
+		{"zero", []any{int64(0)}, []byte{2, 8}},
+		{"small ints", []any{int64(-1), int64(300)}, []byte{3, 1, 2, 0xff, 0x01, 0x2c}},
+		{"float", []any{1.5}, []byte{2, 7, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := sqliteRecord(tt.vals); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: sqliteRecord = % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSqliteValue(t *testing.T) {
+	tests := []struct {
+		col, v string
+		want   any
+	}{
+		{"id", "2434", nil},
+		{"name", "", nil},
+		{"name", "83", "83"},
+		{"elevation_ft", "83", int64(83)},
+		{"elevation_ft", "83.5", 83.5},
+		{"latitude_deg", "51", 51.0},
+		{"latitude_deg", "NaN", nil},
+		{"score", "n/a", "n/a"},
+	}
+	for _, tt := range tests {
+		if got := sqliteValue(tt.col, tt.v); got != tt.want {
+			t.Errorf("sqliteValue(%q, %q) = %#v, want %#v", tt.col, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParquetLevels(t *testing.T) {
+	tests := []struct {
+		defined []bool
+		want    []byte
+	}{
+		{nil, []byte{1, 0, 0, 0, 0x01}},
+		{[]bool{true, false, true}, []byte{2, 0, 0, 0, 0x03, 0x05}},
+		{[]bool{true, true, true, true, true, true, true, true, false, true}, []byte{3, 0, 0, 0, 0x05, 0xff, 0x02}},
+	}
+	for _, tt := range tests {
+		if got := parquetLevels(tt.defined); !bytes.Equal(got, tt.want) {
+			t.Errorf("parquetLevels(%v) = % x, want % x", tt.defined, got, tt.want)
+		}
+	}
+}
+
+func TestThriftWriter(t *testing.T) {
+	w := &thriftWriter{}
+	w.i32(1, 1)       // short form: delta 1, i32, zigzag 2
+	w.i64(20, -1)     // long form: type, zigzag id 40, zigzag 1
+	w.structBegin(21) // delta 1 again
+	w.bool(1, true)
+	w.structEnd()
+	w.listBegin(22, thriftBinary, 1)
+	w.listBinary("id")
+	w.stop()
+	want := []byte{0x15, 0x02, 0x06, 0x28, 0x01, 0x1c, 0x11, 0x00, 0x19, 0x18, 0x02, 'i', 'd', 0x00}
+	if !bytes.Equal(w.buf, want) {
+		t.Errorf("got % x, want % x", w.buf, want)
+	}
+}
@@ -0,0 +1,83 @@
+package iataplaces
+
+import "sort"
+
+// AirportSource is the read side of a Store. FallbackStore implements it
+// too, so layered sources can be used, and nested, wherever a plain store
+// would do.
+type AirportSource interface {
+	LookupIATA(code string) (*Airport, bool)
+	LookupICAO(code string) (*Airport, bool)
+	All() []*Airport
+	Len() int
+}
+
+var (
+	_ AirportSource = (*Store)(nil)
+	_ AirportSource = (*FallbackStore)(nil)
+)
+
+// FallbackStore layers sources: lookups try each in order and the first
+// one that knows a code answers, e.g. local overrides over the latest
+// OurAirports data over an embedded baseline. The sources are consulted
+// live, so changes to them show through.
+type FallbackStore struct {
+	sources []AirportSource
+}
+
+// NewFallbackStore returns a FallbackStore trying primary first, then
+// each fallback in order. Nil sources are skipped.
+func NewFallbackStore(primary AirportSource, fallbacks ...AirportSource) *FallbackStore {
+	f := &FallbackStore{}
+	for _, src := range append([]AirportSource{primary}, fallbacks...) {
+		if src != nil {
+			f.sources = append(f.sources, src)
+		}
+	}
+	return f
+}
+
+// LookupIATA returns the airport from the first source that has the code.
+func (f *FallbackStore) LookupIATA(code string) (*Airport, bool) {
+	for _, src := range f.sources {
+		if a, ok := src.LookupIATA(code); ok {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// LookupICAO returns the airport from the first source that has the code.
+func (f *FallbackStore) LookupICAO(code string) (*Airport, bool) {
+	for _, src := range f.sources {
+		if a, ok := src.LookupICAO(code); ok {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// All returns the airports of every source ordered by IATA code, each
+// code taken from the first source that has it.
+func (f *FallbackStore) All() []*Airport {
+	seen := make(map[string]bool)
+	var out []*Airport
+	for _, src := range f.sources {
+		for _, a := range src.All() {
+			if !seen[a.IATACode] {
+				seen[a.IATACode] = true
+				out = append(out, a)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IATACode < out[j].IATACode })
+	return out
+}
+
+// Len returns the number of distinct IATA codes across the sources.
+func (f *FallbackStore) Len() int {
+	if len(f.sources) == 1 {
+		return f.sources[0].Len()
+	}
+	return len(f.All())
+}
@@ -0,0 +1,65 @@
+package iataplaces
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testCSV is a small extract of the OurAirports data, plus a row without
+// an IATA code and one with empty optional columns.
+const testCSV = `id,ident,type,name,latitude_deg,longitude_deg,elevation_ft,continent,country_name,iso_country,region_name,iso_region,local_region,municipality,scheduled_service,gps_code,icao_code,iata_code,local_code,home_link,wikipedia_link,keywords,score,last_updated
+2434,EGLL,large_airport,London Heathrow Airport,51.4706,-0.461941,83,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGLL,EGLL,LHR,,http://www.heathrowairport.com/,https://en.wikipedia.org/wiki/Heathrow_Airport,"LON, Londres",1251675,2022-10-18T18:48:50+00:00
+2429,EGKK,large_airport,London Gatwick Airport,51.148771,-0.192089,202,EU,United Kingdom,GB,England,GB-ENG,ENG,London,1,EGKK,EGKK,LGW,,http://www.gatwickairport.com/,https://en.wikipedia.org/wiki/Gatwick_Airport,"LON, Crawley, Charlwood",1049275,2025-02-27T12:47:43+00:00
+3622,KJFK,large_airport,John F Kennedy International Airport,40.639447,-73.779317,13,NA,United States,US,New York,US-NY,NY,New York,1,KJFK,KJFK,JFK,JFK,https://www.jfkairport.com/,https://en.wikipedia.org/wiki/John_F._Kennedy_International_Airport,"Manhattan, New York City, NYC, Idlewild, IDL, KIDL",1052075,2022-10-18T18:49:55+00:00
+3643,KLGA,large_airport,LaGuardia Airport,40.777199,-73.872597,21,NA,United States,US,New York,US-NY,NY,New York,1,KLGA,KLGA,LGA,LGA,https://www.laguardiaairport.com/,https://en.wikipedia.org/wiki/LaGuardia_Airport,"Manhattan, New York City, NYC, La Guardia",1030575,2024-08-22T20:42:40+00:00
+4185,LFPG,large_airport,Charles de Gaulle International Airport,49.012798,2.55,392,EU,France,FR,Île-de-France,FR-IDF,IDF,"Paris (Roissy-en-France, Val-d'Oise)",1,LFPG,LFPG,CDG,,http://www.aeroportsdeparis.fr/,https://en.wikipedia.org/wiki/Charles_de_Gaulle_Airport,"PAR, Aéroport Roissy-Charles de Gaulle, Roissy Airport",1127475,2024-06-22T13:11:28+00:00
+4189,LFPO,large_airport,Paris-Orly Airport,48.72333,2.37944,291,EU,France,FR,Île-de-France,FR-IDF,IDF,"Paris (Orly, Val-de-Marne)",1,LFPO,LFPO,ORY,,https://www.parisaeroport.fr/orly,https://en.wikipedia.org/wiki/Orly_Airport,,1032775,2024-06-22T14:10:23+00:00
+2218,EDDM,large_airport,Munich Airport,48.353802,11.7861,1487,EU,Germany,DE,Bavaria,DE-BY,BY,Munich,1,EDDM,EDDM,MUC,,http://www.munich-airport.com/,https://en.wikipedia.org/wiki/Munich_Airport,"Franz Josef Strauss Airport, Flughafen München Franz Josef Strauß",1026675,2022-03-29T22:05:19+00:00
+5531,RJAA,large_airport,Narita International Airport,35.764702,140.386002,141,AS,Japan,JP,Chiba Prefecture,JP-12,12,Narita,1,RJAA,RJAA,NRT,,https://www.narita-airport.jp/en/,https://en.wikipedia.org/wiki/Narita_International_Airport,"TYO, Tokyo, Tokyo Narita Airport",1033675,2024-04-29T19:25:36+00:00
+5627,RJTT,large_airport,Tokyo Haneda International Airport,35.552299,139.779999,35,AS,Japan,JP,Tōkyō Prefecture,JP-13,13,Tokyo,1,RJTT,RJTT,HND,,http://www.haneda-airport.jp/,https://en.wikipedia.org/wiki/Tokyo_International_Airport,"TYO, Haneda",1168475,2021-04-09T01:56:38+00:00
+9000001,XX-0001,small_airport,Example Strip,10.5,20.5,,AF,Chad,TD,,TD-U-A,,Nowhere,0,,,ZZV,,,,,,
+9000002,XX-0002,heliport,Example Helipad,11.5,21.5,,AF,Chad,TD,,TD-U-A,,Nowhere,0,,,,,,,,,
+`
+
+// testCodes are the IATA codes in testCSV, sorted.
+var testCodes = []string{"CDG", "HND", "JFK", "LGA", "LGW", "LHR", "MUC", "NRT", "ORY", "ZZV"}
+
+// loadTestStore loads testCSV.
+func loadTestStore(t testing.TB, opts ...LoadOption) *Store {
+	t.Helper()
+	s, err := LoadFromReader(strings.NewReader(testCSV), opts...)
+	if err != nil {
+		t.Fatalf("load test data: %v", err)
+	}
+	return s
+}
+
+// writeTestCSV writes testCSV to a temporary file and returns its path.
+func writeTestCSV(t testing.TB) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "airports.csv")
+	if err := os.WriteFile(path, []byte(testCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// codesOf returns the IATA codes of airports, in order.
+func codesOf(airports []*Airport) []string {
+	codes := make([]string, len(airports))
+	for i, a := range airports {
+		codes[i] = a.IATACode
+	}
+	return codes
+}
+
+// searchCodes returns the IATA codes Search finds for text, best first.
+func searchCodes(s *Store, text string) []string {
+	var codes []string
+	for _, r := range s.Search(SearchQuery{Text: text}) {
+		codes = append(codes, r.Airport.IATACode)
+	}
+	return codes
+}
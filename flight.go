@@ -0,0 +1,95 @@
+package iataplaces
+
+import "time"
+
+// Default assumptions of EstimateFlightTime, typical of jet airliners.
+const (
+	DefaultCruiseKmh    = 880.0
+	DefaultTaxiTime     = 20 * time.Minute // taxi out plus taxi in
+	DefaultClimbDescent = 15 * time.Minute // extra time climbing and descending over cruising the same distance
+	DefaultRouteFactor  = 1.05             // airways and detours over the great-circle distance
+)
+
+// FlightTimeOption changes an assumption of EstimateFlightTime.
+type FlightTimeOption func(*flightTimeConfig)
+
+type flightTimeConfig struct {
+	cruiseKmh    float64
+	taxi         time.Duration
+	climbDescent time.Duration
+	routeFactor  float64
+}
+
+func newFlightTimeConfig(opts []FlightTimeOption) flightTimeConfig {
+	cfg := flightTimeConfig{
+		cruiseKmh:    DefaultCruiseKmh,
+		taxi:         DefaultTaxiTime,
+		climbDescent: DefaultClimbDescent,
+		routeFactor:  DefaultRouteFactor,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithCruiseSpeed sets the average cruise ground speed in km/h, e.g. 500
+// for turboprops.
+func WithCruiseSpeed(kmh float64) FlightTimeOption {
+	return func(c *flightTimeConfig) {
+		if kmh > 0 {
+			c.cruiseKmh = kmh
+		}
+	}
+}
+
+// WithTaxiTime sets the total taxi time at both ends.
+func WithTaxiTime(d time.Duration) FlightTimeOption {
+	return func(c *flightTimeConfig) { c.taxi = d }
+}
+
+// WithClimbDescent sets the time added for climb and descent.
+func WithClimbDescent(d time.Duration) FlightTimeOption {
+	return func(c *flightTimeConfig) { c.climbDescent = d }
+}
+
+// WithRouteFactor sets how much longer the flown route is than the
+// great-circle distance; 1 assumes a direct route.
+func WithRouteFactor(f float64) FlightTimeOption {
+	return func(c *flightTimeConfig) {
+		if f >= 1 {
+			c.routeFactor = f
+		}
+	}
+}
+
+// duration estimates gate-to-gate time for a great-circle distance,
+// rounded to the minute.
+func (c flightTimeConfig) duration(km float64) time.Duration {
+	air := time.Duration(km * c.routeFactor / c.cruiseKmh * float64(time.Hour))
+	return (air + c.taxi + c.climbDescent).Round(time.Minute)
+}
+
+// EstimateFlightTime roughly estimates the gate-to-gate time between two
+// airports from their great-circle distance: flown at the cruise speed
+// along a route slightly longer than the great circle, plus fixed taxi and
+// climb/descent allowances. See the Default constants for the assumptions
+// and the options to change them. It ignores winds and aircraft type, so
+// treat it as a rough estimate rather than a schedule.
+func (s *Store) EstimateFlightTime(from, to string, opts ...FlightTimeOption) (time.Duration, error) {
+	km, err := s.Distance(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return newFlightTimeConfig(opts).duration(km), nil
+}
+
+// EstimateFlightTime estimates the flight time between two airports in the
+// default store; see Store.EstimateFlightTime.
+func EstimateFlightTime(from, to string, opts ...FlightTimeOption) (time.Duration, error) {
+	store, err := ensureDefaultStore()
+	if err != nil {
+		return 0, err
+	}
+	return store.EstimateFlightTime(from, to, opts...)
+}
@@ -0,0 +1,174 @@
+package iataplaces
+
+import (
+	"time"
+	"unsafe"
+)
+
+// MemoryFootprint is an estimate, in bytes, of the memory held by a store.
+// Figures are approximations of the Go runtime's layout: they count struct
+// sizes, string and slice contents and a typical map overhead, but not
+// allocator rounding or GC headroom.
+type MemoryFootprint struct {
+	Records        int64 `json:"records"`         // Airport structs with their strings and optional fields
+	Duplicates     int64 `json:"duplicates"`      // rows that lost their IATA code, see Store.Duplicates
+	IATAIndex      int64 `json:"iata_index"`      // code -> airport map
+	ICAOIndex      int64 `json:"icao_index"`      // ICAO code -> airport map
+	SlugIndex      int64 `json:"slug_index"`      // slug -> airport map, see LookupSlug
+	SortedIndex    int64 `json:"sorted_index"`    // airports ordered by code
+	LocalizedNames int64 `json:"localized_names"` // translations loaded with LoadLocalizedNames
+	Cities         int64 `json:"cities"`          // municipality positions for CityLocation
+	Popularity     int64 `json:"popularity"`      // figures loaded with LoadPopularity
+	Routes         int64 `json:"routes"`          // airline/airport links from LoadRoutes and LoadAirlines
+	LazyIndex      int64 `json:"lazy_index"`      // row offsets kept by WithLazyFields
+	KeywordIndex   int64 `json:"keyword_index"`   // tokens indexed by WithKeywordIndex
+	CustomIndexes  int64 `json:"custom_indexes"`  // indexes added with BuildIndex
+	WarmIndexes    int64 `json:"warm_indexes"`    // spatial, ICAO prefix and full-text indexes built by WarmUp
+}
+
+// Total is the sum of all parts.
+func (m MemoryFootprint) Total() int64 {
+	return m.Records + m.Duplicates + m.IATAIndex + m.ICAOIndex + m.SlugIndex + m.SortedIndex + m.LocalizedNames + m.Cities + m.Popularity + m.Routes + m.LazyIndex + m.KeywordIndex + m.CustomIndexes + m.WarmIndexes
+}
+
+// Sizes used by the estimates below.
+const (
+	pointerSize = int64(unsafe.Sizeof(uintptr(0)))
+	stringSize  = int64(unsafe.Sizeof(""))
+	sliceSize   = int64(unsafe.Sizeof([]*Airport(nil)))
+	airportSize = int64(unsafe.Sizeof(Airport{}))
+	int64Size   = int64(unsafe.Sizeof(int64(0)))
+	timeSize    = int64(unsafe.Sizeof(time.Time{}))
+	localSize   = int64(unsafe.Sizeof(LocalizedName{}))
+)
+
+// MemoryFootprint estimates the memory used by the store's records and each
+// of its indexes. Snapshots share their records with the store they were
+// taken from, so adding up the footprints of both counts the records twice.
+func (s *Store) MemoryFootprint() MemoryFootprint {
+	var m MemoryFootprint
+	if s == nil {
+		return m
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, a := range s.sorted {
+		m.Records += airportBytes(a)
+	}
+	m.Duplicates = mapBytes(len(s.duplicates), stringSize, sliceSize)
+	for code, losers := range s.duplicates {
+		m.Duplicates += int64(len(code)) + int64(cap(losers))*pointerSize
+		for _, a := range losers {
+			m.Duplicates += airportBytes(a)
+		}
+	}
+	m.IATAIndex = mapBytes(len(s.byIATA), stringSize, pointerSize)
+	for code := range s.byIATA {
+		m.IATAIndex += int64(len(code))
+	}
+	m.ICAOIndex = mapBytes(len(s.byICAO), stringSize, pointerSize)
+	for code := range s.byICAO {
+		m.ICAOIndex += int64(len(code))
+	}
+	m.SlugIndex = mapBytes(len(s.bySlug), stringSize, pointerSize)
+	for slug := range s.bySlug {
+		m.SlugIndex += int64(len(slug))
+	}
+	m.SortedIndex = int64(cap(s.sorted)) * pointerSize
+
+	m.LocalizedNames = mapBytes(len(s.localized), stringSize, pointerSize)
+	for lang, byCode := range s.localized {
+		m.LocalizedNames += int64(len(lang)) + mapBytes(len(byCode), stringSize, localSize)
+		for code, n := range byCode {
+			m.LocalizedNames += int64(len(code) + len(n.Name) + len(n.Municipality))
+		}
+	}
+	m.Cities = mapBytes(len(s.cities), 2*stringSize, int64(unsafe.Sizeof(cityPoint{})))
+	for k := range s.cities {
+		m.Cities += int64(len(k.country) + len(k.city))
+	}
+	m.Popularity = mapBytes(len(s.popularity), stringSize, 8)
+	for code := range s.popularity {
+		m.Popularity += int64(len(code))
+	}
+	if s.routes != nil {
+		for _, idx := range []map[string][]string{s.routes.airports, s.routes.airlines} {
+			m.Routes += mapBytes(len(idx), stringSize, sliceSize)
+			for k, list := range idx {
+				m.Routes += int64(len(k)) + int64(cap(list))*stringSize
+			}
+		}
+	}
+	m.Routes += mapBytes(len(s.airlines), stringSize, pointerSize)
+	counted := map[*Airline]bool{}
+	for _, al := range s.airlines {
+		if !counted[al] {
+			counted[al] = true
+			m.Routes += int64(unsafe.Sizeof(*al)) + int64(len(al.Name)+len(al.Callsign)+len(al.Country))
+		}
+	}
+	if s.lazy != nil {
+		m.LazyIndex = mapBytes(len(s.lazy.offsets), pointerSize, int64Size)
+	}
+	if s.keywords != nil {
+		m.KeywordIndex = s.keywords.bytes()
+	}
+	for name, idx := range s.indexes {
+		m.CustomIndexes += int64(len(name)) + idx.bytes()
+	}
+	for _, o := range []*orderedIndex{s.spatial, s.icaoList} {
+		if o != nil {
+			m.WarmIndexes += int64(cap(o.list)) * pointerSize
+		}
+	}
+	if s.fullText != nil {
+		m.WarmIndexes += s.fullText.bytes()
+	}
+	return m
+}
+
+func airportBytes(a *Airport) int64 {
+	n := airportSize + int64(len(a.Ident)+len(a.Type)+len(a.Name)+
+		len(a.Continent)+len(a.CountryName)+len(a.IsoCountry)+
+		len(a.RegionName)+len(a.IsoRegion)+len(a.LocalRegion)+
+		len(a.Municipality)+len(a.GPSCode)+len(a.ICAOCode)+len(a.IATACode)+
+		len(a.LocalCode)+len(a.HomeLink)+len(a.WikipediaLink)+len(a.Keywords))
+	if a.ElevationFt != nil {
+		n += int64Size
+	}
+	if a.Score != nil {
+		n += int64Size
+	}
+	if a.LastUpdateTime != nil {
+		n += timeSize
+	}
+	if a.raw != nil {
+		// The header is shared by every row, so it isn't counted.
+		n += int64(unsafe.Sizeof(*a.raw)) + int64(cap(a.raw.fields))*stringSize
+		for _, f := range a.raw.fields {
+			n += int64(len(f))
+		}
+	}
+	return n
+}
+
+// mapBytes approximates a Go map with the given number of entries: buckets
+// of eight slots with one tag byte each, kept at most 13/16 full.
+func mapBytes(entries int, keySize, valueSize int64) int64 {
+	if entries == 0 {
+		return 0
+	}
+	slots := (int64(entries)*16 + 12) / 13
+	return slots*(keySize+valueSize+1) + 48
+}
+
+func (idx *keyIndex) bytes() int64 {
+	var n int64
+	for _, m := range []map[string][]string{idx.byToken, idx.byCode} {
+		n += mapBytes(len(m), stringSize, sliceSize)
+		for k, list := range m {
+			n += int64(len(k)) + int64(cap(list))*stringSize
+		}
+	}
+	return n
+}
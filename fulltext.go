@@ -0,0 +1,100 @@
+package iataplaces
+
+import (
+	"sort"
+	"strings"
+)
+
+// airportTrigrams returns the trigrams of the lower-cased fields Search
+// matches query text against. Any text Search finds in a field has all its
+// own trigrams among them, so the IndexFullText candidates for a query
+// include every airport that matches it.
+func airportTrigrams(a *Airport) []string {
+	var grams []string
+	seen := make(map[string]bool)
+	for _, field := range []string{a.IATACode, a.ICAOCode, a.Ident, a.Name, a.Municipality, a.Keywords} {
+		for _, g := range trigrams(strings.ToLower(field)) {
+			if !seen[g] {
+				seen[g] = true
+				grams = append(grams, g)
+			}
+		}
+	}
+	return grams
+}
+
+// trigrams returns the three-byte substrings of s.
+func trigrams(s string) []string {
+	if len(s) < 3 {
+		return nil
+	}
+	grams := make([]string, 0, len(s)-2)
+	for i := 0; i+3 <= len(s); i++ {
+		grams = append(grams, s[i:i+3])
+	}
+	return grams
+}
+
+// candidates returns the IATA codes, sorted, of the airports indexed under
+// every trigram of the lower-cased text. ok is false when text is too short
+// to have trigrams, and every airport is a candidate.
+func (idx *keyIndex) candidates(text string) (codes []string, ok bool) {
+	grams := trigrams(text)
+	if len(grams) == 0 {
+		return nil, false
+	}
+	lists := make([][]string, 0, len(grams))
+	for _, g := range grams {
+		list := idx.byToken[g]
+		if len(list) == 0 {
+			return nil, true
+		}
+		lists = append(lists, list)
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	codes = append([]string(nil), lists[0]...)
+	for _, list := range lists[1:] {
+		codes = intersectSorted(codes, list)
+		if len(codes) == 0 {
+			break
+		}
+	}
+	return codes, true
+}
+
+// intersectSorted keeps the strings of a, reusing its storage, that are
+// also in b. Both must be sorted.
+func intersectSorted(a, b []string) []string {
+	out := a[:0]
+	j := 0
+	for _, s := range a {
+		for j < len(b) && b[j] < s {
+			j++
+		}
+		if j < len(b) && b[j] == s {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// unionSorted merges two sorted lists of strings, dropping repeats.
+func unionSorted(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j >= len(b) || (i < len(a) && a[i] < b[j]):
+			out = append(out, a[i])
+			i++
+		case i >= len(a) || b[j] < a[i]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package iataplaces
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// GDSOptions configures WriteGDS.
+type GDSOptions struct {
+	// Delimiter separates the fields. Zero writes fixed-width columns
+	// instead, with names cut to GDSNameWidth.
+	Delimiter rune
+
+	// CityCode returns the IATA city code for an airport, e.g. "LON" for
+	// LHR. The dataset has no city codes, so by default the airport's own
+	// code is used, which is right for single-airport cities.
+	CityCode func(*Airport) string
+}
+
+// GDSNameWidth is the name column width of the fixed-width GDS layout.
+const GDSNameWidth = 30
+
+// WriteGDS writes airports in the location layout used by GDS-adjacent
+// systems, one line per airport: location code, name, city code, country
+// code, latitude as DDMM plus hemisphere and longitude as DDDMM plus
+// hemisphere, e.g. with a CityCode mapping LHR to LON:
+//
+//	LHR LONDON HEATHROW                LON GB 5128N 00028W
+//
+// Text is upper-case ASCII. Coordinates are rounded to the nearest minute.
+func WriteGDS(w io.Writer, airports []*Airport, opts GDSOptions) error {
+	bw := bufio.NewWriter(w)
+	for _, a := range airports {
+		city := a.IATACode
+		if opts.CityCode != nil {
+			if c := opts.CityCode(a); c != "" {
+				city = c
+			}
+		}
+		name := gdsText(a.Name, opts.Delimiter)
+		lat := gdsCoord(a.LatitudeDeg, 2, "N", "S")
+		lon := gdsCoord(a.LongitudeDeg, 3, "E", "W")
+		var err error
+		if opts.Delimiter == 0 {
+			if len(name) > GDSNameWidth {
+				name = strings.TrimSpace(name[:GDSNameWidth])
+			}
+			_, err = fmt.Fprintf(bw, "%-3s %-*s %-3s %-2s %s %s\n",
+				a.IATACode, GDSNameWidth, name, city, a.IsoCountry, lat, lon)
+		} else {
+			d := string(opts.Delimiter)
+			_, err = fmt.Fprintln(bw, strings.Join([]string{a.IATACode, name, city, a.IsoCountry, lat, lon}, d))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// gdsText renders s in upper-case ASCII for GDS output, dropping words
+// like "airport" and the delimiter.
+func gdsText(s string, delim rune) string {
+	var words []string
+	for _, w := range strings.Fields(s) {
+		var b strings.Builder
+		for _, r := range w {
+			switch {
+			case r == delim:
+			case r < unicode.MaxASCII:
+				b.WriteRune(unicode.ToUpper(r))
+			default:
+				b.WriteString(strings.ToUpper(latinFold[unicode.ToLower(r)]))
+			}
+		}
+		word := b.String()
+		if word != "" && !slugStopWords[strings.ToLower(word)] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// gdsCoord formats decimal degrees as zero-padded degrees and minutes
+// followed by the hemisphere, e.g. 5128N.
+func gdsCoord(deg float64, degDigits int, pos, neg string) string {
+	hemi := pos
+	if deg < 0 {
+		hemi, deg = neg, -deg
+	}
+	minutes := int(math.Round(deg * 60))
+	return fmt.Sprintf("%0*d%02d%s", degDigits, minutes/60, minutes%60, hemi)
+}
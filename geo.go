@@ -0,0 +1,243 @@
+package iataplaces
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownCode is returned (wrapped) when an IATA code is not in the store.
+var ErrUnknownCode = errors.New("unknown IATA code")
+
+// earthRadiusKm is the mean Earth radius used for great-circle distances.
+const earthRadiusKm = 6371.0088
+
+// DistanceKm returns the great-circle (haversine) distance between two
+// points, in kilometres.
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	rlat1, rlat2 := radians(lat1), radians(lat2)
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rlat1)*math.Cos(rlat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InitialBearing returns the initial great-circle course from the first
+// point to the second, in degrees clockwise from true north [0, 360).
+func InitialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	rlat1, rlat2 := radians(lat1), radians(lat2)
+	dLon := radians(lon2 - lon1)
+
+	y := math.Sin(dLon) * math.Cos(rlat2)
+	x := math.Cos(rlat1)*math.Sin(rlat2) - math.Sin(rlat1)*math.Cos(rlat2)*math.Cos(dLon)
+	deg := math.Atan2(y, x) * 180 / math.Pi
+	return math.Mod(deg+360, 360)
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Distance returns the great-circle distance in kilometres between two
+// airports given by IATA code.
+func (s *Store) Distance(from, to string) (float64, error) {
+	a, b, err := s.pair(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return DistanceKm(a.LatitudeDeg, a.LongitudeDeg, b.LatitudeDeg, b.LongitudeDeg), nil
+}
+
+// Bearing returns the initial great-circle course in degrees from one
+// airport to another.
+func (s *Store) Bearing(from, to string) (float64, error) {
+	a, b, err := s.pair(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return InitialBearing(a.LatitudeDeg, a.LongitudeDeg, b.LatitudeDeg, b.LongitudeDeg), nil
+}
+
+// pair looks up both ends of a route.
+func (s *Store) pair(from, to string) (*Airport, *Airport, error) {
+	a, ok := s.LookupIATA(from)
+	if !ok {
+		return nil, nil, fmt.Errorf("iataplaces: %w %q", ErrUnknownCode, from)
+	}
+	b, ok := s.LookupIATA(to)
+	if !ok {
+		return nil, nil, fmt.Errorf("iataplaces: %w %q", ErrUnknownCode, to)
+	}
+	return a, b, nil
+}
+
+// NearbyAirport is a Nearest result.
+type NearbyAirport struct {
+	Airport    *Airport
+	DistanceKm float64
+}
+
+// NearestOption narrows a Nearest query.
+type NearestOption func(*nearestConfig)
+
+type nearestConfig struct {
+	filters []func(*Airport) bool
+	maxKm   float64
+}
+
+// MajorOnly keeps large and medium airports with scheduled service.
+func MajorOnly() NearestOption {
+	return func(c *nearestConfig) {
+		c.filters = append(c.filters, func(a *Airport) bool {
+			return a.Scheduled && (a.Type == "large_airport" || a.Type == "medium_airport")
+		})
+	}
+}
+
+// OfType keeps airports whose Type is one of types.
+func OfType(types ...string) NearestOption {
+	return func(c *nearestConfig) {
+		c.filters = append(c.filters, func(a *Airport) bool {
+			for _, t := range types {
+				if a.Type == t {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// InCountries keeps airports in one of the given ISO countries, so the
+// nearest airports to Geneva in France are Nearest(lat, lon, n,
+// InCountries("FR")).
+func InCountries(isos ...string) NearestOption {
+	return func(c *nearestConfig) {
+		c.filters = append(c.filters, func(a *Airport) bool { return inCountries(a, isos) })
+	}
+}
+
+// NotInCountries drops airports in any of the given ISO countries, e.g.
+// to look for airports across the border from a point.
+func NotInCountries(isos ...string) NearestOption {
+	return func(c *nearestConfig) {
+		c.filters = append(c.filters, func(a *Airport) bool { return !inCountries(a, isos) })
+	}
+}
+
+func inCountries(a *Airport, isos []string) bool {
+	for _, iso := range isos {
+		if strings.EqualFold(a.IsoCountry, strings.TrimSpace(iso)) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithinKm drops airports further than km away.
+func WithinKm(km float64) NearestOption {
+	return func(c *nearestConfig) { c.maxKm = km }
+}
+
+// Nearest returns up to n airports closest to the given point, nearest
+// first. n <= 0 returns every airport that passes the options.
+func (s *Store) Nearest(lat, lon float64, n int, opts ...NearestOption) []NearbyAirport {
+	if s == nil {
+		return nil
+	}
+	var cfg nearestConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []NearbyAirport
+	if s.spatial != nil && (n > 0 || cfg.maxKm > 0) {
+		out = s.spatial.nearest(lat, lon, n, &cfg)
+	} else {
+		for _, a := range s.sorted {
+			if !cfg.keep(a) {
+				continue
+			}
+			d := DistanceKm(lat, lon, a.LatitudeDeg, a.LongitudeDeg)
+			if cfg.maxKm > 0 && d > cfg.maxKm {
+				continue
+			}
+			out = append(out, NearbyAirport{Airport: a, DistanceKm: d})
+		}
+		sort.SliceStable(out, func(i, j int) bool {
+			return out[i].DistanceKm < out[j].DistanceKm
+		})
+	}
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	for i := range out {
+		out[i].Airport = s.out(out[i].Airport)
+	}
+	return out
+}
+
+// NearestToAirport returns up to n airports closest to the airport with
+// the given IATA code, nearest first, leaving that airport out. It takes
+// the same options as Nearest.
+func (s *Store) NearestToAirport(code string, n int, opts ...NearestOption) ([]NearbyAirport, error) {
+	from, ok := s.LookupIATA(code)
+	if !ok {
+		return nil, fmt.Errorf("iataplaces: %w %q", ErrUnknownCode, code)
+	}
+	opts = append(opts[:len(opts):len(opts)], func(c *nearestConfig) {
+		c.filters = append(c.filters, func(a *Airport) bool { return a.IATACode != from.IATACode })
+	})
+	return s.Nearest(from.LatitudeDeg, from.LongitudeDeg, n, opts...), nil
+}
+
+// alternativesMaxKm bounds Alternatives when no WithinKm option is given,
+// roughly what travellers accept as a ground transfer.
+const alternativesMaxKm = 200
+
+// alternativeSizeKm is how much further away an airport one size class
+// larger may be and still rank level with a smaller one.
+const alternativeSizeKm = 30
+
+// Alternatives suggests up to n airports to fall back to from the airport
+// with the given IATA code, as booking and rebooking tools need when it is
+// closed or sold out: open airports with scheduled service, within 200 km
+// unless WithinKm says otherwise. They are ranked by distance, with larger
+// airports allowed to be somewhat further away, so for LCY the London
+// hubs come before closer small fields. It takes the same options as
+// Nearest.
+func (s *Store) Alternatives(code string, n int, opts ...NearestOption) ([]NearbyAirport, error) {
+	base := []NearestOption{
+		WithinKm(alternativesMaxKm),
+		func(c *nearestConfig) {
+			c.filters = append(c.filters, func(a *Airport) bool { return a.Scheduled && a.Type != "closed" })
+		},
+	}
+	near, err := s.NearestToAirport(code, 0, append(base, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	rank := func(na NearbyAirport) float64 {
+		return na.DistanceKm - alternativeSizeKm*float64(typeRank(na.Airport.Type))
+	}
+	sort.SliceStable(near, func(i, j int) bool { return rank(near[i]) < rank(near[j]) })
+	if n > 0 && len(near) > n {
+		near = near[:n]
+	}
+	return near, nil
+}
+
+func (c *nearestConfig) keep(a *Airport) bool {
+	for _, f := range c.filters {
+		if !f(a) {
+			return false
+		}
+	}
+	return true
+}
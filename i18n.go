@@ -0,0 +1,140 @@
+package iataplaces
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LocalizedName is a translated airport name and city.
+type LocalizedName struct {
+	Name         string
+	Municipality string
+}
+
+// LoadLocalizedNamesFromFile reads translations from a CSV file; see
+// LoadLocalizedNames.
+func (s *Store) LoadLocalizedNamesFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open localized names csv: %w", err)
+	}
+	defer f.Close()
+
+	return s.LoadLocalizedNames(f)
+}
+
+// LoadLocalizedNames attaches translations read from a CSV with the columns
+// iata_code, lang, name and municipality. lang is a BCP 47 tag such as "de"
+// or "pt-BR". Rows for codes not in the store are ignored.
+func (s *Store) LoadLocalizedNames(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	colIndex := make(map[string]int, len(header))
+	for i, col := range header {
+		colIndex[strings.TrimSpace(col)] = i
+	}
+	for _, col := range []string{"iata_code", "lang"} {
+		if _, ok := colIndex[col]; !ok {
+			return fmt.Errorf("localized names csv: missing %q column", col)
+		}
+	}
+	get := func(rec []string, col string) string {
+		idx, ok := colIndex[col]
+		if !ok || idx >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[idx])
+	}
+
+	parsed := make(map[string]map[string]LocalizedName)
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read record: %w", err)
+		}
+
+		code := toUpperASCII(get(rec, "iata_code"))
+		lang := strings.ToLower(get(rec, "lang"))
+		if code == "" || lang == "" {
+			continue
+		}
+		byLang := parsed[lang]
+		if byLang == nil {
+			byLang = make(map[string]LocalizedName)
+			parsed[lang] = byLang
+		}
+		byLang[code] = LocalizedName{
+			Name:         get(rec, "name"),
+			Municipality: get(rec, "municipality"),
+		}
+	}
+
+	// The file is fully parsed before the store changes, so readers never
+	// see half of it.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if s.localized == nil {
+		s.localized = make(map[string]map[string]LocalizedName)
+	}
+	for lang, names := range parsed {
+		for code, n := range names {
+			if _, ok := s.byIATA[code]; !ok {
+				continue
+			}
+			byLang := s.localized[lang]
+			if byLang == nil {
+				byLang = make(map[string]LocalizedName)
+				s.localized[lang] = byLang
+			}
+			byLang[code] = n
+		}
+	}
+	return nil
+}
+
+// Localized returns the translation of an airport for lang. A regional tag
+// such as "de-AT" falls back to its base language "de".
+func (s *Store) Localized(code, lang string) (LocalizedName, bool) {
+	if s == nil {
+		return LocalizedName{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	code = normalizeCode(code)
+	lang = strings.ToLower(lang)
+	for lang != "" {
+		if n, ok := s.localized[lang][code]; ok {
+			return n, true
+		}
+		i := strings.LastIndexByte(lang, '-')
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	return LocalizedName{}, false
+}
+
+// HasLocalizedNames reports whether any translations have been loaded.
+func (s *Store) HasLocalizedNames() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.localized) > 0
+}
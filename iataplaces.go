@@ -1,69 +1,226 @@
 package iataplaces
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Airport represents one row from ourairports.com/airports.csv.
 type Airport struct {
-	ID             int64
-	Ident          string
-	Type           string
-	Name           string
-	LatitudeDeg    float64
-	LongitudeDeg   float64
-	ElevationFt    *int64
-	Continent      string
-	CountryName    string
-	IsoCountry     string
-	RegionName     string
-	IsoRegion      string
-	LocalRegion    string
-	Municipality   string
-	Scheduled      bool
-	GPSCode        string
-	ICAOCode       string
-	IATACode       string
-	LocalCode      string
-	HomeLink       string
-	WikipediaLink  string
-	Keywords       string
-	Score          *int64
-	LastUpdateTime *time.Time
-}
-
-// Store holds airports indexed for fast lookup.
+	ID             int64      `json:"id"`
+	Ident          string     `json:"ident"`
+	Type           string     `json:"type"`
+	Name           string     `json:"name"`
+	LatitudeDeg    float64    `json:"latitude_deg"`
+	LongitudeDeg   float64    `json:"longitude_deg"`
+	ElevationFt    *int64     `json:"elevation_ft,omitempty"`
+	Continent      string     `json:"continent"`
+	CountryName    string     `json:"country_name"`
+	IsoCountry     string     `json:"iso_country"`
+	RegionName     string     `json:"region_name"`
+	IsoRegion      string     `json:"iso_region"`
+	LocalRegion    string     `json:"local_region"`
+	Municipality   string     `json:"municipality"`
+	Scheduled      bool       `json:"scheduled_service"`
+	GPSCode        string     `json:"gps_code"`
+	ICAOCode       string     `json:"icao_code"`
+	IATACode       string     `json:"iata_code"`
+	LocalCode      string     `json:"local_code"`
+	HomeLink       string     `json:"home_link"`
+	WikipediaLink  string     `json:"wikipedia_link"`
+	Keywords       string     `json:"keywords"`
+	Score          *int64     `json:"score,omitempty"`
+	LastUpdateTime *time.Time `json:"last_updated,omitempty"`
+
+	raw *rawRecord // see WithRawRecords
+}
+
+// Store holds airports indexed for fast lookup. It is safe for concurrent
+// use, including while Put, Remove or ApplyDelta modify it.
 type Store struct {
+	mu sync.RWMutex // guards the fields below; airports are never modified in place
+
 	byIATA map[string]*Airport
+	byICAO map[string]*Airport // first airport in IATA order with each ICAO code
+	bySlug map[string]*Airport // see LookupSlug
+	sorted []*Airport          // every indexed airport, ordered by IATA code
+
+	sourceRows int     // CSV rows read, including airports without IATA codes
+	loadIssues []Issue // values that didn't parse, see LoadIssues
+
+	localized map[string]map[string]LocalizedName // lang -> IATA -> names
+
+	duplicates map[string][]*Airport // rows that lost their IATA code to the indexed airport
+	cities     cityIndex             // municipality positions from every row; not changed after loading
+	popularity map[string]float64    // IATA code -> [0, 1], see LoadPopularity
+	routes     *routeIndex           // see LoadRoutes
+	airlines   airlineIndex          // see LoadAirlines
+	lazy       *lazySource           // where WithLazyFields left columns out; nil otherwise
+	keywords   *keyIndex             // see WithKeywordIndex; nil otherwise
+	indexes    map[string]*keyIndex  // see BuildIndex
+
+	// Optional indexes built by WarmUp; nil until then.
+	spatial  *orderedIndex // by latitude, see IndexSpatial
+	icaoList *orderedIndex // by ICAO code, see IndexICAOPrefix
+	fullText *keyIndex     // trigrams, see IndexFullText
+
+	readOnly     bool // set on snapshots
+	closed       bool // set by Close
+	copyOnReturn bool // hand out copies of airports, see CopyOnReturn
+
+	subMu   sync.Mutex // guards subs and nextSub
+	subs    map[int]func(ChangeEvent)
+	nextSub int
 }
 
-// LookupIATA on a Store (used by the default global store).
+// LookupIATA on a Store (used by the default global store). The code is
+// case-insensitive and may contain full-width characters or stray spaces,
+// as codes copied from PDFs often do.
 func (s *Store) LookupIATA(code string) (*Airport, bool) {
 	if s == nil {
 		return nil, false
 	}
-	if code == "" {
+	upper := normalizeCode(code)
+	if upper == "" {
 		return nil, false
 	}
-	upper := toUpperASCII(code)
+	s.mu.RLock()
 	a, ok := s.byIATA[upper]
-	return a, ok
+	s.mu.RUnlock()
+	return s.out(a), ok
+}
+
+// LookupICAO returns the airport with the given ICAO code, normalized like
+// LookupIATA's input. Only airports with an IATA code are in a store.
+func (s *Store) LookupICAO(code string) (*Airport, bool) {
+	if s == nil {
+		return nil, false
+	}
+	upper := normalizeCode(code)
+	if upper == "" {
+		return nil, false
+	}
+	s.mu.RLock()
+	a, ok := s.byICAO[upper]
+	s.mu.RUnlock()
+	return s.out(a), ok
+}
+
+// ByICAOPrefix returns the airports whose ICAO code starts with prefix,
+// normalized like LookupICAO's input, ordered by ICAO code: "EG" for the
+// United Kingdom, "K" for the contiguous United States. Airports without
+// an ICAO code never match, and an empty prefix matches nothing.
+func (s *Store) ByICAOPrefix(prefix string) []*Airport {
+	if s == nil {
+		return nil
+	}
+	prefix = normalizeCode(prefix)
+	if prefix == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Airport
+	if s.icaoList != nil {
+		list := s.icaoList.list
+		i := sort.Search(len(list), func(i int) bool { return list[i].ICAOCode >= prefix })
+		for ; i < len(list) && strings.HasPrefix(list[i].ICAOCode, prefix); i++ {
+			out = append(out, s.out(list[i]))
+		}
+		return out
+	}
+	for _, a := range s.sorted {
+		if strings.HasPrefix(a.ICAOCode, prefix) {
+			out = append(out, s.out(a))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ICAOCode < out[j].ICAOCode })
+	return out
+}
+
+func newICAOIndex(airports []*Airport) *orderedIndex {
+	return newOrderedIndex(byICAOCode, func(a *Airport) bool { return a.ICAOCode != "" }, airports)
+}
+
+// byICAOCode orders airports by ICAO code, then IATA code.
+func byICAOCode(a, b *Airport) int {
+	if c := strings.Compare(a.ICAOCode, b.ICAOCode); c != 0 {
+		return c
+	}
+	return strings.Compare(a.IATACode, b.IATACode)
+}
+
+// Len returns the number of airports indexed by IATA code.
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.byIATA)
+}
+
+// All returns every indexed airport ordered by IATA code. The slice is a
+// fresh copy; the airports themselves are shared unless the store came
+// from CopyOnReturn.
+func (s *Store) All() []*Airport {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*Airport, len(s.sorted))
+	for i, a := range s.sorted {
+		out[i] = s.out(a)
+	}
+	return out
+}
+
+// Close drops the store's airports and indexes so their memory can be
+// reclaimed once callers let go of the airports they hold. Afterwards the
+// store is empty: lookups miss, modifications return ErrClosed and
+// BuildIndex and WarmUp do nothing. Snapshots and clones taken earlier
+// are unaffected. Close always returns nil.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.byIATA, s.byICAO, s.bySlug, s.sorted = nil, nil, nil, nil
+	s.sourceRows, s.loadIssues = 0, nil
+	s.localized, s.duplicates, s.cities = nil, nil, nil
+	s.popularity, s.routes, s.airlines = nil, nil, nil
+	s.lazy, s.keywords, s.indexes = nil, nil, nil
+	s.spatial, s.icaoList, s.fullText = nil, nil, nil
+	s.closed = true
+	s.mu.Unlock()
+
+	s.subMu.Lock()
+	s.subs = nil
+	s.subMu.Unlock()
+	return nil
 }
 
 // -------- Global default store & public API --------
 
-var (
-	defaultStore *Store
-	loadOnce     sync.Once
-	loadErr      error
-)
+// defaultLoad is one lazy load of the default store. UnloadDefault swaps
+// in a fresh one, so the next lookup loads the CSV again.
+type defaultLoad struct {
+	once  sync.Once
+	store *Store
+	err   error
+}
+
+var currentDefault atomic.Pointer[defaultLoad]
+
+func init() { currentDefault.Store(new(defaultLoad)) }
 
 // defaultCSVPath returns where we load from by default.
 //
@@ -78,15 +235,27 @@ func defaultCSVPath() string {
 
 // ensureDefaultStore lazily loads the CSV into memory once.
 func ensureDefaultStore() (*Store, error) {
-	loadOnce.Do(func() {
+	d := currentDefault.Load()
+	d.once.Do(func() {
 		path := defaultCSVPath()
 		var err error
-		defaultStore, err = LoadFromFile(path)
+		d.store, err = LoadFromFile(path)
 		if err != nil {
-			loadErr = fmt.Errorf("iataplaces: failed to load CSV from %s: %w", path, err)
+			d.err = fmt.Errorf("iataplaces: failed to load CSV from %s: %w", path, err)
 		}
 	})
-	return defaultStore, loadErr
+	return d.store, d.err
+}
+
+// UnloadDefault releases the default store used by the package-level
+// functions, for processes that only need the data during one phase. The
+// next lookup loads the CSV again, which also retries a failed load.
+// Lookups running concurrently may see an empty store.
+func UnloadDefault() {
+	d := currentDefault.Swap(new(defaultLoad))
+	// Waits for a load in progress, and marks one never started as done.
+	d.once.Do(func() {})
+	d.store.Close()
 }
 
 // LookupIATA is the simple API you want.
@@ -100,136 +269,143 @@ func LookupIATA(code string) (*Airport, bool) {
 	return store.LookupIATA(code)
 }
 
+// LookupICAO looks up an ICAO code in the default store.
+func LookupICAO(code string) (*Airport, bool) {
+	store, err := ensureDefaultStore()
+	if err != nil {
+		return nil, false
+	}
+	return store.LookupICAO(code)
+}
+
+// Distance returns the great-circle distance in kilometres between two
+// airports in the default store.
+func Distance(from, to string) (float64, error) {
+	store, err := ensureDefaultStore()
+	if err != nil {
+		return 0, err
+	}
+	return store.Distance(from, to)
+}
+
 // -------- Loader helpers (used internally, but also handy for tests/tools) --------
 
 // LoadFromFile loads airports from a CSV file on disk into memory.
-func LoadFromFile(path string) (*Store, error) {
+func LoadFromFile(path string, opts ...LoadOption) (*Store, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open airports csv: %w", err)
 	}
 	defer f.Close()
 
-	return LoadFromReader(f)
+	return LoadFromReader(f, opts...)
 }
 
-// LoadFromReader loads airports from any io.Reader.
-func LoadFromReader(r io.Reader) (*Store, error) {
-	reader := csv.NewReader(r)
-	reader.FieldsPerRecord = -1 // allow variable length lines
-
-	header, err := reader.Read()
+// LoadFromFS loads airports from a CSV file in fsys, such as an embed.FS,
+// a *zip.Reader or an fstest.MapFS.
+func LoadFromFS(fsys fs.FS, path string, opts ...LoadOption) (*Store, error) {
+	f, err := fsys.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("read header: %w", err)
-	}
-
-	colIndex := make(map[string]int, len(header))
-	for i, col := range header {
-		colIndex[strings.TrimSpace(col)] = i
+		return nil, fmt.Errorf("open airports csv: %w", err)
 	}
+	defer f.Close()
 
-	get := func(rec []string, col string) string {
-		idx, ok := colIndex[col]
-		if !ok || idx >= len(rec) {
-			return ""
-		}
-		return strings.TrimSpace(rec[idx])
-	}
+	return LoadFromReader(f, opts...)
+}
 
+// LoadFromReader loads airports from any io.Reader.
+func LoadFromReader(r io.Reader, opts ...LoadOption) (*Store, error) {
 	// Preallocate with a sensible size. OurAirports has ~70k airports,
 	// but only a subset has IATA codes.
-	byIATA := make(map[string]*Airport, 80000)
+	set := duplicateSet{byIATA: make(map[string]*Airport, 80000)}
+	cities := cityIndex{}
 
-	for {
-		rec, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("read record: %w", err)
-		}
-
-		idStr := get(rec, "id")
-		if idStr == "" {
-			continue
-		}
-		id, err := strconv.ParseInt(idStr, 10, 64)
-		if err != nil {
-			// Skip bad rows rather than failing the whole load.
-			continue
-		}
-
-		lat, _ := strconv.ParseFloat(get(rec, "latitude_deg"), 64)
-		lon, _ := strconv.ParseFloat(get(rec, "longitude_deg"), 64)
-
-		var elev *int64
-		if ev := get(rec, "elevation_ft"); ev != "" {
-			if v, err := strconv.ParseInt(ev, 10, 64); err == nil {
-				elev = &v
-			}
+	cfg, err := newLoadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	var lazy *lazySource
+	if cfg.lazy {
+		if lazy, err = newLazySource(r); err != nil {
+			return nil, err
 		}
+	}
 
-		var score *int64
-		if sc := get(rec, "score"); sc != "" {
-			if v, err := strconv.ParseInt(sc, 10, 64); err == nil {
-				score = &v
-			}
+	rows, err := parseCSV(r, cfg, func(a *Airport, row rowInfo) error {
+		issues = append(issues, row.issues...)
+		if row.hasCoords {
+			cities.add(a.IsoCountry, a.Municipality, a.LatitudeDeg, a.LongitudeDeg)
 		}
-
-		var lastUpdated *time.Time
-		if lu := get(rec, "last_updated"); lu != "" {
-			if t, err := time.Parse(time.RFC3339, lu); err == nil {
-				lastUpdated = &t
-			}
+		if a.IATACode == "" {
+			// Many airports have no IATA; skip them for an IATA-focused index.
+			return nil
 		}
-
-		sched := false
-		if ss := strings.ToLower(get(rec, "scheduled_service")); ss == "1" || ss == "yes" || ss == "true" {
-			sched = true
+		if lazy != nil {
+			lazy.add(a, row)
 		}
+		// Only one entry per IATA; see preferAirport for which one wins.
+		set.add(a)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		iata := strings.ToUpper(strings.TrimSpace(get(rec, "iata_code")))
-		if iata == "" {
-			// Many airports have no IATA; skip them for an IATA-focused index.
-			continue
-		}
+	store := set.store()
+	store.sourceRows = rows
+	store.loadIssues = issues
+	store.cities = cities
+	if cfg.keywords {
+		store.keywords = newKeyIndex(airportKeywords, store.sorted)
+	}
+	if lazy != nil {
+		lazy.strip(store)
+		store.lazy = lazy
+	}
+	return store, nil
+}
 
-		airport := &Airport{
-			ID:             id,
-			Ident:          get(rec, "ident"),
-			Type:           get(rec, "type"),
-			Name:           get(rec, "name"), // csv.Reader already unquotes
-			LatitudeDeg:    lat,
-			LongitudeDeg:   lon,
-			ElevationFt:    elev,
-			Continent:      get(rec, "continent"),
-			CountryName:    get(rec, "country_name"),
-			IsoCountry:     get(rec, "iso_country"),
-			RegionName:     get(rec, "region_name"),
-			IsoRegion:      get(rec, "iso_region"),
-			LocalRegion:    get(rec, "local_region"),
-			Municipality:   get(rec, "municipality"),
-			Scheduled:      sched,
-			GPSCode:        get(rec, "gps_code"),
-			ICAOCode:       get(rec, "icao_code"),
-			IATACode:       iata,
-			LocalCode:      get(rec, "local_code"),
-			HomeLink:       get(rec, "home_link"),
-			WikipediaLink:  get(rec, "wikipedia_link"),
-			Keywords:       get(rec, "keywords"),
-			Score:          score,
-			LastUpdateTime: lastUpdated,
-		}
+// newStore builds the secondary indexes over byIATA.
+func newStore(byIATA map[string]*Airport) *Store {
+	sorted := make([]*Airport, 0, len(byIATA))
+	for _, a := range byIATA {
+		sorted = append(sorted, a)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].IATACode < sorted[j].IATACode
+	})
 
-		// Only one entry per IATA – if duplicates exist, keep the first one.
-		if _, exists := byIATA[iata]; !exists {
-			byIATA[iata] = airport
+	byICAO := make(map[string]*Airport, len(sorted))
+	bySlug := make(map[string]*Airport, len(sorted))
+	for _, a := range sorted {
+		if a.ICAOCode != "" && byICAO[a.ICAOCode] == nil {
+			byICAO[a.ICAOCode] = a
 		}
+		bySlug[a.Slug()] = a
 	}
 
 	return &Store{
 		byIATA: byIATA,
-	}, nil
+		byICAO: byICAO,
+		bySlug: bySlug,
+		sorted: sorted,
+	}
+}
+
+// reindexICAO points the ICAO index entry for code at the first airport in
+// IATA order that has it, or drops it. s.mu must be held.
+func (s *Store) reindexICAO(code string) {
+	if code == "" {
+		return
+	}
+	delete(s.byICAO, code)
+	for _, a := range s.sorted {
+		if a.ICAOCode == code {
+			s.byICAO[code] = a
+			return
+		}
+	}
 }
 
 // toUpperASCII turns a short ASCII string into upper-case efficiently.
@@ -0,0 +1,180 @@
+package iataplaces
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	s := loadTestStore(t, WithKeywordIndex())
+	if err := s.WarmUp(context.Background(), IndexSpatial, IndexICAOPrefix, IndexFullText); err != nil {
+		t.Fatal(err)
+	}
+	snap := s.Snapshot()
+	clone := s.Clone()
+	readOnly := s.Snapshot()
+	events := 0
+	s.Subscribe(func(ChangeEvent) { events++ })
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+
+	if n := s.Len(); n != 0 {
+		t.Errorf("Len() = %d after Close", n)
+	}
+	if _, ok := s.LookupIATA("LHR"); ok {
+		t.Error("LookupIATA hit after Close")
+	}
+	if _, ok := s.LookupICAO("EGLL"); ok {
+		t.Error("LookupICAO hit after Close")
+	}
+	if got := s.All(); len(got) != 0 {
+		t.Errorf("All() = %v after Close", codesOf(got))
+	}
+	if got := s.Nearest(51.5, 0, 3); len(got) != 0 {
+		t.Errorf("Nearest found %d airports after Close", len(got))
+	}
+	if got := searchCodes(s, "london"); len(got) != 0 {
+		t.Errorf("Search found %v after Close", got)
+	}
+	if got := s.ByICAOPrefix("EG"); len(got) != 0 {
+		t.Errorf("ByICAOPrefix found %v after Close", codesOf(got))
+	}
+
+	mutators := []struct {
+		name string
+		fn   func() error
+	}{
+		{"Put", func() error { return s.Put(testAirport("QQQ", "New")) }},
+		{"Remove", func() error { _, err := s.Remove("LHR"); return err }},
+		{"ApplyDelta", func() error { _, err := s.ApplyDelta(Delta{Remove: []string{"LHR"}}); return err }},
+		{"Replace", func() error { _, err := s.Replace(loadTestStore(t)); return err }},
+	}
+	for _, m := range mutators {
+		if err := m.fn(); !errors.Is(err, ErrClosed) {
+			t.Errorf("%s: err = %v, want ErrClosed", m.name, err)
+		}
+	}
+	if err := s.WarmUp(context.Background(), IndexSpatial); err != nil {
+		t.Errorf("WarmUp after Close: %v", err)
+	}
+	s.BuildIndex("country", func(a *Airport) []string { return []string{a.IsoCountry} })
+	if got, ok := s.LookupIn("country", "GB"); ok || len(got) != 0 {
+		t.Errorf("LookupIn found %v after Close", codesOf(got))
+	}
+	if n := s.Len(); n != 0 {
+		t.Errorf("Len() = %d after rejected changes", n)
+	}
+	if events != 0 {
+		t.Errorf("%d change events after Close", events)
+	}
+
+	for name, v := range map[string]*Store{"Snapshot": snap, "Clone": clone} {
+		if got := codesOf(v.All()); !slices.Equal(got, testCodes) {
+			t.Errorf("%s codes = %v after Close, want %v", name, got, testCodes)
+		}
+		if got := searchCodes(v, "heathrow"); !slices.Equal(got, []string{"LHR"}) {
+			t.Errorf("%s Search(heathrow) = %v after Close", name, got)
+		}
+	}
+	if err := clone.Put(testAirport("QQQ", "New")); err != nil {
+		t.Errorf("clone Put after Close: %v", err)
+	}
+
+	readOnly.Close()
+	if err := readOnly.Put(testAirport("QQQ", "New")); !errors.Is(err, ErrClosed) {
+		t.Errorf("closed snapshot Put: err = %v, want ErrClosed", err)
+	}
+	if err := (*Store)(nil).Close(); err != nil {
+		t.Errorf("nil Close: %v", err)
+	}
+}
+
+// TestCloseConcurrent closes a store while it is being modified; run it
+// with -race. Every change must either land before Close or fail with
+// ErrClosed.
+func TestCloseConcurrent(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		s := loadTestStore(t)
+		var wg sync.WaitGroup
+		errs := make(chan error, 64)
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					var err error
+					switch i % 3 {
+					case 0:
+						err = s.Put(testAirport("QQQ", "New"))
+					case 1:
+						_, err = s.Remove("QQQ")
+					case 2:
+						_, err = s.ApplyDelta(Delta{Upsert: []*Airport{testAirport("QQR", "New")}})
+					}
+					if err != nil && !errors.Is(err, ErrClosed) {
+						errs <- err
+						return
+					}
+					s.LookupIATA("QQQ")
+					s.All()
+				}
+			}()
+		}
+		s.Close()
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatal(err)
+		}
+		if n := s.Len(); n != 0 {
+			t.Fatalf("Len() = %d after Close", n)
+		}
+	}
+}
+
+func TestUnloadDefault(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.csv")
+	second := filepath.Join(dir, "second.csv")
+	if err := os.WriteFile(first, []byte(testCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte(strings.Replace(testCSV, "London Heathrow Airport", "Heathrow", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	UnloadDefault()
+	t.Cleanup(UnloadDefault)
+	t.Setenv("AIRPORTS_CSV_PATH", first)
+	a, ok := LookupIATA("LHR")
+	if !ok || a.Name != "London Heathrow Airport" {
+		t.Fatalf("LookupIATA(LHR) = %+v, %v", a, ok)
+	}
+
+	// The default store stays loaded until it is unloaded.
+	t.Setenv("AIRPORTS_CSV_PATH", second)
+	if a, ok := LookupIATA("LHR"); !ok || a.Name != "London Heathrow Airport" {
+		t.Errorf("default store reloaded without UnloadDefault: %+v", a)
+	}
+	UnloadDefault()
+	if a, ok := LookupIATA("LHR"); !ok || a.Name != "Heathrow" {
+		t.Errorf("LookupIATA(LHR) after UnloadDefault = %+v, want the reloaded name", a)
+	}
+
+	t.Setenv("AIRPORTS_CSV_PATH", filepath.Join(dir, "missing.csv"))
+	UnloadDefault()
+	if _, ok := LookupIATA("LHR"); ok {
+		t.Error("lookup succeeded with a missing CSV")
+	}
+}
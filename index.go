@@ -0,0 +1,71 @@
+package iataplaces
+
+import "sort"
+
+// BuildIndex adds an exact-match index named name to the store, or
+// replaces the one with that name. keyFn returns the keys an airport is
+// found under with LookupIn, e.g. an internal station ID parsed out of
+// its keywords; empty keys are ignored. The index follows later Put,
+// Remove, ApplyDelta and Replace calls, so keyFn must be safe to call
+// while the store is locked and must not use the store itself.
+func (s *Store) BuildIndex(name string, keyFn func(*Airport) []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.indexes == nil {
+		s.indexes = make(map[string]*keyIndex)
+	}
+	s.indexes[name] = newKeyIndex(keyFn, s.sorted)
+}
+
+// DropIndex removes the index built under name, if any.
+func (s *Store) DropIndex(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.indexes, name)
+}
+
+// IndexNames returns the names of the indexes built with BuildIndex,
+// sorted.
+func (s *Store) IndexNames() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.indexes))
+	for name := range s.indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupIn returns the airports the named index has under key, ordered by
+// IATA code, and whether the index exists.
+func (s *Store) LookupIn(name, key string) ([]*Airport, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	idx, ok := s.indexes[name]
+	if !ok {
+		return nil, false
+	}
+	return idx.airports(s, key), true
+}
+
+// cloneIndexes copies the indexes built with BuildIndex.
+func cloneIndexes(indexes map[string]*keyIndex) map[string]*keyIndex {
+	if indexes == nil {
+		return nil
+	}
+	c := make(map[string]*keyIndex, len(indexes))
+	for name, idx := range indexes {
+		c[name] = idx.clone()
+	}
+	return c
+}
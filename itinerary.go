@@ -0,0 +1,86 @@
+package iataplaces
+
+import (
+	"errors"
+	"strings"
+)
+
+// Leg is one hop of an itinerary.
+type Leg struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	DistanceKm float64 `json:"distance_km"`
+	CO2Kg      float64 `json:"co2_kg,omitempty"` // set by Itinerary.EstimateCO2
+
+	from, to *Airport
+}
+
+// Itinerary summarizes a multi-leg journey.
+type Itinerary struct {
+	Legs    []Leg   `json:"legs"`
+	TotalKm float64 `json:"total_km"`
+	CO2Kg   float64 `json:"co2_kg,omitempty"` // set by Itinerary.EstimateCO2
+}
+
+// Route renders the itinerary as "LHR-DXB-SIN".
+func (it *Itinerary) Route() string {
+	if len(it.Legs) == 0 {
+		return ""
+	}
+	codes := []string{it.Legs[0].From}
+	for _, l := range it.Legs {
+		codes = append(codes, l.To)
+	}
+	return strings.Join(codes, "-")
+}
+
+// CO2Estimator returns the estimated emissions in kilograms for flying
+// between two airports, given their great-circle distance. Plug in
+// whatever methodology (ICAO, DEFRA, per seat or per aircraft) you report
+// with; this package doesn't pick one.
+type CO2Estimator func(from, to *Airport, km float64) float64
+
+// EstimateCO2 fills in the per-leg and total CO2 using est and returns the
+// total.
+func (it *Itinerary) EstimateCO2(est CO2Estimator) float64 {
+	it.CO2Kg = 0
+	for i := range it.Legs {
+		l := &it.Legs[i]
+		l.CO2Kg = est(l.from, l.to, l.DistanceKm)
+		it.CO2Kg += l.CO2Kg
+	}
+	return it.CO2Kg
+}
+
+// ItineraryDistance returns the great-circle distance of each leg of a
+// journey through the given airports, in order, and the total. Codes may
+// also be given as a single "LHR-DXB-SIN-SYD" string.
+func (s *Store) ItineraryDistance(codes ...string) (*Itinerary, error) {
+	if len(codes) == 1 {
+		codes = strings.Split(codes[0], "-")
+	}
+	if len(codes) < 2 {
+		return nil, errors.New("iataplaces: an itinerary needs at least two airports")
+	}
+	it := &Itinerary{Legs: make([]Leg, 0, len(codes)-1)}
+	for i := 1; i < len(codes); i++ {
+		a, b, err := s.pair(codes[i-1], codes[i])
+		if err != nil {
+			return nil, err
+		}
+		km := DistanceKm(a.LatitudeDeg, a.LongitudeDeg, b.LatitudeDeg, b.LongitudeDeg)
+		it.Legs = append(it.Legs, Leg{From: a.IATACode, To: b.IATACode, DistanceKm: km, from: a, to: b})
+		it.TotalKm += km
+	}
+	return it, nil
+}
+
+// ItineraryDistance summarizes a journey through airports in the default
+// store; see Store.ItineraryDistance.
+func ItineraryDistance(codes ...string) (*Itinerary, error) {
+	store, err := ensureDefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.ItineraryDistance(codes...)
+}
@@ -0,0 +1,209 @@
+package iataplaces
+
+import (
+	"sort"
+	"strings"
+)
+
+// keyIndex maps keys derived from airports to their IATA codes. It backs
+// WithKeywordIndex, BuildIndex and IndexFullText, and is kept up to date by
+// put and remove, so stores don't share it.
+type keyIndex struct {
+	keys    func(*Airport) []string
+	byToken map[string][]string // key -> IATA codes, sorted
+	byCode  map[string][]string // IATA code -> its keys
+}
+
+func newKeyIndex(keys func(*Airport) []string, airports []*Airport) *keyIndex {
+	idx := &keyIndex{
+		keys:    keys,
+		byToken: make(map[string][]string),
+		byCode:  make(map[string][]string),
+	}
+	// Appending in place is safe until the index is shared; after that,
+	// add and remove replace the lists.
+	for _, a := range airports {
+		tokens := idx.tokens(a)
+		if len(tokens) == 0 {
+			continue
+		}
+		idx.byCode[a.IATACode] = tokens
+		for _, tok := range tokens {
+			idx.byToken[tok] = append(idx.byToken[tok], a.IATACode)
+		}
+	}
+	for _, codes := range idx.byToken {
+		if !sort.StringsAreSorted(codes) {
+			sort.Strings(codes)
+		}
+	}
+	return idx
+}
+
+func airportKeywords(a *Airport) []string {
+	return keywordTokens(a.Keywords)
+}
+
+// keywordTokens splits a keywords column on commas and normalizes each
+// token like normalizeKeyword, dropping empty and repeated ones.
+func keywordTokens(keywords string) []string {
+	var tokens []string
+	for _, tok := range strings.Split(keywords, ",") {
+		tok = normalizeKeyword(tok)
+		if tok != "" && !containsString(tokens, tok) {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// normalizeKeyword lower-cases k, folds full-width characters and
+// collapses runs of spaces.
+func normalizeKeyword(k string) string {
+	k = strings.Map(func(r rune) rune {
+		if r >= '！' && r <= '～' {
+			r -= '！' - '!'
+		}
+		return r
+	}, k)
+	return strings.Join(strings.Fields(strings.ToLower(k)), " ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// tokens returns the keys of a without empty or repeated ones.
+func (idx *keyIndex) tokens(a *Airport) []string {
+	keys := idx.keys(a)
+	var seen map[string]bool // for long key lists, such as trigrams
+	if len(keys) > 16 {
+		seen = make(map[string]bool, len(keys))
+	}
+	var tokens []string
+	for _, tok := range keys {
+		switch {
+		case tok == "":
+			continue
+		case seen != nil:
+			if seen[tok] {
+				continue
+			}
+			seen[tok] = true
+		case containsString(tokens, tok):
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+func (idx *keyIndex) add(a *Airport) {
+	code := a.IATACode
+	tokens := idx.tokens(a)
+	if len(tokens) == 0 {
+		return
+	}
+	idx.byCode[code] = tokens
+	for _, tok := range tokens {
+		codes := idx.byToken[tok]
+		i := sort.SearchStrings(codes, code)
+		if i < len(codes) && codes[i] == code {
+			continue
+		}
+		grown := make([]string, 0, len(codes)+1)
+		grown = append(append(append(grown, codes[:i]...), code), codes[i:]...)
+		idx.byToken[tok] = grown
+	}
+}
+
+// update moves the index from old to a, either of which may be nil.
+func (idx *keyIndex) update(old, a *Airport) {
+	if old != nil {
+		idx.remove(old.IATACode)
+	}
+	if a != nil {
+		idx.add(a)
+	}
+}
+
+func (idx *keyIndex) remove(code string) {
+	for _, tok := range idx.byCode[code] {
+		codes := idx.byToken[tok]
+		i := sort.SearchStrings(codes, code)
+		if i < len(codes) && codes[i] == code {
+			codes = append(codes[:i:i], codes[i+1:]...)
+		}
+		if len(codes) == 0 {
+			delete(idx.byToken, tok)
+		} else {
+			idx.byToken[tok] = codes
+		}
+	}
+	delete(idx.byCode, code)
+}
+
+func (idx *keyIndex) clone() *keyIndex {
+	if idx == nil {
+		return nil
+	}
+	c := &keyIndex{
+		keys:    idx.keys,
+		byToken: make(map[string][]string, len(idx.byToken)),
+		byCode:  make(map[string][]string, len(idx.byCode)),
+	}
+	// The slices are replaced, never modified in place, so they can be
+	// shared.
+	for k, v := range idx.byToken {
+		c.byToken[k] = v
+	}
+	for k, v := range idx.byCode {
+		c.byCode[k] = v
+	}
+	return c
+}
+
+// airports returns the indexed airports for key. s.mu must be held.
+func (idx *keyIndex) airports(s *Store, key string) []*Airport {
+	codes := idx.byToken[key]
+	out := make([]*Airport, 0, len(codes))
+	for _, code := range codes {
+		if a, ok := s.byIATA[code]; ok {
+			out = append(out, s.out(a))
+		}
+	}
+	return out
+}
+
+// HasKeywordIndex reports whether the store was loaded with
+// WithKeywordIndex.
+func (s *Store) HasKeywordIndex() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keywords != nil
+}
+
+// LookupKeyword returns the airports listing keyword as one of their
+// comma-separated keywords, such as a former code or a local name,
+// ordered by IATA code. The match is exact after lower-casing and
+// collapsing spaces. It needs a store loaded with WithKeywordIndex and
+// returns nil otherwise.
+func (s *Store) LookupKeyword(keyword string) []*Airport {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.keywords == nil {
+		return nil
+	}
+	return s.keywords.airports(s, normalizeKeyword(keyword))
+}
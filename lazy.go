@@ -0,0 +1,175 @@
+package iataplaces
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrSourceChanged is returned by Expand when the file a store was lazily
+// loaded from has changed since.
+var ErrSourceChanged = errors.New("iataplaces: lazily loaded file has changed")
+
+// lazyColumns are the columns WithLazyFields leaves on disk.
+var lazyColumns = []string{"home_link", "wikipedia_link", "keywords"}
+
+// lazySource remembers where the rows of a WithLazyFields store are. It
+// is built while loading and read-only afterwards, so stores share it.
+// Rows are keyed by the airport loaded from them, so an airport that Put,
+// ApplyDelta or Replace puts in its place is never expanded from the row.
+type lazySource struct {
+	path    string
+	size    int64
+	modTime time.Time
+	start   int64              // file offset the CSV reader started at
+	offsets map[*Airport]int64 // loaded airport -> row offset from start
+	columns []int              // field index of each lazyColumns entry, -1 when absent
+}
+
+func newLazySource(r io.Reader) (*lazySource, error) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return nil, errors.New("iataplaces: WithLazyFields needs a file; use LoadFromFile")
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: WithLazyFields needs a seekable file: %w", err)
+	}
+	path, err := filepath.Abs(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &lazySource{
+		path:    path,
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+		start:   start,
+		offsets: make(map[*Airport]int64),
+	}, nil
+}
+
+// add records where a's row is.
+func (l *lazySource) add(a *Airport, row rowInfo) {
+	if l.columns == nil {
+		l.columns = make([]int, len(lazyColumns))
+		for i, col := range lazyColumns {
+			idx, ok := row.columns[col]
+			if !ok {
+				idx = -1
+			}
+			l.columns[i] = idx
+		}
+	}
+	l.offsets[a] = row.offset
+}
+
+// rekey returns a copy of l for a store whose airports are clones; moved
+// maps each original airport to its clone.
+func (l *lazySource) rekey(moved map[*Airport]*Airport) *lazySource {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	c.offsets = make(map[*Airport]int64, len(l.offsets))
+	for a, offset := range l.offsets {
+		if m, ok := moved[a]; ok {
+			c.offsets[m] = offset
+		}
+	}
+	return &c
+}
+
+// lazyOffset returns the row offset of a, which may be a copy handed out
+// by a CopyOnReturn store; copies are matched to the airport the store
+// holds under the same IATA code and ID. s.mu must be held.
+func (s *Store) lazyOffset(a *Airport) (int64, bool) {
+	if offset, ok := s.lazy.offsets[a]; ok || !s.copyOnReturn {
+		return offset, ok
+	}
+	if held := s.byIATA[a.IATACode]; held != nil && held.ID == a.ID {
+		offset, ok := s.lazy.offsets[held]
+		return offset, ok
+	}
+	for _, held := range s.duplicates[a.IATACode] {
+		if held.ID == a.ID {
+			offset, ok := s.lazy.offsets[held]
+			return offset, ok
+		}
+	}
+	return 0, false
+}
+
+// strip drops the lazy fields of the airports in a freshly loaded s, once
+// the keyword index has seen them.
+func (l *lazySource) strip(s *Store) {
+	drop := func(a *Airport) { a.HomeLink, a.WikipediaLink, a.Keywords = "", "", "" }
+	for _, a := range s.sorted {
+		drop(a)
+	}
+	for _, losers := range s.duplicates {
+		for _, a := range losers {
+			drop(a)
+		}
+	}
+}
+
+// Expand returns a copy of a with the columns left out by WithLazyFields
+// read back from the file. For other stores, and airports added later with
+// Put, ApplyDelta or Replace, even under the ID of a loaded row, it returns
+// a unchanged.
+func (s *Store) Expand(a *Airport) (*Airport, error) {
+	if s == nil || a == nil {
+		return a, nil
+	}
+	s.mu.RLock()
+	l := s.lazy
+	var offset int64
+	ok := false
+	if l != nil {
+		offset, ok = s.lazyOffset(a)
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return a, nil
+	}
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: expand %s: %w", a.IATACode, err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: expand %s: %w", a.IATACode, err)
+	}
+	if fi.Size() != l.size || !fi.ModTime().Equal(l.modTime) {
+		return nil, ErrSourceChanged
+	}
+	if _, err := f.Seek(l.start+offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("iataplaces: expand %s: %w", a.IATACode, err)
+	}
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	rec, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: expand %s: %w", a.IATACode, err)
+	}
+
+	c := a.Clone()
+	fields := []*string{&c.HomeLink, &c.WikipediaLink, &c.Keywords}
+	for i, idx := range l.columns {
+		if idx >= 0 && idx < len(rec) {
+			*fields[i] = strings.TrimSpace(rec[idx])
+		}
+	}
+	return c, nil
+}
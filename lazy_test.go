@@ -0,0 +1,125 @@
+package iataplaces
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	eager := loadTestStore(t)
+	lazy, err := LoadFromFile(writeTestCSV(t), WithLazyFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	views := []struct {
+		name  string
+		store *Store
+	}{
+		{"store", lazy},
+		{"Snapshot", lazy.Snapshot()},
+		{"Clone", lazy.Clone()},
+		{"CopyOnReturn", lazy.CopyOnReturn()},
+	}
+	for _, v := range views {
+		t.Run(v.name, func(t *testing.T) {
+			for _, code := range testCodes {
+				a, ok := v.store.LookupIATA(code)
+				if !ok {
+					t.Fatalf("%s missing", code)
+				}
+				if a.HomeLink != "" || a.WikipediaLink != "" || a.Keywords != "" {
+					t.Errorf("%s: lazy fields loaded: %q %q %q", code, a.HomeLink, a.WikipediaLink, a.Keywords)
+				}
+				got, err := v.store.Expand(a)
+				if err != nil {
+					t.Fatalf("Expand(%s): %v", code, err)
+				}
+				want, _ := eager.LookupIATA(code)
+				if !got.Equal(want) {
+					t.Errorf("Expand(%s) = %+v, want %+v", code, got, want)
+				}
+				if got == a {
+					t.Errorf("Expand(%s) returned its argument", code)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandReplaced(t *testing.T) {
+	lazy, err := LoadFromFile(writeTestCSV(t), WithLazyFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := lazy.LookupIATA("LHR")
+
+	tests := []struct {
+		name    string
+		replace func(a *Airport) error
+	}{
+		{"Put", lazy.Put},
+		{"ApplyDelta", func(a *Airport) error {
+			_, err := lazy.ApplyDelta(Delta{Upsert: []*Airport{a}})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Same ID as the loaded row, so only the airport's identity
+			// tells them apart.
+			a := loaded.Clone()
+			a.Name = "Replaced by " + tt.name
+			if err := tt.replace(a); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range []*Store{lazy, lazy.Snapshot(), lazy.Clone(), lazy.CopyOnReturn()} {
+				held, _ := s.LookupIATA("LHR")
+				got, err := s.Expand(held)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Keywords != "" || got.WikipediaLink != "" {
+					t.Errorf("replacement was expanded from the loaded row: %q %q", got.Keywords, got.WikipediaLink)
+				}
+				if got.Name != a.Name {
+					t.Errorf("Name = %q, want %q", got.Name, a.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandSourceChanged(t *testing.T) {
+	path := writeTestCSV(t)
+	lazy, err := LoadFromFile(path, WithLazyFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(testCSV, "Heathrow", "Heathrow!", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := lazy.LookupIATA("LHR")
+	if _, err := lazy.Expand(a); !errors.Is(err, ErrSourceChanged) {
+		t.Errorf("err = %v, want ErrSourceChanged", err)
+	}
+}
+
+func TestExpandNotLazy(t *testing.T) {
+	s := loadTestStore(t)
+	a, _ := s.LookupIATA("CDG")
+	got, err := s.Expand(a)
+	if err != nil || got != a {
+		t.Errorf("Expand = %p, %v; want %p, nil", got, err, a)
+	}
+	if got, err := s.Expand(nil); got != nil || err != nil {
+		t.Errorf("Expand(nil) = %v, %v", got, err)
+	}
+}
+
+func TestLazyFieldsNeedAFile(t *testing.T) {
+	if _, err := LoadFromReader(strings.NewReader(testCSV), WithLazyFields()); err == nil {
+		t.Error("LoadFromReader accepted WithLazyFields")
+	}
+}
@@ -0,0 +1,148 @@
+// Package mobile is a gomobile-friendly wrapper around iataplaces for iOS
+// and Android apps. It only uses types gomobile bind can translate:
+// strings, numbers, bools, []byte and structs of those. Slices of airports
+// are returned as an AirportList with Len and Get.
+//
+//	gomobile bind -target=android ./mobile   # or -target=ios
+//
+// Apps bundle airports-latest.csv as an asset and pass its bytes to
+// NewStoreFromCSV, or a path on the device to NewStoreFromFile.
+package mobile
+
+import (
+	"bytes"
+
+	iataplaces "github.com/achamwada/iata-lookup-places"
+)
+
+// Airport is a flattened copy of an iataplaces.Airport. ElevationFt is
+// only meaningful when HasElevation is set.
+type Airport struct {
+	IATACode      string
+	ICAOCode      string
+	Name          string
+	Type          string
+	Municipality  string
+	RegionName    string
+	CountryName   string
+	IsoCountry    string
+	Continent     string
+	LatitudeDeg   float64
+	LongitudeDeg  float64
+	ElevationFt   int64
+	HasElevation  bool
+	Scheduled     bool
+	HomeLink      string
+	WikipediaLink string
+
+	// DistanceKm is set on Nearest results.
+	DistanceKm float64
+}
+
+func newAirport(a *iataplaces.Airport) *Airport {
+	out := &Airport{
+		IATACode:      a.IATACode,
+		ICAOCode:      a.ICAOCode,
+		Name:          a.Name,
+		Type:          a.Type,
+		Municipality:  a.Municipality,
+		RegionName:    a.RegionName,
+		CountryName:   a.CountryName,
+		IsoCountry:    a.IsoCountry,
+		Continent:     a.Continent,
+		LatitudeDeg:   a.LatitudeDeg,
+		LongitudeDeg:  a.LongitudeDeg,
+		Scheduled:     a.Scheduled,
+		HomeLink:      a.HomeLink,
+		WikipediaLink: a.WikipediaLink,
+	}
+	if a.ElevationFt != nil {
+		out.ElevationFt, out.HasElevation = *a.ElevationFt, true
+	}
+	return out
+}
+
+// AirportList is an ordered list of airports.
+type AirportList struct {
+	items []*Airport
+}
+
+// Len returns the number of airports in the list.
+func (l *AirportList) Len() int { return len(l.items) }
+
+// Get returns the i-th airport, or nil when i is out of range.
+func (l *AirportList) Get(i int) *Airport {
+	if i < 0 || i >= len(l.items) {
+		return nil
+	}
+	return l.items[i]
+}
+
+// Store is a loaded dataset.
+type Store struct {
+	s *iataplaces.Store
+}
+
+// NewStoreFromCSV parses an airports CSV held in memory, such as a bundled
+// app asset.
+func NewStoreFromCSV(csv []byte) (*Store, error) {
+	s, err := iataplaces.LoadFromReader(bytes.NewReader(csv))
+	if err != nil {
+		return nil, err
+	}
+	return &Store{s: s}, nil
+}
+
+// NewStoreFromFile parses the airports CSV at path.
+func NewStoreFromFile(path string) (*Store, error) {
+	s, err := iataplaces.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{s: s}, nil
+}
+
+// Len returns the number of airports with an IATA code.
+func (s *Store) Len() int { return s.s.Len() }
+
+// Lookup returns the airport with the given IATA code, or nil.
+func (s *Store) Lookup(code string) *Airport {
+	a, ok := s.s.LookupIATA(code)
+	if !ok {
+		return nil
+	}
+	return newAirport(a)
+}
+
+// Search returns up to limit airports matching text, best first. limit <= 0
+// returns every match.
+func (s *Store) Search(text string, limit int) *AirportList {
+	results := s.s.Search(iataplaces.SearchQuery{Text: text, Limit: limit})
+	l := &AirportList{items: make([]*Airport, len(results))}
+	for i, r := range results {
+		l.items[i] = newAirport(r.Airport)
+	}
+	return l
+}
+
+// Nearest returns the n airports closest to a point, nearest first, with
+// DistanceKm set. majorOnly keeps large and medium airports with scheduled
+// service.
+func (s *Store) Nearest(lat, lon float64, n int, majorOnly bool) *AirportList {
+	var opts []iataplaces.NearestOption
+	if majorOnly {
+		opts = append(opts, iataplaces.MajorOnly())
+	}
+	hits := s.s.Nearest(lat, lon, n, opts...)
+	l := &AirportList{items: make([]*Airport, len(hits))}
+	for i, h := range hits {
+		l.items[i] = newAirport(h.Airport)
+		l.items[i].DistanceKm = h.DistanceKm
+	}
+	return l
+}
+
+// DistanceKm returns the great-circle distance between two airports.
+func (s *Store) DistanceKm(from, to string) (float64, error) {
+	return s.s.Distance(from, to)
+}
@@ -0,0 +1,179 @@
+package iataplaces
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Mapbox Vector Tile (version 2.1) layout, encoded by hand since the
+// format is three small protobuf messages.
+const (
+	mvtExtent = 4096
+	mvtBuffer = 64 // points this far outside the tile are kept, so edge markers aren't cut
+
+	mvtTileLayers = 3
+
+	mvtLayerName     = 1
+	mvtLayerFeatures = 2
+	mvtLayerKeys     = 3
+	mvtLayerValues   = 4
+	mvtLayerExtent   = 5
+	mvtLayerVersion  = 15
+
+	mvtFeatureID       = 1
+	mvtFeatureTags     = 2
+	mvtFeatureType     = 3
+	mvtFeatureGeometry = 4
+	mvtPoint           = 1
+
+	mvtValueString = 1
+	mvtValueSint   = 6
+	mvtValueBool   = 7
+)
+
+// MVTLayer is the name of the layer VectorTile writes.
+const MVTLayer = "airports"
+
+// VectorTile returns the Mapbox Vector Tile z/x/y of the airport layer,
+// with one point feature per airport carrying iata_code, icao_code, name,
+// type, municipality, iso_country, scheduled and, when known, elevation_ft
+// and score. Feature IDs are the OurAirports IDs. Empty tiles have a layer
+// without features.
+func (s *Store) VectorTile(z, x, y int) ([]byte, error) {
+	if z < 0 || z > 22 {
+		return nil, fmt.Errorf("iataplaces: tile zoom %d out of range 0-22", z)
+	}
+	n := 1 << z
+	if x < 0 || x >= n || y < 0 || y >= n {
+		return nil, fmt.Errorf("iataplaces: tile %d/%d/%d does not exist", z, x, y)
+	}
+
+	type point struct {
+		a    *Airport
+		x, y int64
+	}
+	var points []point
+	scale := float64(n) * mvtExtent
+	if s != nil {
+		s.mu.RLock()
+		for _, a := range s.sorted {
+			px := int64(math.Round(mercatorX(a.LongitudeDeg)*scale)) - int64(x)*mvtExtent
+			py := int64(math.Round(mercatorY(a.LatitudeDeg)*scale)) - int64(y)*mvtExtent
+			if px >= -mvtBuffer && px <= mvtExtent+mvtBuffer && py >= -mvtBuffer && py <= mvtExtent+mvtBuffer {
+				points = append(points, point{a, px, py})
+			}
+		}
+		s.mu.RUnlock()
+	}
+
+	var l mvtLayer
+	var features []byte
+	for _, p := range points {
+		a := p.a
+		var tags []byte
+		tags = l.tag(tags, "iata_code", mvtString(a.IATACode))
+		tags = l.tag(tags, "icao_code", mvtString(a.ICAOCode))
+		tags = l.tag(tags, "name", mvtString(a.Name))
+		tags = l.tag(tags, "type", mvtString(a.Type))
+		tags = l.tag(tags, "municipality", mvtString(a.Municipality))
+		tags = l.tag(tags, "iso_country", mvtString(a.IsoCountry))
+		tags = l.tag(tags, "scheduled", mvtBool(a.Scheduled))
+		if a.ElevationFt != nil {
+			tags = l.tag(tags, "elevation_ft", mvtInt(*a.ElevationFt))
+		}
+		if a.Score != nil {
+			tags = l.tag(tags, "score", mvtInt(*a.Score))
+		}
+
+		var geom []byte
+		geom = binary.AppendUvarint(geom, 1<<3|1) // MoveTo, one point
+		geom = binary.AppendUvarint(geom, zigzag(p.x))
+		geom = binary.AppendUvarint(geom, zigzag(p.y))
+
+		var f []byte
+		if a.ID > 0 {
+			f = pbVarint(f, mvtFeatureID, uint64(a.ID))
+		}
+		f = pbBytes(f, mvtFeatureTags, tags)
+		f = pbVarint(f, mvtFeatureType, mvtPoint)
+		f = pbBytes(f, mvtFeatureGeometry, geom)
+		features = pbBytes(features, mvtLayerFeatures, f)
+	}
+
+	var layer []byte
+	layer = pbVarint(layer, mvtLayerVersion, 2)
+	layer = pbBytes(layer, mvtLayerName, []byte(MVTLayer))
+	layer = append(layer, features...)
+	for _, k := range l.keys {
+		layer = pbBytes(layer, mvtLayerKeys, []byte(k))
+	}
+	for _, v := range l.values {
+		layer = pbBytes(layer, mvtLayerValues, []byte(v))
+	}
+	layer = pbVarint(layer, mvtLayerExtent, mvtExtent)
+
+	return pbBytes(nil, mvtTileLayers, layer), nil
+}
+
+// mvtLayer interns the keys and values shared by a layer's features.
+type mvtLayer struct {
+	keys     []string
+	values   []string // encoded Value messages
+	keyIdx   map[string]uint64
+	valueIdx map[string]uint64
+}
+
+// tag appends the key and value indexes of one feature property.
+func (l *mvtLayer) tag(tags []byte, key, value string) []byte {
+	if l.keyIdx == nil {
+		l.keyIdx, l.valueIdx = map[string]uint64{}, map[string]uint64{}
+	}
+	ki, ok := l.keyIdx[key]
+	if !ok {
+		ki = uint64(len(l.keys))
+		l.keyIdx[key] = ki
+		l.keys = append(l.keys, key)
+	}
+	vi, ok := l.valueIdx[value]
+	if !ok {
+		vi = uint64(len(l.values))
+		l.valueIdx[value] = vi
+		l.values = append(l.values, value)
+	}
+	tags = binary.AppendUvarint(tags, ki)
+	return binary.AppendUvarint(tags, vi)
+}
+
+func mvtString(v string) string {
+	return string(pbBytes(nil, mvtValueString, []byte(v)))
+}
+
+func mvtInt(v int64) string {
+	return string(pbVarint(nil, mvtValueSint, zigzag(v)))
+}
+
+func mvtBool(v bool) string {
+	var n uint64
+	if v {
+		n = 1
+	}
+	return string(pbVarint(nil, mvtValueBool, n))
+}
+
+// pbVarint appends a varint protobuf field.
+func pbVarint(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3)
+	return binary.AppendUvarint(b, v)
+}
+
+// pbBytes appends a length-delimited protobuf field.
+func pbBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
@@ -0,0 +1,286 @@
+package iataplaces
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+// pbField is one decoded protobuf field: a varint or a byte string.
+type pbField struct {
+	num   int
+	value uint64
+	bytes []byte
+}
+
+func pbDecode(t *testing.T, b []byte) []pbField {
+	t.Helper()
+	var fields []pbField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad field key in % x", b)
+		}
+		b = b[n:]
+		f := pbField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.value, n = binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("bad varint for field %d", f.num)
+			}
+			b = b[n:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < size {
+				t.Fatalf("bad length for field %d", f.num)
+			}
+			f.bytes = b[n : n+int(size)]
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("field %d has unexpected wire type %d", f.num, key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func pbPacked(t *testing.T, b []byte) []uint64 {
+	t.Helper()
+	var vs []uint64
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("bad packed varint in % x", b)
+		}
+		vs = append(vs, v)
+		b = b[n:]
+	}
+	return vs
+}
+
+func unzigzag(v uint64) int64 { return int64(v>>1) ^ -int64(v&1) }
+
+// mvtFeature is a decoded point feature.
+type mvtFeature struct {
+	id    uint64
+	props map[string]any
+	x, y  int64
+}
+
+// decodeTile decodes a tile with a single point layer.
+func decodeTile(t *testing.T, tile []byte) (name string, extent uint64, features []mvtFeature) {
+	t.Helper()
+	top := pbDecode(t, tile)
+	if len(top) != 1 || top[0].num != 3 {
+		t.Fatalf("tile has fields %+v, want one layer", top)
+	}
+	var (
+		keys     []string
+		values   []any
+		rawFeats [][]byte
+		version  uint64
+	)
+	for _, f := range pbDecode(t, top[0].bytes) {
+		switch f.num {
+		case 1:
+			name = string(f.bytes)
+		case 2:
+			rawFeats = append(rawFeats, f.bytes)
+		case 3:
+			keys = append(keys, string(f.bytes))
+		case 4:
+			v := pbDecode(t, f.bytes)
+			if len(v) != 1 {
+				t.Fatalf("value has %d fields", len(v))
+			}
+			switch v[0].num {
+			case 1:
+				values = append(values, string(v[0].bytes))
+			case 6:
+				values = append(values, unzigzag(v[0].value))
+			case 7:
+				values = append(values, v[0].value == 1)
+			default:
+				t.Fatalf("unexpected value field %d", v[0].num)
+			}
+		case 5:
+			extent = f.value
+		case 15:
+			version = f.value
+		default:
+			t.Fatalf("unexpected layer field %d", f.num)
+		}
+	}
+	if version != 2 {
+		t.Errorf("layer version %d, want 2", version)
+	}
+
+	for _, raw := range rawFeats {
+		feat := mvtFeature{props: map[string]any{}}
+		for _, f := range pbDecode(t, raw) {
+			switch f.num {
+			case 1:
+				feat.id = f.value
+			case 2:
+				tags := pbPacked(t, f.bytes)
+				if len(tags)%2 != 0 {
+					t.Fatalf("odd number of tags: %v", tags)
+				}
+				for i := 0; i < len(tags); i += 2 {
+					feat.props[keys[tags[i]]] = values[tags[i+1]]
+				}
+			case 3:
+				if f.value != 1 {
+					t.Errorf("feature type %d, want point", f.value)
+				}
+			case 4:
+				geom := pbPacked(t, f.bytes)
+				if len(geom) != 3 || geom[0] != 1<<3|1 {
+					t.Fatalf("geometry %v isn't a single MoveTo", geom)
+				}
+				feat.x, feat.y = unzigzag(geom[1]), unzigzag(geom[2])
+			}
+		}
+		features = append(features, feat)
+	}
+	return name, extent, features
+}
+
+func TestVectorTile(t *testing.T) {
+	s := loadTestStore(t)
+
+	// The whole world fits in tile 0/0/0.
+	tile, err := s.VectorTile(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, extent, features := decodeTile(t, tile)
+	if name != MVTLayer || extent != 4096 {
+		t.Errorf("layer %q with extent %d", name, extent)
+	}
+	var codes []string
+	for _, f := range features {
+		codes = append(codes, f.props["iata_code"].(string))
+	}
+	slices.Sort(codes)
+	if !slices.Equal(codes, testCodes) {
+		t.Errorf("tile 0/0/0 has %v, want %v", codes, testCodes)
+	}
+
+	// Heathrow is at (2810, 2762) in tile 10/510/340: 51.4706°N 0.461941°W
+	// projects to (510.686, 340.674) tiles at zoom 10.
+	tile, err = s.VectorTile(10, 510, 340)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, features = decodeTile(t, tile)
+	if len(features) != 1 {
+		t.Fatalf("tile 10/510/340 has %d features, want 1", len(features))
+	}
+	lhr := features[0]
+	if lhr.id != 2434 || lhr.x != 2810 || lhr.y != 2762 {
+		t.Errorf("LHR: id %d at (%d, %d), want 2434 at (2810, 2762)", lhr.id, lhr.x, lhr.y)
+	}
+	want := map[string]any{
+		"iata_code":    "LHR",
+		"icao_code":    "EGLL",
+		"name":         "London Heathrow Airport",
+		"type":         "large_airport",
+		"municipality": "London",
+		"iso_country":  "GB",
+		"scheduled":    true,
+		"elevation_ft": int64(83),
+		"score":        int64(1251675),
+	}
+	if len(lhr.props) != len(want) {
+		t.Errorf("LHR has properties %v", lhr.props)
+	}
+	for k, v := range want {
+		if lhr.props[k] != v {
+			t.Errorf("LHR %s = %#v, want %#v", k, lhr.props[k], v)
+		}
+	}
+
+	// ZZV has no elevation or score, so neither property is written.
+	zzv := tileFeature(t, s, "ZZV", 6)
+	for _, k := range []string{"elevation_ft", "score"} {
+		if _, ok := zzv.props[k]; ok {
+			t.Errorf("ZZV has %s", k)
+		}
+	}
+	if zzv.props["scheduled"] != false {
+		t.Errorf("ZZV scheduled = %v", zzv.props["scheduled"])
+	}
+}
+
+// tileFeature returns the feature of an airport in the tile containing it
+// at zoom z.
+func tileFeature(t *testing.T, s *Store, code string, z int) mvtFeature {
+	t.Helper()
+	a, ok := s.LookupIATA(code)
+	if !ok {
+		t.Fatalf("no %s", code)
+	}
+	n := math.Exp2(float64(z))
+	x := int((a.LongitudeDeg + 180) / 360 * n)
+	lat := a.LatitudeDeg * math.Pi / 180
+	y := int((1 - math.Asinh(math.Tan(lat))/math.Pi) / 2 * n)
+	tile, err := s.VectorTile(z, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, features := decodeTile(t, tile)
+	for _, f := range features {
+		if f.props["iata_code"] == code {
+			return f
+		}
+	}
+	t.Fatalf("%s isn't in tile %d/%d/%d", code, z, x, y)
+	return mvtFeature{}
+}
+
+func TestVectorTileEdges(t *testing.T) {
+	s := loadTestStore(t)
+
+	// An empty tile still has its layer.
+	tile, err := s.VectorTile(10, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _, features := decodeTile(t, tile); name != MVTLayer || len(features) != 0 {
+		t.Errorf("empty tile: layer %q with %d features", name, len(features))
+	}
+
+	// LHR is 2810 units into tile 10/510/340, so it's far outside the
+	// next tile's buffer, but a point just over the edge is kept.
+	if _, _, features := decodeTile(t, mustTile(t, s, 10, 511, 340)); len(features) != 0 {
+		t.Errorf("tile 10/511/340 has %d features", len(features))
+	}
+	edge := testAirport("QQE", "Edge")
+	edge.LatitudeDeg = 0
+	edge.LongitudeDeg = 0.001 // 0.0029 tiles east of 0° at zoom 10, about 12 units
+	if err := s.Put(edge); err != nil {
+		t.Fatal(err)
+	}
+	_, _, features := decodeTile(t, mustTile(t, s, 10, 511, 512))
+	if len(features) != 1 || features[0].x != 4096+12 {
+		t.Errorf("tile 10/511/512 has %+v, want QQE at x = 4108", features)
+	}
+
+	for _, zxy := range [][3]int{{-1, 0, 0}, {23, 0, 0}, {0, 1, 0}, {2, 0, 4}, {3, -1, 0}} {
+		if _, err := s.VectorTile(zxy[0], zxy[1], zxy[2]); err == nil {
+			t.Errorf("VectorTile(%v) accepted", zxy)
+		}
+	}
+}
+
+func mustTile(t *testing.T, s *Store, z, x, y int) []byte {
+	t.Helper()
+	tile, err := s.VectorTile(z, x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tile
+}
@@ -0,0 +1,36 @@
+package iataplaces
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// normalizeCode cleans up a code typed or pasted by a user: full-width
+// letters and digits ("ＬＨＲ") become ASCII, spaces of any kind (NBSP,
+// ideographic space) and invisible format characters (zero-width space,
+// BOM) are dropped, and the result is upper-cased.
+func normalizeCode(code string) string {
+	ascii := true
+	for i := 0; i < len(code); i++ {
+		if code[i] >= utf8.RuneSelf || code[i] <= ' ' {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return toUpperASCII(code)
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～': // full-width forms of ASCII ! to ~
+			r -= '！' - '!'
+		case unicode.IsSpace(r), unicode.Is(unicode.Cf, r), unicode.IsControl(r):
+			return -1
+		}
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		return r
+	}, code)
+}
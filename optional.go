@@ -0,0 +1,47 @@
+package iataplaces
+
+import "time"
+
+// Accessors for the optional fields, which are pointers so that a missing
+// value is distinguishable from zero. They are safe on nil airports and
+// convenient in templates, e.g. {{.ElevationFtOr 0}}.
+
+// HasElevation reports whether the elevation is known.
+func (a *Airport) HasElevation() bool {
+	return a != nil && a.ElevationFt != nil
+}
+
+// ElevationFtOr returns the elevation in feet, or def when it's unknown.
+func (a *Airport) ElevationFtOr(def int64) int64 {
+	if !a.HasElevation() {
+		return def
+	}
+	return *a.ElevationFt
+}
+
+// HasScore reports whether OurAirports gave the airport a score.
+func (a *Airport) HasScore() bool {
+	return a != nil && a.Score != nil
+}
+
+// ScoreOr returns the OurAirports score, or def when there is none.
+func (a *Airport) ScoreOr(def int64) int64 {
+	if !a.HasScore() {
+		return def
+	}
+	return *a.Score
+}
+
+// HasLastUpdate reports whether the record's last update time is known.
+func (a *Airport) HasLastUpdate() bool {
+	return a != nil && a.LastUpdateTime != nil
+}
+
+// LastUpdateOr returns when the record was last updated, or def when
+// that's unknown.
+func (a *Airport) LastUpdateOr(def time.Time) time.Time {
+	if !a.HasLastUpdate() {
+		return def
+	}
+	return *a.LastUpdateTime
+}
@@ -0,0 +1,93 @@
+package iataplaces
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LoadOption configures how a dataset is loaded.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	client   *http.Client
+	columns  map[string]string // file header -> CSVColumns name
+	lazy     bool
+	keywords bool
+	raw      bool
+
+	transforms []func(map[string]string) map[string]string
+	enrichers  []func(*Airport) error
+
+	onHeader func(header []string) error // called by parseCSV with the file's header
+}
+
+func newLoadConfig(opts []LoadOption) (loadConfig, error) {
+	cfg := loadConfig{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	for from, to := range cfg.columns {
+		if _, ok := csvColumnIndex[to]; !ok {
+			return cfg, fmt.Errorf("iataplaces: column mapping %q -> %q: unknown column", from, to)
+		}
+	}
+	return cfg, nil
+}
+
+// WithColumnMapping loads files whose header names differ from the
+// OurAirports ones. mapping goes from a header name in the file to one of
+// CSVColumns, e.g. {"airport_iata": "iata_code", "lat": "latitude_deg"};
+// unmapped headers keep their meaning. Files without an id column load
+// with zero IDs.
+func WithColumnMapping(mapping map[string]string) LoadOption {
+	return func(cfg *loadConfig) {
+		if cfg.columns == nil {
+			cfg.columns = make(map[string]string, len(mapping))
+		}
+		for from, to := range mapping {
+			cfg.columns[strings.TrimSpace(from)] = to
+		}
+	}
+}
+
+// WithEnricher adds fn to the stages run on every parsed record, in the
+// order given, before the airport is indexed. Stages may fill in or
+// rewrite any field, e.g. to resolve a timezone, attach an internal ID or
+// look a value up in another service; the IATA code is normalized again
+// afterwards, so one can be assigned to a record that lacks it. Stages see
+// records without an IATA code too, which a store then drops, so costly
+// ones should return early on those. An error from fn aborts the load.
+func WithEnricher(fn func(*Airport) error) LoadOption {
+	return func(cfg *loadConfig) { cfg.enrichers = append(cfg.enrichers, fn) }
+}
+
+// WithRowTransform adds fn to the functions every CSV record passes
+// through, in the order given, before it is parsed into an Airport. The
+// record maps CSVColumns names (after WithColumnMapping) and any other
+// header names to the raw values; fn returns the record to use, which may
+// be the same map modified, or nil to drop the row. Use it to clean up
+// names, patch known bad values or redact fields so they never reach the
+// store; Airport.Raw reports the transformed values. It can't be combined
+// with WithLazyFields, which reads columns back from the file.
+func WithRowTransform(fn func(record map[string]string) map[string]string) LoadOption {
+	return func(cfg *loadConfig) { cfg.transforms = append(cfg.transforms, fn) }
+}
+
+// WithKeywordIndex indexes the comma-separated tokens of the keywords
+// column, such as former codes and local names, for Store.LookupKeyword.
+// Search then ranks airports whose keyword equals the query text just
+// below code matches, instead of as a substring hit.
+func WithKeywordIndex() LoadOption {
+	return func(cfg *loadConfig) { cfg.keywords = true }
+}
+
+// WithLazyFields keeps the rarely used home_link, wikipedia_link and
+// keywords columns out of memory and reads them back from the file on
+// demand with Store.Expand. It only works when loading from an *os.File,
+// as LoadFromFile does, and the file must stay unchanged while the store
+// is in use. Search doesn't match keywords of a lazily loaded store, but
+// WithKeywordIndex still indexes them.
+func WithLazyFields() LoadOption {
+	return func(cfg *loadConfig) { cfg.lazy = true }
+}
@@ -0,0 +1,310 @@
+package iataplaces
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WriteParquet writes airports as an Apache Parquet file with the same
+// typed columns as WriteArrow, in one row group of uncompressed, plainly
+// encoded pages. Nullable columns are optional; the rest are required.
+func WriteParquet(w io.Writer, airports []*Airport) error {
+	pw := &arrowWriter{w: w}
+	pw.write([]byte(parquetMagic))
+
+	chunks := make([]parquetChunk, len(arrowColumns))
+	for i, col := range arrowColumns {
+		page := parquetPage(col, airports)
+		header := parquetPageHeader(len(airports), len(page))
+		chunks[i] = parquetChunk{offset: pw.n, size: int64(len(header) + len(page))}
+		pw.write(header)
+		pw.write(page)
+	}
+
+	footer := parquetFileMetaData(airports, chunks)
+	pw.write(footer)
+	pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	pw.write([]byte(parquetMagic))
+	return pw.err
+}
+
+const parquetMagic = "PAR1"
+
+// Enum values from the Parquet format's parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMillis = 9
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetUncompressed = 0
+
+	parquetDataPage = 0
+)
+
+// parquetChunk locates a column chunk, for the footer.
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+func parquetType(kind arrowKind) int32 {
+	switch kind {
+	case arrowInt64, arrowTimestamp:
+		return parquetInt64
+	case arrowFloat64:
+		return parquetDouble
+	case arrowBool:
+		return parquetBoolean
+	}
+	return parquetByteArray
+}
+
+// parquetPage returns the body of a data page holding col for airports:
+// definition levels for nullable columns, then the non-null values.
+func parquetPage(col arrowColumn, airports []*Airport) []byte {
+	var levels, values []byte
+	switch col.kind {
+	case arrowUtf8:
+		for _, a := range airports {
+			s := col.str(a)
+			values = binary.LittleEndian.AppendUint32(values, uint32(len(s)))
+			values = append(values, s...)
+		}
+	case arrowInt64, arrowTimestamp:
+		defined := make([]bool, len(airports))
+		for i, a := range airports {
+			v, ok := col.num(a)
+			if !ok {
+				continue
+			}
+			defined[i] = true
+			if col.kind == arrowTimestamp {
+				v *= 1000 // Parquet timestamps stop at milliseconds
+			}
+			values = binary.LittleEndian.AppendUint64(values, uint64(v))
+		}
+		if col.nullable {
+			levels = parquetLevels(defined)
+		}
+	case arrowFloat64:
+		for _, a := range airports {
+			values = binary.LittleEndian.AppendUint64(values, math.Float64bits(col.flt(a)))
+		}
+	case arrowBool:
+		values = make([]byte, (len(airports)+7)/8)
+		for i, a := range airports {
+			if col.flag(a) {
+				values[i/8] |= 1 << (i % 8)
+			}
+		}
+	}
+	return append(levels, values...)
+}
+
+// parquetLevels encodes one-bit definition levels as a single bit-packed
+// run of the RLE/bit-packing hybrid, prefixed with its length.
+func parquetLevels(defined []bool) []byte {
+	groups := (len(defined) + 7) / 8
+	run := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	bits := make([]byte, groups)
+	for i, ok := range defined {
+		if ok {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	}
+	run = append(run, bits...)
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(len(run))), run...)
+}
+
+func parquetPageHeader(numValues, size int) []byte {
+	t := &thriftWriter{}
+	t.i32(1, parquetDataPage)
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.structBegin(5) // DataPageHeader
+	t.i32(1, int32(numValues))
+	t.i32(2, parquetEncodingPlain)
+	t.i32(3, parquetEncodingRLE)
+	t.i32(4, parquetEncodingRLE)
+	t.structEnd()
+	t.stop()
+	return t.buf
+}
+
+func parquetFileMetaData(airports []*Airport, chunks []parquetChunk) []byte {
+	t := &thriftWriter{}
+	t.i32(1, 1) // version
+
+	t.listBegin(2, thriftStruct, len(arrowColumns)+1)
+	t.elemBegin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(arrowColumns)))
+	t.elemEnd()
+	for _, col := range arrowColumns {
+		t.elemBegin()
+		t.i32(1, parquetType(col.kind))
+		repetition := int32(parquetRequired)
+		if col.nullable {
+			repetition = parquetOptional
+		}
+		t.i32(3, repetition)
+		t.binary(4, col.name)
+		switch col.kind {
+		case arrowUtf8:
+			t.i32(6, parquetConvertedUTF8)
+			t.structBegin(10) // LogicalType
+			t.structBegin(1)  // STRING
+			t.structEnd()
+			t.structEnd()
+		case arrowTimestamp:
+			t.i32(6, parquetConvertedTimestampMillis)
+			t.structBegin(10) // LogicalType
+			t.structBegin(8)  // TIMESTAMP
+			t.bool(1, true)   // isAdjustedToUTC
+			t.structBegin(2)  // unit
+			t.structBegin(1)  // MILLIS
+			t.structEnd()
+			t.structEnd()
+			t.structEnd()
+			t.structEnd()
+		}
+		t.elemEnd()
+	}
+
+	t.i64(3, int64(len(airports)))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	t.listBegin(4, thriftStruct, 1)
+	t.elemBegin() // RowGroup
+	t.listBegin(1, thriftStruct, len(chunks))
+	for i, col := range arrowColumns {
+		t.elemBegin() // ColumnChunk
+		t.i64(2, chunks[i].offset)
+		t.structBegin(3) // ColumnMetaData
+		t.i32(1, parquetType(col.kind))
+		t.listBegin(2, thriftI32, 2)
+		t.listI32(parquetEncodingPlain)
+		t.listI32(parquetEncodingRLE)
+		t.listBegin(3, thriftBinary, 1)
+		t.listBinary(col.name)
+		t.i32(4, parquetUncompressed)
+		t.i64(5, int64(len(airports)))
+		t.i64(6, chunks[i].size)
+		t.i64(7, chunks[i].size)
+		t.i64(9, chunks[i].offset)
+		t.structEnd()
+		t.elemEnd()
+	}
+	t.i64(2, total)
+	t.i64(3, int64(len(airports)))
+	t.elemEnd()
+
+	t.binary(6, "iataplaces")
+	t.stop()
+	return t.buf
+}
+
+// Thrift compact protocol type codes.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol, which
+// writes field ids as deltas from the previous field of the same struct.
+type thriftWriter struct {
+	buf   []byte
+	last  int16
+	outer []int16
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	t.last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+// structBegin starts a struct-valued field; elemBegin starts a struct
+// inside a list. Both are closed by the matching End.
+func (t *thriftWriter) structBegin(id int16) {
+	t.field(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) structEnd() { t.elemEnd() }
+
+func (t *thriftWriter) elemBegin() {
+	t.outer = append(t.outer, t.last)
+	t.last = 0
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.stop()
+	t.last = t.outer[len(t.outer)-1]
+	t.outer = t.outer[:len(t.outer)-1]
+}
+
+func (t *thriftWriter) stop() { t.buf = append(t.buf, 0) }
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xf0|elem)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+func (t *thriftWriter) listI32(v int32) { t.buf = binary.AppendVarint(t.buf, int64(v)) }
+
+func (t *thriftWriter) listBinary(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
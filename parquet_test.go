@@ -0,0 +1,313 @@
+package iataplaces
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+// thriftReader decodes the Thrift compact protocol into maps from field
+// id to value, independently of thriftWriter. Structs are
+// map[int16]any, lists []any, binaries string and integers int64.
+type thriftReader struct {
+	t   *testing.T
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	if r.pos >= len(r.buf) {
+		r.t.Fatalf("thrift: read past the end at %d", r.pos)
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		r.t.Fatalf("thrift: bad varint at %d", r.pos)
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		r.t.Fatalf("thrift: bad varint at %d", r.pos)
+	}
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) any {
+	switch typ {
+	case thriftTrue:
+		return true
+	case thriftFalse:
+		return false
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		s := string(r.buf[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		h := r.byte()
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		return r.structure()
+	}
+	r.t.Fatalf("thrift: unexpected type %d at %d", typ, r.pos)
+	return nil
+}
+
+func (r *thriftReader) structure() map[int16]any {
+	fields := map[int16]any{}
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return fields
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(h & 0x0f)
+		last = id
+	}
+}
+
+func readThrift(t *testing.T, buf []byte) (map[int16]any, int) {
+	t.Helper()
+	r := &thriftReader{t: t, buf: buf}
+	s := r.structure()
+	return s, r.pos
+}
+
+// parquetColumn is a decoded column chunk; nulls are nil.
+type parquetColumn struct {
+	name       string
+	typ        int64
+	repetition int64
+	converted  any
+	values     []any
+}
+
+// readParquet decodes a file written by WriteParquet.
+func readParquet(t *testing.T, file []byte) (rows int64, cols []parquetColumn) {
+	t.Helper()
+	if !bytes.HasPrefix(file, []byte(parquetMagic)) || !bytes.HasSuffix(file, []byte(parquetMagic)) {
+		t.Fatal("missing PAR1 magic")
+	}
+	n := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	if n <= 0 || n > len(file)-12 {
+		t.Fatalf("footer length %d out of range", n)
+	}
+	meta, used := readThrift(t, file[len(file)-8-n:len(file)-8])
+	if used != n {
+		t.Errorf("FileMetaData is %d bytes, footer length says %d", used, n)
+	}
+	if meta[6] != "iataplaces" {
+		t.Errorf("created_by = %v", meta[6])
+	}
+	rows = meta[3].(int64)
+
+	schema := meta[2].([]any)
+	root := schema[0].(map[int16]any)
+	if root[4] != "schema" || root[5] != int64(len(schema)-1) {
+		t.Errorf("schema root = %v with %d children", root, len(schema)-1)
+	}
+	groups := meta[4].([]any)
+	if len(groups) != 1 {
+		t.Fatalf("%d row groups, want 1", len(groups))
+	}
+	group := groups[0].(map[int16]any)
+	if group[3] != rows {
+		t.Errorf("row group has %v rows, file %d", group[3], rows)
+	}
+	chunks := group[1].([]any)
+	if len(chunks) != len(schema)-1 {
+		t.Fatalf("%d column chunks for %d columns", len(chunks), len(schema)-1)
+	}
+
+	for i, el := range schema[1:] {
+		el := el.(map[int16]any)
+		col := parquetColumn{name: el[4].(string), typ: el[1].(int64), repetition: el[3].(int64), converted: el[6]}
+		chunk := chunks[i].(map[int16]any)[3].(map[int16]any)
+		if path := chunk[3].([]any); len(path) != 1 || path[0] != col.name {
+			t.Errorf("chunk %d has path %v, want [%s]", i, path, col.name)
+		}
+		if chunk[1] != col.typ || chunk[4] != int64(parquetUncompressed) || chunk[5] != rows {
+			t.Errorf("%s: chunk metadata %v", col.name, chunk)
+		}
+
+		offset := int(chunk[9].(int64))
+		header, used := readThrift(t, file[offset:])
+		data := header[5].(map[int16]any)
+		if header[1] != int64(parquetDataPage) || data[1] != rows || data[2] != int64(parquetEncodingPlain) {
+			t.Errorf("%s: page header %v", col.name, header)
+		}
+		size := int(header[3].(int64))
+		if int64(used+size) != chunk[7] {
+			t.Errorf("%s: page is %d bytes, chunk %v", col.name, used+size, chunk[7])
+		}
+		col.values = parquetValues(t, col, int(rows), file[offset+used:offset+used+size])
+		cols = append(cols, col)
+	}
+	return rows, cols
+}
+
+// parquetValues decodes a PLAIN data page, with one bit-packed run of
+// definition levels for optional columns.
+func parquetValues(t *testing.T, col parquetColumn, rows int, page []byte) []any {
+	t.Helper()
+	defined := func(int) bool { return true }
+	if col.repetition == parquetOptional {
+		n := int(binary.LittleEndian.Uint32(page))
+		run := page[4 : 4+n]
+		header, m := binary.Uvarint(run)
+		if header&1 != 1 || int(header>>1) != (rows+7)/8 {
+			t.Fatalf("%s: levels run header %d", col.name, header)
+		}
+		bits := run[m:]
+		defined = func(i int) bool { return bits[i/8]&(1<<(i%8)) != 0 }
+		page = page[4+n:]
+	}
+	values := make([]any, rows)
+	for i := range values {
+		if !defined(i) {
+			continue
+		}
+		switch col.typ {
+		case parquetByteArray:
+			n := int(binary.LittleEndian.Uint32(page))
+			values[i] = string(page[4 : 4+n])
+			page = page[4+n:]
+		case parquetInt64:
+			values[i] = int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case parquetDouble:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case parquetBoolean:
+			values[i] = page[i/8]&(1<<(i%8)) != 0
+		default:
+			t.Fatalf("%s: unexpected type %d", col.name, col.typ)
+		}
+	}
+	if col.typ == parquetBoolean {
+		page = page[(rows+7)/8:]
+	}
+	if len(page) != 0 {
+		t.Errorf("%s: %d bytes left in the page", col.name, len(page))
+	}
+	return values
+}
+
+func TestWriteParquet(t *testing.T) {
+	airports := loadTestStore(t).All()
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, airports); err != nil {
+		t.Fatal(err)
+	}
+	rows, cols := readParquet(t, buf.Bytes())
+	if rows != int64(len(airports)) {
+		t.Errorf("num_rows = %d, want %d", rows, len(airports))
+	}
+
+	var names []string
+	for _, col := range cols {
+		names = append(names, col.name)
+		optional := col.name == "elevation_ft" || col.name == "score" || col.name == "last_updated"
+		if (col.repetition == parquetOptional) != optional {
+			t.Errorf("%s: repetition %d", col.name, col.repetition)
+		}
+		var wantType int64 = parquetByteArray
+		var wantConverted any = int64(parquetConvertedUTF8)
+		switch col.name {
+		case "id", "elevation_ft", "score":
+			wantType, wantConverted = parquetInt64, nil
+		case "latitude_deg", "longitude_deg":
+			wantType, wantConverted = parquetDouble, nil
+		case "scheduled_service":
+			wantType, wantConverted = parquetBoolean, nil
+		case "last_updated":
+			wantType, wantConverted = parquetInt64, int64(parquetConvertedTimestampMillis)
+		}
+		if col.typ != wantType || col.converted != wantConverted {
+			t.Errorf("%s: type %d, converted %v; want %d, %v", col.name, col.typ, col.converted, wantType, wantConverted)
+		}
+	}
+	if !slices.Equal(names, CSVColumns) {
+		t.Fatalf("columns = %v, want %v", names, CSVColumns)
+	}
+
+	row := func(code string) map[string]any {
+		iata := slices.Index(names, "iata_code")
+		i := slices.Index(cols[iata].values, any(code))
+		if i < 0 {
+			t.Fatalf("no row for %s", code)
+		}
+		m := map[string]any{}
+		for _, col := range cols {
+			m[col.name] = col.values[i]
+		}
+		return m
+	}
+	lhr := row("LHR")
+	want := map[string]any{
+		"id":                int64(2434),
+		"ident":             "EGLL",
+		"name":              "London Heathrow Airport",
+		"latitude_deg":      51.4706,
+		"longitude_deg":     -0.461941,
+		"elevation_ft":      int64(83),
+		"iso_country":       "GB",
+		"scheduled_service": true,
+		"keywords":          "LON, Londres",
+		"local_code":        "",
+		"score":             int64(1251675),
+		"last_updated":      time.Date(2022, 10, 18, 18, 48, 50, 0, time.UTC).UnixMilli(),
+	}
+	for name, v := range want {
+		if lhr[name] != v {
+			t.Errorf("LHR %s = %#v, want %#v", name, lhr[name], v)
+		}
+	}
+	zzv := row("ZZV")
+	for _, name := range []string{"elevation_ft", "score", "last_updated"} {
+		if zzv[name] != nil {
+			t.Errorf("ZZV %s = %#v, want null", name, zzv[name])
+		}
+	}
+	if zzv["id"] != int64(9000001) || zzv["scheduled_service"] != false {
+		t.Errorf("ZZV id %v, scheduled_service %v", zzv["id"], zzv["scheduled_service"])
+	}
+}
+
+func TestWriteParquetEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, cols := readParquet(t, buf.Bytes())
+	if rows != 0 || len(cols) != len(CSVColumns) {
+		t.Errorf("%d rows in %d columns", rows, len(cols))
+	}
+}
@@ -0,0 +1,140 @@
+package iataplaces
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// area is a union of polygons, each a list of rings of [lon, lat] points:
+// the outer boundary first, then holes, as in GeoJSON.
+type area [][][][2]float64
+
+// WithinPolygon returns the airports inside a GeoJSON Polygon or
+// MultiPolygon, ordered by IATA code. A Feature or FeatureCollection of
+// them is accepted too, selecting airports inside any of the shapes. Holes
+// are honoured. Edges are straight lines in longitude/latitude, which is
+// how GeoJSON tools draw them; shapes crossing the antimeridian must be
+// split in two, as RFC 7946 requires.
+func (s *Store) WithinPolygon(geojson []byte) ([]*Airport, error) {
+	var ar area
+	if err := ar.parse(geojson); err != nil {
+		return nil, fmt.Errorf("iataplaces: polygon: %w", err)
+	}
+	if len(ar) == 0 {
+		return nil, errors.New("iataplaces: polygon: no Polygon or MultiPolygon geometry")
+	}
+	boxes := make([][4]float64, len(ar))
+	for i, poly := range ar {
+		boxes[i] = ringBounds(poly[0])
+	}
+	return s.Filter(func(a *Airport) bool {
+		for i, poly := range ar {
+			b := boxes[i]
+			if a.LongitudeDeg < b[0] || a.LatitudeDeg < b[1] || a.LongitudeDeg > b[2] || a.LatitudeDeg > b[3] {
+				continue
+			}
+			if polygonContains(poly, a.LongitudeDeg, a.LatitudeDeg) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// parse appends the polygons of a GeoJSON object to ar.
+func (ar *area) parse(data []byte) error {
+	var obj struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometry    json.RawMessage   `json:"geometry"`
+		Features    []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	switch obj.Type {
+	case "Polygon":
+		var poly [][][2]float64
+		if err := json.Unmarshal(obj.Coordinates, &poly); err != nil {
+			return fmt.Errorf("Polygon coordinates: %w", err)
+		}
+		return ar.add(poly)
+	case "MultiPolygon":
+		var polys [][][][2]float64
+		if err := json.Unmarshal(obj.Coordinates, &polys); err != nil {
+			return fmt.Errorf("MultiPolygon coordinates: %w", err)
+		}
+		for _, poly := range polys {
+			if err := ar.add(poly); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Feature":
+		if len(obj.Geometry) == 0 || string(obj.Geometry) == "null" {
+			return nil
+		}
+		return ar.parse(obj.Geometry)
+	case "FeatureCollection":
+		for _, f := range obj.Features {
+			if err := ar.parse(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Other geometry types (points, lines) enclose nothing.
+	return nil
+}
+
+func (ar *area) add(poly [][][2]float64) error {
+	if len(poly) == 0 {
+		return errors.New("polygon without rings")
+	}
+	for _, ring := range poly {
+		if len(ring) < 4 {
+			return fmt.Errorf("ring with %d positions, need at least 4", len(ring))
+		}
+	}
+	*ar = append(*ar, poly)
+	return nil
+}
+
+// ringBounds returns min lon, min lat, max lon, max lat.
+func ringBounds(ring [][2]float64) [4]float64 {
+	b := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range ring {
+		b[0], b[1] = math.Min(b[0], p[0]), math.Min(b[1], p[1])
+		b[2], b[3] = math.Max(b[2], p[0]), math.Max(b[3], p[1])
+	}
+	return b
+}
+
+// polygonContains reports whether (x, y) is inside the outer ring of poly
+// and outside all of its holes.
+func polygonContains(poly [][][2]float64, x, y float64) bool {
+	if !ringContains(poly[0], x, y) {
+		return false
+	}
+	for _, hole := range poly[1:] {
+		if ringContains(hole, x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains is the even-odd ray casting test.
+func ringContains(ring [][2]float64, x, y float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
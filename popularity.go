@@ -0,0 +1,185 @@
+package iataplaces
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// popularityColumns are the value columns LoadPopularity recognizes, in
+// order of preference.
+var popularityColumns = []string{"popularity", "pagerank", "passengers", "patronage", "traffic"}
+
+// LoadPopularityFromFile reads popularity data from a file; see
+// LoadPopularity.
+func (s *Store) LoadPopularityFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open popularity csv: %w", err)
+	}
+	defer f.Close()
+	return s.LoadPopularity(f)
+}
+
+// LoadPopularity attaches passenger traffic or popularity figures, such as
+// OPTD PageRanks or Wikidata patronage, read from a CSV. The header names
+// the airport by iata_code, icao_code or ident, and the value in one of
+// popularity, pagerank, passengers, patronage or traffic. Files separated
+// by '^', as OPTD publishes them, are read too. Values are scaled so the
+// most popular airport has 1.
+//
+// Popularity lifts search results and, where rows share an IATA code, a
+// value given per ICAO code or ident picks the row that holds the code, so
+// the busy airport wins over a namesake airstrip.
+func (s *Store) LoadPopularity(r io.Reader) error {
+	br := bufio.NewReader(r)
+	first, _ := br.Peek(4096)
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	if line, _, _ := bytes.Cut(first, []byte("\n")); bytes.Count(line, []byte("^")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = '^'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	colIndex := make(map[string]int, len(header))
+	for i, col := range header {
+		colIndex[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	valueCol := -1
+	for _, col := range popularityColumns {
+		if i, ok := colIndex[col]; ok {
+			valueCol = i
+			break
+		}
+	}
+	if valueCol < 0 {
+		return fmt.Errorf("popularity csv: no value column (want one of %s)", strings.Join(popularityColumns, ", "))
+	}
+	get := func(rec []string, col string) string {
+		idx, ok := colIndex[col]
+		if !ok || idx >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[idx])
+	}
+
+	byCode := map[string]float64{}  // IATA code -> value
+	byIdent := map[string]float64{} // ICAO code or ident -> value
+	maxValue := 0.0
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read record: %w", err)
+		}
+		if valueCol >= len(rec) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(rec[valueCol]), 64)
+		if err != nil || v < 0 {
+			continue
+		}
+		maxValue = max(maxValue, v)
+		if code := toUpperASCII(get(rec, "iata_code")); code != "" {
+			byCode[code] = max(byCode[code], v)
+		}
+		for _, col := range []string{"icao_code", "ident"} {
+			if id := toUpperASCII(get(rec, col)); id != "" {
+				byIdent[id] = max(byIdent[id], v)
+			}
+		}
+	}
+	if maxValue == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	if err := s.writable(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if s.popularity == nil {
+		s.popularity = make(map[string]float64)
+	}
+	identValue := func(a *Airport) (float64, bool) {
+		if v, ok := byIdent[toUpperASCII(a.ICAOCode)]; ok && a.ICAOCode != "" {
+			return v, true
+		}
+		v, ok := byIdent[toUpperASCII(a.Ident)]
+		return v, ok && a.Ident != ""
+	}
+	var changed []string
+	for code, a := range s.byIATA {
+		if losers := s.duplicates[code]; len(losers) > 0 {
+			if s.promotePopular(code, identValue) {
+				changed = append(changed, code)
+			}
+			a = s.byIATA[code]
+		}
+		v, ok := identValue(a)
+		if !ok {
+			v, ok = byCode[code]
+		}
+		if ok {
+			s.popularity[code] = v / maxValue
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(changed)
+	s.notify(ChangeDelta, ChangeSet{Changed: changed})
+	return nil
+}
+
+// promotePopular makes the most popular of the rows sharing code the
+// indexed one, and reports whether that changed it. s.mu must be held.
+func (s *Store) promotePopular(code string, value func(*Airport) (float64, bool)) bool {
+	current := s.byIATA[code]
+	best, bestValue, found := current, 0.0, false
+	if v, ok := value(current); ok {
+		bestValue, found = v, true
+	}
+	losers := s.duplicates[code]
+	for _, a := range losers {
+		if v, ok := value(a); ok && (!found || v > bestValue) {
+			best, bestValue, found = a, v, true
+		}
+	}
+	if best == current {
+		return false
+	}
+
+	rest := make([]*Airport, 0, len(losers))
+	rest = append(rest, current)
+	for _, a := range losers {
+		if a != best {
+			rest = append(rest, a)
+		}
+	}
+	s.duplicates[code] = rest
+	s.put(best)
+	return true
+}
+
+// Popularity returns the popularity loaded for an airport, scaled to
+// [0, 1].
+func (s *Store) Popularity(code string) (float64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.popularity[normalizeCode(code)]
+	return v, ok
+}
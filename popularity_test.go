@@ -0,0 +1,120 @@
+package iataplaces
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadPopularity(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    map[string]float64 // missing codes must have no figure
+		wantErr bool
+	}{
+		{
+			name: "scaled by the largest",
+			csv:  "iata_code,passengers\nLHR,80000000\nLGW,40000000\nJFK,20000000\n",
+			want: map[string]float64{"LHR": 1, "LGW": 0.5, "JFK": 0.25},
+		},
+		{
+			name: "OPTD caret separated",
+			csv:  "iata_code^pagerank\nCDG^0.8\nORY^0.2\n",
+			want: map[string]float64{"CDG": 1, "ORY": 0.25},
+		},
+		{
+			name: "preferred value column",
+			csv:  "iata_code,traffic,popularity\nHND,1,10\nNRT,100,5\n",
+			want: map[string]float64{"HND": 1, "NRT": 0.5},
+		},
+		{
+			name: "bad values skipped",
+			csv:  "iata_code,passengers\nMUC,50\nLHR,-5\nLGW,lots\nJFK\nlga, 25 \n",
+			want: map[string]float64{"MUC": 1, "LGA": 0.5},
+		},
+		{
+			name: "ICAO code and ident",
+			csv:  "icao_code,ident,traffic\nEGLL,,10\n,EGKK,5\nKJFK,XXXX,2.5\n",
+			want: map[string]float64{"LHR": 1, "LGW": 0.5, "JFK": 0.25},
+		},
+		{
+			name: "an ICAO figure beats an IATA one",
+			csv:  "iata_code,icao_code,traffic\nLHR,,100\n,EGLL,50\n",
+			want: map[string]float64{"LHR": 0.5},
+		},
+		{
+			name: "all zero",
+			csv:  "iata_code,passengers\nLHR,0\n",
+			want: map[string]float64{},
+		},
+		{name: "no value column", csv: "iata_code,name\nLHR,Heathrow\n", wantErr: true},
+		{name: "empty", csv: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := loadTestStore(t)
+			err := s.LoadPopularity(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadPopularity = %v, want error = %v", err, tt.wantErr)
+			}
+			for _, code := range testCodes {
+				got, ok := s.Popularity(code)
+				want, wantOK := tt.want[code]
+				if ok != wantOK || math.Abs(got-want) > 1e-9 {
+					t.Errorf("Popularity(%s) = %v, %v; want %v, %v", code, got, ok, want, wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPopularityPicksDuplicate(t *testing.T) {
+	// The airstrip wins on type order alone; traffic given per ident shows
+	// the other row is the busy airport.
+	s := loadDupStore(t,
+		"1,STRP,medium_airport,Namesake Strip,1,1,0,QQA,",
+		"2,BUSY,small_airport,Busy Airport,1,1,0,QQA,",
+		"3,OTHR,small_airport,Other,1,1,0,QQB,",
+	)
+	var events []ChangeEvent
+	s.Subscribe(func(e ChangeEvent) { events = append(events, e) })
+
+	if err := s.LoadPopularity(strings.NewReader("ident,passengers\nBUSY,1000\nSTRP,10\nOTHR,100\n")); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := s.LookupIATA("QQA"); a.Ident != "BUSY" {
+		t.Errorf("QQA is held by %s, want BUSY", a.Ident)
+	}
+	if got := identsOf(s.Duplicates("QQA")); !slices.Equal(got, []string{"STRP"}) {
+		t.Errorf("Duplicates = %v, want [STRP]", got)
+	}
+	if v, _ := s.Popularity("QQA"); v != 1 {
+		t.Errorf("Popularity(QQA) = %v, want the busy row's 1", v)
+	}
+	if v, _ := s.Popularity("QQB"); v != 0.1 {
+		t.Errorf("Popularity(QQB) = %v, want 0.1", v)
+	}
+	if len(events) != 1 || !slices.Equal(events[0].Changed, []string{"QQA"}) {
+		t.Errorf("events = %+v, want QQA changed", events)
+	}
+}
+
+func TestLoadPopularityRanksSearch(t *testing.T) {
+	s := loadTestStore(t)
+	if got := searchCodes(s, "london"); !slices.Equal(got, []string{"LGW", "LHR"}) {
+		t.Fatalf("Search before = %v", got)
+	}
+	if err := s.LoadPopularity(strings.NewReader("iata_code,passengers\nLHR,10\nLGW,90\n")); err != nil {
+		t.Fatal(err)
+	}
+	results := s.Search(SearchQuery{Text: "london"})
+	if len(results) != 2 || results[0].Airport.IATACode != "LGW" {
+		t.Fatalf("Search after = %+v", results)
+	}
+	// LGW's figure is the largest, so it gets the whole boost.
+	if want := 70 + sizeBoost(results[0].Airport) + popularityBoost; results[0].Score != want {
+		t.Errorf("LGW scored %v, want %v", results[0].Score, want)
+	}
+}
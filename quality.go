@@ -0,0 +1,191 @@
+package iataplaces
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RequiredColumns are the columns a dataset must have to be usable.
+var RequiredColumns = []string{
+	"id", "ident", "type", "name", "latitude_deg", "longitude_deg",
+	"iso_country", "iata_code",
+}
+
+// Severity grades a quality issue.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is one problem found while validating a dataset.
+type Issue struct {
+	Line     int      `json:"line"` // CSV line, the header being line 1
+	IATACode string   `json:"iata_code,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// QualityReport summarizes the schema and row-level checks of a dataset.
+type QualityReport struct {
+	Rows           int      `json:"rows"`
+	IATAAirports   int      `json:"iata_airports"` // rows with an IATA code
+	MissingColumns []string `json:"missing_columns,omitempty"`
+	Issues         []Issue  `json:"issues,omitempty"`
+}
+
+// Errors counts issues of error severity, including missing columns.
+func (r *QualityReport) Errors() int {
+	n := len(r.MissingColumns)
+	for _, is := range r.Issues {
+		if is.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// Warnings counts issues of warning severity.
+func (r *QualityReport) Warnings() int {
+	n := 0
+	for _, is := range r.Issues {
+		if is.Severity == SeverityWarning {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidateFile runs ValidateCSV on a file.
+func ValidateFile(path string) (*QualityReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open airports csv: %w", err)
+	}
+	defer f.Close()
+
+	return ValidateCSV(f)
+}
+
+// ValidateCSV checks an OurAirports-style CSV: required columns, numeric
+// and coordinate ranges, IATA code shape, duplicate codes and timestamps.
+// The returned error is only for unreadable input; data problems are
+// reported as issues.
+func ValidateCSV(r io.Reader) (*QualityReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	colIndex := make(map[string]int, len(header))
+	for i, col := range header {
+		colIndex[strings.TrimSpace(col)] = i
+	}
+
+	report := &QualityReport{}
+	for _, col := range RequiredColumns {
+		if _, ok := colIndex[col]; !ok {
+			report.MissingColumns = append(report.MissingColumns, col)
+		}
+	}
+
+	get := func(rec []string, col string) string {
+		idx, ok := colIndex[col]
+		if !ok || idx >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[idx])
+	}
+
+	firstSeen := make(map[string]int)
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read record: %w", err)
+		}
+		report.Rows++
+		line, _ := reader.FieldPos(0)
+		iata := strings.ToUpper(get(rec, "iata_code"))
+
+		add := func(field string, sev Severity, format string, args ...any) {
+			report.Issues = append(report.Issues, Issue{
+				Line:     line,
+				IATACode: iata,
+				Field:    field,
+				Severity: sev,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		}
+
+		if _, err := strconv.ParseInt(get(rec, "id"), 10, 64); err != nil {
+			add("id", SeverityError, "invalid id %q", get(rec, "id"))
+		}
+		checkCoord := func(field string, limit float64) {
+			v, err := strconv.ParseFloat(get(rec, field), 64)
+			switch {
+			case err != nil:
+				add(field, SeverityError, "invalid number %q", get(rec, field))
+			case v < -limit || v > limit:
+				add(field, SeverityError, "%v out of range ±%v", v, limit)
+			}
+		}
+		checkCoord("latitude_deg", 90)
+		checkCoord("longitude_deg", 180)
+		for _, field := range []string{"elevation_ft", "score"} {
+			if v := get(rec, field); v != "" {
+				if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+					add(field, SeverityWarning, "invalid integer %q", v)
+				}
+			}
+		}
+		if v := get(rec, "last_updated"); v != "" {
+			if _, ok := parseTimestamp(v); !ok {
+				add("last_updated", SeverityWarning, "unparseable timestamp %q", v)
+			}
+		}
+
+		if iata == "" {
+			continue
+		}
+		report.IATAAirports++
+		if !isIATACode(iata) {
+			add("iata_code", SeverityError, "IATA code %q is not three letters or digits", iata)
+		}
+		if get(rec, "name") == "" {
+			add("name", SeverityWarning, "airport has no name")
+		}
+		if prev, ok := firstSeen[iata]; ok {
+			add("iata_code", SeverityWarning, "duplicate IATA code (first seen on line %d)", prev)
+		} else {
+			firstSeen[iata] = line
+		}
+	}
+
+	return report, nil
+}
+
+// isIATACode reports whether s looks like an IATA airport code: three
+// ASCII letters or digits.
+func isIATACode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
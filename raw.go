@@ -0,0 +1,53 @@
+package iataplaces
+
+import (
+	"slices"
+	"strings"
+)
+
+// RawRecord is the CSV row an airport was parsed from, see WithRawRecords.
+type RawRecord struct {
+	Header []string // the file's header as written, before WithColumnMapping
+	Fields []string // the row's fields, untrimmed
+}
+
+// Get returns the field under the named header column.
+func (r RawRecord) Get(column string) (string, bool) {
+	for i, h := range r.Header {
+		if strings.TrimSpace(h) == column {
+			if i < len(r.Fields) {
+				return r.Fields[i], true
+			}
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// rawRecord is what WithRawRecords keeps per airport; header is shared by
+// every row of a load.
+type rawRecord struct {
+	header []string
+	fields []string
+}
+
+// WithRawRecords keeps each airport's original CSV fields, so columns the
+// Airport struct doesn't model (including ones OurAirports adds later)
+// stay reachable through Airport.Raw. It roughly doubles the memory a
+// store needs.
+func WithRawRecords() LoadOption {
+	return func(cfg *loadConfig) { cfg.raw = true }
+}
+
+// Raw returns the CSV row a was parsed from. It is only available for
+// airports loaded with WithRawRecords, not for ones built in code or read
+// from gob.
+func (a *Airport) Raw() (RawRecord, bool) {
+	if a == nil || a.raw == nil {
+		return RawRecord{}, false
+	}
+	return RawRecord{
+		Header: slices.Clone(a.raw.header),
+		Fields: slices.Clone(a.raw.fields),
+	}, true
+}
@@ -0,0 +1,48 @@
+package iataplaces
+
+import (
+	"sort"
+	"sync"
+)
+
+// Named stores let one process serve several datasets, for example a
+// production snapshot next to a sandbox fixture or a region-restricted view,
+// and pick one per tenant or environment.
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]*Store{}
+)
+
+// RegisterStore makes s available as StoreNamed(name), replacing any store
+// already registered under that name. Readers that looked up the old store
+// keep using it, so registering a new Clone or Snapshot is a safe way to
+// publish a dataset update. Registering nil removes the name.
+func RegisterStore(name string, s *Store) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if s == nil {
+		delete(registry, name)
+		return
+	}
+	registry[name] = s
+}
+
+// StoreNamed returns the store registered under name.
+func StoreNamed(name string) (*Store, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// StoreNames returns the registered names in sorted order.
+func StoreNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,53 @@
+package iataplaces
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithHTTPClient makes remote loads use c instead of http.DefaultClient,
+// for proxies, authenticating transports or instrumentation.
+func WithHTTPClient(c *http.Client) LoadOption {
+	return func(cfg *loadConfig) {
+		if c != nil {
+			cfg.client = c
+		}
+	}
+}
+
+// LoadFromURL downloads an airports CSV, such as the OurAirports file or a
+// mirror written by airports-update, and loads it. URLs ending in .gz are
+// decompressed.
+func LoadFromURL(ctx context.Context, url string, opts ...LoadOption) (*Store, error) {
+	cfg, err := newLoadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch airports csv: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch airports csv: status %d from %s", resp.StatusCode, url)
+	}
+
+	var r io.Reader = resp.Body
+	if strings.HasSuffix(req.URL.Path, ".gz") {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("fetch airports csv: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	return LoadFromReader(r, opts...)
+}
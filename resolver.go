@@ -0,0 +1,163 @@
+package iataplaces
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Resolver looks up airports a local dataset doesn't have yet, such as a
+// code opened since the last CSV refresh. Implement it over a remote API
+// or a secondary dataset. A nil airport with a nil error means the code is
+// unknown there too.
+type Resolver interface {
+	ResolveIATA(ctx context.Context, code string) (*Airport, error)
+}
+
+// ResolverFunc adapts a function to Resolver.
+type ResolverFunc func(ctx context.Context, code string) (*Airport, error)
+
+// ResolveIATA calls f.
+func (f ResolverFunc) ResolveIATA(ctx context.Context, code string) (*Airport, error) {
+	return f(ctx, code)
+}
+
+// ResolvingStore reads through to a Resolver when its source misses a
+// code, and caches the answers, found or not. Errors aren't cached, so
+// the next lookup asks again.
+type ResolvingStore struct {
+	src      AirportSource
+	resolver Resolver
+	ttl      time.Duration
+
+	mu    sync.Mutex
+	cache map[string]resolved // IATA code -> answer
+}
+
+type resolved struct {
+	airport *Airport // nil when the resolver didn't know the code
+	expires time.Time
+}
+
+var _ AirportSource = (*ResolvingStore)(nil)
+
+// NewResolvingStore returns a store answering from src, falling back to r
+// for codes src doesn't have. Answers are cached for ttl, or until the
+// next Forget when ttl is 0.
+func NewResolvingStore(src AirportSource, r Resolver, ttl time.Duration) *ResolvingStore {
+	return &ResolvingStore{src: src, resolver: r, ttl: ttl, cache: make(map[string]resolved)}
+}
+
+// LookupIATA is LookupIATAContext without a deadline. An error from the
+// resolver counts as a miss.
+func (s *ResolvingStore) LookupIATA(code string) (*Airport, bool) {
+	a, err := s.LookupIATAContext(context.Background(), code)
+	return a, err == nil && a != nil
+}
+
+// LookupIATAContext returns the airport from the source or, when it
+// misses, from the cache or the resolver. It returns a nil airport and a
+// nil error for codes neither knows.
+func (s *ResolvingStore) LookupIATAContext(ctx context.Context, code string) (*Airport, error) {
+	if a, ok := s.src.LookupIATA(code); ok {
+		return a, nil
+	}
+	code = normalizeCode(code)
+	if !isIATACode(code) {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	r, ok := s.cache[code]
+	s.mu.Unlock()
+	if ok && (r.expires.IsZero() || time.Now().Before(r.expires)) {
+		return r.airport.Clone(), nil
+	}
+
+	a, err := s.resolver.ResolveIATA(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: resolve %s: %w", code, err)
+	}
+	if a != nil {
+		a = a.Clone()
+		a.IATACode = code
+	}
+	r = resolved{airport: a}
+	if s.ttl > 0 {
+		r.expires = time.Now().Add(s.ttl)
+	}
+	s.mu.Lock()
+	s.cache[code] = r
+	s.mu.Unlock()
+	return a.Clone(), nil
+}
+
+// LookupICAO asks the source, then the airports resolved so far. The
+// resolver itself is only asked for IATA codes.
+func (s *ResolvingStore) LookupICAO(code string) (*Airport, bool) {
+	if a, ok := s.src.LookupICAO(code); ok {
+		return a, true
+	}
+	code = normalizeCode(code)
+	if code == "" {
+		return nil, false
+	}
+	for _, a := range s.resolvedAirports() {
+		if a.ICAOCode == code {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// All returns the source's airports and the ones resolved so far, ordered
+// by IATA code.
+func (s *ResolvingStore) All() []*Airport {
+	out := s.src.All()
+	extra := s.resolvedAirports()
+	if len(extra) == 0 {
+		return out
+	}
+	for _, a := range extra {
+		if _, ok := s.src.LookupIATA(a.IATACode); !ok {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IATACode < out[j].IATACode })
+	return out
+}
+
+// Len returns the number of airports All would return.
+func (s *ResolvingStore) Len() int {
+	return len(s.All())
+}
+
+// Forget drops the cached answers for the given codes, or all of them
+// when called without any, e.g. after the source has been refreshed.
+func (s *ResolvingStore) Forget(codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(codes) == 0 {
+		clear(s.cache)
+		return
+	}
+	for _, code := range codes {
+		delete(s.cache, normalizeCode(code))
+	}
+}
+
+// resolvedAirports returns copies of the unexpired airports in the cache.
+func (s *ResolvingStore) resolvedAirports() []*Airport {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Airport
+	for _, r := range s.cache {
+		if r.airport != nil && (r.expires.IsZero() || now.Before(r.expires)) {
+			out = append(out, r.airport.Clone())
+		}
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+package iataplaces
+
+import (
+	"sort"
+	"strings"
+)
+
+// SearchQuery describes a free-text and/or filtered airport search.
+type SearchQuery struct {
+	// Text is matched case-insensitively against the IATA/ICAO codes,
+	// name, municipality and keywords. Empty text matches every airport
+	// the filters allow.
+	Text string
+
+	City    string // municipality, case-insensitive exact match
+	Country string // ISO 3166-1 alpha-2 country code
+	Type    string // e.g. "large_airport"
+
+	Limit int // maximum results; 0 means no limit
+
+	// Popularity holds the caller's own figures, such as click or booking
+	// counts, keyed by IATA code. Scaled by their maximum, they are blended
+	// with the dataset's popularity (from LoadPopularity, or else the
+	// OurAirports score) to rank airports the caller's users pick often
+	// higher.
+	Popularity map[string]float64
+
+	// PopularityWeight is the share of Popularity in that blend, from 0 to
+	// 1. Zero means an even blend; use 1 to ignore the dataset's figures.
+	PopularityWeight float64
+}
+
+// SearchResult is one ranked search hit. Higher scores rank first.
+type SearchResult struct {
+	Airport *Airport
+	Score   float64
+
+	// Matches lists where the query text was found, best match first, so
+	// UIs can highlight it. It is empty when the query had no text.
+	Matches []Match
+}
+
+// Match is a match of the query text in one airport field. Start and End
+// are byte offsets into the field's value, so the matched part is
+// value[Start:End]; they are zero for keyword hits that matched only after
+// normalization.
+type Match struct {
+	Field string `json:"field"` // a CSVColumns name, e.g. "name" or "iata_code"
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+
+	score float64 // what the match is worth, for ordering
+}
+
+// Search returns airports matching q, best matches first. Ties are broken
+// by IATA code so results are stable.
+func (s *Store) Search(q SearchQuery) []SearchResult {
+	if s == nil {
+		return nil
+	}
+
+	text := strings.ToLower(strings.TrimSpace(q.Text))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var aliases []string
+	if s.keywords != nil && text != "" {
+		aliases = s.keywords.byToken[normalizeKeyword(text)]
+	}
+	popularity := s.popularityFor(q)
+	airports := s.sorted
+	if s.fullText != nil && text != "" {
+		if codes, ok := s.fullText.candidates(text); ok {
+			codes = unionSorted(codes, aliases)
+			airports = make([]*Airport, 0, len(codes))
+			for _, code := range codes {
+				if a, ok := s.byIATA[code]; ok {
+					airports = append(airports, a)
+				}
+			}
+		}
+	}
+	var results []SearchResult
+	for _, a := range airports {
+		if q.City != "" && !strings.EqualFold(a.Municipality, q.City) {
+			continue
+		}
+		if q.Country != "" && !strings.EqualFold(a.IsoCountry, q.Country) {
+			continue
+		}
+		if q.Type != "" && a.Type != q.Type {
+			continue
+		}
+
+		score := 1.0
+		var matches []Match
+		if text != "" {
+			score, matches = textScore(a, text)
+			if containsString(aliases, a.IATACode) {
+				score = max(score, keywordScore)
+				matches = keywordMatch(matches)
+			}
+			if score == 0 {
+				continue
+			}
+		}
+		results = append(results, SearchResult{
+			Airport: a,
+			Score:   score + sizeBoost(a) + popularityBoost*popularity(a),
+			Matches: matches,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	for i := range results {
+		results[i].Airport = s.out(results[i].Airport)
+	}
+	return results
+}
+
+// textScore rates how well a matches the lower-cased query text; 0 means
+// no match. It also returns where each field matched, best first.
+func textScore(a *Airport, text string) (float64, []Match) {
+	var best float64
+	var matches []Match
+	hit := func(field, value string, v float64, start int) {
+		if v == 0 {
+			return
+		}
+		best = max(best, v)
+		matches = append(matches, Match{Field: field, Start: start, End: matchEnd(value, start, text), score: v})
+	}
+
+	if strings.EqualFold(a.IATACode, text) {
+		hit("iata_code", a.IATACode, 100, 0)
+	}
+	if strings.EqualFold(a.ICAOCode, text) {
+		hit("icao_code", a.ICAOCode, 90, 0)
+	}
+	if strings.EqualFold(a.Ident, text) {
+		hit("ident", a.Ident, 90, 0)
+	}
+	v, start := fieldScore(a.Name, text, 80, 60, 50, 30)
+	hit("name", a.Name, v, start)
+	v, start = fieldScore(a.Municipality, text, 70, 45, 40, 25)
+	hit("municipality", a.Municipality, v, start)
+	if start := indexFold(a.Keywords, text); start >= 0 {
+		hit("keywords", a.Keywords, 20, start)
+	}
+	sortMatches(matches)
+	return best, matches
+}
+
+// sortMatches orders matches best first.
+func sortMatches(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+}
+
+// keywordMatch ranks the keywords match of an exact keyword hit at
+// keywordScore, adding one without offsets when the text only matched
+// after normalization.
+func keywordMatch(matches []Match) []Match {
+	for i := range matches {
+		if matches[i].Field == "keywords" {
+			matches[i].score = keywordScore
+			sortMatches(matches)
+			return matches
+		}
+	}
+	matches = append(matches, Match{Field: "keywords", score: keywordScore})
+	sortMatches(matches)
+	return matches
+}
+
+// keywordScore ranks an exact keyword hit from WithKeywordIndex, just
+// below ICAO code matches.
+const keywordScore = 85
+
+// popularityFor returns the [0, 1] popularity Search ranks by: the loaded
+// figures, blended with q.Popularity when the query has some. s.mu must be
+// held.
+func (s *Store) popularityFor(q SearchQuery) func(*Airport) float64 {
+	dataset := func(a *Airport) float64 { return s.popularity[a.IATACode] }
+	if len(q.Popularity) == 0 {
+		return dataset
+	}
+
+	if len(s.popularity) == 0 {
+		var maxScore int64
+		for _, a := range s.sorted {
+			maxScore = max(maxScore, a.ScoreOr(0))
+		}
+		dataset = func(a *Airport) float64 {
+			if maxScore <= 0 {
+				return 0
+			}
+			return float64(max(a.ScoreOr(0), 0)) / float64(maxScore)
+		}
+	}
+	var maxOwn float64
+	for _, v := range q.Popularity {
+		maxOwn = max(maxOwn, v)
+	}
+	weight := q.PopularityWeight
+	if weight <= 0 {
+		weight = 0.5
+	}
+	weight = min(weight, 1)
+	return func(a *Airport) float64 {
+		own := 0.0
+		if maxOwn > 0 {
+			own = max(q.Popularity[a.IATACode], 0) / maxOwn
+		}
+		return weight*own + (1-weight)*dataset(a)
+	}
+}
+
+// fieldScore grades a case-insensitive match of the lower-cased text
+// against field as exact, prefix, word-prefix or substring, and returns
+// the byte offset in field where it starts.
+func fieldScore(field, text string, exact, prefix, wordPrefix, contains float64) (float64, int) {
+	if field == "" {
+		return 0, 0
+	}
+	i := indexFold(field, text)
+	switch {
+	case i < 0:
+		return 0, 0
+	case i == 0 && matchEnd(field, 0, text) == len(field):
+		return exact, 0
+	case i == 0:
+		return prefix, 0
+	}
+	if j := indexFold(field, " "+text); j >= 0 {
+		return wordPrefix, j + 1
+	}
+	return contains, i
+}
+
+// indexFold returns the byte offset in s of the first case-insensitive
+// occurrence of the lower-cased text, or -1.
+func indexFold(s, text string) int {
+	lower := strings.ToLower(s)
+	i := strings.Index(lower, text)
+	if i < 0 || len(lower) == len(s) {
+		return i
+	}
+	// Lower-casing changed some byte lengths; map the offset back.
+	return origOffset(s, i)
+}
+
+// matchEnd returns the byte offset in s where a case-insensitive match of
+// text starting at start ends.
+func matchEnd(s string, start int, text string) int {
+	if len(strings.ToLower(s)) == len(s) {
+		return min(start+len(text), len(s))
+	}
+	n := len(strings.ToLower(s[:start]))
+	return origOffset(s, n+len(text))
+}
+
+// origOffset maps a byte offset in strings.ToLower(s) to the offset of the
+// same rune in s.
+func origOffset(s string, lowerOffset int) int {
+	n := 0
+	for i, r := range s {
+		if n >= lowerOffset {
+			return i
+		}
+		n += len(strings.ToLower(string(r)))
+	}
+	return len(s)
+}
+
+// sizeBoost nudges bigger, scheduled airports above small fields with
+// similar text matches.
+func sizeBoost(a *Airport) float64 {
+	var b float64
+	switch a.Type {
+	case "large_airport":
+		b = 5
+	case "medium_airport":
+		b = 3
+	case "small_airport":
+		b = 1
+	}
+	if a.Scheduled {
+		b += 2
+	}
+	return b
+}
+
+// popularityBoost is the most LoadPopularity figures add to a search
+// score, enough to order airports with similar text matches by traffic
+// but not to lift a weak match over a strong one.
+const popularityBoost = 8
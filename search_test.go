@@ -0,0 +1,161 @@
+package iataplaces
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchRanking(t *testing.T) {
+	s := loadTestStore(t)
+	keyed := loadTestStore(t, WithKeywordIndex())
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"lhr", []string{"LHR"}},
+		{"EGLL", []string{"LHR"}},
+		// Municipality "London" is an exact match for both; ties go by code.
+		{"london", []string{"LGW", "LHR"}},
+		{"lon", []string{"LGW", "LHR"}},
+		// A name prefix beats a municipality prefix.
+		{"paris", []string{"ORY", "CDG"}},
+		// Name and municipality beat a keyword.
+		{"tokyo", []string{"HND", "NRT"}},
+		{"new york", []string{"JFK", "LGA"}},
+		{"int", []string{"CDG", "HND", "JFK", "NRT"}},
+		// Size boosts don't lift a substring above a prefix.
+		{"ex", []string{"ZZV"}},
+		{"crawley", []string{"LGW"}},
+		{"nowhere at all", nil},
+	}
+	for _, tt := range tests {
+		if got := searchCodes(s, tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	// An exact keyword hit from WithKeywordIndex ranks just below an ICAO
+	// code, above name and municipality matches.
+	if got := searchCodes(keyed, "tyo"); !slices.Equal(got, []string{"HND", "NRT"}) {
+		t.Errorf("keyword Search(tyo) = %v", got)
+	}
+	for _, r := range keyed.Search(SearchQuery{Text: "tyo"}) {
+		if r.Score != keywordScore+sizeBoost(r.Airport) || r.Matches[0].Field != "keywords" {
+			t.Errorf("keyword hit %s scored %v with matches %+v", r.Airport.IATACode, r.Score, r.Matches)
+		}
+	}
+
+	results := s.Search(SearchQuery{Text: "airport", Country: "gb", Limit: 1})
+	if len(results) != 1 || results[0].Airport.IATACode != "LGW" {
+		t.Errorf("filtered Search = %+v", results)
+	}
+	if got := s.Search(SearchQuery{Type: "small_airport"}); len(got) != 1 || got[0].Score != 1+sizeBoost(got[0].Airport) {
+		t.Errorf("Search without text = %+v", got)
+	}
+}
+
+func TestSearchPopularity(t *testing.T) {
+	s := loadTestStore(t)
+	// Without LoadPopularity the dataset's side of the blend is the score
+	// scaled by the largest, LHR's.
+	lhrData, lgwData := 1.0, 1049275.0/1251675.0
+	tests := []struct {
+		name    string
+		own     map[string]float64
+		weight  float64
+		lhr     float64
+		lgw     float64
+		ranking []string
+	}{
+		{"none", nil, 0, 0, 0, []string{"LGW", "LHR"}},
+		{"default weight", map[string]float64{"LHR": 10, "LGW": 1}, 0, 0.5 + 0.5*lhrData, 0.5*0.1 + 0.5*lgwData, []string{"LHR", "LGW"}},
+		{"default weight favours own figures", map[string]float64{"LGW": 10}, 0, 0.5 * lhrData, 0.5 + 0.5*lgwData, []string{"LGW", "LHR"}},
+		{"small weight", map[string]float64{"LGW": 10}, 0.1, 0.9 * lhrData, 0.1 + 0.9*lgwData, []string{"LHR", "LGW"}},
+		{"own figures only", map[string]float64{"LGW": 10}, 1, 0, 1, []string{"LGW", "LHR"}},
+		{"weight capped at 1", map[string]float64{"LGW": 10}, 5, 0, 1, []string{"LGW", "LHR"}},
+		{"negative figures", map[string]float64{"LGW": -3, "LHR": 4}, 1, 1, 0, []string{"LHR", "LGW"}},
+		{"negative weight", map[string]float64{"LGW": 10}, -1, 0.5 * lhrData, 0.5 + 0.5*lgwData, []string{"LGW", "LHR"}},
+	}
+	lhr, _ := s.LookupIATA("LHR")
+	lgw, _ := s.LookupIATA("LGW")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := SearchQuery{Text: "london", Popularity: tt.own, PopularityWeight: tt.weight}
+			s.mu.RLock()
+			popularity := s.popularityFor(q)
+			gotLHR, gotLGW := popularity(lhr), popularity(lgw)
+			s.mu.RUnlock()
+			if math.Abs(gotLHR-tt.lhr) > 1e-9 || math.Abs(gotLGW-tt.lgw) > 1e-9 {
+				t.Errorf("popularity LHR %v, LGW %v; want %v, %v", gotLHR, gotLGW, tt.lhr, tt.lgw)
+			}
+
+			results := s.Search(q)
+			var codes []string
+			for _, r := range results {
+				codes = append(codes, r.Airport.IATACode)
+			}
+			if !slices.Equal(codes, tt.ranking) {
+				t.Errorf("Search = %v, want %v", codes, tt.ranking)
+			}
+			if want := 70 + sizeBoost(lhr) + popularityBoost*tt.lhr; math.Abs(results[slices.Index(codes, "LHR")].Score-want) > 1e-9 {
+				t.Errorf("LHR scored %v, want %v", results[slices.Index(codes, "LHR")].Score, want)
+			}
+		})
+	}
+}
+
+func TestSearchMatchOffsets(t *testing.T) {
+	s := loadTestStore(t)
+	// "İ" is two bytes but lower-cases to one, "i", so offsets found in
+	// the lower-cased name have to be mapped back.
+	ist := testAirport("IST", "İstanbul İnternational Airport")
+	ist.Municipality = "İstanbul"
+	ist.Keywords = "İST, Avrupa"
+	if err := s.Put(ist); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want []Match // Field, Start, End
+	}{
+		{"stanbul", []Match{{Field: "name", Start: 2, End: 9}, {Field: "municipality", Start: 2, End: 9}}},
+		{"İstanbul", []Match{{Field: "municipality", Start: 0, End: 9}, {Field: "name", Start: 0, End: 9}}},
+		{"istanbul", []Match{{Field: "municipality", Start: 0, End: 9}, {Field: "name", Start: 0, End: 9}}},
+		{"international", []Match{{Field: "name", Start: 10, End: 24}}},
+		{"airport", []Match{{Field: "name", Start: 25, End: 32}}},
+		{"nternational", []Match{{Field: "name", Start: 12, End: 24}}},
+		{"avrupa", []Match{{Field: "keywords", Start: 6, End: 12}}},
+		{"ist", []Match{
+			{Field: "iata_code", Start: 0, End: 3},
+			{Field: "name", Start: 0, End: 4},
+			{Field: "municipality", Start: 0, End: 4},
+			{Field: "keywords", Start: 0, End: 4},
+		}},
+	}
+	for _, tt := range tests {
+		var got []Match
+		for _, r := range s.Search(SearchQuery{Text: tt.text}) {
+			if r.Airport.IATACode == "IST" {
+				got = r.Matches
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Search(%q) matches %+v, want %+v", tt.text, got, tt.want)
+			continue
+		}
+		for i, m := range got {
+			w := tt.want[i]
+			if m.Field != w.Field || m.Start != w.Start || m.End != w.End {
+				t.Errorf("Search(%q) match %d = %+v, want %+v", tt.text, i, m, w)
+				continue
+			}
+			value := map[string]string{"name": ist.Name, "municipality": ist.Municipality, "keywords": ist.Keywords, "iata_code": "IST"}[m.Field]
+			if matched := value[m.Start:m.End]; strings.ToLower(matched) != strings.ToLower(tt.text) {
+				t.Errorf("Search(%q) highlights %q in %s", tt.text, matched, m.Field)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package iataplaces
+
+import (
+	"strings"
+	"unicode"
+)
+
+// slugStopWords are left out of slugs; nearly every name has one.
+var slugStopWords = map[string]bool{
+	"airport": true, "international": true, "intl": true,
+	"aerodrome": true, "airfield": true, "airstrip": true,
+}
+
+// latinFold spells accented and special Latin letters in ASCII.
+var latinFold = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ľ': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ș': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'ț': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// Slug returns a URL-friendly name for a, e.g. "london-heathrow-lhr": the
+// name in lower-case ASCII without words like "airport", followed by the
+// IATA code (or the ident when there is none). The code makes slugs
+// unique within a store even when names collide: "paris-cdg" and
+// "paris-ory" are both Paris.
+func (a *Airport) Slug() string {
+	code := a.IATACode
+	if code == "" {
+		code = a.Ident
+	}
+	code = slugify(code)
+	words := strings.Split(slugify(a.Name), "-")
+	kept := words[:0]
+	for _, w := range words {
+		if w != "" && !slugStopWords[w] {
+			kept = append(kept, w)
+		}
+	}
+	if n := len(kept); n > 0 && kept[n-1] == code {
+		kept = kept[:n-1]
+	}
+	return strings.Join(append(kept, code), "-")
+}
+
+// slugify lower-cases s, spells Latin letters in ASCII and joins the
+// remaining runs of letters and digits with hyphens. Other scripts are
+// dropped.
+func slugify(s string) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range strings.ToLower(s) {
+		var part string
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			part = string(r)
+		default:
+			part = latinFold[r]
+		}
+		if part == "" {
+			pending = b.Len() > 0
+			continue
+		}
+		if pending {
+			b.WriteByte('-')
+			pending = false
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// LookupSlug returns the airport whose Slug is slug, ignoring case and
+// surrounding spaces. Slugs are indexed when airports are loaded or
+// changed, so a slug only matches the current name: "anything-lhr" and
+// the slug from before a name was corrected miss. Callers that want old
+// URLs to keep working can redirect them to the Slug of the airport
+// LookupIATA finds for the code after the last hyphen.
+func (s *Store) LookupSlug(slug string) (*Airport, bool) {
+	if s == nil {
+		return nil, false
+	}
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	s.mu.RLock()
+	a, ok := s.bySlug[slug]
+	s.mu.RUnlock()
+	return s.out(a), ok
+}
@@ -0,0 +1,109 @@
+package iataplaces
+
+import "testing"
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name, iata, ident string
+		want              string
+	}{
+		{"London Heathrow Airport", "LHR", "EGLL", "london-heathrow-lhr"},
+		{"Charles de Gaulle International Airport", "CDG", "LFPG", "charles-de-gaulle-cdg"},
+		{"São Paulo–Guarulhos International Airport", "GRU", "SBGR", "sao-paulo-guarulhos-gru"},
+		{"Zürich Airport", "ZRH", "LSZH", "zurich-zrh"},
+		{"Aeroporto di Bologna (BLQ)", "BLQ", "LIPE", "aeroporto-di-bologna-blq"},
+		{"東京国際空港", "HND", "RJTT", "hnd"},
+		{"Battersea Heliport", "", "EGLW", "battersea-heliport-eglw"},
+	}
+	for _, tt := range tests {
+		a := &Airport{Name: tt.name, IATACode: tt.iata, Ident: tt.ident}
+		if got := a.Slug(); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLookupSlug(t *testing.T) {
+	s := loadTestStore(t)
+	tests := []struct {
+		slug string
+		want string // IATA code; "" for a miss
+	}{
+		{"london-heathrow-lhr", "LHR"},
+		{" London-Heathrow-LHR ", "LHR"},
+		{"anything-lhr", ""},
+		{"lhr", ""},
+		{"london-gatwick-lhr", ""},
+		{"london-heathrow-lhr-x", ""},
+		{"", ""},
+		{"-", ""},
+	}
+	for _, tt := range tests {
+		a, ok := s.LookupSlug(tt.slug)
+		if got := codeOrEmpty(a, ok); got != tt.want {
+			t.Errorf("LookupSlug(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+
+	// Every airport is found under its own slug.
+	for _, a := range s.All() {
+		if got, ok := s.LookupSlug(a.Slug()); !ok || got.IATACode != a.IATACode {
+			t.Errorf("LookupSlug(%q) = %q", a.Slug(), codeOrEmpty(got, ok))
+		}
+	}
+}
+
+func TestLookupSlugAfterChanges(t *testing.T) {
+	s := loadTestStore(t)
+	lhr, _ := s.LookupIATA("LHR")
+	renamed := lhr.Clone()
+	renamed.Name = "Heathrow"
+	if err := s.Put(renamed); err != nil {
+		t.Fatal(err)
+	}
+	snap := s.Snapshot()
+	if _, err := s.Remove("LGW"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ApplyDelta(Delta{Upsert: []*Airport{testAirport("QQQ", "London Qwerty Airport")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		store *Store
+		slug  string
+		want  string
+	}{
+		{s, "london-heathrow-lhr", ""},
+		{s, "heathrow-lhr", "LHR"},
+		{s, "london-gatwick-lgw", ""},
+		{s, "london-qwerty-qqq", "QQQ"},
+		{snap, "heathrow-lhr", "LHR"},
+		{snap, "london-gatwick-lgw", "LGW"},
+		{snap, "london-qwerty-qqq", ""},
+	}
+	for _, tt := range tests {
+		a, ok := tt.store.LookupSlug(tt.slug)
+		if got := codeOrEmpty(a, ok); got != tt.want {
+			t.Errorf("LookupSlug(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+
+	if _, err := s.Replace(loadTestStore(t)); err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := s.LookupSlug("london-heathrow-lhr"); !ok || a.Name != "London Heathrow Airport" {
+		t.Errorf("after Replace: LookupSlug = %q", codeOrEmpty(a, ok))
+	}
+	s.Close()
+	if _, ok := s.LookupSlug("london-heathrow-lhr"); ok {
+		t.Error("closed store found a slug")
+	}
+}
+
+func codeOrEmpty(a *Airport, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return a.IATACode
+}
@@ -0,0 +1,312 @@
+package iataplaces
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+)
+
+// ErrReadOnly is returned when modifying a store obtained from Snapshot.
+var ErrReadOnly = errors.New("iataplaces: store is read-only")
+
+// ErrClosed is returned when modifying a store after Close.
+var ErrClosed = errors.New("iataplaces: store is closed")
+
+// Clone returns a copy of a with its own optional fields, so changing one
+// doesn't affect the other.
+func (a *Airport) Clone() *Airport {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	if a.ElevationFt != nil {
+		v := *a.ElevationFt
+		c.ElevationFt = &v
+	}
+	if a.Score != nil {
+		v := *a.Score
+		c.Score = &v
+	}
+	if a.LastUpdateTime != nil {
+		t := *a.LastUpdateTime
+		c.LastUpdateTime = &t
+	}
+	return &c
+}
+
+// Clone returns a deep copy of the store, airports and localized names
+// included, for building a modified version with Put and Remove while
+// readers keep using the original. Publish it by swapping the pointer
+// readers use once it is complete.
+func (s *Store) Clone() *Store {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var moved map[*Airport]*Airport // for the lazy row offsets
+	if s.lazy != nil {
+		moved = make(map[*Airport]*Airport, len(s.lazy.offsets))
+	}
+	clone := func(a *Airport) *Airport {
+		c := a.Clone()
+		if moved != nil {
+			moved[a] = c
+		}
+		return c
+	}
+	byIATA := make(map[string]*Airport, len(s.byIATA))
+	for code, a := range s.byIATA {
+		byIATA[code] = clone(a)
+	}
+	c := newStore(byIATA)
+	c.sourceRows = s.sourceRows
+	c.loadIssues = s.loadIssues
+	c.localized = cloneLocalized(s.localized)
+	c.copyOnReturn = s.copyOnReturn
+	c.cities = s.cities
+	c.keywords = s.keywords.clone()
+	c.indexes = cloneIndexes(s.indexes)
+	c.spatial = s.spatial.rebuild(c.sorted)
+	c.icaoList = s.icaoList.rebuild(c.sorted)
+	c.fullText = s.fullText.clone()
+	c.popularity = maps.Clone(s.popularity)
+	c.routes, c.airlines = s.routes, s.airlines
+	if s.duplicates != nil {
+		c.duplicates = make(map[string][]*Airport, len(s.duplicates))
+		for code, losers := range s.duplicates {
+			cl := make([]*Airport, len(losers))
+			for i, a := range losers {
+				cl[i] = clone(a)
+			}
+			c.duplicates[code] = cl
+		}
+	}
+	c.lazy = s.lazy.rekey(moved)
+	return c
+}
+
+// Snapshot returns a read-only view of the store as it is now. It shares
+// the airports with s but not the indexes, so later Put and Remove calls on
+// s don't show through; modifying the snapshot returns ErrReadOnly.
+func (s *Store) Snapshot() *Store {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	byIATA := make(map[string]*Airport, len(s.byIATA))
+	for code, a := range s.byIATA {
+		byIATA[code] = a
+	}
+	sorted := make([]*Airport, len(s.sorted))
+	copy(sorted, s.sorted)
+	return &Store{
+		byIATA:       byIATA,
+		byICAO:       maps.Clone(s.byICAO),
+		bySlug:       maps.Clone(s.bySlug),
+		sorted:       sorted,
+		sourceRows:   s.sourceRows,
+		loadIssues:   s.loadIssues,
+		localized:    cloneLocalized(s.localized),
+		duplicates:   cloneDuplicates(s.duplicates),
+		cities:       s.cities,
+		lazy:         s.lazy,
+		keywords:     s.keywords.clone(),
+		indexes:      cloneIndexes(s.indexes),
+		spatial:      s.spatial.clone(),
+		icaoList:     s.icaoList.clone(),
+		fullText:     s.fullText.clone(),
+		popularity:   maps.Clone(s.popularity),
+		routes:       s.routes,
+		airlines:     s.airlines,
+		readOnly:     true,
+		copyOnReturn: s.copyOnReturn,
+	}
+}
+
+// CopyOnReturn returns a read-only snapshot of the store whose LookupIATA,
+// All, Filter, Search and Nearest hand out copies of the airports, so a
+// caller changing a result can't affect anyone else. Copies cost an
+// allocation per airport returned; stores whose callers are trusted not to
+// modify results can skip it.
+func (s *Store) CopyOnReturn() *Store {
+	v := s.Snapshot()
+	if v != nil {
+		v.copyOnReturn = true
+	}
+	return v
+}
+
+// out returns a as callers of s should see it.
+func (s *Store) out(a *Airport) *Airport {
+	if s.copyOnReturn {
+		return a.Clone()
+	}
+	return a
+}
+
+// ReadOnly reports whether the store came from Snapshot.
+func (s *Store) ReadOnly() bool {
+	return s != nil && s.readOnly
+}
+
+// Put adds a copy of a to the store, replacing any airport with the same
+// IATA code. Airports already handed to readers are left untouched.
+func (s *Store) Put(a *Airport) error {
+	if a == nil {
+		return errors.New("iataplaces: nil airport")
+	}
+	code := toUpperASCII(a.IATACode)
+	if !isIATACode(code) {
+		return fmt.Errorf("iataplaces: invalid IATA code %q", a.IATACode)
+	}
+	c := a.Clone()
+	c.IATACode = code
+
+	s.mu.Lock()
+	if err := s.writable(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	old, existed := s.byIATA[code]
+	s.put(c)
+	s.mu.Unlock()
+
+	var cs ChangeSet
+	switch {
+	case !existed:
+		cs.Added = []string{code}
+	case !old.Equal(c):
+		cs.Changed = []string{code}
+	}
+	s.notify(ChangeDelta, cs)
+	return nil
+}
+
+// writable returns ErrClosed or ErrReadOnly when s may not be modified.
+// s.mu must be held.
+func (s *Store) writable() error {
+	switch {
+	case s.closed:
+		return ErrClosed
+	case s.readOnly:
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// put indexes c, whose IATA code is already normalized. s.mu must be held.
+func (s *Store) put(c *Airport) {
+	code := c.IATACode
+	i := sort.Search(len(s.sorted), func(i int) bool { return s.sorted[i].IATACode >= code })
+	old, exists := s.byIATA[code]
+	if exists {
+		s.sorted[i] = c
+	} else {
+		s.sorted = append(s.sorted, nil)
+		copy(s.sorted[i+1:], s.sorted[i:])
+		s.sorted[i] = c
+	}
+	s.byIATA[code] = c
+	if exists {
+		s.reindexICAO(old.ICAOCode)
+		delete(s.bySlug, old.Slug())
+	}
+	s.bySlug[c.Slug()] = c
+	s.reindexICAO(c.ICAOCode)
+	if s.keywords != nil {
+		s.keywords.remove(code)
+		s.keywords.add(c)
+	}
+	for _, idx := range s.indexes {
+		idx.remove(code)
+		idx.add(c)
+	}
+	s.updateWarmed(old, c)
+}
+
+// updateWarmed moves the indexes built by WarmUp from old to a, either of
+// which may be nil. s.mu must be held.
+func (s *Store) updateWarmed(old, a *Airport) {
+	if s.spatial != nil {
+		s.spatial.update(old, a)
+	}
+	if s.icaoList != nil {
+		s.icaoList.update(old, a)
+	}
+	if s.fullText != nil {
+		s.fullText.update(old, a)
+	}
+}
+
+// Remove deletes the airport with the given IATA code, with its localized
+// names, and reports whether it was present.
+func (s *Store) Remove(code string) (bool, error) {
+	code = toUpperASCII(code)
+	s.mu.Lock()
+	if err := s.writable(); err != nil {
+		s.mu.Unlock()
+		return false, err
+	}
+	removed := s.remove(code)
+	s.mu.Unlock()
+	if removed {
+		s.notify(ChangeDelta, ChangeSet{Removed: []string{code}})
+	}
+	return removed, nil
+}
+
+// remove drops the airport with the normalized code. s.mu must be held.
+func (s *Store) remove(code string) bool {
+	old, ok := s.byIATA[code]
+	if !ok {
+		return false
+	}
+	delete(s.byIATA, code)
+	i := sort.Search(len(s.sorted), func(i int) bool { return s.sorted[i].IATACode >= code })
+	s.sorted = append(s.sorted[:i], s.sorted[i+1:]...)
+	s.reindexICAO(old.ICAOCode)
+	delete(s.bySlug, old.Slug())
+	if s.keywords != nil {
+		s.keywords.remove(code)
+	}
+	for _, idx := range s.indexes {
+		idx.remove(code)
+	}
+	s.updateWarmed(old, nil)
+	for _, byCode := range s.localized {
+		delete(byCode, code)
+	}
+	delete(s.duplicates, code)
+	delete(s.popularity, code)
+	return true
+}
+
+func cloneLocalized(m map[string]map[string]LocalizedName) map[string]map[string]LocalizedName {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]map[string]LocalizedName, len(m))
+	for lang, byCode := range m {
+		c := make(map[string]LocalizedName, len(byCode))
+		for code, n := range byCode {
+			c[code] = n
+		}
+		out[lang] = c
+	}
+	return out
+}
+
+// cloneDuplicates copies the map of losing rows; the airports are shared.
+func cloneDuplicates(m map[string][]*Airport) map[string][]*Airport {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string][]*Airport, len(m))
+	for code, losers := range m {
+		out[code] = append([]*Airport(nil), losers...)
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package iataplaces
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testAirport returns a new airport with the given code, for Put and
+// ApplyDelta.
+func testAirport(code, name string) *Airport {
+	return &Airport{ID: 8000000, IATACode: code, Name: name, Type: "small_airport", LatitudeDeg: 1, LongitudeDeg: 2}
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	s := loadTestStore(t)
+	snap := s.Snapshot()
+
+	if err := s.Put(testAirport("QQQ", "New")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Remove("LHR"); err != nil {
+		t.Fatal(err)
+	}
+	jfk, _ := s.LookupIATA("JFK")
+	renamed := jfk.Clone()
+	renamed.Name = "Renamed"
+	if err := s.Put(renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := codesOf(snap.All()); !slices.Equal(got, testCodes) {
+		t.Errorf("snapshot codes = %v, want %v", got, testCodes)
+	}
+	if a, ok := snap.LookupIATA("JFK"); !ok || a.Name != "John F Kennedy International Airport" {
+		t.Errorf("snapshot JFK = %+v", a)
+	}
+	if _, ok := snap.LookupICAO("EGLL"); !ok {
+		t.Error("snapshot lost EGLL")
+	}
+	if _, ok := snap.LookupIATA("QQQ"); ok {
+		t.Error("snapshot sees QQQ")
+	}
+
+	if _, ok := s.LookupIATA("LHR"); ok {
+		t.Error("store still has LHR")
+	}
+	if _, ok := s.LookupICAO("EGLL"); ok {
+		t.Error("store still has EGLL by ICAO code")
+	}
+	if a, _ := s.LookupIATA("JFK"); a.Name != "Renamed" {
+		t.Errorf("store JFK = %q", a.Name)
+	}
+	if jfk.Name != "John F Kennedy International Airport" {
+		t.Error("Put modified an airport handed out earlier")
+	}
+}
+
+func TestPutInvalid(t *testing.T) {
+	s := loadTestStore(t)
+	tests := []struct {
+		name string
+		a    *Airport
+		err  string
+	}{
+		{"nil airport", nil, "nil airport"},
+		{"invalid code", testAirport("Q1", "Bad"), `invalid IATA code "Q1"`},
+		{"no code", testAirport("", "None"), `invalid IATA code ""`},
+	}
+	for _, tt := range tests {
+		err := s.Put(tt.a)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+	if got := s.Len(); got != len(testCodes) {
+		t.Errorf("Len() = %d after rejected Puts, want %d", got, len(testCodes))
+	}
+}
+
+func TestSnapshotReadOnly(t *testing.T) {
+	s := loadTestStore(t)
+	for name, view := range map[string]*Store{"Snapshot": s.Snapshot(), "CopyOnReturn": s.CopyOnReturn()} {
+		t.Run(name, func(t *testing.T) {
+			if !view.ReadOnly() {
+				t.Fatal("ReadOnly() = false")
+			}
+			mutators := []struct {
+				name string
+				fn   func() error
+			}{
+				{"Put", func() error { return view.Put(testAirport("QQQ", "New")) }},
+				{"Remove", func() error { _, err := view.Remove("LHR"); return err }},
+				{"ApplyDelta", func() error { _, err := view.ApplyDelta(Delta{Remove: []string{"LHR"}}); return err }},
+				{"Replace", func() error { _, err := view.Replace(loadTestStore(t)); return err }},
+			}
+			for _, m := range mutators {
+				if err := m.fn(); !errors.Is(err, ErrReadOnly) {
+					t.Errorf("%s: err = %v, want ErrReadOnly", m.name, err)
+				}
+			}
+			if got := codesOf(view.All()); !slices.Equal(got, testCodes) {
+				t.Errorf("codes = %v after rejected changes", got)
+			}
+		})
+	}
+	if s.ReadOnly() {
+		t.Error("original store is read-only")
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := loadTestStore(t)
+	c := s.Clone()
+	if c.ReadOnly() {
+		t.Fatal("clone is read-only")
+	}
+
+	orig, _ := s.LookupIATA("CDG")
+	copied, _ := c.LookupIATA("CDG")
+	if orig == copied || !orig.Equal(copied) {
+		t.Fatalf("clone airport %p %+v, original %p %+v", copied, copied, orig, orig)
+	}
+
+	if _, err := c.Remove("CDG"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(testAirport("QQQ", "New")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.LookupIATA("CDG"); !ok {
+		t.Error("removing from the clone removed from the original")
+	}
+	if _, ok := c.LookupIATA("QQQ"); ok {
+		t.Error("adding to the original added to the clone")
+	}
+	if got, want := c.Len(), len(testCodes)-1; got != want {
+		t.Errorf("clone Len() = %d, want %d", got, want)
+	}
+}
+
+func TestCopyOnReturn(t *testing.T) {
+	s := loadTestStore(t)
+	view := s.CopyOnReturn()
+	a, _ := view.LookupIATA("MUC")
+	a.Name = "Changed"
+	*a.ElevationFt = 0
+	if b, _ := view.LookupIATA("MUC"); b.Name != "Munich Airport" || *b.ElevationFt != 1487 {
+		t.Errorf("change to a returned copy showed through: %q %d", b.Name, *b.ElevationFt)
+	}
+	if b, _ := s.LookupIATA("MUC"); b.Name != "Munich Airport" {
+		t.Errorf("change to a returned copy reached the store: %q", b.Name)
+	}
+	for _, a := range view.All() {
+		a.Name = "Changed"
+	}
+	if b, _ := view.LookupIATA("LHR"); b.Name != "London Heathrow Airport" {
+		t.Errorf("change to All's copies showed through: %q", b.Name)
+	}
+}
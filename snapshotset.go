@@ -0,0 +1,172 @@
+package iataplaces
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoSnapshotAt is returned (wrapped) when a SnapshotSet has no snapshot
+// taken at or before the requested time.
+var ErrNoSnapshotAt = errors.New("iataplaces: no snapshot at or before that time")
+
+// snapshotFileLayout is the UTC timestamp in the updater's
+// airports-<timestamp>.csv file names.
+const snapshotFileLayout = "20060102-150405"
+
+// snapshotSetCache is how many loaded stores a SnapshotSet keeps; queries
+// usually cluster around a few dates, and each store holds a full dataset.
+const snapshotSetCache = 4
+
+// SnapshotSet answers point-in-time queries over a directory of the
+// timestamped airports-YYYYMMDD-HHMMSS.csv snapshots airports-update
+// keeps, so historical analyses see an airport as it was on a given date.
+// Plain and gzip-compressed snapshots are read; zstd ones are skipped.
+// Snapshots are loaded on first use and the most recently used few are
+// kept in memory. It is safe for concurrent use.
+type SnapshotSet struct {
+	dir  string
+	opts []LoadOption
+
+	mu     sync.Mutex
+	files  []snapshotFile // oldest first
+	loaded []*loadedSnapshot
+}
+
+type snapshotFile struct {
+	path  string
+	taken time.Time
+}
+
+type loadedSnapshot struct {
+	path  string
+	store *Store
+}
+
+// OpenSnapshotSet lists the airports snapshots in dir. opts apply to every
+// snapshot it loads.
+func OpenSnapshotSet(dir string, opts ...LoadOption) (*SnapshotSet, error) {
+	set := &SnapshotSet{dir: dir, opts: opts}
+	if err := set.Rescan(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// Rescan re-reads the directory listing, picking up snapshots saved since
+// the set was opened and dropping deleted ones.
+func (set *SnapshotSet) Rescan() error {
+	entries, err := os.ReadDir(set.dir)
+	if err != nil {
+		return fmt.Errorf("iataplaces: list snapshots: %w", err)
+	}
+	var files []snapshotFile
+	for _, e := range entries {
+		stem, ok := strings.CutPrefix(e.Name(), "airports-")
+		if !ok {
+			continue
+		}
+		stem = strings.TrimSuffix(stem, ".gz")
+		stem, ok = strings.CutSuffix(stem, ".csv")
+		if !ok {
+			continue
+		}
+		taken, err := time.Parse(snapshotFileLayout, stem)
+		if err != nil {
+			continue // airports-latest.csv and other files
+		}
+		files = append(files, snapshotFile{path: filepath.Join(set.dir, e.Name()), taken: taken})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].taken.Before(files[j].taken) })
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.files = files
+	return nil
+}
+
+// Times returns when each snapshot was taken, oldest first.
+func (set *SnapshotSet) Times() []time.Time {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	out := make([]time.Time, len(set.files))
+	for i, f := range set.files {
+		out[i] = f.taken
+	}
+	return out
+}
+
+// StoreAsOf returns the store of the newest snapshot taken at or before
+// at, and when that snapshot was taken.
+func (set *SnapshotSet) StoreAsOf(at time.Time) (*Store, time.Time, error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	i := sort.Search(len(set.files), func(i int) bool { return set.files[i].taken.After(at) }) - 1
+	if i < 0 {
+		return nil, time.Time{}, fmt.Errorf("%w: %s", ErrNoSnapshotAt, at.UTC().Format(time.RFC3339))
+	}
+	f := set.files[i]
+	for j, l := range set.loaded {
+		if l.path == f.path {
+			copy(set.loaded[1:j+1], set.loaded[:j])
+			set.loaded[0] = l
+			return l.store, f.taken, nil
+		}
+	}
+
+	store, err := loadSnapshotFile(f.path, set.opts)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	set.loaded = append([]*loadedSnapshot{{path: f.path, store: store}}, set.loaded...)
+	if len(set.loaded) > snapshotSetCache {
+		set.loaded = set.loaded[:snapshotSetCache]
+	}
+	return store, f.taken, nil
+}
+
+// LookupIATAAsOf returns the airport with the given IATA code as recorded
+// in the newest snapshot taken at or before at, e.g. the date of a
+// booking. It wraps ErrUnknownCode when that snapshot lacks the code.
+func (set *SnapshotSet) LookupIATAAsOf(code string, at time.Time) (*Airport, error) {
+	store, _, err := set.StoreAsOf(at)
+	if err != nil {
+		return nil, err
+	}
+	a, ok := store.LookupIATA(code)
+	if !ok {
+		return nil, fmt.Errorf("iataplaces: %w %q", ErrUnknownCode, code)
+	}
+	return a, nil
+}
+
+func loadSnapshotFile(path string, opts []LoadOption) (*Store, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("iataplaces: open snapshot %s: %w", filepath.Base(path), err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	store, err := LoadFromReader(r, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("iataplaces: load snapshot %s: %w", filepath.Base(path), err)
+	}
+	return store, nil
+}
@@ -0,0 +1,150 @@
+package iataplaces
+
+import (
+	"cmp"
+	"container/heap"
+	"math"
+	"slices"
+	"sort"
+)
+
+// orderedIndex keeps airports sorted for range scans. It backs
+// IndexSpatial and IndexICAOPrefix. Like s.sorted it is modified in place
+// by put and remove, so stores don't share it.
+type orderedIndex struct {
+	cmp     func(a, b *Airport) int
+	include func(*Airport) bool // whether an airport belongs in the index
+	list    []*Airport
+}
+
+func newOrderedIndex(cmp func(a, b *Airport) int, include func(*Airport) bool, airports []*Airport) *orderedIndex {
+	o := &orderedIndex{cmp: cmp, include: include}
+	for _, a := range airports {
+		if include(a) {
+			o.list = append(o.list, a)
+		}
+	}
+	slices.SortFunc(o.list, cmp)
+	return o
+}
+
+// update moves the index from old to a, either of which may be nil.
+func (o *orderedIndex) update(old, a *Airport) {
+	if old != nil && o.include(old) {
+		if i, ok := slices.BinarySearchFunc(o.list, old, o.cmp); ok {
+			o.list = slices.Delete(o.list, i, i+1)
+		}
+	}
+	if a != nil && o.include(a) {
+		i, _ := slices.BinarySearchFunc(o.list, a, o.cmp)
+		o.list = slices.Insert(o.list, i, a)
+	}
+}
+
+func (o *orderedIndex) clone() *orderedIndex {
+	if o == nil {
+		return nil
+	}
+	return &orderedIndex{cmp: o.cmp, include: o.include, list: slices.Clone(o.list)}
+}
+
+// rebuild returns a fresh index of the same kind over airports, for
+// stores whose airports were replaced wholesale.
+func (o *orderedIndex) rebuild(airports []*Airport) *orderedIndex {
+	if o == nil {
+		return nil
+	}
+	return newOrderedIndex(o.cmp, o.include, airports)
+}
+
+func newSpatialIndex(airports []*Airport) *orderedIndex {
+	return newOrderedIndex(byLatitude, func(a *Airport) bool {
+		return !math.IsNaN(a.LatitudeDeg) && !math.IsNaN(a.LongitudeDeg)
+	}, airports)
+}
+
+// byLatitude orders airports south to north, then by IATA code.
+func byLatitude(a, b *Airport) int {
+	if c := cmp.Compare(a.LatitudeDeg, b.LatitudeDeg); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.IATACode, b.IATACode)
+}
+
+// nearest is Nearest over a spatial index. It walks outward from lat in
+// both directions and stops once the latitude difference alone puts every
+// remaining airport further away than the n-th nearest found, or than
+// cfg.maxKm. Results are ordered like Nearest's scan: by distance, then
+// IATA code. n <= 0 needs cfg.maxKm. s.mu must be held.
+func (o *orderedIndex) nearest(lat, lon float64, n int, cfg *nearestConfig) []NearbyAirport {
+	list := o.list
+	hi := sort.Search(len(list), func(i int) bool { return list[i].LatitudeDeg >= lat })
+	lo := hi - 1
+	var found nearbyHeap
+	for lo >= 0 || hi < len(list) {
+		var a *Airport
+		if hi >= len(list) || (lo >= 0 && lat-list[lo].LatitudeDeg < list[hi].LatitudeDeg-lat) {
+			a = list[lo]
+			lo--
+		} else {
+			a = list[hi]
+			hi++
+		}
+		// No great circle is shorter than the arc along a meridian; the
+		// factor allows for rounding in DistanceKm.
+		bound := radians(math.Abs(a.LatitudeDeg-lat)) * earthRadiusKm * (1 - 1e-9)
+		if cfg.maxKm > 0 && bound > cfg.maxKm {
+			break
+		}
+		if n > 0 && len(found) == n && bound > found[0].DistanceKm {
+			break
+		}
+		if !cfg.keep(a) {
+			continue
+		}
+		d := DistanceKm(lat, lon, a.LatitudeDeg, a.LongitudeDeg)
+		if cfg.maxKm > 0 && d > cfg.maxKm {
+			continue
+		}
+		nb := NearbyAirport{Airport: a, DistanceKm: d}
+		switch {
+		case n <= 0 || len(found) < n:
+			heap.Push(&found, nb)
+		case nearbyLess(nb, found[0]):
+			found[0] = nb
+			heap.Fix(&found, 0)
+		}
+	}
+	out := []NearbyAirport(found)
+	slices.SortFunc(out, func(a, b NearbyAirport) int {
+		if nearbyLess(a, b) {
+			return -1
+		}
+		if nearbyLess(b, a) {
+			return 1
+		}
+		return 0
+	})
+	return out
+}
+
+func nearbyLess(a, b NearbyAirport) bool {
+	if a.DistanceKm != b.DistanceKm {
+		return a.DistanceKm < b.DistanceKm
+	}
+	return a.Airport.IATACode < b.Airport.IATACode
+}
+
+// nearbyHeap keeps the furthest of the airports found so far on top.
+type nearbyHeap []NearbyAirport
+
+func (h nearbyHeap) Len() int           { return len(h) }
+func (h nearbyHeap) Less(i, j int) bool { return nearbyLess(h[j], h[i]) }
+func (h nearbyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nearbyHeap) Push(x any)        { *h = append(*h, x.(NearbyAirport)) }
+func (h *nearbyHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,421 @@
+package iataplaces
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// WriteSQLite writes airports as a SQLite 3 database file holding table and
+// its iata_code index, with the same schema WriteSQL creates, so the output
+// opens directly in sqlite3 or any SQLite driver. The id column is the
+// rowid, so ids must be unique, and so must non-empty IATA codes. table
+// must be a plain identifier, as for WriteSQL.
+func WriteSQLite(w io.Writer, airports []*Airport, table string) error {
+	if !isSQLIdentifier(table) {
+		return fmt.Errorf("iataplaces: invalid SQL table name %q", table)
+	}
+	rows := make([]*Airport, len(airports))
+	copy(rows, airports)
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+	for i := 1; i < len(rows); i++ {
+		if rows[i].ID == rows[i-1].ID {
+			return fmt.Errorf("iataplaces: WriteSQLite: %s and %s share id %d", rows[i-1].Ident, rows[i].Ident, rows[i].ID)
+		}
+	}
+	keys := make([]*Airport, len(rows))
+	copy(keys, rows)
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].IATACode < keys[j].IATACode })
+	for i := 1; i < len(keys); i++ {
+		if code := keys[i].IATACode; code != "" && code == keys[i-1].IATACode {
+			return fmt.Errorf("iataplaces: WriteSQLite: %s and %s share IATA code %s", keys[i-1].Ident, keys[i].Ident, code)
+		}
+	}
+
+	f := &sqliteFile{}
+	f.alloc() // page 1 holds the file header and the schema table
+
+	tableCells := make([]sqliteLeafCell, len(rows))
+	for i, a := range rows {
+		rec := csvRecord(a)
+		vals := make([]any, len(rec))
+		for j, v := range rec {
+			vals[j] = sqliteValue(CSVColumns[j], v)
+		}
+		tableCells[i] = sqliteLeafCell{rowid: a.ID, body: f.tableLeafCell(a.ID, sqliteRecord(vals))}
+	}
+	tableRoot := f.tableTree(tableCells)
+
+	indexCells := make([][]byte, len(keys))
+	for i, a := range keys {
+		var code any
+		if a.IATACode != "" {
+			code = a.IATACode
+		}
+		indexCells[i] = f.indexCell(sqliteRecord([]any{code, a.ID}))
+	}
+	indexRoot := f.indexTree(indexCells)
+
+	createTable, createIndex := sqlSchema(table)
+	schema := []sqliteLeafCell{
+		{rowid: 1, body: sqliteRecord([]any{"table", table, table, int64(tableRoot), createTable})},
+		{rowid: 2, body: sqliteRecord([]any{"index", table + "_iata_code", table, int64(indexRoot), createIndex})},
+	}
+	cells := make([][]byte, len(schema))
+	for i, c := range schema {
+		cells[i] = f.tableLeafCell(c.rowid, c.body)
+	}
+	if !f.fits(1, false, cells) {
+		return fmt.Errorf("iataplaces: WriteSQLite: schema for table %q does not fit on the first page", table)
+	}
+	f.writePage(1, sqliteLeafTable, cells, 0)
+	f.writeHeader()
+
+	for _, p := range f.pages {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	sqlitePageSize = 4096
+
+	// B-tree page types.
+	sqliteInteriorIndex = 0x02
+	sqliteInteriorTable = 0x05
+	sqliteLeafIndex     = 0x0a
+	sqliteLeafTable     = 0x0d
+
+	// sqliteVersion is the library version recorded in the file header.
+	sqliteVersion = 3046000
+)
+
+// Payload limits from the SQLite file format: the most a cell keeps on its
+// page before spilling to overflow pages, and the least it keeps once it
+// spills.
+const (
+	sqliteMaxLocalTable = sqlitePageSize - 35
+	sqliteMaxLocalIndex = (sqlitePageSize-12)*64/255 - 23
+	sqliteMinLocal      = (sqlitePageSize-12)*32/255 - 23
+)
+
+// sqliteFile accumulates the pages of a database file; page n is pages[n-1].
+type sqliteFile struct {
+	pages [][]byte
+}
+
+// sqliteLeafCell is an encoded table leaf cell with its rowid.
+type sqliteLeafCell struct {
+	rowid int64
+	body  []byte
+}
+
+// sqliteChild is an entry of an interior level: a child page and the key
+// that follows it, which is a rowid varint for tables and a whole index
+// cell for indexes.
+type sqliteChild struct {
+	page uint32
+	key  []byte
+}
+
+func (f *sqliteFile) alloc() uint32 {
+	f.pages = append(f.pages, make([]byte, sqlitePageSize))
+	return uint32(len(f.pages))
+}
+
+// tableLeafCell encodes a table leaf cell for payload, spilling to overflow
+// pages if it is too large.
+func (f *sqliteFile) tableLeafCell(rowid int64, payload []byte) []byte {
+	cell := appendSqliteVarint(nil, uint64(len(payload)))
+	cell = appendSqliteVarint(cell, uint64(rowid))
+	return f.appendPayload(cell, payload, sqliteMaxLocalTable)
+}
+
+// indexCell encodes the payload part of an index cell; interior cells put
+// the child page number in front of it.
+func (f *sqliteFile) indexCell(payload []byte) []byte {
+	cell := appendSqliteVarint(nil, uint64(len(payload)))
+	return f.appendPayload(cell, payload, sqliteMaxLocalIndex)
+}
+
+// appendPayload appends as much of payload as fits locally to cell and
+// writes the rest to a chain of overflow pages.
+func (f *sqliteFile) appendPayload(cell, payload []byte, maxLocal int) []byte {
+	if len(payload) <= maxLocal {
+		return append(cell, payload...)
+	}
+	local := sqliteMinLocal + (len(payload)-sqliteMinLocal)%(sqlitePageSize-4)
+	if local > maxLocal {
+		local = sqliteMinLocal
+	}
+	cell = append(cell, payload[:local]...)
+	rest := payload[local:]
+	first := f.alloc()
+	for pg := first; ; {
+		n := copy(f.pages[pg-1][4:], rest)
+		rest = rest[n:]
+		if len(rest) == 0 {
+			break
+		}
+		next := f.alloc()
+		binary.BigEndian.PutUint32(f.pages[pg-1], next)
+		pg = next
+	}
+	return binary.BigEndian.AppendUint32(cell, first)
+}
+
+// tableTree writes the leaves of a table b-tree, whose cells must be in
+// rowid order, and the interior pages above them, returning the root page.
+func (f *sqliteFile) tableTree(cells []sqliteLeafCell) uint32 {
+	var children []sqliteChild
+	for {
+		n := 0
+		var page [][]byte
+		for n < len(cells) && f.fits(0, false, append(page, cells[n].body)) {
+			page = append(page, cells[n].body)
+			n++
+		}
+		pg := f.alloc()
+		f.writePage(pg, sqliteLeafTable, page, 0)
+		var key []byte
+		if n > 0 {
+			key = appendSqliteVarint(nil, uint64(cells[n-1].rowid))
+		}
+		children = append(children, sqliteChild{page: pg, key: key})
+		cells = cells[n:]
+		if len(cells) == 0 {
+			break
+		}
+	}
+	return f.interior(sqliteInteriorTable, children)
+}
+
+// indexTree writes an index b-tree from cells in key order and returns its
+// root page. Unlike a table, each index entry is stored exactly once, so the
+// entry that ends a leaf moves up to separate it from the next.
+func (f *sqliteFile) indexTree(cells [][]byte) uint32 {
+	var children []sqliteChild
+	for {
+		n := 0
+		for n < len(cells) && f.fits(0, false, cells[:n+1]) {
+			n++
+		}
+		pg := f.alloc()
+		if n == len(cells) {
+			f.writePage(pg, sqliteLeafIndex, cells, 0)
+			children = append(children, sqliteChild{page: pg})
+			break
+		}
+		if n == len(cells)-1 {
+			n-- // keep the last leaf from being empty
+		}
+		f.writePage(pg, sqliteLeafIndex, cells[:n], 0)
+		children = append(children, sqliteChild{page: pg, key: cells[n]})
+		cells = cells[n+1:]
+	}
+	return f.interior(sqliteInteriorIndex, children)
+}
+
+// interior builds interior levels over children until one page remains.
+// Each page takes a run of children as cells, keyed by the key that follows
+// each one, plus one more as its right-most child, whose key moves up a
+// level to follow the page itself.
+func (f *sqliteFile) interior(kind byte, children []sqliteChild) uint32 {
+	for len(children) > 1 {
+		var parents []sqliteChild
+		for len(children) > 0 {
+			var cells [][]byte
+			for len(cells) < len(children)-1 {
+				cell := binary.BigEndian.AppendUint32(nil, children[len(cells)].page)
+				cell = append(cell, children[len(cells)].key...)
+				if !f.fits(0, true, append(cells, cell)) {
+					break
+				}
+				cells = append(cells, cell)
+			}
+			n := len(cells)
+			if n == len(children)-2 {
+				n-- // keep the last page from having no cells
+				cells = cells[:n]
+			}
+			pg := f.alloc()
+			f.writePage(pg, kind, cells, children[n].page)
+			parents = append(parents, sqliteChild{page: pg, key: children[n].key})
+			children = children[n+1:]
+		}
+		children = parents
+	}
+	return children[0].page
+}
+
+// fits reports whether cells fit on page pgno, whose header starts after
+// the file header on page 1.
+func (f *sqliteFile) fits(pgno uint32, interior bool, cells [][]byte) bool {
+	used := sqlitePageHeader(pgno, interior)
+	for _, c := range cells {
+		used += len(c) + 2
+	}
+	return used <= sqlitePageSize
+}
+
+func sqlitePageHeader(pgno uint32, interior bool) int {
+	n := 8
+	if interior {
+		n = 12
+	}
+	if pgno == 1 {
+		n += 100
+	}
+	return n
+}
+
+// writePage lays out a b-tree page: the page header and cell pointer array
+// at the top, the cells packed against the end of the page.
+func (f *sqliteFile) writePage(pgno uint32, kind byte, cells [][]byte, right uint32) {
+	p := f.pages[pgno-1]
+	hdr := 0
+	if pgno == 1 {
+		hdr = 100
+	}
+	interior := kind == sqliteInteriorIndex || kind == sqliteInteriorTable
+	ptr := hdr + sqlitePageHeader(0, interior)
+	end := len(p)
+	for i, c := range cells {
+		end -= len(c)
+		copy(p[end:], c)
+		binary.BigEndian.PutUint16(p[ptr+2*i:], uint16(end))
+	}
+	p[hdr] = kind
+	binary.BigEndian.PutUint16(p[hdr+3:], uint16(len(cells)))
+	binary.BigEndian.PutUint16(p[hdr+5:], uint16(end))
+	if interior {
+		binary.BigEndian.PutUint32(p[hdr+8:], right)
+	}
+}
+
+// writeHeader fills in the 100-byte database header on page 1.
+func (f *sqliteFile) writeHeader() {
+	h := f.pages[0][:100]
+	copy(h, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(h[16:], sqlitePageSize)
+	h[18], h[19] = 1, 1 // rollback journal
+	h[21], h[22], h[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(h[24:], 1) // file change counter
+	binary.BigEndian.PutUint32(h[28:], uint32(len(f.pages)))
+	binary.BigEndian.PutUint32(h[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(h[44:], 4) // schema format
+	binary.BigEndian.PutUint32(h[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(h[92:], 1)
+	binary.BigEndian.PutUint32(h[96:], sqliteVersion)
+}
+
+// sqliteValue converts a CSV value to the value SQLite would store for it
+// under the column's type affinity, as if inserted by WriteSQL's script.
+// The id column is the rowid, which the record leaves NULL.
+func sqliteValue(col, v string) any {
+	if v == "" || col == "id" {
+		return nil
+	}
+	switch sqlColumnTypes[col] {
+	case "INTEGER":
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+		fallthrough
+	case "REAL":
+		if fv, err := strconv.ParseFloat(v, 64); err == nil {
+			if math.IsNaN(fv) || math.IsInf(fv, 0) {
+				return nil
+			}
+			return fv
+		}
+	}
+	return v
+}
+
+// sqliteRecord encodes values (nil, int64, float64 or string) in the SQLite
+// record format: a header of serial types followed by the values.
+func sqliteRecord(vals []any) []byte {
+	var types, body []byte
+	for _, v := range vals {
+		switch v := v.(type) {
+		case nil:
+			types = appendSqliteVarint(types, 0)
+		case int64:
+			typ, size := sqliteIntType(v)
+			types = appendSqliteVarint(types, typ)
+			for i := size - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(8*i)))
+			}
+		case float64:
+			types = appendSqliteVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendSqliteVarint(types, uint64(2*len(v)+13))
+			body = append(body, v...)
+		}
+	}
+	// The header length counts its own varint.
+	n := len(types) + 1
+	for len(appendSqliteVarint(nil, uint64(n))) != n-len(types) {
+		n = len(types) + len(appendSqliteVarint(nil, uint64(n)))
+	}
+	rec := appendSqliteVarint(nil, uint64(n))
+	rec = append(rec, types...)
+	return append(rec, body...)
+}
+
+// sqliteIntType returns the serial type and byte size of the smallest
+// integer encoding for v.
+func sqliteIntType(v int64) (typ uint64, size int) {
+	switch {
+	case v == 0:
+		return 8, 0
+	case v == 1:
+		return 9, 0
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return 1, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2, 2
+	case v >= -1<<23 && v < 1<<23:
+		return 3, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4, 4
+	case v >= -1<<47 && v < 1<<47:
+		return 5, 6
+	}
+	return 6, 8
+}
+
+// appendSqliteVarint appends v as a SQLite varint: big-endian groups of
+// seven bits with the high bit set on all but the last byte, except that a
+// ninth byte carries a full eight bits.
+func appendSqliteVarint(b []byte, v uint64) []byte {
+	if v&0xff00000000000000 != 0 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf)
+	for {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	buf[len(buf)-1] &= 0x7f
+	return append(b, buf[i:]...)
+}
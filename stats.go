@@ -0,0 +1,81 @@
+package iataplaces
+
+import (
+	"slices"
+	"time"
+)
+
+// Stats summarizes the contents of a store.
+type Stats struct {
+	Airports    int            `json:"airports"`
+	SourceRows  int            `json:"source_rows"` // CSV rows read, with or without IATA codes
+	LoadIssues  int            `json:"load_issues"` // values that didn't parse, see Store.LoadIssues
+	Scheduled   int            `json:"scheduled"`
+	WithICAO    int            `json:"with_icao"`
+	ByType      map[string]int `json:"by_type"`
+	ByCountry   map[string]int `json:"by_country"`
+	ByContinent map[string]int `json:"by_continent"`
+
+	// Oldest and newest last_updated values among the indexed airports.
+	OldestUpdate *time.Time `json:"oldest_update,omitempty"`
+	NewestUpdate *time.Time `json:"newest_update,omitempty"`
+}
+
+// IATACoverage is the fraction of source rows that carry an IATA code.
+func (st Stats) IATACoverage() float64 {
+	if st.SourceRows == 0 {
+		return 0
+	}
+	return float64(st.Airports) / float64(st.SourceRows)
+}
+
+// Stats computes counts by type, country and continent plus freshness
+// information for the store.
+func (s *Store) Stats() Stats {
+	st := Stats{
+		ByType:      map[string]int{},
+		ByCountry:   map[string]int{},
+		ByContinent: map[string]int{},
+	}
+	if s == nil {
+		return st
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st.Airports = len(s.sorted)
+	st.SourceRows = s.sourceRows
+	st.LoadIssues = len(s.loadIssues)
+	for _, a := range s.sorted {
+		st.ByType[a.Type]++
+		st.ByCountry[a.IsoCountry]++
+		st.ByContinent[a.Continent]++
+		if a.Scheduled {
+			st.Scheduled++
+		}
+		if a.ICAOCode != "" {
+			st.WithICAO++
+		}
+		if t := a.LastUpdateTime; t != nil {
+			if st.OldestUpdate == nil || t.Before(*st.OldestUpdate) {
+				st.OldestUpdate = t
+			}
+			if st.NewestUpdate == nil || t.After(*st.NewestUpdate) {
+				st.NewestUpdate = t
+			}
+		}
+	}
+	return st
+}
+
+// LoadIssues lists the values that didn't parse when the store was loaded
+// from CSV, such as last_updated timestamps in an unknown format. The
+// fields they were in are left empty; the rest of the row loads.
+func (s *Store) LoadIssues() []Issue {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.loadIssues)
+}
@@ -0,0 +1,264 @@
+package iataplaces
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadStream parses an airports CSV like LoadFromReader and calls fn with
+// each airport in file order, without building a store, so ETL jobs get
+// the same parsing and normalization with flat memory use. Unlike a store
+// it yields airports without an IATA code too, and every row sharing a
+// code. Rows LoadFromReader would skip (no or a malformed id) are skipped
+// here as well. An error from fn stops the parse and is returned as is.
+func LoadStream(r io.Reader, fn func(Airport) error, opts ...LoadOption) error {
+	cfg, err := newLoadConfig(opts)
+	if err != nil {
+		return err
+	}
+	_, err = parseCSV(r, cfg, func(a *Airport, _ rowInfo) error {
+		return fn(*a)
+	})
+	return err
+}
+
+// rowInfo describes where a parsed airport came from.
+type rowInfo struct {
+	hasCoords bool           // latitude and longitude parsed
+	offset    int64          // byte offset of the row in the input
+	columns   map[string]int // CSVColumns name -> field index, shared by all rows
+	issues    []Issue        // values that didn't parse and were left empty
+}
+
+// parseCSV reads an airports CSV and calls fn for every usable row. It
+// returns the number of data rows read.
+func parseCSV(r io.Reader, cfg loadConfig, fn func(a *Airport, row rowInfo) error) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // allow variable length lines
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("read header: %w", err)
+	}
+	if cfg.onHeader != nil {
+		if err := cfg.onHeader(header); err != nil {
+			return 0, err
+		}
+	}
+
+	colIndex := make(map[string]int, len(header))
+	names := make([]string, len(header)) // column name of each field
+	for i, col := range header {
+		col = strings.TrimSpace(col)
+		if mapped, ok := cfg.columns[col]; ok {
+			col = mapped
+		}
+		colIndex[col] = i
+		names[i] = col
+	}
+	_, hasID := colIndex["id"]
+	if cfg.lazy && !hasID {
+		return 0, fmt.Errorf("WithLazyFields needs an id column")
+	}
+	if cfg.lazy && len(cfg.transforms) > 0 {
+		return 0, fmt.Errorf("WithLazyFields can't be combined with WithRowTransform")
+	}
+
+	// transform runs rec through cfg.transforms and returns the resulting
+	// fields, or nil to drop the row. Columns a transform adds are given
+	// indexes past the header's.
+	transform := func(rec []string) []string {
+		record := make(map[string]string, len(names))
+		for i, v := range rec {
+			if i < len(names) {
+				record[names[i]] = v
+			}
+		}
+		for _, fn := range cfg.transforms {
+			if record = fn(record); record == nil {
+				return nil
+			}
+		}
+		for col := range record {
+			if _, ok := colIndex[col]; !ok {
+				colIndex[col] = len(names)
+				names = append(names, col)
+			}
+		}
+		out := make([]string, len(names))
+		for i, col := range names {
+			out[i] = record[col]
+		}
+		return out
+	}
+
+	get := func(rec []string, col string) string {
+		idx, ok := colIndex[col]
+		if !ok || idx >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[idx])
+	}
+
+	rows := 0
+
+	for {
+		offset := reader.InputOffset()
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("read record: %w", err)
+		}
+		rows++
+		if len(cfg.transforms) > 0 {
+			if rec = transform(rec); rec == nil {
+				continue
+			}
+		}
+
+		// Files without an id column (see WithColumnMapping) load with
+		// zero IDs; in OurAirports files a row without one is skipped.
+		var id int64
+		if hasID {
+			idStr := get(rec, "id")
+			if idStr == "" {
+				continue
+			}
+			id, err = strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				// Skip bad rows rather than failing the whole load.
+				continue
+			}
+		}
+
+		lat, errLat := strconv.ParseFloat(get(rec, "latitude_deg"), 64)
+		lon, errLon := strconv.ParseFloat(get(rec, "longitude_deg"), 64)
+
+		var elev *int64
+		if ev := get(rec, "elevation_ft"); ev != "" {
+			if v, err := strconv.ParseInt(ev, 10, 64); err == nil {
+				elev = &v
+			}
+		}
+
+		var score *int64
+		if sc := get(rec, "score"); sc != "" {
+			if v, err := strconv.ParseInt(sc, 10, 64); err == nil {
+				score = &v
+			}
+		}
+
+		var issues []Issue
+		var lastUpdated *time.Time
+		if lu := get(rec, "last_updated"); lu != "" {
+			if t, ok := parseTimestamp(lu); ok {
+				lastUpdated = &t
+			} else {
+				line, _ := reader.FieldPos(0)
+				issues = append(issues, Issue{
+					Line:     line,
+					IATACode: strings.ToUpper(get(rec, "iata_code")),
+					Field:    "last_updated",
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("unparseable timestamp %q", lu),
+				})
+			}
+		}
+
+		sched := false
+		if ss := strings.ToLower(get(rec, "scheduled_service")); ss == "1" || ss == "yes" || ss == "true" {
+			sched = true
+		}
+
+		iata := strings.ToUpper(strings.TrimSpace(get(rec, "iata_code")))
+
+		airport := &Airport{
+			ID:             id,
+			Ident:          get(rec, "ident"),
+			Type:           get(rec, "type"),
+			Name:           get(rec, "name"), // csv.Reader already unquotes
+			LatitudeDeg:    lat,
+			LongitudeDeg:   lon,
+			ElevationFt:    elev,
+			Continent:      get(rec, "continent"),
+			CountryName:    get(rec, "country_name"),
+			IsoCountry:     get(rec, "iso_country"),
+			RegionName:     get(rec, "region_name"),
+			IsoRegion:      get(rec, "iso_region"),
+			LocalRegion:    get(rec, "local_region"),
+			Municipality:   get(rec, "municipality"),
+			Scheduled:      sched,
+			GPSCode:        get(rec, "gps_code"),
+			ICAOCode:       get(rec, "icao_code"),
+			IATACode:       iata,
+			LocalCode:      get(rec, "local_code"),
+			HomeLink:       get(rec, "home_link"),
+			WikipediaLink:  get(rec, "wikipedia_link"),
+			Keywords:       get(rec, "keywords"),
+			Score:          score,
+			LastUpdateTime: lastUpdated,
+		}
+		if cfg.raw {
+			fields := rec
+			if len(cfg.transforms) > 0 {
+				fields = rec[:len(header)] // without added columns
+			}
+			airport.raw = &rawRecord{header: header, fields: fields}
+		}
+		if len(cfg.enrichers) > 0 {
+			for _, enrich := range cfg.enrichers {
+				if err := enrich(airport); err != nil {
+					line, _ := reader.FieldPos(0)
+					return rows, fmt.Errorf("enrich line %d (%s): %w", line, airport.Ident, err)
+				}
+			}
+			airport.IATACode = strings.ToUpper(strings.TrimSpace(airport.IATACode))
+		}
+
+		row := rowInfo{hasCoords: errLat == nil && errLon == nil, offset: offset, columns: colIndex, issues: issues}
+		if err := fn(airport, row); err != nil {
+			return rows, err
+		}
+	}
+	return rows, nil
+
+}
+
+// timestampLayouts are the last_updated formats seen in OurAirports data,
+// tried in order. Values without a zone are UTC.
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05-0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// ParseTimestamp parses a last_updated value the way loading does,
+// accepting RFC 3339 and the other layouts seen in OurAirports data, such
+// as "2006-01-02 15:04:05" or a bare date. Values without a zone are UTC.
+func ParseTimestamp(v string) (time.Time, error) {
+	if t, ok := parseTimestamp(v); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("iataplaces: unrecognized timestamp %q", v)
+}
+
+// parseTimestamp parses a last_updated value in any of timestampLayouts.
+func parseTimestamp(v string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
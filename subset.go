@@ -0,0 +1,111 @@
+package iataplaces
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Subset selects the rows of a trimmed dataset, e.g. the scheduled
+// airports of Europe for a mobile build. Empty lists don't filter; within
+// a list any value matches.
+type Subset struct {
+	Continents []string // continent codes, e.g. "EU"
+	Countries  []string // ISO 3166-1 alpha-2 codes
+	Types      []string // e.g. "large_airport"
+	Scheduled  bool     // only airports with scheduled service
+	IATAOnly   bool     // only rows with an IATA code
+}
+
+// ParseSubset parses the form String returns: semicolon-separated terms
+// continent=, country= and type= with comma-separated values, plus the
+// flags scheduled and iata, e.g. "continent=EU;type=large_airport,medium_airport;scheduled".
+func ParseSubset(spec string) (Subset, error) {
+	var f Subset
+	for _, term := range strings.Split(spec, ";") {
+		term = strings.TrimSpace(term)
+		key, value, hasValue := strings.Cut(term, "=")
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		switch {
+		case term == "":
+		case key == "continent" && hasValue:
+			f.Continents = append(f.Continents, values...)
+		case key == "country" && hasValue:
+			f.Countries = append(f.Countries, values...)
+		case key == "type" && hasValue:
+			f.Types = append(f.Types, values...)
+		case term == "scheduled":
+			f.Scheduled = true
+		case term == "iata":
+			f.IATAOnly = true
+		default:
+			return Subset{}, fmt.Errorf("iataplaces: invalid subset term %q", term)
+		}
+	}
+	return f, nil
+}
+
+// String renders f in the form ParseSubset reads.
+func (f Subset) String() string {
+	var terms []string
+	for _, t := range []struct {
+		key    string
+		values []string
+	}{{"continent", f.Continents}, {"country", f.Countries}, {"type", f.Types}} {
+		if len(t.values) > 0 {
+			terms = append(terms, t.key+"="+strings.Join(t.values, ","))
+		}
+	}
+	if f.Scheduled {
+		terms = append(terms, "scheduled")
+	}
+	if f.IATAOnly {
+		terms = append(terms, "iata")
+	}
+	return strings.Join(terms, ";")
+}
+
+// Match reports whether a belongs to the subset.
+func (f Subset) Match(a *Airport) bool {
+	inList := func(list []string, v string) bool {
+		return len(list) == 0 || slices.ContainsFunc(list, func(s string) bool { return strings.EqualFold(s, v) })
+	}
+	return inList(f.Continents, a.Continent) &&
+		inList(f.Countries, a.IsoCountry) &&
+		inList(f.Types, a.Type) &&
+		(!f.Scheduled || a.Scheduled) &&
+		(!f.IATAOnly || a.IATACode != "")
+}
+
+// WriteSubset copies the header and the rows of the airports CSV r that
+// match f to w, unchanged, so the result loads like the full file. It
+// returns the number of rows kept.
+func WriteSubset(w io.Writer, r io.Reader, f Subset, opts ...LoadOption) (int, error) {
+	cfg, err := newLoadConfig(opts)
+	if err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	cfg.raw = true
+	cfg.onHeader = cw.Write
+	kept := 0
+	_, err = parseCSV(r, cfg, func(a *Airport, _ rowInfo) error {
+		if !f.Match(a) {
+			return nil
+		}
+		kept++
+		return cw.Write(a.raw.fields)
+	})
+	if err != nil {
+		return kept, err
+	}
+	cw.Flush()
+	return kept, cw.Error()
+}
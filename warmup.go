@@ -0,0 +1,161 @@
+package iataplaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// IndexKind names an optional index Store.WarmUp can build.
+type IndexKind int
+
+const (
+	// IndexKeywords is the keyword index WithKeywordIndex builds while
+	// loading, for Store.LookupKeyword and keyword ranking in Search.
+	IndexKeywords IndexKind = iota + 1
+
+	// IndexSpatial orders airports by latitude so Nearest, and the
+	// queries built on it, only measure the airports near the point
+	// instead of all of them. It is used when Nearest has a limit or a
+	// WithinKm bound.
+	IndexSpatial
+
+	// IndexICAOPrefix orders airports by ICAO code so ByICAOPrefix reads
+	// just the matching range.
+	IndexICAOPrefix
+
+	// IndexFullText maps three-character substrings of the fields Search
+	// matches text against to airports, so Search only scores airports
+	// containing the query text. Queries shorter than three bytes still
+	// scan every airport.
+	IndexFullText
+)
+
+func (k IndexKind) String() string {
+	switch k {
+	case IndexKeywords:
+		return "keywords"
+	case IndexSpatial:
+		return "spatial"
+	case IndexICAOPrefix:
+		return "icao-prefix"
+	case IndexFullText:
+		return "full-text"
+	}
+	return fmt.Sprintf("IndexKind(%d)", int(k))
+}
+
+// WarmUp builds the given optional indexes of a loaded store, each in its
+// own goroutine, and returns once all are in place. Lookups and queries
+// run meanwhile and take up an index as soon as it is ready, so a service
+// can load without the indexes, start serving, and call
+//
+//	go store.WarmUp(ctx, iataplaces.IndexKeywords, iataplaces.IndexSpatial)
+//
+// Built indexes follow later Put, Remove, ApplyDelta and Replace calls
+// and are carried over by Clone and Snapshot. Indexes the store already
+// has are left as they are. Lazily loaded stores only have keywords to
+// index when loaded WithKeywordIndex. If ctx is done before an index is
+// ready, it is discarded and WarmUp returns ctx's error.
+func (s *Store) WarmUp(ctx context.Context, indexes ...IndexKind) error {
+	if s == nil {
+		return nil
+	}
+	errs := make([]error, len(indexes))
+	var wg sync.WaitGroup
+	for i, kind := range indexes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch kind {
+			case IndexKeywords:
+				errs[i] = s.warmKeywords(ctx)
+			case IndexSpatial:
+				errs[i] = s.warmOrdered(ctx, &s.spatial, newSpatialIndex)
+			case IndexICAOPrefix:
+				errs[i] = s.warmOrdered(ctx, &s.icaoList, newICAOIndex)
+			case IndexFullText:
+				errs[i] = s.warmFullText(ctx)
+			default:
+				errs[i] = fmt.Errorf("iataplaces: unknown index %v", kind)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *Store) warmKeywords(ctx context.Context) error {
+	s.mu.RLock()
+	lazy := s.lazy != nil && s.keywords == nil
+	s.mu.RUnlock()
+	if lazy {
+		return errors.New("iataplaces: keywords of a lazily loaded store can only be indexed with WithKeywordIndex")
+	}
+	var idx *keyIndex
+	return s.warm(ctx, func() bool { return s.keywords != nil },
+		func(airports []*Airport) { idx = newKeyIndex(airportKeywords, airports) },
+		func(old, a *Airport) { idx.update(old, a) },
+		func() { s.keywords = idx })
+}
+
+func (s *Store) warmFullText(ctx context.Context) error {
+	var idx *keyIndex
+	return s.warm(ctx, func() bool { return s.fullText != nil },
+		func(airports []*Airport) { idx = newKeyIndex(airportTrigrams, airports) },
+		func(old, a *Airport) { idx.update(old, a) },
+		func() { s.fullText = idx })
+}
+
+// warmOrdered builds the ordered index *field points at with build.
+func (s *Store) warmOrdered(ctx context.Context, field **orderedIndex, build func([]*Airport) *orderedIndex) error {
+	var idx *orderedIndex
+	return s.warm(ctx, func() bool { return *field != nil },
+		func(airports []*Airport) { idx = build(airports) },
+		func(old, a *Airport) { idx.update(old, a) },
+		func() { *field = idx })
+}
+
+// warm builds an index with build from a copy of the airports without
+// holding the lock, then, under the lock, catches up with any Put or
+// Remove made in the meantime through update(old, a) and installs it.
+// built reports whether the store already has the index.
+func (s *Store) warm(ctx context.Context, built func() bool, build func([]*Airport), update func(old, a *Airport), install func()) error {
+	s.mu.RLock()
+	if built() || s.closed {
+		s.mu.RUnlock()
+		return nil
+	}
+	airports := make([]*Airport, len(s.sorted))
+	copy(airports, s.sorted)
+	s.mu.RUnlock()
+	base := make(map[string]*Airport, len(airports))
+	for _, a := range airports {
+		base[a.IATACode] = a
+	}
+
+	build(airports)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if built() || s.closed {
+		return nil
+	}
+	// Airports are replaced, never modified in place, so a changed
+	// pointer means a Put since the copy was taken.
+	for _, a := range s.sorted {
+		if old := base[a.IATACode]; old != a {
+			update(old, a)
+		}
+		delete(base, a.IATACode)
+	}
+	for _, old := range base {
+		update(old, nil)
+	}
+	install()
+	return nil
+}
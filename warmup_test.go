@@ -0,0 +1,199 @@
+package iataplaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+var allIndexes = []IndexKind{IndexKeywords, IndexSpatial, IndexICAOPrefix, IndexFullText}
+
+// loadFullStore loads the bundled dataset, which is large enough for the
+// indexes to matter.
+func loadFullStore(t testing.TB, opts ...LoadOption) *Store {
+	t.Helper()
+	s, err := LoadFromFile("data/airports-latest.csv", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// sameResults compares the queries WarmUp's indexes speed up on a store
+// without them and one with them. The keyword index changes Search's
+// ranking, so scan should be loaded WithKeywordIndex.
+func sameResults(t *testing.T, scan, indexed *Store) {
+	t.Helper()
+	nearest := []struct {
+		lat, lon float64
+		n        int
+		opts     []NearestOption
+	}{
+		{51.5, -0.12, 5, nil},
+		{51.5, -0.12, 1, []NearestOption{MajorOnly()}},
+		{40.7, -74.0, 10, []NearestOption{InCountries("US")}},
+		{46.2, 6.14, 3, []NearestOption{InCountries("FR")}},
+		{0, 0, 3, nil},
+		{89.9, 0, 4, nil},
+		{-77.8, 166.7, 2, nil},
+		{35.6, 139.7, 0, []NearestOption{WithinKm(80)}},
+		{35.6, 139.7, 2, []NearestOption{WithinKm(5)}},
+		{-33.9, 151.2, 20, []NearestOption{OfType("large_airport", "medium_airport"), WithinKm(2000)}},
+		{10, -179.9, 3, nil}, // nearest airports are across the antimeridian
+	}
+	for _, q := range nearest {
+		want, got := scan.Nearest(q.lat, q.lon, q.n, q.opts...), indexed.Nearest(q.lat, q.lon, q.n, q.opts...)
+		if !reflect.DeepEqual(nearbyCodes(got), nearbyCodes(want)) {
+			t.Errorf("Nearest(%v, %v, %d) = %v, scan gives %v", q.lat, q.lon, q.n, nearbyCodes(got), nearbyCodes(want))
+		}
+	}
+
+	for _, prefix := range []string{"E", "EG", "egl", "K", "KJF", "Y", "ZZZZ", ""} {
+		want, got := codesOf(scan.ByICAOPrefix(prefix)), codesOf(indexed.ByICAOPrefix(prefix))
+		if !slices.Equal(got, want) {
+			t.Errorf("ByICAOPrefix(%q) = %d airports, scan gives %d", prefix, len(got), len(want))
+		}
+	}
+
+	for _, text := range []string{"london", "Heathrow", "new york", "lhr", "ab", "x", "zzzzq", "aéro", "san j", "international airport", "TYO"} {
+		want, got := scan.Search(SearchQuery{Text: text}), indexed.Search(SearchQuery{Text: text})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Search(%q) = %d results, scan gives %d", text, len(got), len(want))
+		}
+	}
+}
+
+func nearbyCodes(nb []NearbyAirport) []string {
+	codes := make([]string, len(nb))
+	for i, n := range nb {
+		codes[i] = fmt.Sprintf("%s %.6f", n.Airport.IATACode, n.DistanceKm)
+	}
+	return codes
+}
+
+func TestWarmUpMatchesScan(t *testing.T) {
+	scan := loadFullStore(t, WithKeywordIndex())
+	indexed := loadFullStore(t)
+	if err := indexed.WarmUp(context.Background(), allIndexes...); err != nil {
+		t.Fatal(err)
+	}
+	sameResults(t, scan, indexed)
+
+	t.Run("after changes", func(t *testing.T) {
+		lhr, _ := scan.LookupIATA("LHR")
+		moved := lhr.Clone()
+		moved.LatitudeDeg, moved.LongitudeDeg, moved.ICAOCode, moved.Name = 0.01, 0.01, "ZZZZ", "Null Island"
+		delta := Delta{
+			Upsert: []*Airport{moved, testAirport("QQQ", "London Qwerty")},
+			Remove: []string{"LGW", "JFK"},
+		}
+		for _, s := range []*Store{scan, indexed} {
+			if _, err := s.ApplyDelta(delta); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Remove("CDG"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sameResults(t, scan, indexed)
+	})
+	t.Run("Snapshot", func(t *testing.T) { sameResults(t, scan, indexed.Snapshot()) })
+	t.Run("Clone", func(t *testing.T) { sameResults(t, scan, indexed.Clone()) })
+	t.Run("Replace", func(t *testing.T) {
+		next := loadFullStore(t, WithKeywordIndex())
+		if _, err := indexed.Replace(next); err != nil {
+			t.Fatal(err)
+		}
+		sameResults(t, next, indexed)
+	})
+}
+
+// TestWarmUpConcurrent builds the indexes while the store is being
+// changed; run it with -race. Changes made during the build must reach
+// the indexes.
+func TestWarmUpConcurrent(t *testing.T) {
+	scan := loadFullStore(t, WithKeywordIndex())
+	indexed := loadFullStore(t)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			code := fmt.Sprintf("Q%c%c", 'A'+i/26%26, 'A'+i%26)
+			if err := indexed.Put(testAirport(code, "Concurrent "+code)); err != nil {
+				t.Error(err)
+				return
+			}
+			if i%3 == 0 {
+				if _, err := indexed.Remove(code); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			indexed.Nearest(1, 2, 3)
+			indexed.Search(SearchQuery{Text: "concurrent", Limit: 3})
+		}
+	}()
+	if err := indexed.WarmUp(context.Background(), allIndexes...); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	if _, err := scan.Replace(indexed); err != nil {
+		t.Fatal(err)
+	}
+	sameResults(t, scan, indexed)
+}
+
+func TestWarmUpErrors(t *testing.T) {
+	s := loadTestStore(t)
+	if err := s.WarmUp(context.Background(), IndexSpatial, IndexKind(99)); err == nil {
+		t.Error("WarmUp accepted an unknown index")
+	}
+	if s.spatial == nil {
+		t.Error("WarmUp skipped the known index next to an unknown one")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.WarmUp(ctx, IndexICAOPrefix, IndexFullText); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if s.icaoList != nil || s.fullText != nil {
+		t.Error("WarmUp installed an index after its context was canceled")
+	}
+
+	lazy, err := LoadFromFile(writeTestCSV(t), WithLazyFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lazy.WarmUp(context.Background(), IndexKeywords); err == nil {
+		t.Error("WarmUp indexed the keywords of a lazily loaded store")
+	}
+	if err := (*Store)(nil).WarmUp(context.Background(), allIndexes...); err != nil {
+		t.Errorf("nil WarmUp: %v", err)
+	}
+}
+
+func TestIndexKindString(t *testing.T) {
+	tests := []struct {
+		kind IndexKind
+		want string
+	}{
+		{IndexKeywords, "keywords"},
+		{IndexSpatial, "spatial"},
+		{IndexICAOPrefix, "icao-prefix"},
+		{IndexFullText, "full-text"},
+		{IndexKind(0), "IndexKind(0)"},
+	}
+	for _, tt := range tests {
+		if got := tt.kind.String(); got != tt.want {
+			t.Errorf("%d.String() = %q, want %q", int(tt.kind), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package iataplaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoWeatherStation is returned when an airport has no identifier a
+// weather service would know it by.
+var ErrNoWeatherStation = errors.New("iataplaces: airport has no weather station identifier")
+
+// WeatherStation returns the identifier METAR and TAF reports for a are
+// filed under: the ICAO code, or a four-letter GPS code when the ICAO code
+// is missing. It is empty for airports without either.
+func (a *Airport) WeatherStation() string {
+	if a == nil {
+		return ""
+	}
+	if a.ICAOCode != "" {
+		return a.ICAOCode
+	}
+	if isStationID(a.GPSCode) {
+		return a.GPSCode
+	}
+	return ""
+}
+
+// isStationID reports whether s looks like a four-letter ICAO location
+// indicator.
+func isStationID(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && i > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// WeatherReport is current weather for a station as returned by a
+// WeatherProvider. Fields a provider doesn't supply stay empty.
+type WeatherReport struct {
+	Station  string     `json:"station"`
+	METAR    string     `json:"metar,omitempty"` // raw report text
+	TAF      string     `json:"taf,omitempty"`
+	Observed *time.Time `json:"observed,omitempty"`
+}
+
+// WeatherProvider fetches reports by station identifier. Implement it over
+// whatever METAR/TAF source you use; this package ships none, so it never
+// makes network calls of its own.
+type WeatherProvider interface {
+	Weather(ctx context.Context, station string) (WeatherReport, error)
+}
+
+// WeatherProviderFunc adapts a function to WeatherProvider.
+type WeatherProviderFunc func(ctx context.Context, station string) (WeatherReport, error)
+
+// Weather calls f.
+func (f WeatherProviderFunc) Weather(ctx context.Context, station string) (WeatherReport, error) {
+	return f(ctx, station)
+}
+
+// AirportWeather asks p for the weather at the airport with the given IATA
+// code, via its WeatherStation.
+func (s *Store) AirportWeather(ctx context.Context, p WeatherProvider, code string) (WeatherReport, error) {
+	a, ok := s.LookupIATA(code)
+	if !ok {
+		return WeatherReport{}, fmt.Errorf("iataplaces: %w %q", ErrUnknownCode, code)
+	}
+	station := a.WeatherStation()
+	if station == "" {
+		return WeatherReport{}, fmt.Errorf("%w: %s", ErrNoWeatherStation, a.IATACode)
+	}
+	r, err := p.Weather(ctx, station)
+	if err != nil {
+		return WeatherReport{}, fmt.Errorf("iataplaces: weather for %s (%s): %w", a.IATACode, station, err)
+	}
+	if r.Station == "" {
+		r.Station = station
+	}
+	return r, nil
+}